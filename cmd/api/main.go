@@ -3,17 +3,20 @@ package main
 import (
 	"context"
 	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/config"
 	"dewarrum/vocabulary-leveling/internal/server"
-	"dewarrum/vocabulary-leveling/internal/subtitles"
-	"dewarrum/vocabulary-leveling/internal/videos"
+	"dewarrum/vocabulary-leveling/internal/storage"
+	"dewarrum/vocabulary-leveling/internal/worker"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gofiber/contrib/fiberzerolog"
 	"github.com/gofiber/contrib/otelfiber"
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -23,13 +26,28 @@ import (
 )
 
 func main() {
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	exporters := flag.Bool("exporters", true, "run the video and subtitles exporters in the API process")
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML file with settings, overridden by the environment")
+	metricsAddress := flag.String("metrics-address", ":9464", "address the Prometheus scrape endpoint listens on, empty to disable it")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
 	godotenv.Load(".env")
 	godotenv.Load(".env.secret")
 
-	dependencies, err := app.NewDependencies(ctx)
+	// Without the exporters nothing in the process consumes the messages it
+	// publishes.
+	sections := config.ApiSections
+	sections.MemoryBus = *exporters
+	cfg, err := config.Load(*configPath, sections)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	dependencies, err := app.NewDependencies(cfg, ctx)
 	if err != nil {
 		dependencies.Logger.Fatal().Err(err).Msg("Failed to create dependencies")
 		panic(err)
@@ -48,68 +66,113 @@ func main() {
 		panic(err)
 	}
 
-	videoExporter, err := videos.NewExporter(dependencies)
-	if err != nil {
-		dependencies.Logger.Fatal().Err(err).Msg("Failed to create video exporter")
-		panic(err)
-	}
-	err = videoExporter.Run(ctx)
-	if err != nil {
-		dependencies.Logger.Fatal().Err(err).Msg("Failed to run video exporter")
-		panic(err)
-	}
-
-	subtitlesExporter, err := subtitles.NewExporter(dependencies, ctx)
-	if err != nil {
-		dependencies.Logger.Fatal().Err(err).Msg("Failed to create subtitles exporter")
-		panic(err)
-	}
-	err = subtitlesExporter.Run(ctx)
-	if err != nil {
-		dependencies.Logger.Fatal().Err(err).Msg("Failed to run subtitles exporter")
-		panic(err)
+	var exporterWorker *worker.Worker
+	if *exporters {
+		exporterWorker, err = worker.Start(dependencies, worker.DefaultOptions, ctx)
+		if err != nil {
+			dependencies.Logger.Fatal().Err(err).Msg("Failed to start exporters")
+			panic(err)
+		}
+	} else {
+		dependencies.Logger.Info().Msg("Exporters are disabled, videos are exported by the worker")
 	}
 
 	app := fiber.New(fiber.Config{
-		BodyLimit: 500 * 1024 * 1024,
+		BodyLimit:         cfg.Server.BodyLimit,
+		StreamRequestBody: true,
+		ErrorHandler:      srv.ErrorHandler,
 	})
 	app.Use(fiberzerolog.New(fiberzerolog.Config{
 		Logger: &dependencies.Logger,
 	}))
-	app.Use(otelfiber.Middleware())
-
-	api := app.Group("/api", srv.RequireAuthenticationMiddleware())
-	api.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-	}))
-
-	srv.VideosManifest(api)
-	srv.SubtitlesSearch(api)
-
-	adminApi := api.Group("/admin", srv.RequireAuthorizationMiddleware("Admin"))
-	srv.VideosUpload(adminApi)
-
-	authApi := app.Group("/auth")
-
-	srv.Profile(authApi)
-	srv.SignIn(authApi)
-	srv.SignInCallback(authApi)
-	srv.SignOut(authApi)
+	app.Use(otelfiber.Middleware(otelfiber.WithNext(func(c *fiber.Ctx) bool {
+		switch c.Path() {
+		case server.HealthzPath, server.ReadyzPath:
+			return true
+		default:
+			return false
+		}
+	})))
+
+	localStore, _ := dependencies.ObjectStore.(*storage.LocalStore)
+	if dependencies.S3Client == nil {
+		dependencies.Logger.Warn().Msg("Resumable uploads require the s3 storage backend and are disabled")
+	}
+	srv.Routes(app, server.RouteOptions{
+		CorsOrigins: cfg.Server.CorsOrigins,
+		Tus:         dependencies.S3Client != nil,
+		LocalStore:  localStore,
+	})
 
-	if os.Getenv("ENVIRONMENT") != "development" {
+	if cfg.Server.Environment != config.EnvironmentDevelopment {
 		app.Static("/", "./web/build")
 		app.Get("/*", func(c *fiber.Ctx) error {
 			return c.SendFile("./web/build/index.html")
 		})
 	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		panic("PORT is required")
+	err = server.CheckApiDocument(server.ApiDocument(), app.GetRoutes(true))
+	if err != nil {
+		dependencies.Logger.Fatal().Err(err).Msg("Routes are missing from the OpenAPI document")
+	}
+
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- app.Listen(fmt.Sprintf(":%d", cfg.Server.Port))
+	}()
+
+	// Metrics are served on a port of their own, which is not exposed to the
+	// public, so that scrapers need no session. The api keeps serving requests
+	// when the metrics port is taken.
+	var metricsApp *fiber.App
+	if *metricsAddress != "" && dependencies.MetricsHandler != nil {
+		metricsApp = fiber.New(fiber.Config{DisableStartupMessage: true})
+		srv.Metrics(metricsApp, dependencies.MetricsHandler)
+		go func() {
+			err := metricsApp.Listen(*metricsAddress)
+			if err != nil {
+				dependencies.Logger.Error().Err(err).Str("address", *metricsAddress).Msg("Failed to serve metrics")
+			}
+		}()
 	}
 
-	if err := app.Listen(fmt.Sprintf(":%s", port)); err != nil {
+	select {
+	case err := <-listenErr:
 		dependencies.Logger.Fatal().Err(err).Msg("Failed to start server")
+	case <-ctx.Done():
+	}
+	// A second signal terminates the process right away.
+	stop()
+
+	shutdown(app, srv, exporterWorker, cfg.Shutdown.GracePeriod, dependencies)
+	if metricsApp != nil {
+		metricsApp.Shutdown()
+	}
+}
+
+// shutdown stops accepting requests and waits for the requests, clips and
+// exports in flight, all within gracePeriod. Dependencies are closed by main
+// afterwards.
+func shutdown(fiberApp *fiber.App, srv *server.Server, exporterWorker *worker.Worker, gracePeriod time.Duration, dependencies *app.Dependencies) {
+	dependencies.Logger.Info().Dur("gracePeriod", gracePeriod).Msg("Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+
+	err := fiberApp.ShutdownWithContext(ctx)
+	if err != nil {
+		dependencies.Logger.Error().Err(err).Msg("Failed to finish requests in flight")
+	}
+
+	err = srv.Clips.Shutdown(ctx)
+	if err != nil {
+		dependencies.Logger.Error().Err(err).Msg("Failed to finish clips in flight")
+	}
+
+	if exporterWorker != nil {
+		err = exporterWorker.Shutdown(ctx)
+		if err != nil {
+			dependencies.Logger.Error().Err(err).Msg("Failed to finish exports in flight")
+		}
 	}
 }
 
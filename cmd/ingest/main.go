@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/config"
+	"dewarrum/vocabulary-leveling/internal/ingest"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	directory := flag.String("dir", "", "media library directory to ingest")
+	watch := flag.Bool("watch", false, "keep watching the directory for new videos")
+	interval := flag.Duration("interval", time.Minute, "how often to rescan the directory in watch mode")
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML file with settings, overridden by the environment")
+	flag.Parse()
+
+	if *directory == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	godotenv.Load(".env")
+	godotenv.Load(".env.secret")
+
+	cfg, err := config.Load(*configPath, config.IngestSections)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	dependencies, err := app.NewDependencies(cfg, ctx)
+	if err != nil {
+		dependencies.Logger.Fatal().Err(err).Msg("Failed to create dependencies")
+		panic(err)
+	}
+	defer dependencies.Close(ctx)
+
+	ingester, err := ingest.NewIngester(dependencies)
+	if err != nil {
+		dependencies.Logger.Fatal().Err(err).Msg("Failed to create ingester")
+		panic(err)
+	}
+
+	if *watch {
+		dependencies.Logger.Info().Str("directory", *directory).Dur("interval", *interval).Msg("Watching media library")
+		err = ingester.Watch(*directory, *interval, ctx)
+	} else {
+		dependencies.Logger.Info().Str("directory", *directory).Msg("Ingesting media library")
+		err = ingester.Run(*directory, ctx)
+	}
+	if err != nil {
+		dependencies.Logger.Error().Err(err).Msg("Failed to ingest media library")
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"dewarrum/vocabulary-leveling/internal/server"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// openapi writes the OpenAPI document and the TypeScript client generated from
+// it into the web app. It needs no configuration, both only depend on the
+// routes.
+func main() {
+	directory := flag.String("dir", "web/src/lib/api", "directory to write openapi.json and client.ts to")
+	flag.Parse()
+
+	document := server.ApiDocument()
+	encoded, err := document.Encode()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	err = os.WriteFile(filepath.Join(*directory, "openapi.json"), encoded, 0o644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	err = os.WriteFile(filepath.Join(*directory, "client.ts"), document.TypeScript(), 0o644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
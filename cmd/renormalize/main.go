@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/config"
+	"dewarrum/vocabulary-leveling/internal/subtitles"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/joho/godotenv"
+)
+
+// renormalize normalizes the text of the subtitles stored before migration
+// 000017 and indexes them again. It has to run once after migrating.
+func main() {
+	batchSize := flag.Int("batch-size", 500, "number of subtitles read at a time")
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML file with settings, overridden by the environment")
+	flag.Parse()
+
+	if *batchSize < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	godotenv.Load(".env")
+	godotenv.Load(".env.secret")
+
+	cfg, err := config.Load(*configPath, config.RenormalizeSections)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	dependencies, err := app.NewDependencies(cfg, ctx)
+	if err != nil {
+		dependencies.Logger.Fatal().Err(err).Msg("Failed to create dependencies")
+		panic(err)
+	}
+	defer dependencies.Close(ctx)
+
+	renormalizer, err := subtitles.NewRenormalizer(dependencies, ctx)
+	if err != nil {
+		dependencies.Logger.Fatal().Err(err).Msg("Failed to create renormalizer")
+		panic(err)
+	}
+
+	dependencies.Logger.Info().Int("batchSize", *batchSize).Msg("Renormalizing subtitles")
+	renormalized, err := renormalizer.Run(*batchSize, ctx)
+	if err != nil {
+		dependencies.Logger.Error().Err(err).Int("renormalized", renormalized).Msg("Failed to renormalize subtitles")
+		os.Exit(1)
+	}
+	dependencies.Logger.Info().Int("renormalized", renormalized).Msg("Renormalized subtitles")
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/config"
+	"dewarrum/vocabulary-leveling/internal/health"
+	"dewarrum/vocabulary-leveling/internal/worker"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	videoConcurrency := flag.Int("video-concurrency", 1, "number of videos exported in parallel")
+	videoPrefetch := flag.Int("video-prefetch", 0, "number of unacknowledged video messages to prefetch, defaults to the concurrency")
+	subtitlesConcurrency := flag.Int("subtitles-concurrency", 4, "number of subtitle tracks exported in parallel")
+	subtitlesPrefetch := flag.Int("subtitles-prefetch", 0, "number of unacknowledged subtitles messages to prefetch, defaults to the concurrency")
+	metricsAddress := flag.String("metrics-address", ":9465", "address the Prometheus scrape endpoint and the health probes listen on, empty to disable them")
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML file with settings, overridden by the environment")
+	flag.Parse()
+
+	if *videoConcurrency < 1 || *subtitlesConcurrency < 1 || *videoPrefetch < 0 || *subtitlesPrefetch < 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *videoPrefetch == 0 {
+		*videoPrefetch = *videoConcurrency
+	}
+	if *subtitlesPrefetch == 0 {
+		*subtitlesPrefetch = *subtitlesConcurrency
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	godotenv.Load(".env")
+	godotenv.Load(".env.secret")
+
+	cfg, err := config.Load(*configPath, config.WorkerSections)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	dependencies, err := app.NewDependencies(cfg, ctx)
+	if err != nil {
+		dependencies.Logger.Fatal().Err(err).Msg("Failed to create dependencies")
+		panic(err)
+	}
+	defer dependencies.Close(ctx)
+
+	exporterWorker, err := worker.Start(dependencies, worker.Options{
+		VideoConcurrency:     *videoConcurrency,
+		VideoPrefetch:        *videoPrefetch,
+		SubtitlesConcurrency: *subtitlesConcurrency,
+		SubtitlesPrefetch:    *subtitlesPrefetch,
+	}, ctx)
+	if err != nil {
+		dependencies.Logger.Fatal().Err(err).Msg("Failed to start exporters")
+		panic(err)
+	}
+
+	var metricsServer *http.Server
+	if *metricsAddress != "" {
+		metricsServer = serveMetrics(*metricsAddress, dependencies)
+	}
+
+	dependencies.Logger.Info().Msg("Worker started")
+	<-ctx.Done()
+	// A second signal terminates the process right away.
+	stop()
+	dependencies.Logger.Info().Dur("gracePeriod", cfg.Shutdown.GracePeriod).Msg("Worker stopping")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.GracePeriod)
+	defer cancel()
+
+	err = exporterWorker.Shutdown(shutdownCtx)
+	if err != nil {
+		dependencies.Logger.Error().Err(err).Msg("Failed to finish exports in flight")
+	}
+
+	// The probes stay up while the exports drain.
+	if metricsServer != nil {
+		metricsServer.Shutdown(shutdownCtx)
+	}
+}
+
+func serveMetrics(address string, dependencies *app.Dependencies) *http.Server {
+	mux := http.NewServeMux()
+	if dependencies.MetricsHandler != nil {
+		mux.Handle("/metrics", dependencies.MetricsHandler)
+	}
+
+	checker := health.NewChecker(dependencies)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		report := checker.Check(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		if !report.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"status": report.Status()})
+	})
+
+	server := &http.Server{Addr: address, Handler: mux}
+	go func() {
+		dependencies.Logger.Info().Str("address", address).Msg("Serving metrics")
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			dependencies.Logger.Error().Err(err).Msg("Failed to serve metrics")
+		}
+	}()
+
+	return server
+}
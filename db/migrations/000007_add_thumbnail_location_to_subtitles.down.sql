@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE subtitles DROP COLUMN IF EXISTS thumbnail_location;
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE subtitles ADD COLUMN IF NOT EXISTS thumbnail_location TEXT NULL;
+
+COMMIT;
\ No newline at end of file
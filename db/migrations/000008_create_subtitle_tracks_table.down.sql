@@ -0,0 +1,23 @@
+BEGIN;
+
+-- Before subtitle tracks a video had a single set of subtitles, numbered by
+-- sequence. Only the subtitles of the first track of every video are kept,
+-- subtitles stored without a track first, the other tracks are dropped.
+DELETE FROM subtitles s
+WHERE s.track_id IS DISTINCT FROM (
+    SELECT kept.track_id
+    FROM subtitles kept
+    LEFT JOIN subtitle_tracks t ON t.id = kept.track_id
+    WHERE kept.video_id = s.video_id
+    ORDER BY kept.track_id IS NOT NULL, t.created_at, kept.track_id
+    LIMIT 1
+);
+
+DROP INDEX IF EXISTS udx_subtitles_video_id_track_id_sequence;
+CREATE UNIQUE INDEX udx_subtitles_video_id_sequence ON subtitles (video_id, sequence);
+
+ALTER TABLE subtitles DROP COLUMN IF EXISTS track_id;
+
+DROP TABLE IF EXISTS subtitle_tracks;
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,21 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS subtitle_tracks (
+    id UUID PRIMARY KEY NOT NULL,
+    video_id UUID NOT NULL,
+    source TEXT NOT NULL,
+    status TEXT NOT NULL,
+    language TEXT NULL,
+    stream_index INTEGER NULL,
+    codec TEXT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_subtitle_tracks_video_id ON subtitle_tracks (video_id);
+
+ALTER TABLE subtitles ADD COLUMN IF NOT EXISTS track_id UUID NULL;
+
+DROP INDEX IF EXISTS udx_subtitles_video_id_sequence;
+CREATE UNIQUE INDEX udx_subtitles_video_id_track_id_sequence ON subtitles (video_id, track_id, sequence);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE IF EXISTS uploads;
+
+COMMIT;
\ No newline at end of file
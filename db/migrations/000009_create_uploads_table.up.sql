@@ -0,0 +1,17 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS uploads (
+    id UUID PRIMARY KEY NOT NULL,
+    video_id UUID NOT NULL,
+    video_name TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    length BIGINT NOT NULL,
+    upload_offset BIGINT NOT NULL,
+    s3_upload_id TEXT NOT NULL,
+    parts_count INTEGER NOT NULL,
+    incomplete_part_size BIGINT NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    completed_at TIMESTAMP WITHOUT TIME ZONE NULL
+);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE IF EXISTS ingestions;
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,16 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS ingestions (
+    id UUID PRIMARY KEY NOT NULL,
+    path TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    video_id UUID NOT NULL,
+    series_name TEXT NULL,
+    season INTEGER NULL,
+    episode INTEGER NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+CREATE UNIQUE INDEX udx_ingestions_path_size ON ingestions (path, size);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,15 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_videos_season_id;
+
+ALTER TABLE videos DROP COLUMN IF EXISTS poster_location;
+ALTER TABLE videos DROP COLUMN IF EXISTS genres;
+ALTER TABLE videos DROP COLUMN IF EXISTS description;
+ALTER TABLE videos DROP COLUMN IF EXISTS air_year;
+ALTER TABLE videos DROP COLUMN IF EXISTS episode_number;
+ALTER TABLE videos DROP COLUMN IF EXISTS season_id;
+
+DROP TABLE IF EXISTS seasons;
+DROP TABLE IF EXISTS series;
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,34 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS series (
+    id UUID PRIMARY KEY NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NULL,
+    genres TEXT[] NOT NULL DEFAULT '{}',
+    poster_location TEXT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+CREATE UNIQUE INDEX udx_series_name ON series (name);
+
+CREATE TABLE IF NOT EXISTS seasons (
+    id UUID PRIMARY KEY NOT NULL,
+    series_id UUID NOT NULL REFERENCES series (id) ON DELETE CASCADE,
+    number INTEGER NOT NULL,
+    air_year INTEGER NULL,
+    description TEXT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+CREATE UNIQUE INDEX udx_seasons_series_id_number ON seasons (series_id, number);
+
+ALTER TABLE videos ADD COLUMN IF NOT EXISTS season_id UUID NULL REFERENCES seasons (id) ON DELETE SET NULL;
+ALTER TABLE videos ADD COLUMN IF NOT EXISTS episode_number INTEGER NULL;
+ALTER TABLE videos ADD COLUMN IF NOT EXISTS air_year INTEGER NULL;
+ALTER TABLE videos ADD COLUMN IF NOT EXISTS description TEXT NULL;
+ALTER TABLE videos ADD COLUMN IF NOT EXISTS genres TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE videos ADD COLUMN IF NOT EXISTS poster_location TEXT NULL;
+
+CREATE INDEX idx_videos_season_id ON videos (season_id);
+
+COMMIT;
\ No newline at end of file
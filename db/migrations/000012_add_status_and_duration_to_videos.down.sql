@@ -0,0 +1,13 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_subtitles_video_id;
+DROP INDEX IF EXISTS idx_videos_genres;
+DROP INDEX IF EXISTS idx_videos_status;
+DROP INDEX IF EXISTS idx_videos_duration_ms_id;
+DROP INDEX IF EXISTS idx_videos_name_id;
+DROP INDEX IF EXISTS idx_videos_created_at_id;
+
+ALTER TABLE videos DROP COLUMN IF EXISTS duration_ms;
+ALTER TABLE videos DROP COLUMN IF EXISTS status;
+
+COMMIT;
\ No newline at end of file
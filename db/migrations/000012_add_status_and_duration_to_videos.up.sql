@@ -0,0 +1,17 @@
+BEGIN;
+
+ALTER TABLE videos ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'ready';
+ALTER TABLE videos ADD COLUMN IF NOT EXISTS duration_ms BIGINT NULL;
+
+UPDATE videos SET duration_ms = (
+    SELECT MAX(chunks.end_ms) FROM chunks WHERE chunks.video_id = videos.id AND chunks.representation_id <> 'thumbnails'
+);
+
+CREATE INDEX idx_videos_created_at_id ON videos (created_at, id);
+CREATE INDEX idx_videos_name_id ON videos (name, id);
+CREATE INDEX idx_videos_duration_ms_id ON videos ((COALESCE(duration_ms, 0)), id);
+CREATE INDEX idx_videos_status ON videos (status);
+CREATE INDEX idx_videos_genres ON videos USING GIN (genres);
+CREATE INDEX idx_subtitles_video_id ON subtitles (video_id);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_videos_content_sha256;
+
+ALTER TABLE videos DROP COLUMN IF EXISTS duplicate_of;
+ALTER TABLE videos DROP COLUMN IF EXISTS fingerprint;
+ALTER TABLE videos DROP COLUMN IF EXISTS content_sha256;
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE videos ADD COLUMN IF NOT EXISTS content_sha256 TEXT NULL;
+ALTER TABLE videos ADD COLUMN IF NOT EXISTS fingerprint TEXT NULL;
+ALTER TABLE videos ADD COLUMN IF NOT EXISTS duplicate_of UUID NULL REFERENCES videos (id) ON DELETE SET NULL;
+
+CREATE INDEX idx_videos_content_sha256 ON videos (content_sha256);
+
+COMMIT;
\ No newline at end of file
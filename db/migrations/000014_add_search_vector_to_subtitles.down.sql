@@ -0,0 +1,10 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_subtitles_text_trgm;
+DROP INDEX IF EXISTS idx_subtitles_search_vector;
+
+ALTER TABLE subtitles DROP COLUMN IF EXISTS search_vector;
+
+DROP FUNCTION IF EXISTS subtitles_search_terms(TEXT);
+
+COMMIT;
\ No newline at end of file
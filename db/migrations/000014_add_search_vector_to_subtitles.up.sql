@@ -0,0 +1,38 @@
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Splits text into the terms the Postgres search index matches on. Hangul has
+-- no dictionary in Postgres, so words containing it are indexed as overlapping
+-- character bigrams, which lets a query match inside longer agglutinated words.
+CREATE OR REPLACE FUNCTION subtitles_search_terms(input TEXT) RETURNS TEXT
+LANGUAGE plpgsql IMMUTABLE PARALLEL SAFE AS $$
+DECLARE
+    word TEXT;
+    terms TEXT[] := '{}';
+BEGIN
+    FOREACH word IN ARRAY regexp_split_to_array(lower(input), '[^[:alnum:]가-힣]+') LOOP
+        IF word = '' THEN
+            CONTINUE;
+        END IF;
+
+        IF word ~ '[가-힣]' AND char_length(word) > 1 THEN
+            FOR i IN 1..char_length(word) - 1 LOOP
+                terms := terms || substr(word, i, 2);
+            END LOOP;
+        ELSE
+            terms := terms || word;
+        END IF;
+    END LOOP;
+
+    RETURN array_to_string(terms, ' ');
+END;
+$$;
+
+ALTER TABLE subtitles ADD COLUMN IF NOT EXISTS search_vector TSVECTOR
+    GENERATED ALWAYS AS (to_tsvector('simple', subtitles_search_terms(text))) STORED;
+
+CREATE INDEX idx_subtitles_search_vector ON subtitles USING GIN (search_vector);
+CREATE INDEX idx_subtitles_text_trgm ON subtitles USING GIN (text gin_trgm_ops);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,11 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS sessions (
+    key TEXT PRIMARY KEY NOT NULL,
+    value BYTEA NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NULL
+);
+
+CREATE INDEX idx_sessions_expires_at ON sessions (expires_at);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE subtitles DROP COLUMN IF EXISTS style;
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE subtitles ADD COLUMN IF NOT EXISTS style JSONB NULL;
+
+COMMIT;
\ No newline at end of file
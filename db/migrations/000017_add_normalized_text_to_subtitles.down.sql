@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE subtitles DROP COLUMN IF EXISTS non_speech;
+ALTER TABLE subtitles DROP COLUMN IF EXISTS utterances;
+ALTER TABLE subtitles DROP COLUMN IF EXISTS raw_text;
+
+COMMIT;
\ No newline at end of file
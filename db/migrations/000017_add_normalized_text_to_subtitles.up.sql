@@ -0,0 +1,13 @@
+BEGIN;
+
+-- text holds the normalized text that search indexes, raw_text the text as
+-- shown on screen. Subtitles stored before were not normalized, cmd/renormalize
+-- normalizes them and has to run once after this migration.
+ALTER TABLE subtitles ADD COLUMN IF NOT EXISTS raw_text TEXT NULL;
+UPDATE subtitles SET raw_text = text WHERE raw_text IS NULL;
+ALTER TABLE subtitles ALTER COLUMN raw_text SET NOT NULL;
+
+ALTER TABLE subtitles ADD COLUMN IF NOT EXISTS utterances JSONB NULL;
+ALTER TABLE subtitles ADD COLUMN IF NOT EXISTS non_speech BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMIT;
\ No newline at end of file
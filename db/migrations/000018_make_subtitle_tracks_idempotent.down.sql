@@ -0,0 +1,10 @@
+BEGIN;
+
+UPDATE subtitle_tracks SET status = 'queued' WHERE status IN ('ready', 'failed');
+
+DROP INDEX IF EXISTS udx_subtitles_video_id_track_id_sequence;
+CREATE UNIQUE INDEX udx_subtitles_video_id_track_id_sequence ON subtitles (video_id, track_id, sequence);
+
+DROP INDEX IF EXISTS udx_subtitle_tracks_video_id_stream_index;
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,30 @@
+BEGIN;
+
+-- Redelivered export messages extracted the embedded streams again into new
+-- tracks. Only the first track of every stream is kept.
+CREATE TEMPORARY TABLE duplicate_subtitle_tracks ON COMMIT DROP AS
+    SELECT id FROM subtitle_tracks track
+    WHERE source = 'embedded' AND EXISTS (
+        SELECT 1 FROM subtitle_tracks other
+        WHERE other.video_id = track.video_id
+            AND other.source = 'embedded'
+            AND other.stream_index = track.stream_index
+            AND (other.created_at, other.id) < (track.created_at, track.id)
+    );
+
+DELETE FROM subtitles WHERE track_id IN (SELECT id FROM duplicate_subtitle_tracks);
+DELETE FROM subtitle_tracks WHERE id IN (SELECT id FROM duplicate_subtitle_tracks);
+
+CREATE UNIQUE INDEX udx_subtitle_tracks_video_id_stream_index ON subtitle_tracks (video_id, stream_index)
+    WHERE source = 'embedded';
+
+-- Subtitles exported before tracks existed have no track, which a plain unique
+-- index would let through any number of times.
+DROP INDEX IF EXISTS udx_subtitles_video_id_track_id_sequence;
+CREATE UNIQUE INDEX udx_subtitles_video_id_track_id_sequence ON subtitles (video_id, track_id, sequence) NULLS NOT DISTINCT;
+
+-- Tracks that were exported before their status was kept are ready.
+UPDATE subtitle_tracks SET status = 'ready'
+WHERE status = 'queued' AND EXISTS (SELECT 1 FROM subtitles WHERE subtitles.track_id = subtitle_tracks.id);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_videos_fingerprinted_duration_ms;
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,5 @@
+BEGIN;
+
+CREATE INDEX idx_videos_fingerprinted_duration_ms ON videos (duration_ms) WHERE fingerprint IS NOT NULL AND duplicate_of IS NULL;
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,40 @@
+BEGIN;
+
+-- lower() and the [:alnum:] class follow the collation of their input, so the
+-- terms depended on the locale the database was created with. The function is
+-- declared IMMUTABLE and feeds a stored column, it now uses the ICU root
+-- collation whatever the database locale is. The column is generated again so
+-- that rows stored before use the same terms as new ones.
+DROP INDEX IF EXISTS idx_subtitles_search_vector;
+ALTER TABLE subtitles DROP COLUMN IF EXISTS search_vector;
+
+CREATE OR REPLACE FUNCTION subtitles_search_terms(input TEXT) RETURNS TEXT
+LANGUAGE plpgsql IMMUTABLE PARALLEL SAFE AS $$
+DECLARE
+    word TEXT COLLATE "und-x-icu";
+    terms TEXT[] := '{}';
+BEGIN
+    FOREACH word IN ARRAY regexp_split_to_array(lower(input COLLATE "und-x-icu"), '[^[:alnum:]가-힣]+') LOOP
+        IF word = '' THEN
+            CONTINUE;
+        END IF;
+
+        IF word ~ '[가-힣]' AND char_length(word) > 1 THEN
+            FOR i IN 1..char_length(word) - 1 LOOP
+                terms := terms || substr(word, i, 2);
+            END LOOP;
+        ELSE
+            terms := terms || word;
+        END IF;
+    END LOOP;
+
+    RETURN array_to_string(terms, ' ');
+END;
+$$;
+
+ALTER TABLE subtitles ADD COLUMN search_vector TSVECTOR
+    GENERATED ALWAYS AS (to_tsvector('simple', subtitles_search_terms(text))) STORED;
+
+CREATE INDEX idx_subtitles_search_vector ON subtitles USING GIN (search_vector);
+
+COMMIT;
\ No newline at end of file
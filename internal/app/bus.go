@@ -0,0 +1,27 @@
+package app
+
+import (
+	"dewarrum/vocabulary-leveling/internal/bus"
+	"dewarrum/vocabulary-leveling/internal/config"
+	"errors"
+
+	"github.com/rs/zerolog"
+)
+
+var (
+	ErrUnsupportedMessageBus = errors.New("MESSAGE_BUS must be either rabbitmq or memory")
+)
+
+// createBus connects to the configured message bus. The memory bus only
+// delivers messages within this process, so the exporters have to run next to
+// the API that publishes to them.
+func createBus(busConfig config.Bus, logger zerolog.Logger) (bus.Bus, error) {
+	switch busConfig.Backend {
+	case config.MessageBusRabbitMq:
+		return bus.NewRabbitMqBus(busConfig.RabbitMqUrl, busConfig.PublisherPoolSize, logger)
+	case config.MessageBusMemory:
+		return bus.NewMemoryBus(), nil
+	default:
+		return nil, ErrUnsupportedMessageBus
+	}
+}
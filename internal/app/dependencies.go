@@ -2,100 +2,176 @@ package app
 
 import (
 	"context"
+	"dewarrum/vocabulary-leveling/internal/bus"
+	"dewarrum/vocabulary-leveling/internal/config"
+	"dewarrum/vocabulary-leveling/internal/storage"
+	"errors"
+	"net/http"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/session"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
-	"github.com/rabbitmq/amqp091-go"
 	"github.com/rs/zerolog"
-	"github.com/uptrace/uptrace-go/uptrace"
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 )
 
 type Dependencies struct {
+	Config              *config.Config
 	S3Client            *s3.Client
 	S3PresignClient     *s3.PresignClient
-	RabbitMqChannel     *amqp091.Channel
+	ObjectStore         storage.ObjectStore
+	Bus                 bus.Bus
 	Postgres            *sqlx.DB
 	ElasticsearchClient *elasticsearch.TypedClient
 	SessionStore        *session.Store
-	Logger              zerolog.Logger
-	Tracer              trace.Tracer
+	// SessionStorage backs SessionStore. It is nil for the memory storage.
+	SessionStorage fiber.Storage
+	Logger         zerolog.Logger
+	Tracer         trace.Tracer
+	Meter          metric.Meter
+	// MetricsHandler serves the Prometheus scrape endpoint. It is nil unless
+	// the prometheus metrics exporter is enabled.
+	MetricsHandler http.Handler
+
+	telemetry              *telemetry
+	elasticsearchTransport *http.Transport
 }
 
-func NewDependencies(ctx context.Context) (*Dependencies, error) {
+// NewDependencies opens the clients of the sections cfg was loaded for. The
+// dependencies of the other sections are left nil.
+func NewDependencies(cfg *config.Config, ctx context.Context) (*Dependencies, error) {
 	logger := createLogger()
 	logger.Info().Msg("Creating dependencies")
 
 	logger.Info().Msg("Initializing OpenTelemetry")
-	tracer, err := createTracer(ctx)
+	telemetry, err := createTelemetry(cfg.Telemetry, ctx)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("Failed to initialize OpenTelemetry")
 		return nil, err
 	}
 
-	logger.Info().Msg("Creating S3 client")
-	s3Client, err := createS3Client()
-	if err != nil {
-		logger.Fatal().Err(err).Msg("Failed to create S3 client")
-		return nil, err
+	dependencies := &Dependencies{
+		Config:         cfg,
+		Logger:         logger,
+		Tracer:         telemetry.tracer(),
+		Meter:          telemetry.meter(),
+		MetricsHandler: telemetry.metricsHandler,
+		telemetry:      telemetry,
 	}
 
-	s3PresignClient := createS3PresignClient(s3Client)
+	if cfg.Sections.ObjectStore {
+		err = dependencies.createObjectStore()
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to create object store")
+			return nil, err
+		}
+	}
 
-	logger.Info().Msg("Creating RabbitMQ channel")
-	rabbitMqChannel, err := createRabbitMqChannel()
-	if err != nil {
-		logger.Fatal().Err(err).Msg("Failed to create RabbitMQ channel")
-		return nil, err
+	if cfg.Sections.Bus {
+		logger.Info().Str("backend", cfg.Bus.Backend).Msg("Creating message bus")
+		dependencies.Bus, err = createBus(cfg.Bus, logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to create message bus")
+			return nil, err
+		}
 	}
 
-	logger.Info().Msg("Creating Postgres connection")
-	db, err := createPostgresConnection(logger)
-	if err != nil {
-		logger.Fatal().Err(err).Msg("Failed to create Postgres connection")
-		return nil, err
+	if cfg.Sections.Postgres {
+		logger.Info().Msg("Creating Postgres connection")
+		dependencies.Postgres, err = createPostgresConnection(cfg.Postgres, logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to create Postgres connection")
+			return nil, err
+		}
 	}
 
-	logger.Info().Msg("Creating Elasticsearch client")
-	elasticsearchClient, err := createElasticSearchClient()
-	if err != nil {
-		logger.Fatal().Err(err).Msg("Failed to create Elasticsearch client")
-		return nil, err
+	// Elasticsearch is only needed when it backs the subtitle search.
+	if cfg.Sections.Search && cfg.Search.Backend == config.SearchBackendElasticsearch {
+		logger.Info().Msg("Creating Elasticsearch client")
+		dependencies.ElasticsearchClient, dependencies.elasticsearchTransport, err = createElasticSearchClient(cfg.Search.Elasticsearch)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to create Elasticsearch client")
+			return nil, err
+		}
 	}
 
-	logger.Info().Msg("Creating Redis client")
-	sessionStore, err := createSessionStore()
-	if err != nil {
-		logger.Fatal().Err(err).Msg("Failed to create Redis client")
-		return nil, err
+	if cfg.Sections.Sessions {
+		logger.Info().Str("storage", cfg.Sessions.Storage).Msg("Creating session store")
+		dependencies.SessionStorage, err = createSessionStorage(cfg.Sessions, dependencies.Postgres, logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to create session store")
+			return nil, err
+		}
+		dependencies.SessionStore = NewSessionStore(cfg.Sessions, dependencies.SessionStorage)
+	}
+
+	return dependencies, nil
+}
+
+// createObjectStore creates the S3 client only for the s3 backend. Everything
+// except resumable uploads goes through the object store.
+func (d *Dependencies) createObjectStore() error {
+	switch d.Config.Storage.Backend {
+	case config.StorageBackendS3:
+		d.Logger.Info().Msg("Creating S3 client")
+		d.S3Client = createS3Client(d.Config.Storage.S3)
+		d.S3PresignClient = createS3PresignClient(d.S3Client)
+		d.ObjectStore = createS3Store(d.S3Client, d.S3PresignClient, d.Config.Storage.S3)
+	case config.StorageBackendLocal:
+		d.Logger.Info().Msg("Creating local object store")
+		d.ObjectStore = createLocalStore(d.Config.Storage.Local)
+	default:
+		return ErrUnsupportedStorageBackend
 	}
 
-	return &Dependencies{
-		S3Client:            s3Client,
-		S3PresignClient:     s3PresignClient,
-		RabbitMqChannel:     rabbitMqChannel,
-		Postgres:            db,
-		ElasticsearchClient: elasticsearchClient,
-		SessionStore:        sessionStore,
-		Logger:              logger,
-		Tracer:              tracer,
-	}, nil
+	return nil
 }
 
+// Close flushes the telemetry and then closes every client. The server and
+// the exporters have to be stopped first, as nothing may use the clients
+// afterwards.
 func (d *Dependencies) Close(ctx context.Context) error {
-	if d.Postgres != nil {
-		d.Postgres.Close()
+	// ctx is usually done by the time the process shuts down, the flush gets
+	// a timeout of its own.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.Config.Shutdown.FlushTimeout)
+	defer cancel()
+
+	var errs []error
+
+	if d.telemetry != nil {
+		d.Logger.Info().Msg("Flushing telemetry")
+		err := d.telemetry.shutdown(ctx)
+		if err != nil {
+			d.Logger.Error().Err(err).Msg("Failed to flush telemetry")
+			errs = append(errs, err)
+		}
+	}
+
+	// The Postgres session storage sweeps expired sessions until it is
+	// closed, so it goes before the connection.
+	if d.SessionStorage != nil {
+		d.Logger.Info().Msg("Closing session storage")
+		errs = append(errs, d.SessionStorage.Close())
 	}
 
-	if d.RabbitMqChannel != nil {
-		d.Logger.Info().Msg("Closing RabbitMQ channel")
-		d.RabbitMqChannel.Close()
+	if d.Bus != nil {
+		d.Logger.Info().Msg("Closing message bus")
+		errs = append(errs, d.Bus.Close())
 	}
 
-	uptrace.Shutdown(ctx)
+	if d.elasticsearchTransport != nil {
+		d.Logger.Info().Msg("Closing Elasticsearch connections")
+		d.elasticsearchTransport.CloseIdleConnections()
+	}
 
-	return nil
+	if d.Postgres != nil {
+		d.Logger.Info().Msg("Closing Postgres connection")
+		errs = append(errs, d.Postgres.Close())
+	}
+
+	return errors.Join(errs...)
 }
@@ -1,41 +1,25 @@
 package app
 
 import (
-	"errors"
-	"os"
+	"dewarrum/vocabulary-leveling/internal/config"
+	"net/http"
 
 	"github.com/elastic/go-elasticsearch/v8"
 )
 
-var (
-	ErrElasticsearchUrlIsRequired      = errors.New("ELASTICSEARCH_URL is required")
-	ErrElasticsearchUsernameIsRequired = errors.New("ELASTICSEARCH_USERNAME is required")
-	ErrElasticsearchPasswordIsRequired = errors.New("ELASTICSEARCH_PASSWORD is required")
-)
-
-func createElasticSearchClient() (*elasticsearch.TypedClient, error) {
-	url := os.Getenv("ELASTICSEARCH_URL")
-	if url == "" {
-		return nil, ErrElasticsearchUrlIsRequired
-	}
-
-	username := os.Getenv("ELASTICSEARCH_USERNAME")
-	if username == "" {
-		return nil, ErrElasticsearchUsernameIsRequired
-	}
-
-	password := os.Getenv("ELASTICSEARCH_PASSWORD")
-	if password == "" {
-		return nil, ErrElasticsearchPasswordIsRequired
-	}
+// createElasticSearchClient returns the transport of the client as well, the
+// client has no Close of its own to release its connections.
+func createElasticSearchClient(elasticsearchConfig config.Elasticsearch) (*elasticsearch.TypedClient, *http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
 	elasticsearchClient, err := elasticsearch.NewTypedClient(elasticsearch.Config{
-		Addresses: []string{url},
-		Username:  username,
-		Password:  password,
+		Addresses: []string{elasticsearchConfig.Url},
+		Username:  elasticsearchConfig.Username,
+		Password:  elasticsearchConfig.Password,
+		Transport: transport,
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return elasticsearchClient, nil
+	return elasticsearchClient, transport, nil
 }
@@ -2,8 +2,8 @@ package app
 
 import (
 	"database/sql"
+	"dewarrum/vocabulary-leveling/internal/config"
 	"errors"
-	"os"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/rs/zerolog"
@@ -12,22 +12,18 @@ import (
 )
 
 var (
-	ErrPostgresUrlIsRequired     = errors.New("POSTGRES_URL is required")
 	ErrFailedToConnectToPostgres = errors.New("failed to connect to Postgres")
 )
 
-func createPostgresConnection(logger zerolog.Logger) (*sqlx.DB, error) {
-	dsn := os.Getenv("POSTGRES_URL")
-	if dsn == "" {
-		return nil, ErrPostgresUrlIsRequired
-	}
+func createPostgresConnection(postgresConfig config.Postgres, logger zerolog.Logger) (*sqlx.DB, error) {
+	dsn := postgresConfig.Url
 
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return nil, errors.Join(err, ErrFailedToConnectToPostgres)
 	}
-	db.SetMaxOpenConns(100)
-	db.SetMaxIdleConns(10)
+	db.SetMaxOpenConns(postgresConfig.MaxOpenConns)
+	db.SetMaxIdleConns(postgresConfig.MaxIdleConns)
 	db.SetConnMaxLifetime(0)
 
 	loggerOptions := []sqldblogger.Option{
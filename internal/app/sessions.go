@@ -1,31 +1,52 @@
 package app
 
 import (
+	"dewarrum/vocabulary-leveling/internal/config"
+	"dewarrum/vocabulary-leveling/internal/sessions"
 	"errors"
-	"os"
 
+	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/session"
 	"github.com/gofiber/storage/redis/v3"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
 )
 
 var (
-	ErrRedisUrlIsRequired = errors.New("REDIS_URL is required")
+	ErrUnsupportedSessionStorage = errors.New("SESSION_STORAGE must be one of redis, postgres or memory")
 )
 
-func createSessionStore() (*session.Store, error) {
-	url := os.Getenv("REDIS_URL")
-	if url == "" {
-		return nil, ErrRedisUrlIsRequired
+// createSessionStorage returns the storage the Logto client keeps its tokens
+// in. It is nil for the memory storage, which forgets every session on restart
+// and is only meant for development.
+func createSessionStorage(sessionsConfig config.Sessions, db *sqlx.DB, logger zerolog.Logger) (fiber.Storage, error) {
+	switch sessionsConfig.Storage {
+	case config.SessionStorageRedis:
+		return redis.New(redis.Config{
+			URL:      sessionsConfig.RedisUrl,
+			Database: 0,
+		}), nil
+	case config.SessionStoragePostgres:
+		return sessions.NewPostgresStorage(db, sessionsConfig.SweepInterval, logger), nil
+	case config.SessionStorageMemory:
+		// Fiber falls back to its in-memory storage when none is set.
+		return nil, nil
+	default:
+		return nil, ErrUnsupportedSessionStorage
 	}
+}
 
-	redisStorage := redis.New(redis.Config{
-		URL:      url,
-		Database: 0,
-	})
-
-	sessionStore := session.New(session.Config{
-		Storage: redisStorage,
+// NewSessionStore returns the store whose cookie identifies the session of a
+// signed in user. The cookie is never readable from scripts.
+func NewSessionStore(sessionsConfig config.Sessions, storage fiber.Storage) *session.Store {
+	return session.New(session.Config{
+		Storage:        storage,
+		CookieDomain:   sessionsConfig.CookieDomain,
+		CookieHTTPOnly: true,
+		CookieSecure:   sessionsConfig.CookieSecure,
+		// The config package only accepts the modes Fiber names Lax, Strict
+		// and None.
+		CookieSameSite: sessionsConfig.CookieSameSite,
+		Expiration:     sessionsConfig.Expiration,
 	})
-
-	return sessionStore, nil
 }
@@ -0,0 +1,58 @@
+package app_test
+
+import (
+	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestSessionStoreSetsTheConfiguredCookieFlags(t *testing.T) {
+	store := app.NewSessionStore(config.Sessions{
+		CookieSecure:   true,
+		CookieSameSite: "Strict",
+		CookieDomain:   "example.com",
+		Expiration:     time.Hour,
+	}, nil)
+
+	server := fiber.New()
+	server.Get("/", func(c *fiber.Ctx) error {
+		session, err := store.Get(c)
+		if err != nil {
+			return err
+		}
+		session.Set("user", "alice")
+		return session.Save()
+	})
+
+	response, err := server.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cookies := response.Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Expected one cookie, but got %d", len(cookies))
+	}
+
+	cookie := cookies[0]
+	if !cookie.HttpOnly {
+		t.Error("Expected the cookie to be HttpOnly")
+	}
+	if !cookie.Secure {
+		t.Error("Expected the cookie to be Secure")
+	}
+	if cookie.SameSite != http.SameSiteStrictMode {
+		t.Errorf("Expected SameSite to be %v, but got %v", http.SameSiteStrictMode, cookie.SameSite)
+	}
+	if cookie.Domain != "example.com" {
+		t.Errorf("Expected domain to be %s, but got %s", "example.com", cookie.Domain)
+	}
+	if cookie.MaxAge != int(time.Hour.Seconds()) {
+		t.Errorf("Expected max age to be %d, but got %d", int(time.Hour.Seconds()), cookie.MaxAge)
+	}
+}
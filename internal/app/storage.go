@@ -0,0 +1,21 @@
+package app
+
+import (
+	"dewarrum/vocabulary-leveling/internal/config"
+	"dewarrum/vocabulary-leveling/internal/storage"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+var (
+	ErrUnsupportedStorageBackend = errors.New("STORAGE_BACKEND must be either s3 or local")
+)
+
+func createS3Store(s3Client *s3.Client, s3PresignClient *s3.PresignClient, s3Config config.S3Storage) *storage.S3Store {
+	return storage.NewS3Store(s3Client, s3PresignClient, s3Config.Bucket, s3Config.Prefix)
+}
+
+func createLocalStore(localConfig config.LocalStorage) *storage.LocalStore {
+	return storage.NewLocalStore(localConfig.Root, localConfig.Url, []byte(localConfig.Secret))
+}
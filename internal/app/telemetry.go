@@ -0,0 +1,201 @@
+package app
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/config"
+	"errors"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "vocabulary-leveling"
+	serviceName         = "vocabulary-leveling"
+	serviceVersion      = "1.0.0"
+)
+
+var (
+	ErrUnsupportedTracesExporter  = errors.New("OTEL_TRACES_EXPORTER must be otlp, console or none")
+	ErrUnsupportedMetricsExporter = errors.New("OTEL_METRICS_EXPORTER must be a list of otlp, prometheus, console or none")
+	ErrUnsupportedOtlpProtocol    = errors.New("OTEL_EXPORTER_OTLP_PROTOCOL must be either grpc or http/protobuf")
+)
+
+// telemetry owns the providers behind Dependencies.Tracer and
+// Dependencies.Meter so that they can be flushed on shutdown.
+type telemetry struct {
+	tracerProvider *sdktrace.TracerProvider
+	meterProvider  *sdkmetric.MeterProvider
+	metricsHandler http.Handler
+}
+
+// createTelemetry configures tracing and metrics with the exporters selected
+// in telemetryConfig. Endpoint, headers, compression and timeouts are read
+// from the standard OTEL_* variables by the OTLP exporters themselves, and
+// sampling by the tracer provider.
+func createTelemetry(telemetryConfig config.Telemetry, ctx context.Context) (*telemetry, error) {
+	resource, err := resource.New(ctx,
+		resource.WithAttributes(
+			attribute.String("service.name", serviceName),
+			attribute.String("service.version", serviceVersion),
+		),
+		// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the defaults.
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tracerProvider, err := createTracerProvider(telemetryConfig, resource, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	meterProvider, metricsHandler, err := createMeterProvider(telemetryConfig, resource, ctx)
+	if err != nil {
+		tracerProvider.Shutdown(ctx)
+		return nil, err
+	}
+
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetMeterProvider(meterProvider)
+	// Trace context travels with bus messages, so consumers continue the
+	// trace of the request that published them.
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return &telemetry{
+		tracerProvider: tracerProvider,
+		meterProvider:  meterProvider,
+		metricsHandler: metricsHandler,
+	}, nil
+}
+
+func (t *telemetry) tracer() trace.Tracer {
+	return t.tracerProvider.Tracer(instrumentationName)
+}
+
+func (t *telemetry) meter() metric.Meter {
+	return t.meterProvider.Meter(instrumentationName)
+}
+
+// shutdown flushes the spans and metrics that were not exported yet.
+func (t *telemetry) shutdown(ctx context.Context) error {
+	return errors.Join(
+		t.tracerProvider.Shutdown(ctx),
+		t.meterProvider.Shutdown(ctx),
+	)
+}
+
+func createTracerProvider(telemetryConfig config.Telemetry, resource *resource.Resource, ctx context.Context) (*sdktrace.TracerProvider, error) {
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource),
+		sdktrace.WithIDGenerator(xray.NewIDGenerator()),
+	}
+
+	switch telemetryConfig.TracesExporter {
+	case config.TelemetryExporterOtlp:
+		exporter, err := createOtlpTraceExporter(telemetryConfig.TracesProtocol, ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		options = append(options, sdktrace.WithBatcher(exporter,
+			sdktrace.WithMaxQueueSize(10_000),
+			sdktrace.WithMaxExportBatchSize(10_000)))
+	case config.TelemetryExporterConsole:
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, err
+		}
+
+		options = append(options, sdktrace.WithBatcher(exporter))
+	case config.TelemetryExporterNone:
+	default:
+		return nil, ErrUnsupportedTracesExporter
+	}
+
+	return sdktrace.NewTracerProvider(options...), nil
+}
+
+// createMeterProvider returns the handler serving the Prometheus scrape
+// endpoint, or nil when the prometheus exporter is not enabled.
+func createMeterProvider(telemetryConfig config.Telemetry, resource *resource.Resource, ctx context.Context) (*sdkmetric.MeterProvider, http.Handler, error) {
+	options := []sdkmetric.Option{
+		sdkmetric.WithResource(resource),
+	}
+
+	var metricsHandler http.Handler
+	for _, name := range telemetryConfig.MetricsExporters {
+		switch name {
+		case config.TelemetryExporterOtlp:
+			exporter, err := createOtlpMetricExporter(telemetryConfig.MetricsProtocol, ctx)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			options = append(options, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)))
+		case config.TelemetryExporterPrometheus:
+			registry := prometheus.NewRegistry()
+			exporter, err := otelprometheus.New(otelprometheus.WithRegisterer(registry))
+			if err != nil {
+				return nil, nil, err
+			}
+
+			options = append(options, sdkmetric.WithReader(exporter))
+			metricsHandler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+		case config.TelemetryExporterConsole:
+			exporter, err := stdoutmetric.New()
+			if err != nil {
+				return nil, nil, err
+			}
+
+			options = append(options, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)))
+		case config.TelemetryExporterNone:
+		default:
+			return nil, nil, ErrUnsupportedMetricsExporter
+		}
+	}
+
+	return sdkmetric.NewMeterProvider(options...), metricsHandler, nil
+}
+
+func createOtlpTraceExporter(protocol string, ctx context.Context) (sdktrace.SpanExporter, error) {
+	switch protocol {
+	case config.OtlpProtocolHttpProtobuf:
+		return otlptracehttp.New(ctx)
+	case config.OtlpProtocolGrpc:
+		return otlptracegrpc.New(ctx)
+	default:
+		return nil, ErrUnsupportedOtlpProtocol
+	}
+}
+
+func createOtlpMetricExporter(protocol string, ctx context.Context) (sdkmetric.Exporter, error) {
+	switch protocol {
+	case config.OtlpProtocolHttpProtobuf:
+		return otlpmetrichttp.New(ctx)
+	case config.OtlpProtocolGrpc:
+		return otlpmetricgrpc.New(ctx)
+	default:
+		return nil, ErrUnsupportedOtlpProtocol
+	}
+}
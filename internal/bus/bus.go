@@ -0,0 +1,72 @@
+package bus
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrFailedToDeclare   = errors.New("failed to declare topic")
+	ErrFailedToPublish   = errors.New("failed to publish message")
+	ErrFailedToSubscribe = errors.New("failed to subscribe")
+	ErrAlreadyAcked      = errors.New("delivery was already acknowledged")
+	ErrClosed            = errors.New("bus is closed")
+	ErrNotConnected      = errors.New("bus is not connected to the broker")
+)
+
+// Topic names the exchange messages are published to and the queue they are
+// consumed from. Every subscriber of a topic competes for the same messages.
+type Topic struct {
+	Exchange string
+	Queue    string
+	// DurableExchange has to match the flags the exchange was first declared
+	// with, RabbitMQ refuses to redeclare it otherwise.
+	DurableExchange bool
+}
+
+type Message struct {
+	ContentType string
+	Headers     map[string]string
+	Body        []byte
+}
+
+type acknowledger interface {
+	ack() error
+	nack(requeue bool) error
+}
+
+// Delivery is a message handed to a subscriber. It stays unacknowledged, and
+// is redelivered if the subscription ends, until Ack or Nack is called.
+type Delivery struct {
+	Message
+	acknowledger acknowledger
+}
+
+func (d *Delivery) Ack() error {
+	return d.acknowledger.ack()
+}
+
+// Nack rejects the delivery. Requeued deliveries are handed out again, the
+// rest are dropped.
+func (d *Delivery) Nack(requeue bool) error {
+	return d.acknowledger.nack(requeue)
+}
+
+type Publisher interface {
+	Publish(topic Topic, message Message, ctx context.Context) error
+}
+
+type Subscriber interface {
+	// Subscribe delivers messages of topic until ctx is done, after which the
+	// returned channel is closed. At most prefetch deliveries are handed out
+	// without being acknowledged, zero means no limit.
+	Subscribe(topic Topic, consumer string, prefetch int, ctx context.Context) (<-chan *Delivery, error)
+}
+
+type Bus interface {
+	Publisher
+	Subscriber
+	// Ping reports whether messages can currently be published.
+	Ping(ctx context.Context) error
+	Close() error
+}
@@ -0,0 +1,201 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/rs/zerolog"
+)
+
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+	// staleConnectionDelay is how long current waits for the watcher to notice
+	// that the connection it handed out is gone.
+	staleConnectionDelay = 100 * time.Millisecond
+)
+
+var (
+	ErrFailedToConnect = errors.New("failed to connect to RabbitMQ")
+)
+
+// connectionManager keeps one AMQP connection open and replaces it, backing
+// off between attempts, whenever the broker or the network closes it. Channels
+// belong to a connection, so users compare generations to notice that the
+// channels they hold died with an earlier connection.
+type connectionManager struct {
+	url    string
+	logger zerolog.Logger
+	// onConnect prepares every new connection before it is handed out.
+	onConnect func(connection *amqp091.Connection) error
+
+	mutex      sync.Mutex
+	connection *amqp091.Connection
+	generation uint64
+	// ready is closed while connection can be used and replaced as soon as it
+	// is lost.
+	ready     chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newConnectionManager(url string, logger zerolog.Logger, onConnect func(connection *amqp091.Connection) error) (*connectionManager, error) {
+	m := &connectionManager{
+		url:       url,
+		logger:    logger,
+		onConnect: onConnect,
+		ready:     make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	connection, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	m.setConnection(connection)
+	go m.watch(connection)
+
+	return m, nil
+}
+
+func (m *connectionManager) connect() (*amqp091.Connection, error) {
+	connection, err := amqp091.Dial(m.url)
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToConnect)
+	}
+
+	if m.onConnect != nil {
+		err = m.onConnect(connection)
+		if err != nil {
+			connection.Close()
+			return nil, err
+		}
+	}
+
+	return connection, nil
+}
+
+func (m *connectionManager) setConnection(connection *amqp091.Connection) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.connection = connection
+	m.generation++
+	close(m.ready)
+}
+
+func (m *connectionManager) watch(connection *amqp091.Connection) {
+	for {
+		closeErr := <-connection.NotifyClose(make(chan *amqp091.Error, 1))
+
+		select {
+		case <-m.done:
+			return
+		default:
+		}
+
+		m.mutex.Lock()
+		m.ready = make(chan struct{})
+		m.mutex.Unlock()
+
+		if closeErr != nil {
+			m.logger.Warn().Err(closeErr).Msg("Lost RabbitMQ connection, reconnecting")
+		} else {
+			m.logger.Warn().Msg("Lost RabbitMQ connection, reconnecting")
+		}
+
+		connection = m.reconnect()
+		if connection == nil {
+			return
+		}
+
+		m.setConnection(connection)
+		m.logger.Info().Msg("Reconnected to RabbitMQ")
+	}
+}
+
+// reconnect dials until it succeeds or the manager is closed, in which case
+// it returns nil.
+func (m *connectionManager) reconnect() *amqp091.Connection {
+	delay := minReconnectDelay
+	for {
+		select {
+		case <-time.After(delay):
+		case <-m.done:
+			return nil
+		}
+
+		connection, err := m.connect()
+		if err == nil {
+			select {
+			case <-m.done:
+				connection.Close()
+				return nil
+			default:
+				return connection
+			}
+		}
+
+		delay = min(delay*2, maxReconnectDelay)
+		m.logger.Error().Err(err).Dur("retryIn", delay).Msg("Failed to reconnect to RabbitMQ")
+	}
+}
+
+// current waits until a connection is available and returns it together with
+// its generation.
+func (m *connectionManager) current(ctx context.Context) (*amqp091.Connection, uint64, error) {
+	for {
+		m.mutex.Lock()
+		ready, connection, generation := m.ready, m.connection, m.generation
+		m.mutex.Unlock()
+
+		select {
+		case <-ready:
+			if !connection.IsClosed() {
+				return connection, generation, nil
+			}
+
+			select {
+			case <-time.After(staleConnectionDelay):
+			case <-ctx.Done():
+				return nil, 0, ctx.Err()
+			case <-m.done:
+				return nil, 0, ErrClosed
+			}
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		case <-m.done:
+			return nil, 0, ErrClosed
+		}
+	}
+}
+
+// IsConnected reports whether a connection is currently open.
+func (m *connectionManager) IsConnected() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	select {
+	case <-m.ready:
+		return !m.connection.IsClosed()
+	default:
+		return false
+	}
+}
+
+func (m *connectionManager) Close() error {
+	var err error
+	m.closeOnce.Do(func() {
+		close(m.done)
+
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		err = m.connection.Close()
+	})
+
+	return err
+}
@@ -0,0 +1,122 @@
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Received is embedded in the messages handed out by ConsumeJson, which then
+// acknowledge their delivery and continue the trace they were sent from.
+// Messages that were not consumed have no delivery to acknowledge.
+type Received struct {
+	delivery *Delivery
+}
+
+// Ack removes a consumed message from the queue.
+func (r *Received) Ack() error {
+	if r.delivery == nil {
+		return nil
+	}
+
+	return r.delivery.Ack()
+}
+
+// Nack rejects a consumed message, putting it back on the queue if requeue is
+// set.
+func (r *Received) Nack(requeue bool) error {
+	if r.delivery == nil {
+		return nil
+	}
+
+	return r.delivery.Nack(requeue)
+}
+
+// Context returns ctx continuing the trace the message was sent from.
+func (r *Received) Context(ctx context.Context) context.Context {
+	if r.delivery == nil {
+		return ctx
+	}
+
+	return r.delivery.Context(ctx)
+}
+
+func (r *Received) receive(delivery *Delivery) {
+	r.delivery = delivery
+}
+
+// receivable is a pointer to a message embedding Received.
+type receivable[T any] interface {
+	*T
+	receive(delivery *Delivery)
+}
+
+// QueueLag measures how long messages wait between being published and being
+// picked up by a consumer.
+type QueueLag struct {
+	histogram metric.Float64Histogram
+}
+
+func NewQueueLag(meter metric.Meter) (*QueueLag, error) {
+	histogram, err := meter.Float64Histogram(
+		"bus.queue.lag",
+		metric.WithUnit("s"),
+		metric.WithDescription("Time messages wait on the queue before an exporter picks them up"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &QueueLag{histogram: histogram}, nil
+}
+
+func (l *QueueLag) record(topic Topic, delivery *Delivery, ctx context.Context) {
+	publishedAt := delivery.PublishedAt()
+	if publishedAt.IsZero() {
+		return
+	}
+
+	l.histogram.Record(ctx, time.Since(publishedAt).Seconds(), metric.WithAttributes(attribute.String("queue", topic.Queue)))
+}
+
+// ConsumeJson delivers the JSON messages of topic until ctx is done. Every
+// message has to be acknowledged with Ack or Nack once it is handled, and no
+// more than prefetch messages are delivered before that. Messages that cannot
+// be decoded are dropped.
+func ConsumeJson[T any, P receivable[T]](subscriber Subscriber, topic Topic, consumer string, prefetch int, queueLag *QueueLag, logger zerolog.Logger, ctx context.Context) (<-chan T, error) {
+	deliveries, err := subscriber.Subscribe(topic, consumer, prefetch, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make(chan T)
+
+	go func() {
+		defer close(messages)
+
+		for delivery := range deliveries {
+			var message T
+			err := json.Unmarshal(delivery.Body, &message)
+			if err != nil {
+				logger.Error().Err(err).Str("queue", topic.Queue).Msg("Failed to unmarshal message")
+				delivery.Nack(false)
+				continue
+			}
+			P(&message).receive(delivery)
+
+			select {
+			case messages <- message:
+				queueLag.record(topic, delivery, ctx)
+			case <-ctx.Done():
+				// Nobody takes messages anymore, another consumer gets it.
+				delivery.Nack(true)
+			}
+		}
+	}()
+
+	return messages, nil
+}
@@ -0,0 +1,102 @@
+package bus_test
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/bus"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+)
+
+type testMessage struct {
+	Name string `json:"name"`
+
+	bus.Received
+}
+
+func TestConsumeJsonDropsMessagesThatCannotBeDecoded(t *testing.T) {
+	messageBus := bus.NewMemoryBus()
+	defer messageBus.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for _, body := range []string{"not json", `{"name":"first"}`} {
+		err := messageBus.Publish(topic, bus.Message{Body: []byte(body)}, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	queueLag, err := bus.NewQueueLag(metricnoop.NewMeterProvider().Meter(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages, err := bus.ConsumeJson[testMessage](messageBus, topic, "consumer.test", 1, queueLag, zerolog.Nop(), ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := <-messages
+	if message.Name != "first" {
+		t.Errorf("Expected name to be %s, but got %s", "first", message.Name)
+	}
+
+	err = message.Ack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = message.Ack()
+	if err == nil {
+		t.Error("Expected the second acknowledgement to be rejected")
+	}
+}
+
+func TestConsumeJsonRequeuesMessagesNobodyTakes(t *testing.T) {
+	messageBus := bus.NewMemoryBus()
+	defer messageBus.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err := messageBus.Publish(topic, bus.Message{Body: []byte(`{"name":"first"}`)}, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queueLag, err := bus.NewQueueLag(metricnoop.NewMeterProvider().Meter(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	consumeCtx, stopConsuming := context.WithCancel(ctx)
+	messages, err := bus.ConsumeJson[testMessage](messageBus, topic, "consumer.test", 1, queueLag, zerolog.Nop(), consumeCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The message is decoded and waits for a taker when consuming stops.
+	time.Sleep(50 * time.Millisecond)
+	stopConsuming()
+	for range messages {
+		t.Error("Expected no message after consuming stopped")
+	}
+
+	deliveries, err := messageBus.Subscribe(topic, "consumer.test", 1, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivery := receive(t, deliveries); string(delivery.Body) != `{"name":"first"}` {
+		t.Errorf("Expected the message to be requeued, but got %s", delivery.Body)
+	}
+}
+
+func TestReceivedIgnoresMessagesThatWereNotConsumed(t *testing.T) {
+	var message testMessage
+
+	if err := message.Ack(); err != nil {
+		t.Errorf("Expected no error, but got %v", err)
+	}
+	if err := message.Nack(true); err != nil {
+		t.Errorf("Expected no error, but got %v", err)
+	}
+}
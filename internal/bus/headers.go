@@ -0,0 +1,46 @@
+package bus
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	publishedAtHeader = "published-at"
+)
+
+// outgoing returns a copy of message whose headers carry the trace context of
+// ctx, so consumers can continue the publisher's trace, and the time it was
+// published at.
+func outgoing(message Message, ctx context.Context) Message {
+	headers := make(map[string]string, len(message.Headers)+3)
+	for key, value := range message.Headers {
+		headers[key] = value
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(headers))
+	headers[publishedAtHeader] = strconv.FormatInt(time.Now().UnixMilli(), 10)
+
+	message.Headers = headers
+	return message
+}
+
+// Context returns parent carrying the trace context the delivery was
+// published with, if any.
+func (d *Delivery) Context(parent context.Context) context.Context {
+	return otel.GetTextMapPropagator().Extract(parent, propagation.MapCarrier(d.Headers))
+}
+
+// PublishedAt returns when the delivery was published, or the zero time for
+// messages published without it.
+func (d *Delivery) PublishedAt() time.Time {
+	milliseconds, err := strconv.ParseInt(d.Headers[publishedAtHeader], 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return time.UnixMilli(milliseconds)
+}
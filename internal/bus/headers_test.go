@@ -0,0 +1,78 @@
+package bus_test
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/bus"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestDeliveryContinuesPublisherTrace(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	messageBus := bus.NewMemoryBus()
+	defer messageBus.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01},
+		SpanID:     trace.SpanID{0x02},
+		TraceFlags: trace.FlagsSampled,
+	})
+	publishCtx := trace.ContextWithSpanContext(ctx, spanContext)
+
+	headers := map[string]string{"key": "value"}
+	err := messageBus.Publish(topic, bus.Message{Headers: headers, Body: []byte("traced")}, publishCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(headers) != 1 {
+		t.Errorf("Expected the published headers to be left untouched, but got %v", headers)
+	}
+
+	deliveries, err := messageBus.Subscribe(topic, "consumer.test", 0, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	delivery := receive(t, deliveries)
+	if delivery.Headers["key"] != "value" {
+		t.Errorf("Expected header %s to be %s, but got %s", "key", "value", delivery.Headers["key"])
+	}
+
+	received := trace.SpanContextFromContext(delivery.Context(context.Background()))
+	if received.TraceID() != spanContext.TraceID() {
+		t.Errorf("Expected trace id to be %s, but got %s", spanContext.TraceID(), received.TraceID())
+	}
+	if received.SpanID() != spanContext.SpanID() {
+		t.Errorf("Expected span id to be %s, but got %s", spanContext.SpanID(), received.SpanID())
+	}
+}
+
+func TestDeliveryKnowsWhenItWasPublished(t *testing.T) {
+	messageBus := bus.NewMemoryBus()
+	defer messageBus.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	before := time.Now().Truncate(time.Millisecond)
+	err := messageBus.Publish(topic, bus.Message{Body: []byte("timed")}, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := time.Now()
+
+	deliveries, err := messageBus.Subscribe(topic, "consumer.test", 0, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publishedAt := receive(t, deliveries).PublishedAt()
+	if publishedAt.Before(before) || publishedAt.After(after) {
+		t.Errorf("Expected published at to be between %s and %s, but got %s", before, after, publishedAt)
+	}
+}
@@ -0,0 +1,302 @@
+package bus
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// MemoryBus delivers messages between goroutines of a single process. It
+// mirrors the RabbitMQ topology: publishing to an exchange enqueues the message
+// on every queue bound to it, and queues keep messages until a subscriber
+// acknowledges them, even while nobody is subscribed. Closing the bus requeues
+// the deliveries that were not acknowledged yet, as closing a RabbitMQ channel
+// does.
+type MemoryBus struct {
+	mutex    sync.Mutex
+	queues   map[string]*memoryQueue
+	bindings map[string]map[string]bool
+	done     chan struct{}
+	closed   bool
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{
+		queues:   make(map[string]*memoryQueue),
+		bindings: make(map[string]map[string]bool),
+		done:     make(chan struct{}),
+	}
+}
+
+func (b *MemoryBus) declare(topic Topic) (*memoryQueue, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return nil, ErrClosed
+	}
+
+	queue, ok := b.queues[topic.Queue]
+	if !ok {
+		queue = newMemoryQueue()
+		b.queues[topic.Queue] = queue
+	}
+
+	if b.bindings[topic.Exchange] == nil {
+		b.bindings[topic.Exchange] = make(map[string]bool)
+	}
+	b.bindings[topic.Exchange][topic.Queue] = true
+
+	return queue, nil
+}
+
+func (b *MemoryBus) Publish(topic Topic, message Message, ctx context.Context) error {
+	_, err := b.declare(topic)
+	if err != nil {
+		return err
+	}
+	message = outgoing(message, ctx)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for queueName := range b.bindings[topic.Exchange] {
+		b.queues[queueName].push(message)
+	}
+
+	return nil
+}
+
+func (b *MemoryBus) Subscribe(topic Topic, consumer string, prefetch int, ctx context.Context) (<-chan *Delivery, error) {
+	queue, err := b.declare(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every unacknowledged delivery holds one of the slots.
+	var slots chan struct{}
+	if prefetch > 0 {
+		slots = make(chan struct{}, prefetch)
+	}
+
+	deliveries := make(chan *Delivery)
+
+	go func() {
+		defer close(deliveries)
+
+		for {
+			if slots != nil {
+				select {
+				case slots <- struct{}{}:
+				case <-ctx.Done():
+					return
+				case <-b.done:
+					return
+				}
+			}
+
+			release := func() {
+				if slots != nil {
+					<-slots
+				}
+			}
+
+			message, ok := queue.pop(ctx, b.done)
+			if !ok {
+				return
+			}
+
+			acknowledger := &memoryAcknowledger{queue: queue, message: message, release: release}
+			queue.track(acknowledger)
+
+			select {
+			case deliveries <- &Delivery{Message: message, acknowledger: acknowledger}:
+			case <-ctx.Done():
+				acknowledger.requeue()
+				return
+			case <-b.done:
+				acknowledger.requeue()
+				return
+			}
+		}
+	}()
+
+	return deliveries, nil
+}
+
+func (b *MemoryBus) Ping(ctx context.Context) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return ErrClosed
+	}
+
+	return nil
+}
+
+func (b *MemoryBus) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if !b.closed {
+		b.closed = true
+		close(b.done)
+
+		for _, queue := range b.queues {
+			queue.requeueUnacked()
+		}
+	}
+
+	return nil
+}
+
+type memoryQueue struct {
+	mutex    sync.Mutex
+	messages []Message
+	// unacked holds the deliveries handed out but not settled, in the order
+	// they were delivered.
+	unacked []*memoryAcknowledger
+	// notify wakes up one waiting subscriber whenever a message is enqueued.
+	notify chan struct{}
+}
+
+func newMemoryQueue() *memoryQueue {
+	return &memoryQueue{
+		notify: make(chan struct{}, 1),
+	}
+}
+
+func (q *memoryQueue) push(message Message) {
+	q.mutex.Lock()
+	q.messages = append(q.messages, message)
+	q.mutex.Unlock()
+
+	q.signal()
+}
+
+// pushFront puts a requeued message back at the head of the queue, which is
+// where RabbitMQ requeues it as well.
+func (q *memoryQueue) pushFront(message Message) {
+	q.mutex.Lock()
+	q.messages = append([]Message{message}, q.messages...)
+	q.mutex.Unlock()
+
+	q.signal()
+}
+
+func (q *memoryQueue) track(acknowledger *memoryAcknowledger) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.unacked = append(q.unacked, acknowledger)
+}
+
+// settle stops tracking a delivery and reports whether it was still tracked.
+func (q *memoryQueue) settle(acknowledger *memoryAcknowledger) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for i, unacked := range q.unacked {
+		if unacked == acknowledger {
+			q.unacked = append(q.unacked[:i], q.unacked[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// requeueUnacked puts every unsettled delivery back at the head of the queue,
+// keeping the order they were delivered in.
+func (q *memoryQueue) requeueUnacked() {
+	q.mutex.Lock()
+	unacked := q.unacked
+	q.unacked = nil
+	for i := len(unacked) - 1; i >= 0; i-- {
+		q.messages = append([]Message{unacked[i].message}, q.messages...)
+	}
+	q.mutex.Unlock()
+
+	for _, acknowledger := range unacked {
+		acknowledger.release()
+	}
+	q.signal()
+}
+
+func (q *memoryQueue) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (q *memoryQueue) pop(ctx context.Context, done <-chan struct{}) (Message, bool) {
+	for {
+		q.mutex.Lock()
+		if len(q.messages) > 0 {
+			message := q.messages[0]
+			q.messages = q.messages[1:]
+			remaining := len(q.messages)
+			q.mutex.Unlock()
+
+			// Pass the wake up on so that other subscribers pick up the rest.
+			if remaining > 0 {
+				q.signal()
+			}
+			return message, true
+		}
+		q.mutex.Unlock()
+
+		select {
+		case <-q.notify:
+		case <-ctx.Done():
+			return Message{}, false
+		case <-done:
+			return Message{}, false
+		}
+	}
+}
+
+type memoryAcknowledger struct {
+	queue   *memoryQueue
+	message Message
+	release func()
+	acked   atomic.Bool
+}
+
+func (a *memoryAcknowledger) ack() error {
+	if a.acked.Swap(true) {
+		return ErrAlreadyAcked
+	}
+	if !a.queue.settle(a) {
+		return ErrClosed
+	}
+	a.release()
+
+	return nil
+}
+
+func (a *memoryAcknowledger) nack(requeue bool) error {
+	if a.acked.Swap(true) {
+		return ErrAlreadyAcked
+	}
+	if !a.queue.settle(a) {
+		return ErrClosed
+	}
+	a.release()
+
+	if requeue {
+		a.queue.pushFront(a.message)
+	}
+
+	return nil
+}
+
+// requeue returns a delivery that never reached its subscriber.
+func (a *memoryAcknowledger) requeue() {
+	if a.queue.settle(a) {
+		a.release()
+		a.queue.pushFront(a.message)
+	}
+}
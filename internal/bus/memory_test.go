@@ -0,0 +1,191 @@
+package bus_test
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/bus"
+	"errors"
+	"testing"
+	"time"
+)
+
+var topic = bus.Topic{Exchange: "exchange.test", Queue: "queue.test"}
+
+func receive(t *testing.T, deliveries <-chan *bus.Delivery) *bus.Delivery {
+	t.Helper()
+
+	select {
+	case delivery, ok := <-deliveries:
+		if !ok {
+			t.Fatal("Expected a delivery, but the subscription was closed")
+		}
+		return delivery
+	case <-time.After(time.Second):
+		t.Fatal("Expected a delivery, but got none")
+		return nil
+	}
+}
+
+func TestMemoryBusKeepsMessagesUntilSubscribed(t *testing.T) {
+	messageBus := bus.NewMemoryBus()
+	defer messageBus.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := messageBus.Publish(topic, bus.Message{Body: []byte("first")}, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries, err := messageBus.Subscribe(topic, "consumer.test", 0, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	delivery := receive(t, deliveries)
+	if string(delivery.Body) != "first" {
+		t.Errorf("Expected body to be %s, but got %s", "first", delivery.Body)
+	}
+}
+
+func TestMemoryBusRedeliversRequeuedMessages(t *testing.T) {
+	messageBus := bus.NewMemoryBus()
+	defer messageBus.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	deliveries, err := messageBus.Subscribe(topic, "consumer.test", 0, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = messageBus.Publish(topic, bus.Message{Body: []byte("first")}, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = receive(t, deliveries).Nack(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	delivery := receive(t, deliveries)
+	if string(delivery.Body) != "first" {
+		t.Errorf("Expected requeued body to be %s, but got %s", "first", delivery.Body)
+	}
+}
+
+func TestMemoryBusRejectsSecondAcknowledgement(t *testing.T) {
+	messageBus := bus.NewMemoryBus()
+	defer messageBus.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	deliveries, err := messageBus.Subscribe(topic, "consumer.test", 0, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = messageBus.Publish(topic, bus.Message{Body: []byte("first")}, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	delivery := receive(t, deliveries)
+	err = delivery.Ack()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = delivery.Nack(true)
+	if !errors.Is(err, bus.ErrAlreadyAcked) {
+		t.Errorf("Expected %v, but got %v", bus.ErrAlreadyAcked, err)
+	}
+}
+
+func TestMemoryBusClosesSubscriptionWhenCancelled(t *testing.T) {
+	messageBus := bus.NewMemoryBus()
+	defer messageBus.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	deliveries, err := messageBus.Subscribe(topic, "consumer.test", 0, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	select {
+	case _, ok := <-deliveries:
+		if ok {
+			t.Error("Expected the subscription to be closed, but got a delivery")
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the subscription to be closed")
+	}
+}
+
+func TestMemoryBusWithholdsDeliveriesBeyondPrefetch(t *testing.T) {
+	messageBus := bus.NewMemoryBus()
+	defer messageBus.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	deliveries, err := messageBus.Subscribe(topic, "consumer.test", 1, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, body := range []string{"first", "second"} {
+		err = messageBus.Publish(topic, bus.Message{Body: []byte(body)}, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first := receive(t, deliveries)
+
+	select {
+	case delivery := <-deliveries:
+		t.Fatalf("Expected no delivery before the first one is acknowledged, but got %s", delivery.Body)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	err = first.Ack()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second := receive(t, deliveries)
+	if string(second.Body) != "second" {
+		t.Errorf("Expected body to be %s, but got %s", "second", second.Body)
+	}
+}
+
+func TestMemoryBusRequeuesUnacknowledgedDeliveriesWhenClosed(t *testing.T) {
+	messageBus := bus.NewMemoryBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	deliveries, err := messageBus.Subscribe(topic, "consumer.test", 1, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = messageBus.Publish(topic, bus.Message{Body: []byte("first")}, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	delivery := receive(t, deliveries)
+	messageBus.Close()
+
+	// The delivery went back to its queue, acknowledging it now would lose
+	// track of it.
+	err = delivery.Ack()
+	if !errors.Is(err, bus.ErrClosed) {
+		t.Errorf("Expected %v, but got %v", bus.ErrClosed, err)
+	}
+	err = delivery.Nack(true)
+	if !errors.Is(err, bus.ErrAlreadyAcked) {
+		t.Errorf("Expected %v, but got %v", bus.ErrAlreadyAcked, err)
+	}
+}
@@ -0,0 +1,445 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/rs/zerolog"
+)
+
+var (
+	ErrNotConfirmed = errors.New("message was not confirmed by the broker")
+)
+
+// RabbitMqBus publishes on a pool of channels in confirm mode and gives every
+// subscription a channel of its own, as AMQP channels must not be shared
+// between goroutines. Topics and consumers are restored after the connection
+// manager reconnects.
+type RabbitMqBus struct {
+	connections *connectionManager
+	publishers  chan *publisherChannel
+	topics      map[Topic]bool
+	mutex       sync.Mutex
+	logger      zerolog.Logger
+}
+
+type publisherChannel struct {
+	channel    *amqp091.Channel
+	generation uint64
+}
+
+// NewRabbitMqBus keeps at most publisherPoolSize idle publisher channels open.
+func NewRabbitMqBus(url string, publisherPoolSize int, logger zerolog.Logger) (*RabbitMqBus, error) {
+	b := &RabbitMqBus{
+		publishers: make(chan *publisherChannel, publisherPoolSize),
+		topics:     make(map[Topic]bool),
+		logger:     logger,
+	}
+
+	connections, err := newConnectionManager(url, logger, b.redeclare)
+	if err != nil {
+		return nil, err
+	}
+	b.connections = connections
+
+	return b, nil
+}
+
+func declareTopic(channel *amqp091.Channel, topic Topic) error {
+	_, err := channel.QueueDeclare(
+		topic.Queue, // name
+		true,        // durable
+		false,       // delete when unused
+		false,       // exclusive
+		false,       // no-wait
+		nil,         // arguments
+	)
+	if err != nil {
+		return errors.Join(err, ErrFailedToDeclare)
+	}
+
+	err = channel.ExchangeDeclare(topic.Exchange, "direct", topic.DurableExchange, false, false, false, nil)
+	if err != nil {
+		return errors.Join(err, ErrFailedToDeclare)
+	}
+
+	err = channel.QueueBind(topic.Queue, "", topic.Exchange, false, nil)
+	if err != nil {
+		return errors.Join(err, ErrFailedToDeclare)
+	}
+
+	return nil
+}
+
+// redeclare restores every topic used so far on a new connection, in case the
+// broker lost them while it was away.
+func (b *RabbitMqBus) redeclare(connection *amqp091.Connection) error {
+	b.mutex.Lock()
+	topics := make([]Topic, 0, len(b.topics))
+	for topic := range b.topics {
+		topics = append(topics, topic)
+	}
+	b.mutex.Unlock()
+
+	if len(topics) == 0 {
+		return nil
+	}
+
+	channel, err := connection.Channel()
+	if err != nil {
+		return errors.Join(err, ErrFailedToDeclare)
+	}
+	defer channel.Close()
+
+	for _, topic := range topics {
+		err = declareTopic(channel, topic)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// declare creates the exchange and the queue of topic the first time the
+// topic is used.
+func (b *RabbitMqBus) declare(topic Topic, ctx context.Context) error {
+	b.mutex.Lock()
+	declared := b.topics[topic]
+	b.mutex.Unlock()
+
+	if declared {
+		return nil
+	}
+
+	connection, _, err := b.connections.current(ctx)
+	if err != nil {
+		return errors.Join(err, ErrFailedToDeclare)
+	}
+
+	channel, err := connection.Channel()
+	if err != nil {
+		return errors.Join(err, ErrFailedToDeclare)
+	}
+	defer channel.Close()
+
+	err = declareTopic(channel, topic)
+	if err != nil {
+		return err
+	}
+
+	b.mutex.Lock()
+	b.topics[topic] = true
+	b.mutex.Unlock()
+
+	return nil
+}
+
+// Publish returns once the broker has confirmed the message, so a nil error
+// means the message is safely queued.
+func (b *RabbitMqBus) Publish(topic Topic, message Message, ctx context.Context) error {
+	err := b.declare(topic, ctx)
+	if err != nil {
+		return err
+	}
+
+	publisher, err := b.acquirePublisher(ctx)
+	if err != nil {
+		return errors.Join(err, ErrFailedToPublish)
+	}
+
+	message = outgoing(message, ctx)
+	headers := amqp091.Table{}
+	for key, value := range message.Headers {
+		headers[key] = value
+	}
+
+	confirmation, err := publisher.channel.PublishWithDeferredConfirmWithContext(
+		ctx,
+		topic.Exchange, // exchange
+		"",             // routing key
+		false,          // mandatory
+		false,          // immediate
+		amqp091.Publishing{
+			ContentType:  message.ContentType,
+			Headers:      headers,
+			Body:         message.Body,
+			DeliveryMode: amqp091.Persistent,
+		},
+	)
+	if err != nil {
+		publisher.channel.Close()
+		return errors.Join(err, ErrFailedToPublish)
+	}
+
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		// The confirmation may still arrive, the channel cannot be reused
+		// without mixing it up with the next one.
+		publisher.channel.Close()
+		return errors.Join(err, ErrFailedToPublish)
+	}
+	b.releasePublisher(publisher)
+
+	if !acked {
+		return errors.Join(ErrNotConfirmed, ErrFailedToPublish)
+	}
+
+	return nil
+}
+
+func (b *RabbitMqBus) acquirePublisher(ctx context.Context) (*publisherChannel, error) {
+	connection, generation, err := b.connections.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		select {
+		case publisher := <-b.publishers:
+			if publisher.generation == generation && !publisher.channel.IsClosed() {
+				return publisher, nil
+			}
+			// The channel belongs to a connection that is gone.
+			publisher.channel.Close()
+			continue
+		default:
+		}
+
+		break
+	}
+
+	channel, err := connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	err = channel.Confirm(false)
+	if err != nil {
+		channel.Close()
+		return nil, err
+	}
+
+	return &publisherChannel{channel: channel, generation: generation}, nil
+}
+
+func (b *RabbitMqBus) releasePublisher(publisher *publisherChannel) {
+	select {
+	case b.publishers <- publisher:
+	default:
+		publisher.channel.Close()
+	}
+}
+
+func (b *RabbitMqBus) Subscribe(topic Topic, consumer string, prefetch int, ctx context.Context) (<-chan *Delivery, error) {
+	err := b.declare(topic, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := b.consume(topic, consumer, prefetch, ctx)
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToSubscribe)
+	}
+
+	deliveries := make(chan *Delivery)
+
+	go func() {
+		defer close(deliveries)
+
+		for {
+			current.forward(deliveries, ctx)
+
+			// Deliveries already handed out can still be acknowledged as long
+			// as the channel is alive, it is closed once they are settled.
+			go current.closeWhenSettled()
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			b.logger.Warn().Str("consumer", consumer).Msg("Consumer lost its channel, registering it again")
+			current, err = b.resubscribe(topic, consumer, prefetch, ctx)
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return deliveries, nil
+}
+
+// resubscribe consumes topic on a new channel, retrying until it succeeds or
+// the subscription ends.
+func (b *RabbitMqBus) resubscribe(topic Topic, consumer string, prefetch int, ctx context.Context) (*subscription, error) {
+	delay := minReconnectDelay
+	for {
+		current, err := b.consume(topic, consumer, prefetch, ctx)
+		if err == nil {
+			return current, nil
+		}
+		if ctx.Err() != nil || errors.Is(err, ErrClosed) {
+			return nil, err
+		}
+
+		b.logger.Error().Err(err).Str("consumer", consumer).Dur("retryIn", delay).Msg("Failed to register consumer")
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+func (b *RabbitMqBus) consume(topic Topic, consumer string, prefetch int, ctx context.Context) (*subscription, error) {
+	connection, _, err := b.connections.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	channel, err := connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	// The queue may have been deleted while the channel was gone.
+	err = declareTopic(channel, topic)
+	if err != nil {
+		channel.Close()
+		return nil, err
+	}
+
+	err = channel.Qos(prefetch, 0, false)
+	if err != nil {
+		channel.Close()
+		return nil, err
+	}
+
+	amqpDeliveries, err := channel.ConsumeWithContext(
+		ctx,
+		topic.Queue, // queue
+		consumer,    // consumer
+		false,       // auto-ack
+		false,       // exclusive
+		false,       // no-local
+		false,       // no-wait
+		nil,         // args
+	)
+	if err != nil {
+		channel.Close()
+		return nil, err
+	}
+
+	return &subscription{channel: channel, deliveries: amqpDeliveries}, nil
+}
+
+// IsConnected reports whether the bus currently has a connection to the
+// broker.
+func (b *RabbitMqBus) IsConnected() bool {
+	return b.connections.IsConnected()
+}
+
+// Ping opens, or reuses, a publisher channel on the current connection. It
+// does not wait for a reconnect.
+func (b *RabbitMqBus) Ping(ctx context.Context) error {
+	if !b.IsConnected() {
+		return ErrNotConnected
+	}
+
+	publisher, err := b.acquirePublisher(ctx)
+	if err != nil {
+		return err
+	}
+	b.releasePublisher(publisher)
+
+	return nil
+}
+
+func (b *RabbitMqBus) Close() error {
+	for {
+		select {
+		case publisher := <-b.publishers:
+			publisher.channel.Close()
+			continue
+		default:
+		}
+
+		break
+	}
+
+	return b.connections.Close()
+}
+
+// subscription is a consumer registered on its own channel.
+type subscription struct {
+	channel     *amqp091.Channel
+	deliveries  <-chan amqp091.Delivery
+	outstanding sync.WaitGroup
+}
+
+// forward hands deliveries on until ctx is done or the channel is closed.
+func (s *subscription) forward(deliveries chan<- *Delivery, ctx context.Context) {
+	for amqpDelivery := range s.deliveries {
+		headers := make(map[string]string, len(amqpDelivery.Headers))
+		for key, value := range amqpDelivery.Headers {
+			if value, ok := value.(string); ok {
+				headers[key] = value
+			}
+		}
+
+		s.outstanding.Add(1)
+		delivery := &Delivery{
+			Message: Message{
+				ContentType: amqpDelivery.ContentType,
+				Headers:     headers,
+				Body:        amqpDelivery.Body,
+			},
+			acknowledger: &rabbitMqAcknowledger{delivery: amqpDelivery, settled: s.outstanding.Done},
+		}
+
+		select {
+		case deliveries <- delivery:
+		case <-ctx.Done():
+			// The broker requeues the delivery once the channel is closed.
+			s.outstanding.Done()
+			return
+		}
+	}
+}
+
+func (s *subscription) closeWhenSettled() {
+	s.outstanding.Wait()
+	s.channel.Close()
+}
+
+// rabbitMqAcknowledger refuses to acknowledge a delivery twice, which would
+// otherwise close the whole channel with a precondition failure.
+type rabbitMqAcknowledger struct {
+	delivery amqp091.Delivery
+	settled  func()
+	acked    atomic.Bool
+}
+
+func (a *rabbitMqAcknowledger) ack() error {
+	if a.acked.Swap(true) {
+		return ErrAlreadyAcked
+	}
+	defer a.settled()
+
+	return a.delivery.Ack(false)
+}
+
+func (a *rabbitMqAcknowledger) nack(requeue bool) error {
+	if a.acked.Swap(true) {
+		return ErrAlreadyAcked
+	}
+	defer a.settled()
+
+	return a.delivery.Nack(false, requeue)
+}
@@ -0,0 +1,248 @@
+package bus_test
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/bus"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/rs/zerolog"
+)
+
+// brokerProxy forwards connections to the broker and can cut all of them at
+// once, the way a broker restart or a network failure does.
+type brokerProxy struct {
+	listener net.Listener
+	target   string
+
+	mutex       sync.Mutex
+	connections []net.Conn
+}
+
+func newBrokerProxy(t *testing.T, target string) *brokerProxy {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proxy := &brokerProxy{listener: listener, target: target}
+	t.Cleanup(func() {
+		listener.Close()
+		proxy.drop()
+	})
+
+	go proxy.serve()
+
+	return proxy
+}
+
+func (p *brokerProxy) serve() {
+	for {
+		client, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		broker, err := net.Dial("tcp", p.target)
+		if err != nil {
+			client.Close()
+			continue
+		}
+
+		p.mutex.Lock()
+		p.connections = append(p.connections, client, broker)
+		p.mutex.Unlock()
+
+		go func() {
+			io.Copy(broker, client)
+			broker.Close()
+		}()
+		go func() {
+			io.Copy(client, broker)
+			client.Close()
+		}()
+	}
+}
+
+func (p *brokerProxy) drop() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for _, connection := range p.connections {
+		connection.Close()
+	}
+	p.connections = nil
+}
+
+// newTestRabbitMqBus connects a bus to TEST_RABBITMQ_URL through a proxy, or
+// skips the test when it is not set. The returned channel talks to the broker
+// directly and is used to interfere with the topology.
+func newTestRabbitMqBus(t *testing.T) (*bus.RabbitMqBus, *brokerProxy, *amqp091.Channel) {
+	brokerUrl := os.Getenv("TEST_RABBITMQ_URL")
+	if brokerUrl == "" {
+		t.Skip("TEST_RABBITMQ_URL is not set")
+	}
+
+	parsed, err := url.Parse(brokerUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := newBrokerProxy(t, parsed.Host)
+	parsed.Host = proxy.listener.Addr().String()
+
+	messageBus, err := bus.NewRabbitMqBus(parsed.String(), 2, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { messageBus.Close() })
+
+	connection, err := amqp091.Dial(brokerUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { connection.Close() })
+
+	channel, err := connection.Channel()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return messageBus, proxy, channel
+}
+
+// newTestTopic returns a topic of its own for the test, removed afterwards.
+func newTestTopic(t *testing.T, channel *amqp091.Channel) bus.Topic {
+	name := "test." + uuid.NewString()
+	t.Cleanup(func() {
+		channel.QueueDelete(name, false, false, false)
+		channel.ExchangeDelete(name, false, false)
+	})
+
+	return bus.Topic{Exchange: name, Queue: name}
+}
+
+func TestRabbitMqBusResubscribesAfterReconnecting(t *testing.T) {
+	messageBus, proxy, channel := newTestRabbitMqBus(t)
+	testTopic := newTestTopic(t, channel)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	deliveries, err := messageBus.Subscribe(testTopic, "consumer.test", 1, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = messageBus.Publish(testTopic, bus.Message{Body: []byte("first")}, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := receive(t, deliveries)
+
+	proxy.drop()
+
+	// The delivery is acknowledged on a connection that is gone, so the
+	// broker requeues it and it comes again once the consumer is registered
+	// on the new connection.
+	first.Ack()
+
+	select {
+	case delivery, ok := <-deliveries:
+		if !ok {
+			t.Fatal("Expected the subscription to survive the reconnect")
+		}
+		if string(delivery.Body) != "first" {
+			t.Errorf("Expected the requeued body to be %s, but got %s", "first", delivery.Body)
+		}
+		delivery.Ack()
+	case <-ctx.Done():
+		t.Fatal("Expected the requeued delivery after reconnecting")
+	}
+
+	// Publishing waits for the new connection instead of using a publisher
+	// channel of the old one.
+	err = messageBus.Publish(testTopic, bus.Message{Body: []byte("second")}, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivery := receive(t, deliveries); string(delivery.Body) != "second" {
+		t.Errorf("Expected body to be %s, but got %s", "second", delivery.Body)
+	}
+}
+
+func TestRabbitMqBusResubscribesWhenTheQueueIsDeleted(t *testing.T) {
+	messageBus, _, channel := newTestRabbitMqBus(t)
+	testTopic := newTestTopic(t, channel)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	deliveries, err := messageBus.Subscribe(testTopic, "consumer.test", 1, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleting the queue cancels the consumer, the bus declares the queue
+	// again and registers a new one. Messages published before that are lost,
+	// so publishing is repeated until one arrives.
+	_, err = channel.QueueDelete(testTopic.Queue, false, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		err = messageBus.Publish(testTopic, bus.Message{Body: []byte("first")}, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case delivery, ok := <-deliveries:
+			if !ok {
+				t.Fatal("Expected the subscription to survive the deleted queue")
+			}
+			delivery.Ack()
+			return
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatal("Expected a delivery after the queue was declared again")
+		}
+	}
+}
+
+func TestRabbitMqBusFailsPublishesWhoseChannelIsClosed(t *testing.T) {
+	messageBus, _, channel := newTestRabbitMqBus(t)
+	deletedTopic := newTestTopic(t, channel)
+	otherTopic := newTestTopic(t, channel)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err := messageBus.Publish(deletedTopic, bus.Message{Body: []byte("first")}, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Publishing to a missing exchange makes the broker close the channel
+	// instead of confirming the message.
+	err = channel.ExchangeDelete(deletedTopic.Exchange, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = messageBus.Publish(deletedTopic, bus.Message{Body: []byte("second")}, ctx)
+	if !errors.Is(err, bus.ErrFailedToPublish) {
+		t.Errorf("Expected %v, but got %v", bus.ErrFailedToPublish, err)
+	}
+
+	// The closed channel is not handed out again.
+	err = messageBus.Publish(otherTopic, bus.Message{Body: []byte("third")}, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
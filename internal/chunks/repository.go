@@ -12,6 +12,10 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
+const (
+	ThumbnailTilesRepresentationId = "thumbnails"
+)
+
 var (
 	ErrChunkAlreadyExists = errors.New("chunk already exists")
 	ErrFailedToGetChunks  = errors.New("failed to get chunks")
@@ -54,6 +58,8 @@ func NewChunksRepository(dependencies *app.Dependencies) *ChunksRepository {
 }
 
 func (r *ChunksRepository) Insert(chunk *DbChunk, ctx context.Context) (*DbChunk, error) {
+	ctx, span := r.tracer.Start(ctx, "chunks.repository.insert")
+	defer span.End()
 	r.logger.Debug().Str("videoId", chunk.VideoId.String()).Msg("Inserting chunk")
 
 	_, err := r.db.NamedExecContext(ctx, "INSERT INTO chunks (id, video_id, representation_id, sequence, content_location, start_ms, end_ms) VALUES (:id,:video_id, :representation_id, :sequence, :content_location, :start_ms, :end_ms)", chunk)
@@ -61,8 +67,9 @@ func (r *ChunksRepository) Insert(chunk *DbChunk, ctx context.Context) (*DbChunk
 		return chunk, nil
 	}
 
-	pgError := err.(*pq.Error)
-	if pgError.Code == "23505" {
+	// Errors such as a cancelled context do not come from Postgres.
+	var pgError *pq.Error
+	if errors.As(err, &pgError) && pgError.Code == "23505" {
 		return nil, ErrChunkAlreadyExists
 	}
 
@@ -82,3 +89,17 @@ func (r *ChunksRepository) GetMany(videoId uuid.UUID, startMs, endMs int64, ctx
 
 	return chunks, nil
 }
+
+func (r *ChunksRepository) GetOverlapping(videoId uuid.UUID, representationId string, startMs, endMs int64, ctx context.Context) ([]*DbChunk, error) {
+	ctx, span := r.tracer.Start(ctx, "chunks.repository.getOverlapping")
+	defer span.End()
+	r.logger.Debug().Str("videoId", videoId.String()).Str("representationId", representationId).Int64("startMs", startMs).Int64("endMs", endMs).Msg("Searching overlapping chunks")
+
+	var chunks []*DbChunk
+	err := r.db.SelectContext(ctx, &chunks, "SELECT * FROM chunks WHERE video_id = $1 AND representation_id = $2 AND end_ms > $3 AND start_ms < $4 ORDER BY sequence", videoId, representationId, startMs, endMs)
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToGetChunks)
+	}
+
+	return chunks, nil
+}
@@ -0,0 +1,51 @@
+package chunks_test
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/chunks"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestInsertReportsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{name: "stored chunk", err: &pq.Error{Code: "23505"}, expected: chunks.ErrChunkAlreadyExists},
+		// Aborting an export cancels the query.
+		{name: "cancelled context", err: context.Canceled, expected: context.Canceled},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			repository := chunks.NewChunksRepository(&app.Dependencies{
+				Postgres: sqlx.NewDb(db, "postgres"),
+				Logger:   zerolog.Nop(),
+				Tracer:   noop.NewTracerProvider().Tracer(""),
+			})
+			mock.ExpectExec("INSERT INTO chunks").WillReturnError(test.err)
+
+			chunk := chunks.NewDbChunk(uuid.New(), "0", 1, "chunks/0/stream-00001.m4s", 0, 4000)
+			_, err = repository.Insert(chunk, context.Background())
+			if !errors.Is(err, test.expected) {
+				t.Errorf("Expected %v, but got %v", test.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package clips
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/storage"
+	"errors"
+	"io"
+)
+
+const (
+	FailedToUpload   = "failed to upload"
+	FailedToDownload = "failed to download"
+	FailedToPresign  = "failed to presign object"
+)
+
+type FileStorage struct {
+	objectStore storage.ObjectStore
+}
+
+func NewFileStorage(objectStore storage.ObjectStore) *FileStorage {
+	return &FileStorage{
+		objectStore: objectStore,
+	}
+}
+
+func (f *FileStorage) Exists(key string, ctx context.Context) (bool, error) {
+	return f.objectStore.Exists(key, ctx)
+}
+
+func (f *FileStorage) Download(key string, ctx context.Context) (io.ReadCloser, error) {
+	object, err := f.objectStore.Get(key, ctx)
+	if err != nil {
+		return nil, errors.Join(err, errors.New(FailedToDownload))
+	}
+
+	return object.Body, nil
+}
+
+func (f *FileStorage) Upload(key string, body io.Reader, contentType string, ctx context.Context) error {
+	err := f.objectStore.Put(key, body, contentType, ctx)
+	if err != nil {
+		return errors.Join(err, errors.New(FailedToUpload))
+	}
+
+	return nil
+}
+
+func (f *FileStorage) PresignDownload(key string, filename string, ctx context.Context) (string, error) {
+	presignedUrl, err := f.objectStore.PresignGet(key, storage.PresignOptions{Filename: filename}, ctx)
+	if err != nil {
+		return "", errors.Join(err, errors.New(FailedToPresign))
+	}
+
+	return presignedUrl, nil
+}
@@ -0,0 +1,68 @@
+package clips
+
+import (
+	"errors"
+	"slices"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported clip format")
+)
+
+type Format struct {
+	Extension   string
+	ContentType string
+	HasVideo    bool
+	HasAudio    bool
+	VideoFilter string
+	CodecArgs   []string
+}
+
+var formats = map[string]*Format{
+	"mp4": {
+		Extension:   "mp4",
+		ContentType: "video/mp4",
+		HasVideo:    true,
+		HasAudio:    true,
+		CodecArgs:   []string{"-c:v", "libx264", "-preset", "veryfast", "-crf", "23", "-c:a", "aac", "-movflags", "+faststart"},
+	},
+	"m4a": {
+		Extension:   "m4a",
+		ContentType: "audio/mp4",
+		HasAudio:    true,
+		CodecArgs:   []string{"-c:a", "aac", "-movflags", "+faststart"},
+	},
+	"mp3": {
+		Extension:   "mp3",
+		ContentType: "audio/mpeg",
+		HasAudio:    true,
+		CodecArgs:   []string{"-c:a", "libmp3lame", "-q:a", "4"},
+	},
+	"webp": {
+		Extension:   "webp",
+		ContentType: "image/webp",
+		HasVideo:    true,
+		VideoFilter: "fps=10,scale=480:-2",
+		CodecArgs:   []string{"-c:v", "libwebp", "-q:v", "60", "-loop", "0"},
+	},
+}
+
+func ParseFormat(extension string) (*Format, error) {
+	format, ok := formats[extension]
+	if !ok {
+		return nil, ErrUnsupportedFormat
+	}
+
+	return format, nil
+}
+
+// Extensions lists the formats clips can be rendered in.
+func Extensions() []string {
+	extensions := make([]string, 0, len(formats))
+	for extension := range formats {
+		extensions = append(extensions, extension)
+	}
+	slices.Sort(extensions)
+
+	return extensions
+}
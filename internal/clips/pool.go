@@ -0,0 +1,111 @@
+package clips
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/utils"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrRenderPending = errors.New("clip is still rendering")
+	ErrRendererBusy  = errors.New("too many clips are rendering")
+)
+
+// job is a render shared by every request for the same clip.
+type job struct {
+	done chan struct{}
+	err  error
+}
+
+// Pool renders clips in the background, at most concurrency at a time. A
+// request for a clip that is already rendering waits for the same render
+// instead of starting another one, and no more than queueSize clips are
+// rendering or waiting for their turn.
+type Pool struct {
+	slots     chan struct{}
+	queueSize int
+
+	mutex sync.Mutex
+	jobs  map[string]*job
+
+	renders *utils.Jobs
+}
+
+func NewPool(concurrency int, queueSize int) *Pool {
+	return &Pool{
+		slots:     make(chan struct{}, concurrency),
+		queueSize: queueSize,
+		jobs:      make(map[string]*job),
+		renders:   utils.NewJobs(context.Background()),
+	}
+}
+
+// Run renders the clip stored under key with render, unless it is rendering
+// already, and waits at most wait for it to finish. It returns
+// ErrRenderPending when the render takes longer, which keeps going after Run
+// returns, and ErrRendererBusy when the queue is full. Renders outlive ctx but
+// keep its trace.
+func (p *Pool) Run(key string, wait time.Duration, render func(ctx context.Context) error, ctx context.Context) error {
+	j, err := p.schedule(key, render, trace.SpanFromContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-j.done:
+		return j.err
+	case <-timer.C:
+		return ErrRenderPending
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) schedule(key string, render func(ctx context.Context) error, span trace.Span) (*job, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if j, ok := p.jobs[key]; ok {
+		return j, nil
+	}
+	if len(p.jobs) >= p.queueSize {
+		return nil, ErrRendererBusy
+	}
+
+	j := &job{done: make(chan struct{})}
+	p.jobs[key] = j
+	p.renders.Go(func(ctx context.Context) {
+		j.err = p.run(render, trace.ContextWithSpan(ctx, span))
+
+		p.mutex.Lock()
+		delete(p.jobs, key)
+		p.mutex.Unlock()
+		close(j.done)
+	})
+
+	return j, nil
+}
+
+func (p *Pool) run(render func(ctx context.Context) error, ctx context.Context) error {
+	select {
+	case p.slots <- struct{}{}:
+		defer func() { <-p.slots }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return render(ctx)
+}
+
+// Shutdown waits for the renders in flight. Renders still running when ctx is
+// done are aborted, a later request renders them again.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	return p.renders.Shutdown(ctx)
+}
@@ -0,0 +1,150 @@
+package clips_test
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/clips"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoolRendersAtMostConcurrencyClipsAtOnce(t *testing.T) {
+	pool := clips.NewPool(2, 8)
+
+	var rendering, most atomic.Int32
+	render := func(ctx context.Context) error {
+		current := rendering.Add(1)
+		defer rendering.Add(-1)
+		for {
+			seen := most.Load()
+			if current <= seen || most.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := pool.Run(key, time.Second, render, context.Background())
+			if err != nil {
+				t.Errorf("Expected %s to render, but got %v", key, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if most.Load() != 2 {
+		t.Errorf("Expected at most %d clips to render at once, but got %d", 2, most.Load())
+	}
+}
+
+func TestPoolSharesTheRenderOfTheSameClip(t *testing.T) {
+	pool := clips.NewPool(2, 8)
+
+	var renders atomic.Int32
+	release := make(chan struct{})
+	render := func(ctx context.Context) error {
+		renders.Add(1)
+		<-release
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := pool.Run("clip", time.Second, render, context.Background())
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if renders.Load() != 1 {
+		t.Errorf("Expected the clip to be rendered once, but it was rendered %d times", renders.Load())
+	}
+}
+
+func TestPoolReportsRendersThatOutlastTheWait(t *testing.T) {
+	pool := clips.NewPool(1, 1)
+
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	render := func(ctx context.Context) error {
+		<-release
+		close(finished)
+		return nil
+	}
+
+	err := pool.Run("slow", 10*time.Millisecond, render, context.Background())
+	if !errors.Is(err, clips.ErrRenderPending) {
+		t.Fatalf("Expected %v, but got %v", clips.ErrRenderPending, err)
+	}
+
+	err = pool.Run("other", 10*time.Millisecond, render, context.Background())
+	if !errors.Is(err, clips.ErrRendererBusy) {
+		t.Errorf("Expected %v while the queue is full, but got %v", clips.ErrRendererBusy, err)
+	}
+
+	// The render goes on after the request gave up waiting.
+	close(release)
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the pending render to finish")
+	}
+}
+
+func TestPoolReturnsRenderErrors(t *testing.T) {
+	pool := clips.NewPool(1, 1)
+	failure := errors.New("ffmpeg failed")
+
+	err := pool.Run("clip", time.Second, func(ctx context.Context) error { return failure }, context.Background())
+	if !errors.Is(err, failure) {
+		t.Errorf("Expected %v, but got %v", failure, err)
+	}
+
+	// A failed render is not cached, the next request renders again.
+	err = pool.Run("clip", time.Second, func(ctx context.Context) error { return nil }, context.Background())
+	if err != nil {
+		t.Errorf("Expected the clip to render again, but got %v", err)
+	}
+}
+
+func TestPoolShutdownAbortsRendersThatDoNotFinish(t *testing.T) {
+	pool := clips.NewPool(1, 1)
+
+	started := make(chan struct{})
+	aborted := make(chan error, 1)
+	render := func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		aborted <- ctx.Err()
+		return ctx.Err()
+	}
+
+	go pool.Run("clip", time.Second, render, context.Background())
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := pool.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected %v, but got %v", context.DeadlineExceeded, err)
+	}
+	if err := <-aborted; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the render to be canceled, but got %v", err)
+	}
+}
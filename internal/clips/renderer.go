@@ -0,0 +1,301 @@
+package clips
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/chunks"
+	"dewarrum/vocabulary-leveling/internal/inits"
+	"dewarrum/vocabulary-leveling/internal/manifests"
+	"dewarrum/vocabulary-leveling/internal/utils"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	videoRepresentationId = "0"
+	audioRepresentationId = "1"
+)
+
+var (
+	ErrFailedToRender    = errors.New("failed to render clip")
+	ErrNoChunksAvailable = errors.New("no chunks available for clip range")
+)
+
+type Clip struct {
+	VideoId    uuid.UUID
+	SubtitleId string
+	Sequence   int
+	StartMs    int64
+	EndMs      int64
+	Text       string
+	TextFrom   int64
+	TextTo     int64
+	BurnIn     bool
+	Format     *Format
+}
+
+func (c *Clip) Key() string {
+	suffix := ""
+	if c.BurnIn && c.Format.HasVideo {
+		suffix = "-subtitled"
+	}
+
+	return fmt.Sprintf("%s/clips/%s%s.%s", c.VideoId, c.SubtitleId, suffix, c.Format.Extension)
+}
+
+func (c *Clip) Filename() string {
+	return fmt.Sprintf("clip-%d.%s", c.Sequence, c.Format.Extension)
+}
+
+type Renderer struct {
+	chunksRepository    *chunks.ChunksRepository
+	initsRepository     *inits.InitsRepository
+	manifestsRepository *manifests.ManifestsRepository
+	fileStorage         *FileStorage
+	pool                *Pool
+	renderWait          time.Duration
+	logger              zerolog.Logger
+	tracer              trace.Tracer
+}
+
+func NewRenderer(dependencies *app.Dependencies) *Renderer {
+	return &Renderer{
+		chunksRepository:    chunks.NewChunksRepository(dependencies),
+		initsRepository:     inits.NewInitsRepository(dependencies),
+		manifestsRepository: manifests.NewManifestsRepository(dependencies),
+		fileStorage:         NewFileStorage(dependencies.ObjectStore),
+		pool:                NewPool(dependencies.Config.Clips.RenderConcurrency, dependencies.Config.Clips.RenderQueueSize),
+		renderWait:          dependencies.Config.Clips.RenderWait,
+		logger:              dependencies.Logger,
+		tracer:              dependencies.Tracer,
+	}
+}
+
+// Render returns a presigned download URL for the clip, rendering it and caching
+// it in S3 first if it has not been requested before. Clips are rendered in the
+// background by the pool, ErrRenderPending is returned when the render takes
+// longer than the configured wait and ErrRendererBusy when too many clips are
+// rendering already.
+func (r *Renderer) Render(clip *Clip, ctx context.Context) (string, error) {
+	ctx, span := r.tracer.Start(ctx, "clips.renderer.render", trace.WithAttributes(attribute.String("key", clip.Key())))
+	defer span.End()
+
+	exists, err := r.fileStorage.Exists(clip.Key(), ctx)
+	if err != nil {
+		return "", errors.Join(err, ErrFailedToRender)
+	}
+
+	if !exists {
+		err = r.pool.Run(clip.Key(), r.renderWait, func(ctx context.Context) error { return r.render(clip, ctx) }, ctx)
+		if errors.Is(err, ErrRenderPending) || errors.Is(err, ErrRendererBusy) {
+			return "", err
+		}
+		if err != nil {
+			span.RecordError(err, trace.WithStackTrace(true))
+			return "", errors.Join(err, ErrFailedToRender)
+		}
+	}
+
+	return r.fileStorage.PresignDownload(clip.Key(), clip.Filename(), ctx)
+}
+
+// Shutdown waits for the clips that are rendering, see Pool.Shutdown.
+func (r *Renderer) Shutdown(ctx context.Context) error {
+	return r.pool.Shutdown(ctx)
+}
+
+func (r *Renderer) render(clip *Clip, ctx context.Context) error {
+	ctx, span := r.tracer.Start(ctx, "clips.renderer.renderClip", trace.WithAttributes(attribute.String("key", clip.Key())))
+	defer span.End()
+	r.logger.Info().Str("key", clip.Key()).Msg("Rendering clip")
+
+	directory := fmt.Sprintf("tmp/clips/%s", uuid.New())
+	err := os.MkdirAll(directory, 0755)
+	if err != nil {
+		return errors.Join(err, errors.New("failed to create directory"))
+	}
+	defer os.RemoveAll(directory)
+
+	dbManifest, err := r.manifestsRepository.GetByVideoId(clip.VideoId, ctx)
+	if err != nil {
+		return err
+	}
+
+	manifestMeta, err := dbManifest.GetMeta()
+	if err != nil {
+		return err
+	}
+
+	chunkDuration, err := manifestMeta.GetChunkDuration()
+	if err != nil {
+		return err
+	}
+
+	dbChunks, err := r.chunksRepository.GetMany(clip.VideoId, max(clip.StartMs-chunkDuration, 0), clip.EndMs+chunkDuration, ctx)
+	if err != nil {
+		return err
+	}
+
+	dbInits, err := r.initsRepository.GetByVideoId(clip.VideoId, ctx)
+	if err != nil {
+		return err
+	}
+
+	var args []string
+	if clip.Format.HasVideo {
+		inputArgs, err := r.prepareInput(clip, videoRepresentationId, dbInits, dbChunks, directory, ctx)
+		if err != nil {
+			return err
+		}
+		args = append(args, inputArgs...)
+	}
+	if clip.Format.HasAudio {
+		inputArgs, err := r.prepareInput(clip, audioRepresentationId, dbInits, dbChunks, directory, ctx)
+		if err != nil {
+			return err
+		}
+		args = append(args, inputArgs...)
+	}
+
+	args = append(args, "-t", formatSeconds(clip.EndMs-clip.StartMs))
+	if clip.Format.HasVideo {
+		args = append(args, "-map", "0:v:0")
+	} else {
+		args = append(args, "-vn")
+	}
+	if clip.Format.HasAudio && clip.Format.HasVideo {
+		args = append(args, "-map", "1:a:0")
+	} else if clip.Format.HasAudio {
+		args = append(args, "-map", "0:a:0")
+	} else {
+		args = append(args, "-an")
+	}
+
+	videoFilter, err := r.videoFilter(clip, directory)
+	if err != nil {
+		return err
+	}
+	if videoFilter != "" {
+		args = append(args, "-vf", videoFilter)
+	}
+
+	output := fmt.Sprintf("%s/clip.%s", directory, clip.Format.Extension)
+	args = append(args, clip.Format.CodecArgs...)
+	args = append(args, "-y", output)
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	err = cmd.Run()
+	if err != nil {
+		return errors.Join(err, errors.New("failed to run ffmpeg"))
+	}
+
+	file, err := os.Open(output)
+	if err != nil {
+		return errors.Join(err, errors.New("failed to open file"))
+	}
+	defer file.Close()
+
+	err = r.fileStorage.Upload(clip.Key(), file, clip.Format.ContentType, ctx)
+	if err != nil {
+		return err
+	}
+
+	r.logger.Info().Str("key", clip.Key()).Msg("Clip rendered successfully")
+
+	return nil
+}
+
+// prepareInput concatenates the init segment and the media segments of a single
+// representation into one fragmented MP4 and returns the ffmpeg input arguments
+// that seek it to the clip start.
+func (r *Renderer) prepareInput(clip *Clip, representationId string, dbInits []*inits.DbInit, dbChunks []*chunks.DbChunk, directory string, ctx context.Context) ([]string, error) {
+	representationInits := utils.Filter(dbInits, func(init *inits.DbInit) bool { return init.RepresentationId == representationId })
+	representationChunks := utils.Filter(dbChunks, func(chunk *chunks.DbChunk) bool { return chunk.RepresentationId == representationId })
+	if len(representationInits) == 0 || len(representationChunks) == 0 {
+		return nil, ErrNoChunksAvailable
+	}
+
+	sort.Slice(representationChunks, func(i, j int) bool {
+		return representationChunks[i].Sequence < representationChunks[j].Sequence
+	})
+
+	path := fmt.Sprintf("%s/%s.mp4", directory, representationId)
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, errors.Join(err, errors.New("failed to create file"))
+	}
+	defer file.Close()
+
+	err = r.appendObject(file, representationInits[0].ContentLocation, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, chunk := range representationChunks {
+		err = r.appendObject(file, chunk.ContentLocation, ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	offsetMs := max(clip.StartMs-representationChunks[0].StartMs, 0)
+
+	return []string{"-use_tfdt", "0", "-ss", formatSeconds(offsetMs), "-i", path}, nil
+}
+
+func (r *Renderer) appendObject(file *os.File, key string, ctx context.Context) error {
+	body, err := r.fileStorage.Download(key, ctx)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	_, err = io.Copy(file, body)
+	if err != nil {
+		return errors.Join(err, errors.New(FailedToDownload))
+	}
+
+	return nil
+}
+
+func (r *Renderer) videoFilter(clip *Clip, directory string) (string, error) {
+	videoFilter := ""
+	if clip.BurnIn && clip.Format.HasVideo {
+		path := fmt.Sprintf("%s/subtitle.srt", directory)
+		srt := fmt.Sprintf("1\n%s --> %s\n%s\n", formatSrtTimestamp(clip.TextFrom-clip.StartMs), formatSrtTimestamp(clip.TextTo-clip.StartMs), clip.Text)
+		err := os.WriteFile(path, []byte(srt), 0644)
+		if err != nil {
+			return "", errors.Join(err, errors.New("failed to write subtitle file"))
+		}
+
+		videoFilter = fmt.Sprintf("subtitles=%s", path)
+	}
+
+	if clip.Format.VideoFilter != "" {
+		if videoFilter != "" {
+			videoFilter += ","
+		}
+		videoFilter += clip.Format.VideoFilter
+	}
+
+	return videoFilter, nil
+}
+
+func formatSeconds(ms int64) string {
+	return fmt.Sprintf("%d.%03d", ms/1000, ms%1000)
+}
+
+func formatSrtTimestamp(ms int64) string {
+	ms = max(ms, 0)
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
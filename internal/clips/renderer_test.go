@@ -0,0 +1,60 @@
+package clips_test
+
+import (
+	"dewarrum/vocabulary-leveling/internal/clips"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestClipKeyWithoutBurnIn(t *testing.T) {
+	videoId := uuid.MustParse("7f1f2a4e-8f3c-4a53-9e8f-3f4a0c6c2b11")
+	format, err := clips.ParseFormat("mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clip := &clips.Clip{VideoId: videoId, SubtitleId: "7f1f2a4e-8f3c-4a53-9e8f-3f4a0c6c2b11/12", Sequence: 12, Format: format}
+
+	expected := "7f1f2a4e-8f3c-4a53-9e8f-3f4a0c6c2b11/clips/7f1f2a4e-8f3c-4a53-9e8f-3f4a0c6c2b11/12.mp4"
+	if clip.Key() != expected {
+		t.Errorf("Expected key to be %s, but got %s", expected, clip.Key())
+	}
+}
+
+func TestClipKeyWithBurnIn(t *testing.T) {
+	videoId := uuid.MustParse("7f1f2a4e-8f3c-4a53-9e8f-3f4a0c6c2b11")
+	format, err := clips.ParseFormat("webp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clip := &clips.Clip{VideoId: videoId, SubtitleId: "7f1f2a4e-8f3c-4a53-9e8f-3f4a0c6c2b11/12", Sequence: 12, BurnIn: true, Format: format}
+
+	expected := "7f1f2a4e-8f3c-4a53-9e8f-3f4a0c6c2b11/clips/7f1f2a4e-8f3c-4a53-9e8f-3f4a0c6c2b11/12-subtitled.webp"
+	if clip.Key() != expected {
+		t.Errorf("Expected key to be %s, but got %s", expected, clip.Key())
+	}
+}
+
+func TestClipKeyIgnoresBurnInForAudio(t *testing.T) {
+	videoId := uuid.MustParse("7f1f2a4e-8f3c-4a53-9e8f-3f4a0c6c2b11")
+	format, err := clips.ParseFormat("mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clip := &clips.Clip{VideoId: videoId, SubtitleId: "0d9b1c4e-2a6f-4f7e-9b3a-5c8d7e6f1a20/3", Sequence: 3, BurnIn: true, Format: format}
+
+	expected := "7f1f2a4e-8f3c-4a53-9e8f-3f4a0c6c2b11/clips/0d9b1c4e-2a6f-4f7e-9b3a-5c8d7e6f1a20/3.mp3"
+	if clip.Key() != expected {
+		t.Errorf("Expected key to be %s, but got %s", expected, clip.Key())
+	}
+}
+
+func TestParseFormatRejectsUnknownExtension(t *testing.T) {
+	_, err := clips.ParseFormat("gif")
+	if err != clips.ErrUnsupportedFormat {
+		t.Errorf("Expected %v, but got %v", clips.ErrUnsupportedFormat, err)
+	}
+}
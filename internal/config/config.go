@@ -0,0 +1,341 @@
+package config
+
+import (
+	"bytes"
+	"dewarrum/vocabulary-leveling/internal/sessions"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	StorageBackendS3    = "s3"
+	StorageBackendLocal = "local"
+
+	MessageBusRabbitMq = "rabbitmq"
+	MessageBusMemory   = "memory"
+
+	SearchBackendElasticsearch = "elasticsearch"
+	SearchBackendPostgres      = "postgres"
+
+	SessionStorageRedis    = "redis"
+	SessionStoragePostgres = "postgres"
+	SessionStorageMemory   = "memory"
+
+	TelemetryExporterOtlp       = "otlp"
+	TelemetryExporterPrometheus = "prometheus"
+	TelemetryExporterConsole    = "console"
+	TelemetryExporterNone       = "none"
+
+	OtlpProtocolGrpc         = "grpc"
+	OtlpProtocolHttpProtobuf = "http/protobuf"
+
+	EnvironmentDevelopment = "development"
+)
+
+var (
+	ErrInvalidConfig = errors.New("invalid configuration")
+	sameSiteModes    = []string{"Lax", "Strict", "None"}
+)
+
+// Config holds every setting of the application. Each setting can be given in
+// the YAML file passed to Load and overridden by the environment variable in
+// its env tag.
+type Config struct {
+	Server        Server        `yaml:"server"`
+	Logto         Logto         `yaml:"logto"`
+	Storage       Storage       `yaml:"storage"`
+	Bus           Bus           `yaml:"bus"`
+	Postgres      Postgres      `yaml:"postgres"`
+	Search        Search        `yaml:"search"`
+	Sessions      Sessions      `yaml:"sessions"`
+	Telemetry     Telemetry     `yaml:"telemetry"`
+	Clips         Clips         `yaml:"clips"`
+	Export        Export        `yaml:"export"`
+	Shutdown      Shutdown      `yaml:"shutdown"`
+	Normalization Normalization `yaml:"normalization"`
+
+	// Sections are the parts of the configuration that were validated, and
+	// the clients that are opened for them.
+	Sections Sections `yaml:"-"`
+}
+
+type Server struct {
+	Environment string   `yaml:"environment" env:"ENVIRONMENT"`
+	Port        int      `yaml:"port" env:"PORT"`
+	BodyLimit   int      `yaml:"bodyLimit" env:"SERVER_BODY_LIMIT"`
+	CorsOrigins []string `yaml:"corsOrigins" env:"CORS_ORIGINS"`
+}
+
+type Logto struct {
+	Endpoint  string `yaml:"endpoint" env:"LOGTO_ENDPOINT"`
+	AppId     string `yaml:"appId" env:"LOGTO_APP_ID"`
+	AppSecret string `yaml:"appSecret" env:"LOGTO_APP_SECRET"`
+}
+
+type Storage struct {
+	Backend string       `yaml:"backend" env:"STORAGE_BACKEND"`
+	S3      S3Storage    `yaml:"s3"`
+	Local   LocalStorage `yaml:"local"`
+}
+
+type S3Storage struct {
+	Bucket          string `yaml:"bucket" env:"S3_BUCKET"`
+	Prefix          string `yaml:"prefix" env:"S3_PREFIX"`
+	AccessKeyId     string `yaml:"accessKeyId" env:"AWS_ACCESS_KEY_ID"`
+	SecretAccessKey string `yaml:"secretAccessKey" env:"AWS_SECRET_ACCESS_KEY"`
+	Region          string `yaml:"region" env:"AWS_REGION"`
+	EndpointUrl     string `yaml:"endpointUrl" env:"AWS_ENDPOINT_URL"`
+}
+
+type LocalStorage struct {
+	Root   string `yaml:"root" env:"LOCAL_STORAGE_ROOT"`
+	Url    string `yaml:"url" env:"LOCAL_STORAGE_URL"`
+	Secret string `yaml:"secret" env:"LOCAL_STORAGE_SECRET"`
+}
+
+type Bus struct {
+	Backend           string `yaml:"backend" env:"MESSAGE_BUS"`
+	RabbitMqUrl       string `yaml:"rabbitMqUrl" env:"RABBITMQ_URL"`
+	PublisherPoolSize int    `yaml:"publisherPoolSize" env:"RABBITMQ_PUBLISHER_POOL_SIZE"`
+}
+
+type Postgres struct {
+	Url          string `yaml:"url" env:"POSTGRES_URL"`
+	MaxOpenConns int    `yaml:"maxOpenConns" env:"POSTGRES_MAX_OPEN_CONNS"`
+	MaxIdleConns int    `yaml:"maxIdleConns" env:"POSTGRES_MAX_IDLE_CONNS"`
+}
+
+type Search struct {
+	Backend       string        `yaml:"backend" env:"SEARCH_BACKEND"`
+	Elasticsearch Elasticsearch `yaml:"elasticsearch"`
+}
+
+type Elasticsearch struct {
+	Url      string `yaml:"url" env:"ELASTICSEARCH_URL"`
+	Username string `yaml:"username" env:"ELASTICSEARCH_USERNAME"`
+	Password string `yaml:"password" env:"ELASTICSEARCH_PASSWORD"`
+}
+
+type Sessions struct {
+	Storage        string        `yaml:"storage" env:"SESSION_STORAGE"`
+	RedisUrl       string        `yaml:"redisUrl" env:"REDIS_URL"`
+	CookieSecure   bool          `yaml:"cookieSecure" env:"SESSION_COOKIE_SECURE"`
+	CookieSameSite string        `yaml:"cookieSameSite" env:"SESSION_COOKIE_SAME_SITE"`
+	CookieDomain   string        `yaml:"cookieDomain" env:"SESSION_COOKIE_DOMAIN"`
+	Expiration     time.Duration `yaml:"expiration" env:"SESSION_EXPIRATION"`
+	SweepInterval  time.Duration `yaml:"sweepInterval" env:"SESSION_SWEEP_INTERVAL"`
+}
+
+// Telemetry selects the exporters. The OTLP exporters read their endpoint,
+// headers and the sampler from the standard OTEL_* variables themselves, the
+// endpoints are only loaded here to pick the default exporters.
+type Telemetry struct {
+	TracesExporter   string   `yaml:"tracesExporter" env:"OTEL_TRACES_EXPORTER"`
+	MetricsExporters []string `yaml:"metricsExporters" env:"OTEL_METRICS_EXPORTER"`
+	Protocol         string   `yaml:"protocol" env:"OTEL_EXPORTER_OTLP_PROTOCOL"`
+	TracesProtocol   string   `yaml:"tracesProtocol" env:"OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"`
+	MetricsProtocol  string   `yaml:"metricsProtocol" env:"OTEL_EXPORTER_OTLP_METRICS_PROTOCOL"`
+	Endpoint         string   `yaml:"-" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	TracesEndpoint   string   `yaml:"-" env:"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"`
+	MetricsEndpoint  string   `yaml:"-" env:"OTEL_EXPORTER_OTLP_METRICS_ENDPOINT"`
+}
+
+type Clips struct {
+	// PaddingMs is added before and after a subtitle when it is clipped.
+	PaddingMs int64 `yaml:"paddingMs" env:"CLIP_PADDING_MS"`
+	// RenderConcurrency is how many clips are rendered at a time, and
+	// RenderQueueSize how many may be rendering or waiting for their turn.
+	RenderConcurrency int `yaml:"renderConcurrency" env:"CLIP_RENDER_CONCURRENCY"`
+	RenderQueueSize   int `yaml:"renderQueueSize" env:"CLIP_RENDER_QUEUE_SIZE"`
+	// RenderWait is how long a request waits for its clip before it is told
+	// to come back later.
+	RenderWait time.Duration `yaml:"renderWait" env:"CLIP_RENDER_WAIT"`
+}
+
+type Export struct {
+	SegmentDuration time.Duration `yaml:"segmentDuration" env:"EXPORT_SEGMENT_DURATION"`
+}
+
+// Normalization configures how the text of captions is cleaned up for search
+// before it is stored next to the text as shown on screen.
+type Normalization struct {
+	// StripSdh removes sound descriptions such as [음악] or (웃음) and music
+	// notes. Captions made only of them are marked as non-speech either way.
+	StripSdh bool `yaml:"stripSdh" env:"NORMALIZE_STRIP_SDH"`
+	// SplitSpeakers splits captions on dialogue dashes and speaker labels such
+	// as "민수:" into utterances, removing both from the text.
+	SplitSpeakers bool `yaml:"splitSpeakers" env:"NORMALIZE_SPLIT_SPEAKERS"`
+	// MaxSpeakerLength is the longest text before a colon, in characters,
+	// that is taken for a speaker label. 0 leaves labels in the text.
+	MaxSpeakerLength int `yaml:"maxSpeakerLength" env:"NORMALIZE_MAX_SPEAKER_LENGTH"`
+}
+
+type Shutdown struct {
+	// GracePeriod is how long requests and exports in flight may take to
+	// finish after a termination signal. Exports still running are requeued.
+	GracePeriod time.Duration `yaml:"gracePeriod" env:"SHUTDOWN_GRACE_PERIOD"`
+	// FlushTimeout bounds exporting the remaining telemetry and closing the
+	// clients afterwards.
+	FlushTimeout time.Duration `yaml:"flushTimeout" env:"SHUTDOWN_FLUSH_TIMEOUT"`
+}
+
+// Sections selects the parts of the configuration a binary uses. Settings
+// that are only required by the other sections may be left empty.
+type Sections struct {
+	Server      bool
+	ObjectStore bool
+	Bus         bool
+	Postgres    bool
+	Search      bool
+	// Sessions needs Postgres when sessions are stored there.
+	Sessions bool
+	// MemoryBus allows MESSAGE_BUS=memory, whose messages only reach the
+	// exporters of the same process.
+	MemoryBus bool
+}
+
+var (
+	ApiSections    = Sections{Server: true, ObjectStore: true, Bus: true, Postgres: true, Search: true, Sessions: true, MemoryBus: true}
+	WorkerSections = Sections{ObjectStore: true, Bus: true, Postgres: true, Search: true}
+	IngestSections = Sections{ObjectStore: true, Bus: true, Postgres: true}
+	// RenormalizeSections are used by the one-off backfill of migration 000017.
+	RenormalizeSections = Sections{Postgres: true, Search: true}
+)
+
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			BodyLimit:   500 * 1024 * 1024,
+			CorsOrigins: []string{"*"},
+		},
+		Storage: Storage{
+			Backend: StorageBackendS3,
+			S3: S3Storage{
+				Bucket: "default",
+			},
+			Local: LocalStorage{
+				Root: "storage",
+			},
+		},
+		Bus: Bus{
+			Backend:           MessageBusRabbitMq,
+			PublisherPoolSize: 8,
+		},
+		Postgres: Postgres{
+			MaxOpenConns: 100,
+			MaxIdleConns: 10,
+		},
+		Search: Search{
+			Backend: SearchBackendElasticsearch,
+		},
+		Sessions: Sessions{
+			Storage:        SessionStorageRedis,
+			CookieSameSite: "Lax",
+			Expiration:     24 * time.Hour,
+			SweepInterval:  sessions.DefaultSweepInterval,
+		},
+		Clips: Clips{
+			PaddingMs:         500,
+			RenderConcurrency: 2,
+			RenderQueueSize:   16,
+			RenderWait:        20 * time.Second,
+		},
+		Export: Export{
+			SegmentDuration: 2 * time.Second,
+		},
+		Shutdown: Shutdown{
+			GracePeriod:  30 * time.Second,
+			FlushTimeout: 10 * time.Second,
+		},
+		Normalization: Normalization{
+			StripSdh:         true,
+			SplitSpeakers:    true,
+			MaxSpeakerLength: 10,
+		},
+	}
+}
+
+// Load reads the YAML file at path, if any, and then the environment on top
+// of the defaults. Every problem with the settings the sections need is
+// reported at once.
+func Load(path string, sections Sections) (*Config, error) {
+	config := Default()
+
+	var problems []error
+	if path != "" {
+		err := config.loadFile(path)
+		if err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	problems = append(problems, loadEnv(config)...)
+	config.Sessions.normalize()
+	config.Telemetry.resolveDefaults()
+	problems = append(problems, config.validate(sections)...)
+
+	if len(problems) > 0 {
+		return nil, errors.Join(append([]error{ErrInvalidConfig}, problems...)...)
+	}
+
+	config.Sections = sections
+	return config, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	err = decoder.Decode(c)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+// normalize accepts the SameSite mode in any case.
+func (s *Sessions) normalize() {
+	for _, mode := range sameSiteModes {
+		if strings.EqualFold(s.CookieSameSite, mode) {
+			s.CookieSameSite = mode
+		}
+	}
+}
+
+// resolveDefaults exports traces only when an OTLP endpoint is configured, so
+// local runs do not need a collector, and always serves Prometheus metrics.
+func (t *Telemetry) resolveDefaults() {
+	if t.TracesExporter == "" {
+		t.TracesExporter = TelemetryExporterNone
+		if t.Endpoint != "" || t.TracesEndpoint != "" {
+			t.TracesExporter = TelemetryExporterOtlp
+		}
+	}
+
+	if len(t.MetricsExporters) == 0 {
+		t.MetricsExporters = []string{TelemetryExporterPrometheus}
+		if t.Endpoint != "" || t.MetricsEndpoint != "" {
+			t.MetricsExporters = append(t.MetricsExporters, TelemetryExporterOtlp)
+		}
+	}
+
+	if t.Protocol == "" {
+		t.Protocol = OtlpProtocolHttpProtobuf
+	}
+	if t.TracesProtocol == "" {
+		t.TracesProtocol = t.Protocol
+	}
+	if t.MetricsProtocol == "" {
+		t.MetricsProtocol = t.Protocol
+	}
+}
@@ -0,0 +1,142 @@
+package config_test
+
+import (
+	"dewarrum/vocabulary-leveling/internal/config"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadAppliesDefaults(t *testing.T) {
+	t.Setenv("POSTGRES_URL", "postgres://localhost/test")
+	t.Setenv("MESSAGE_BUS", "memory")
+
+	loaded, err := config.Load("", config.Sections{Bus: true, Postgres: true, MemoryBus: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Postgres.MaxOpenConns != 100 {
+		t.Errorf("Expected max open connections to be %d, but got %d", 100, loaded.Postgres.MaxOpenConns)
+	}
+	if loaded.Export.SegmentDuration != 2*time.Second {
+		t.Errorf("Expected segment duration to be %s, but got %s", 2*time.Second, loaded.Export.SegmentDuration)
+	}
+	if !loaded.Normalization.StripSdh || !loaded.Normalization.SplitSpeakers {
+		t.Errorf("Expected sound descriptions to be stripped and speakers split, but got %+v", loaded.Normalization)
+	}
+	if loaded.Telemetry.TracesExporter != config.TelemetryExporterNone {
+		t.Errorf("Expected traces exporter to be %s, but got %s", config.TelemetryExporterNone, loaded.Telemetry.TracesExporter)
+	}
+}
+
+func TestLoadPrefersEnvironmentOverFile(t *testing.T) {
+	path := writeFile(t, `
+postgres:
+  url: postgres://file/test
+  maxOpenConns: 20
+clips:
+  paddingMs: 250
+export:
+  segmentDuration: 4s
+`)
+	t.Setenv("POSTGRES_URL", "postgres://env/test")
+
+	loaded, err := config.Load(path, config.Sections{Postgres: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Postgres.Url != "postgres://env/test" {
+		t.Errorf("Expected url to be %s, but got %s", "postgres://env/test", loaded.Postgres.Url)
+	}
+	if loaded.Postgres.MaxOpenConns != 20 {
+		t.Errorf("Expected max open connections to be %d, but got %d", 20, loaded.Postgres.MaxOpenConns)
+	}
+	if loaded.Clips.PaddingMs != 250 {
+		t.Errorf("Expected clip padding to be %d, but got %d", 250, loaded.Clips.PaddingMs)
+	}
+	if loaded.Export.SegmentDuration != 4*time.Second {
+		t.Errorf("Expected segment duration to be %s, but got %s", 4*time.Second, loaded.Export.SegmentDuration)
+	}
+}
+
+func TestLoadSplitsLists(t *testing.T) {
+	t.Setenv("PORT", "3000")
+	t.Setenv("LOGTO_ENDPOINT", "https://logto.example.com")
+	t.Setenv("LOGTO_APP_ID", "app")
+	t.Setenv("LOGTO_APP_SECRET", "secret")
+	t.Setenv("CORS_ORIGINS", "https://a.example.com, https://b.example.com")
+
+	loaded, err := config.Load("", config.Sections{Server: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(loaded.Server.CorsOrigins) != 2 || loaded.Server.CorsOrigins[1] != "https://b.example.com" {
+		t.Errorf("Expected two origins, but got %v", loaded.Server.CorsOrigins)
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	t.Setenv("POSTGRES_MAX_OPEN_CONNS", "many")
+	t.Setenv("MESSAGE_BUS", "kafka")
+	t.Setenv("SESSION_COOKIE_SAME_SITE", "none")
+
+	_, err := config.Load("", config.Sections{Bus: true, Postgres: true, Sessions: true})
+	if !errors.Is(err, config.ErrInvalidConfig) {
+		t.Fatalf("Expected %v, but got %v", config.ErrInvalidConfig, err)
+	}
+
+	for _, key := range []string{"POSTGRES_MAX_OPEN_CONNS", "POSTGRES_URL", "MESSAGE_BUS", "REDIS_URL", "SESSION_COOKIE_SAME_SITE"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("Expected a problem with %s, but got %v", key, err)
+		}
+	}
+}
+
+func TestLoadRejectsMemoryBusWithoutExporters(t *testing.T) {
+	t.Setenv("POSTGRES_URL", "postgres://localhost/test")
+	t.Setenv("MESSAGE_BUS", "memory")
+
+	_, err := config.Load("", config.Sections{Bus: true, Postgres: true})
+	if !errors.Is(err, config.ErrInvalidConfig) || !strings.Contains(err.Error(), "MESSAGE_BUS") {
+		t.Errorf("Expected a problem with MESSAGE_BUS, but got %v", err)
+	}
+}
+
+func TestLoadOnlyValidatesSelectedSections(t *testing.T) {
+	t.Setenv("SEARCH_BACKEND", "postgres")
+
+	_, err := config.Load("", config.Sections{Search: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadRejectsUnknownFileSettings(t *testing.T) {
+	path := writeFile(t, `
+postgres:
+  uri: postgres://file/test
+`)
+
+	_, err := config.Load(path, config.Sections{})
+	if err == nil || !strings.Contains(err.Error(), "uri") {
+		t.Errorf("Expected the unknown setting to be reported, but got %v", err)
+	}
+}
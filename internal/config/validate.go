@@ -0,0 +1,175 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+type validator struct {
+	problems []error
+}
+
+func (v *validator) require(key string, value string) {
+	if value == "" {
+		v.problems = append(v.problems, fmt.Errorf("%s is required", key))
+	}
+}
+
+func (v *validator) check(ok bool, key string, message string) {
+	if !ok {
+		v.problems = append(v.problems, fmt.Errorf("%s %s", key, message))
+	}
+}
+
+func (v *validator) oneOf(key string, value string, allowed ...string) {
+	if !slices.Contains(allowed, value) {
+		v.problems = append(v.problems, fmt.Errorf("%s must be one of %s, got %q", key, strings.Join(allowed, ", "), value))
+	}
+}
+
+func (v *validator) url(key string, value string) {
+	if value == "" {
+		v.require(key, value)
+		return
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		v.problems = append(v.problems, fmt.Errorf("%s must be an absolute URL", key))
+	}
+}
+
+func (c *Config) validate(sections Sections) []error {
+	v := &validator{}
+
+	c.Telemetry.validate(v)
+	v.check(c.Export.SegmentDuration > 0, "EXPORT_SEGMENT_DURATION", "must be positive")
+	v.check(c.Shutdown.GracePeriod >= 0, "SHUTDOWN_GRACE_PERIOD", "must not be negative")
+	v.check(c.Shutdown.FlushTimeout > 0, "SHUTDOWN_FLUSH_TIMEOUT", "must be positive")
+	v.check(c.Normalization.MaxSpeakerLength >= 0, "NORMALIZE_MAX_SPEAKER_LENGTH", "must not be negative")
+
+	if sections.Server {
+		c.Server.validate(v)
+		c.Logto.validate(v)
+		v.check(c.Clips.PaddingMs >= 0, "CLIP_PADDING_MS", "must not be negative")
+		v.check(c.Clips.RenderConcurrency > 0, "CLIP_RENDER_CONCURRENCY", "must be positive")
+		v.check(c.Clips.RenderQueueSize >= c.Clips.RenderConcurrency, "CLIP_RENDER_QUEUE_SIZE", "must not be less than CLIP_RENDER_CONCURRENCY")
+		v.check(c.Clips.RenderWait >= 0, "CLIP_RENDER_WAIT", "must not be negative")
+	}
+	if sections.ObjectStore {
+		c.Storage.validate(v)
+	}
+	if sections.Bus {
+		c.Bus.validate(v)
+		if c.Bus.Backend == MessageBusMemory && !sections.MemoryBus {
+			v.check(false, "MESSAGE_BUS", "memory needs the exporters to run in the same process, use rabbitmq")
+		}
+	}
+	if sections.Postgres {
+		c.Postgres.validate(v)
+	}
+	if sections.Search {
+		c.Search.validate(v)
+	}
+	if sections.Sessions {
+		c.Sessions.validate(v)
+		if c.Sessions.Storage == SessionStoragePostgres && !sections.Postgres {
+			v.check(false, "SESSION_STORAGE", "postgres needs a Postgres connection")
+		}
+	}
+
+	return v.problems
+}
+
+func (s *Server) validate(v *validator) {
+	if s.Port == 0 {
+		v.require("PORT", "")
+	} else {
+		v.check(s.Port > 0 && s.Port <= 65535, "PORT", "must be between 1 and 65535")
+	}
+	v.check(s.BodyLimit > 0, "SERVER_BODY_LIMIT", "must be positive")
+	v.check(len(s.CorsOrigins) > 0, "CORS_ORIGINS", "must list at least one origin")
+}
+
+func (l *Logto) validate(v *validator) {
+	v.url("LOGTO_ENDPOINT", l.Endpoint)
+	v.require("LOGTO_APP_ID", l.AppId)
+	v.require("LOGTO_APP_SECRET", l.AppSecret)
+}
+
+func (s *Storage) validate(v *validator) {
+	v.oneOf("STORAGE_BACKEND", s.Backend, StorageBackendS3, StorageBackendLocal)
+
+	switch s.Backend {
+	case StorageBackendS3:
+		v.require("S3_BUCKET", s.S3.Bucket)
+		v.require("AWS_ACCESS_KEY_ID", s.S3.AccessKeyId)
+		v.require("AWS_SECRET_ACCESS_KEY", s.S3.SecretAccessKey)
+		v.require("AWS_REGION", s.S3.Region)
+		v.url("AWS_ENDPOINT_URL", s.S3.EndpointUrl)
+	case StorageBackendLocal:
+		v.require("LOCAL_STORAGE_ROOT", s.Local.Root)
+		v.url("LOCAL_STORAGE_URL", s.Local.Url)
+		v.require("LOCAL_STORAGE_SECRET", s.Local.Secret)
+	}
+}
+
+func (b *Bus) validate(v *validator) {
+	v.oneOf("MESSAGE_BUS", b.Backend, MessageBusRabbitMq, MessageBusMemory)
+
+	if b.Backend == MessageBusRabbitMq {
+		if b.RabbitMqUrl == "" {
+			v.require("RABBITMQ_URL", b.RabbitMqUrl)
+		} else {
+			_, err := amqp091.ParseURI(b.RabbitMqUrl)
+			v.check(err == nil, "RABBITMQ_URL", "must be an amqp:// or amqps:// URL")
+		}
+		v.check(b.PublisherPoolSize > 0, "RABBITMQ_PUBLISHER_POOL_SIZE", "must be positive")
+	}
+}
+
+func (p *Postgres) validate(v *validator) {
+	v.require("POSTGRES_URL", p.Url)
+	v.check(p.MaxOpenConns > 0, "POSTGRES_MAX_OPEN_CONNS", "must be positive")
+	v.check(p.MaxIdleConns >= 0 && p.MaxIdleConns <= p.MaxOpenConns, "POSTGRES_MAX_IDLE_CONNS", "must be between 0 and POSTGRES_MAX_OPEN_CONNS")
+}
+
+func (s *Search) validate(v *validator) {
+	v.oneOf("SEARCH_BACKEND", s.Backend, SearchBackendElasticsearch, SearchBackendPostgres)
+
+	if s.Backend == SearchBackendElasticsearch {
+		v.url("ELASTICSEARCH_URL", s.Elasticsearch.Url)
+		v.require("ELASTICSEARCH_USERNAME", s.Elasticsearch.Username)
+		v.require("ELASTICSEARCH_PASSWORD", s.Elasticsearch.Password)
+	}
+}
+
+func (s *Sessions) validate(v *validator) {
+	v.oneOf("SESSION_STORAGE", s.Storage, SessionStorageRedis, SessionStoragePostgres, SessionStorageMemory)
+	if s.Storage == SessionStorageRedis {
+		v.require("REDIS_URL", s.RedisUrl)
+	}
+
+	v.oneOf("SESSION_COOKIE_SAME_SITE", s.CookieSameSite, sameSiteModes...)
+	// Browsers drop SameSite=None cookies that are not secure, which would
+	// silently break the sign in callback.
+	if s.CookieSameSite == "None" {
+		v.check(s.CookieSecure, "SESSION_COOKIE_SAME_SITE", "None requires SESSION_COOKIE_SECURE=true")
+	}
+
+	v.check(s.Expiration > 0, "SESSION_EXPIRATION", "must be a positive duration")
+	v.check(s.SweepInterval > 0, "SESSION_SWEEP_INTERVAL", "must be a positive duration")
+}
+
+func (t *Telemetry) validate(v *validator) {
+	v.oneOf("OTEL_TRACES_EXPORTER", t.TracesExporter, TelemetryExporterOtlp, TelemetryExporterConsole, TelemetryExporterNone)
+	for _, exporter := range t.MetricsExporters {
+		v.oneOf("OTEL_METRICS_EXPORTER", exporter, TelemetryExporterOtlp, TelemetryExporterPrometheus, TelemetryExporterConsole, TelemetryExporterNone)
+	}
+	v.oneOf("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", t.TracesProtocol, OtlpProtocolGrpc, OtlpProtocolHttpProtobuf)
+	v.oneOf("OTEL_EXPORTER_OTLP_METRICS_PROTOCOL", t.MetricsProtocol, OtlpProtocolGrpc, OtlpProtocolHttpProtobuf)
+}
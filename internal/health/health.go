@@ -0,0 +1,183 @@
+package health
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/subtitles"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/healthstatus"
+	"github.com/gofiber/storage/redis/v3"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	DefaultTimeout = 2 * time.Second
+
+	StatusOk          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+var (
+	ErrClusterUnhealthy = errors.New("elasticsearch cluster health is red")
+	ErrIndexMissing     = errors.New("subtitles index does not exist")
+)
+
+// Check probes one dependency. It fails when the dependency cannot serve
+// requests right now.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+type Status struct {
+	Name      string  `json:"name"`
+	Healthy   bool    `json:"healthy"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Report struct {
+	Healthy      bool      `json:"healthy"`
+	CheckedAt    time.Time `json:"checkedAt"`
+	Dependencies []Status  `json:"dependencies"`
+}
+
+// Status is the overall status the readiness probes answer with. Which
+// dependency failed and why is only listed on the admin health endpoint.
+func (r *Report) Status() string {
+	if !r.Healthy {
+		return StatusUnavailable
+	}
+
+	return StatusOk
+}
+
+// Checker runs the checks of every dependency that was opened, each with its
+// own timeout so that one hanging dependency does not hide the others.
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+	logger  zerolog.Logger
+	tracer  trace.Tracer
+}
+
+func NewChecker(dependencies *app.Dependencies) *Checker {
+	var checks []Check
+
+	if dependencies.Postgres != nil {
+		checks = append(checks, Check{Name: "postgres", Run: dependencies.Postgres.PingContext})
+	}
+	if dependencies.Bus != nil {
+		checks = append(checks, Check{Name: "bus", Run: dependencies.Bus.Ping})
+	}
+	if dependencies.ElasticsearchClient != nil {
+		checks = append(checks, Check{Name: "elasticsearch", Run: elasticsearchCheck(dependencies.ElasticsearchClient)})
+	}
+	// Postgres sessions are covered by the postgres check.
+	if storage, ok := dependencies.SessionStorage.(*redis.Storage); ok {
+		checks = append(checks, Check{Name: "redis", Run: func(ctx context.Context) error {
+			return storage.Conn().Ping(ctx).Err()
+		}})
+	}
+	if dependencies.ObjectStore != nil {
+		checks = append(checks, Check{Name: "storage", Run: dependencies.ObjectStore.Ping})
+	}
+
+	return &Checker{
+		checks:  checks,
+		timeout: DefaultTimeout,
+		logger:  dependencies.Logger,
+		tracer:  dependencies.Tracer,
+	}
+}
+
+// elasticsearchCheck accepts a yellow cluster, a single node cluster never
+// allocates replicas.
+func elasticsearchCheck(client *elasticsearch.TypedClient) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		health, err := client.Cluster.Health().Do(ctx)
+		if err != nil {
+			return err
+		}
+		if health.Status == healthstatus.Red {
+			return ErrClusterUnhealthy
+		}
+
+		exists, err := client.Indices.Exists(subtitles.IndexName).Do(ctx)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrIndexMissing
+		}
+
+		return nil
+	}
+}
+
+// Check runs every check concurrently and reports them in the order they were
+// registered.
+func (c *Checker) Check(ctx context.Context) *Report {
+	ctx, span := c.tracer.Start(ctx, "health.checker.check")
+	defer span.End()
+
+	statuses := make([]Status, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = c.run(check, ctx)
+		}()
+	}
+	wg.Wait()
+
+	report := &Report{
+		Healthy:      true,
+		CheckedAt:    time.Now().In(time.UTC),
+		Dependencies: statuses,
+	}
+	for _, status := range statuses {
+		if !status.Healthy {
+			report.Healthy = false
+			c.logger.Warn().Str("dependency", status.Name).Str("error", status.Error).Msg("Dependency is unhealthy")
+		}
+	}
+
+	return report
+}
+
+func (c *Checker) run(check Check, ctx context.Context) Status {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	// A check that ignores its context must not hold up the whole report.
+	result := make(chan error, 1)
+	go func() {
+		result <- check.Run(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	status := Status{
+		Name:      check.Name,
+		Healthy:   err == nil,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+
+	return status
+}
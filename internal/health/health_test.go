@@ -0,0 +1,138 @@
+package health_test
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/bus"
+	"dewarrum/vocabulary-leveling/internal/health"
+	"dewarrum/vocabulary-leveling/internal/storage"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func newDependencies(t *testing.T) *app.Dependencies {
+	return &app.Dependencies{
+		Bus:         bus.NewMemoryBus(),
+		ObjectStore: storage.NewLocalStore(filepath.Join(t.TempDir(), "storage"), "http://localhost/storage", []byte("secret")),
+		Logger:      zerolog.Nop(),
+		Tracer:      noop.NewTracerProvider().Tracer(""),
+	}
+}
+
+// hangingBus is a bus whose broker stopped answering. Its ping only returns
+// once the test is over, whatever its context says.
+type hangingBus struct {
+	bus.Bus
+	release chan struct{}
+}
+
+func (b *hangingBus) Ping(ctx context.Context) error {
+	<-b.release
+	return nil
+}
+
+// slowBus is a bus whose ping takes longer than the timeout, but gives up once
+// its context is done.
+type slowBus struct {
+	bus.Bus
+}
+
+func (b *slowBus) Ping(ctx context.Context) error {
+	select {
+	case <-time.After(time.Minute):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// expectTimedOut checks that a report took about one timeout, with the bus
+// failing and the storage still reported.
+func expectTimedOut(t *testing.T, report *health.Report, elapsed time.Duration) {
+	if elapsed < health.DefaultTimeout || elapsed > health.DefaultTimeout+time.Second {
+		t.Errorf("Expected the check to take %s, but it took %s", health.DefaultTimeout, elapsed)
+	}
+	if report.Healthy || report.Status() != health.StatusUnavailable {
+		t.Errorf("Expected report to be unavailable, but got %+v", report)
+	}
+
+	busStatus := report.Dependencies[0]
+	if busStatus.Healthy || busStatus.Error != context.DeadlineExceeded.Error() {
+		t.Errorf("Expected bus to fail with %v, but got %+v", context.DeadlineExceeded, busStatus)
+	}
+	if !report.Dependencies[1].Healthy {
+		t.Errorf("Expected storage to stay healthy, but got %+v", report.Dependencies[1])
+	}
+}
+
+func TestCheckReportsHealthyDependencies(t *testing.T) {
+	dependencies := newDependencies(t)
+
+	report := health.NewChecker(dependencies).Check(context.Background())
+	if !report.Healthy {
+		t.Errorf("Expected report to be healthy, but got %+v", report)
+	}
+
+	if len(report.Dependencies) != 2 {
+		t.Fatalf("Expected %d dependencies, but got %d", 2, len(report.Dependencies))
+	}
+	if report.Dependencies[0].Name != "bus" || report.Dependencies[1].Name != "storage" {
+		t.Errorf("Expected bus and storage, but got %+v", report.Dependencies)
+	}
+}
+
+func TestCheckReportsUnhealthyDependency(t *testing.T) {
+	dependencies := newDependencies(t)
+	dependencies.Bus.Close()
+
+	report := health.NewChecker(dependencies).Check(context.Background())
+	if report.Healthy {
+		t.Error("Expected report to be unhealthy")
+	}
+
+	busStatus := report.Dependencies[0]
+	if busStatus.Healthy || busStatus.Error != bus.ErrClosed.Error() {
+		t.Errorf("Expected bus to fail with %v, but got %+v", bus.ErrClosed, busStatus)
+	}
+	if !report.Dependencies[1].Healthy {
+		t.Errorf("Expected storage to stay healthy, but got %+v", report.Dependencies[1])
+	}
+}
+
+func TestCheckTimesOutSlowDependency(t *testing.T) {
+	t.Parallel()
+	dependencies := newDependencies(t)
+	dependencies.Bus = &slowBus{Bus: dependencies.Bus}
+
+	start := time.Now()
+	report := health.NewChecker(dependencies).Check(context.Background())
+	expectTimedOut(t, report, time.Since(start))
+}
+
+func TestCheckDoesNotWaitForHangingDependency(t *testing.T) {
+	t.Parallel()
+	dependencies := newDependencies(t)
+	hanging := &hangingBus{Bus: dependencies.Bus, release: make(chan struct{})}
+	defer close(hanging.release)
+	dependencies.Bus = hanging
+
+	start := time.Now()
+	report := health.NewChecker(dependencies).Check(context.Background())
+	expectTimedOut(t, report, time.Since(start))
+}
+
+func TestCheckStopsWithItsContext(t *testing.T) {
+	dependencies := newDependencies(t)
+	dependencies.Bus = &slowBus{Bus: dependencies.Bus}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report := health.NewChecker(dependencies).Check(ctx)
+	if report.Dependencies[0].Error != context.Canceled.Error() {
+		t.Errorf("Expected bus to fail with %v, but got %+v", context.Canceled, report.Dependencies[0])
+	}
+}
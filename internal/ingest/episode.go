@@ -0,0 +1,46 @@
+package ingest
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	episodePattern = regexp.MustCompile(`(?i)^(.*?)[\s._-]*S(\d{1,2})[\s._-]?E(\d{1,3})`)
+)
+
+type EpisodeInfo struct {
+	SeriesName string
+	Season     int
+	Episode    int
+}
+
+// ParseEpisode extracts the series name, season and episode from file names
+// such as "Reply.1988.S01E03.1080p.mkv". It returns nil for anything that
+// does not carry an SxxEyy marker.
+func ParseEpisode(filename string) *EpisodeInfo {
+	matches := episodePattern.FindStringSubmatch(filename)
+	if len(matches) != 4 {
+		return nil
+	}
+
+	season, err := strconv.Atoi(matches[2])
+	if err != nil {
+		return nil
+	}
+
+	episode, err := strconv.Atoi(matches[3])
+	if err != nil {
+		return nil
+	}
+
+	seriesName := strings.NewReplacer(".", " ", "_", " ").Replace(matches[1])
+	seriesName = strings.Join(strings.Fields(seriesName), " ")
+
+	return &EpisodeInfo{
+		SeriesName: seriesName,
+		Season:     season,
+		Episode:    episode,
+	}
+}
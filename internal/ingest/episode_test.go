@@ -0,0 +1,47 @@
+package ingest_test
+
+import (
+	"dewarrum/vocabulary-leveling/internal/ingest"
+	"testing"
+)
+
+func TestParseEpisodeDottedName(t *testing.T) {
+	episode := ingest.ParseEpisode("Reply.1988.S01E03.1080p.WEB-DL")
+	if episode == nil {
+		t.Fatal("Expected episode to be parsed")
+	}
+
+	if episode.SeriesName != "Reply 1988" {
+		t.Errorf("Expected series name to be %s, but got %s", "Reply 1988", episode.SeriesName)
+	}
+
+	if episode.Season != 1 {
+		t.Errorf("Expected season to be %d, but got %d", 1, episode.Season)
+	}
+
+	if episode.Episode != 3 {
+		t.Errorf("Expected episode to be %d, but got %d", 3, episode.Episode)
+	}
+}
+
+func TestParseEpisodeLowercaseWithSpaces(t *testing.T) {
+	episode := ingest.ParseEpisode("Crash Landing on You - s02e116")
+	if episode == nil {
+		t.Fatal("Expected episode to be parsed")
+	}
+
+	if episode.SeriesName != "Crash Landing on You" {
+		t.Errorf("Expected series name to be %s, but got %s", "Crash Landing on You", episode.SeriesName)
+	}
+
+	if episode.Season != 2 || episode.Episode != 116 {
+		t.Errorf("Expected S02E116, but got S%02dE%02d", episode.Season, episode.Episode)
+	}
+}
+
+func TestParseEpisodeWithoutMarker(t *testing.T) {
+	episode := ingest.ParseEpisode("Parasite (2019)")
+	if episode != nil {
+		t.Errorf("Expected no episode, but got %+v", episode)
+	}
+}
@@ -0,0 +1,319 @@
+package ingest
+
+import (
+	"context"
+	"database/sql"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/series"
+	"dewarrum/vocabulary-leveling/internal/subtitles"
+	"dewarrum/vocabulary-leveling/internal/videos"
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ingestNamespace scopes the ids of ingested videos.
+var ingestNamespace = uuid.MustParse("5b0e6c1e-8f3a-4d7b-9c2e-1f4a6d8b3e70")
+
+type Ingester struct {
+	ingestionsRepository *IngestionsRepository
+	seriesRepository     *series.SeriesRepository
+	seasonsRepository    *series.SeasonsRepository
+	videosRepository     *videos.VideosRepository
+	videosFileStorage    *videos.FileStorage
+	videosMessages       *videos.MessageQueue
+	subtitleTracks       *subtitles.SubtitleTracksRepository
+	subtitlesFileStorage *subtitles.FileStorage
+	subtitlesMessages    *subtitles.MessageQueue
+	logger               zerolog.Logger
+	tracer               trace.Tracer
+}
+
+func NewIngester(dependencies *app.Dependencies) (*Ingester, error) {
+	videosMessages, err := videos.NewMessageQueue(dependencies)
+	if err != nil {
+		return nil, errors.Join(err, errors.New("failed to create videos message queue"))
+	}
+
+	subtitlesMessages, err := subtitles.NewMessageQueue(dependencies)
+	if err != nil {
+		return nil, errors.Join(err, errors.New("failed to create subtitles message queue"))
+	}
+
+	return &Ingester{
+		ingestionsRepository: NewIngestionsRepository(dependencies),
+		seriesRepository:     series.NewSeriesRepository(dependencies),
+		seasonsRepository:    series.NewSeasonsRepository(dependencies),
+		videosRepository:     videos.NewVideosRepository(dependencies),
+		videosFileStorage:    videos.NewFileStorage(dependencies.ObjectStore),
+		videosMessages:       videosMessages,
+		subtitleTracks:       subtitles.NewSubtitleTracksRepository(dependencies),
+		subtitlesFileStorage: subtitles.NewFileStorage(dependencies.ObjectStore),
+		subtitlesMessages:    subtitlesMessages,
+		logger:               dependencies.Logger,
+		tracer:               dependencies.Tracer,
+	}, nil
+}
+
+// Run ingests every video under root that has not been ingested before.
+func (i *Ingester) Run(root string, ctx context.Context) error {
+	mediaFiles, err := ScanLibrary(root)
+	if err != nil {
+		return errors.Join(err, errors.New("failed to scan library"))
+	}
+
+	return i.ingestAll(mediaFiles, ctx)
+}
+
+// Watch rescans root every interval until ctx is cancelled. A file is only
+// picked up once its size has not changed between two scans, so videos that
+// are still being copied into the folder are left alone.
+func (i *Ingester) Watch(root string, interval time.Duration, ctx context.Context) error {
+	previousSizes := make(map[string]int64)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		mediaFiles, err := ScanLibrary(root)
+		if err != nil {
+			i.logger.Error().Err(err).Str("root", root).Msg("Failed to scan library")
+		}
+
+		var stableFiles []*MediaFile
+		sizes := make(map[string]int64)
+		for _, mediaFile := range mediaFiles {
+			sizes[mediaFile.Path] = mediaFile.Size
+			if previousSize, ok := previousSizes[mediaFile.Path]; ok && previousSize == mediaFile.Size {
+				stableFiles = append(stableFiles, mediaFile)
+			}
+		}
+		previousSizes = sizes
+
+		err = i.ingestAll(stableFiles, ctx)
+		if err != nil {
+			i.logger.Error().Err(err).Str("root", root).Msg("Failed to ingest library")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (i *Ingester) ingestAll(mediaFiles []*MediaFile, ctx context.Context) error {
+	var errs []error
+	for _, mediaFile := range mediaFiles {
+		if ctx.Err() != nil {
+			break
+		}
+
+		exists, err := i.ingestionsRepository.Exists(mediaFile.Path, mediaFile.Size, ctx)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if exists {
+			i.logger.Debug().Str("path", mediaFile.Path).Msg("Skipping already ingested video")
+			continue
+		}
+
+		err = i.ingest(mediaFile, ctx)
+		if err != nil {
+			i.logger.Error().Err(err).Str("path", mediaFile.Path).Msg("Failed to ingest video")
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ingest uploads a video and its sidecars and queues their exports. The ids of
+// the video and its tracks are derived from the file, so that ingesting it
+// again after a failure, or from two ingesters at once, finishes the same
+// video instead of creating another one. The file is only recorded as ingested
+// once everything is queued.
+func (i *Ingester) ingest(mediaFile *MediaFile, ctx context.Context) error {
+	ctx, span := i.tracer.Start(ctx, "ingest.ingester.ingest", trace.WithAttributes(attribute.String("path", mediaFile.Path)))
+	defer span.End()
+
+	i.logger.Info().Str("path", mediaFile.Path).Int("sidecars", len(mediaFile.Sidecars)).Msg("Ingesting video")
+
+	videoFile, err := os.Open(mediaFile.Path)
+	if err != nil {
+		return errors.Join(err, errors.New("failed to open video"))
+	}
+	defer videoFile.Close()
+
+	video, saved, err := i.findVideo(mediaFile, ctx)
+	if err != nil {
+		return err
+	}
+
+	// The content is hashed while it is uploaded, so the file is read once.
+	contentHash := videos.NewContentHash(videoFile)
+	err = i.videosFileStorage.Upload(video.Id, contentHash, contentType(mediaFile.Path, "application/octet-stream"), ctx)
+	if err != nil {
+		return err
+	}
+	contentSha256 := contentHash.Sum()
+
+	// A copy of a video that is already in the library is recorded against
+	// the original so that the next scan skips it as well.
+	original, err := i.videosRepository.GetOriginalByContentSha256(contentSha256, video.Id, video.CreatedAt, ctx)
+	if err == nil {
+		i.logger.Warn().Str("path", mediaFile.Path).Str("duplicateOf", original.Id.String()).Msg("Skipping duplicate video")
+
+		err = i.videosFileStorage.Delete(video.Id, ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = i.ingestionsRepository.Insert(NewDbIngestion(mediaFile, original.Id), ctx)
+		if err != nil && !errors.Is(err, ErrIngestionAlreadyExists) {
+			return err
+		}
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return errors.Join(err, errors.New("failed to check for duplicates"))
+	}
+
+	if !saved {
+		video.ContentSha256 = &contentSha256
+		if mediaFile.Episode != nil {
+			seasonId, err := i.findOrCreateSeason(mediaFile.Episode, ctx)
+			if err != nil {
+				return errors.Join(err, errors.New("failed to catalogue episode"))
+			}
+
+			video.SeasonId = &seasonId
+			video.EpisodeNumber = &mediaFile.Episode.Episode
+		}
+
+		_, err = i.videosRepository.Insert(video, ctx)
+		if err != nil && !errors.Is(err, videos.ErrVideoAlreadyExists) {
+			return errors.Join(err, errors.New("failed to save video"))
+		}
+	}
+
+	err = i.videosMessages.Send(videos.NewExportVideoMessage(video.Id, len(mediaFile.Sidecars) == 0), ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, sidecar := range mediaFile.Sidecars {
+		err = i.ingestSidecar(video, sidecar, ctx)
+		if err != nil {
+			return errors.Join(err, fmt.Errorf("failed to ingest sidecar %s", sidecar.Path))
+		}
+	}
+
+	_, err = i.ingestionsRepository.Insert(NewDbIngestion(mediaFile, video.Id), ctx)
+	if err != nil && !errors.Is(err, ErrIngestionAlreadyExists) {
+		return err
+	}
+
+	i.logger.Info().Str("path", mediaFile.Path).Str("videoId", video.Id.String()).Msg("Video ingested successfully")
+
+	return nil
+}
+
+// findVideo returns the video an earlier attempt at ingesting the file saved,
+// or a new one with the id of the file that is not saved yet.
+func (i *Ingester) findVideo(mediaFile *MediaFile, ctx context.Context) (*videos.DbVideo, bool, error) {
+	videoId := ingestedVideoId(mediaFile)
+
+	video, err := i.videosRepository.GetById(videoId, ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return videos.NewDbVideoWithId(videoId, videoName(mediaFile)), false, nil
+	}
+	if err != nil {
+		return nil, false, errors.Join(err, errors.New("failed to get video"))
+	}
+
+	return video, true, nil
+}
+
+// ingestedVideoId is the id of the video ingested from the file. A file that
+// changes size is ingested as another video.
+func ingestedVideoId(mediaFile *MediaFile) uuid.UUID {
+	return uuid.NewSHA1(ingestNamespace, []byte(fmt.Sprintf("%s:%d", mediaFile.Path, mediaFile.Size)))
+}
+
+// findOrCreateSeason returns the season the episode belongs to, creating the
+// series and the season on first sight. A concurrent insert of the same row is
+// resolved by reading it back.
+func (i *Ingester) findOrCreateSeason(episode *EpisodeInfo, ctx context.Context) (uuid.UUID, error) {
+	dbSeries, err := i.seriesRepository.GetByName(episode.SeriesName, ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		dbSeries, err = i.seriesRepository.Insert(series.NewDbSeries(episode.SeriesName, nil, nil), ctx)
+		if errors.Is(err, series.ErrSeriesAlreadyExists) {
+			dbSeries, err = i.seriesRepository.GetByName(episode.SeriesName, ctx)
+		}
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	dbSeason, err := i.seasonsRepository.GetBySeriesIdAndNumber(dbSeries.Id, episode.Season, ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		dbSeason, err = i.seasonsRepository.Insert(series.NewDbSeason(dbSeries.Id, episode.Season, nil, nil), ctx)
+		if errors.Is(err, series.ErrSeasonAlreadyExists) {
+			dbSeason, err = i.seasonsRepository.GetBySeriesIdAndNumber(dbSeries.Id, episode.Season, ctx)
+		}
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return dbSeason.Id, nil
+}
+
+func (i *Ingester) ingestSidecar(video *videos.DbVideo, sidecar *Sidecar, ctx context.Context) error {
+	file, err := os.Open(sidecar.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	track := subtitles.NewUploadedDbSubtitleTrack(video.Id, sidecar.Language)
+	track.Id = uuid.NewSHA1(video.Id, []byte(sidecar.Path))
+	_, err = i.subtitleTracks.Insert(track, ctx)
+	if err != nil && !errors.Is(err, subtitles.ErrTrackAlreadyExists) {
+		return err
+	}
+
+	err = i.subtitlesFileStorage.Upload(video.Id, track.Id, file, contentType(sidecar.Path, "text/plain"), ctx)
+	if err != nil {
+		return err
+	}
+
+	return i.subtitlesMessages.Send(subtitles.NewExportSubtitlesMessage(video.Id, track.Id), ctx)
+}
+
+func videoName(mediaFile *MediaFile) string {
+	if mediaFile.Episode == nil {
+		return mediaFile.Name()
+	}
+
+	return fmt.Sprintf("%s S%02dE%02d", mediaFile.Episode.SeriesName, mediaFile.Episode.Season, mediaFile.Episode.Episode)
+}
+
+func contentType(path string, fallback string) string {
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		return fallback
+	}
+
+	return contentType
+}
@@ -0,0 +1,143 @@
+package ingest_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/bus"
+	"dewarrum/vocabulary-leveling/internal/ingest"
+	"dewarrum/vocabulary-leveling/internal/storage"
+	"dewarrum/vocabulary-leveling/internal/videos"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// capture matches any argument and keeps the last one it saw.
+type capture struct {
+	value driver.Value
+}
+
+func (c *capture) Match(value driver.Value) bool {
+	c.value = value
+	return true
+}
+
+func newTestIngester(t *testing.T) (*ingest.Ingester, sqlmock.Sqlmock, *videos.MessageQueue, string) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	memoryBus := bus.NewMemoryBus()
+	t.Cleanup(func() { memoryBus.Close() })
+
+	dependencies := &app.Dependencies{
+		Postgres:    sqlx.NewDb(db, "postgres"),
+		Bus:         memoryBus,
+		ObjectStore: storage.NewLocalStore(t.TempDir(), "http://localhost/storage", []byte("secret")),
+		Logger:      zerolog.Nop(),
+		Tracer:      noop.NewTracerProvider().Tracer(""),
+		Meter:       metricnoop.NewMeterProvider().Meter(""),
+	}
+
+	ingester, err := ingest.NewIngester(dependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages, err := videos.NewMessageQueue(dependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	err = os.WriteFile(filepath.Join(root, "Movie.mp4"), []byte("video"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ingester, mock, messages, root
+}
+
+func exportedVideos(t *testing.T, messages *videos.MessageQueue) []uuid.UUID {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	consumed, err := messages.Consume(10, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var videoIds []uuid.UUID
+	for message := range consumed {
+		videoIds = append(videoIds, message.VideoId)
+		message.Ack()
+	}
+
+	return videoIds
+}
+
+func TestIngesterFinishesTheVideoOfAnInterruptedIngestion(t *testing.T) {
+	ingester, mock, messages, root := newTestIngester(t)
+	path := filepath.Join(root, "Movie.mp4")
+
+	videoId := &capture{}
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(path, int64(5)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery("SELECT .* FROM videos WHERE id = \\$1").WithArgs(videoId).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT .* FROM videos WHERE content_sha256 = \\$1").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectExec("INSERT INTO videos").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO ingestions").WillReturnError(errors.New("connection reset"))
+
+	err := ingester.Run(root, context.Background())
+	if err == nil {
+		t.Fatal("Expected the interrupted ingestion to fail")
+	}
+
+	// The next scan finds the saved video and finishes it.
+	createdAt := time.Now().Add(-time.Minute)
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(path, int64(5)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery("SELECT .* FROM videos WHERE id = \\$1").WithArgs(videoId.value).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at", "status", "content_sha256"}).AddRow(videoId.value, "Movie", createdAt, videos.VideoStatusProcessing, "hash"))
+	mock.ExpectQuery("SELECT .* FROM videos WHERE content_sha256 = \\$1").WithArgs(sqlmock.AnyArg(), createdAt, videoId.value).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectExec("INSERT INTO ingestions").WithArgs(sqlmock.AnyArg(), path, int64(5), videoId.value, nil, nil, nil, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = ingester.Run(root, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exported := exportedVideos(t, messages)
+	if len(exported) != 2 || exported[0].String() != videoId.value || exported[1].String() != videoId.value {
+		t.Errorf("Expected both attempts to export video %v, but got %v", videoId.value, exported)
+	}
+}
+
+func TestIngesterSkipsIngestedFiles(t *testing.T) {
+	ingester, mock, messages, root := newTestIngester(t)
+
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(filepath.Join(root, "Movie.mp4"), int64(5)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	err := ingester.Run(root, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exported := exportedVideos(t, messages); len(exported) != 0 {
+		t.Errorf("Expected no exports, but got %v", exported)
+	}
+}
@@ -0,0 +1,91 @@
+package ingest
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrIngestionAlreadyExists = errors.New("ingestion already exists")
+	ErrFailedToGetIngestion   = errors.New("failed to get ingestion")
+)
+
+type DbIngestion struct {
+	Id         uuid.UUID `db:"id"`
+	Path       string    `db:"path"`
+	Size       int64     `db:"size"`
+	VideoId    uuid.UUID `db:"video_id"`
+	SeriesName *string   `db:"series_name"`
+	Season     *int      `db:"season"`
+	Episode    *int      `db:"episode"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+func NewDbIngestion(mediaFile *MediaFile, videoId uuid.UUID) *DbIngestion {
+	ingestion := &DbIngestion{
+		Id:        uuid.New(),
+		Path:      mediaFile.Path,
+		Size:      mediaFile.Size,
+		VideoId:   videoId,
+		CreatedAt: time.Now().In(time.UTC),
+	}
+
+	if mediaFile.Episode != nil {
+		ingestion.SeriesName = &mediaFile.Episode.SeriesName
+		ingestion.Season = &mediaFile.Episode.Season
+		ingestion.Episode = &mediaFile.Episode.Episode
+	}
+
+	return ingestion
+}
+
+type IngestionsRepository struct {
+	db     *sqlx.DB
+	logger zerolog.Logger
+	tracer trace.Tracer
+}
+
+func NewIngestionsRepository(dependencies *app.Dependencies) *IngestionsRepository {
+	return &IngestionsRepository{
+		db:     dependencies.Postgres,
+		logger: dependencies.Logger,
+		tracer: dependencies.Tracer,
+	}
+}
+
+func (r *IngestionsRepository) Insert(ingestion *DbIngestion, ctx context.Context) (*DbIngestion, error) {
+	r.logger.Debug().Str("path", ingestion.Path).Msg("Inserting ingestion")
+
+	_, err := r.db.NamedExecContext(ctx, "INSERT INTO ingestions (id, path, size, video_id, series_name, season, episode, created_at) VALUES (:id, :path, :size, :video_id, :series_name, :season, :episode, :created_at)", ingestion)
+	if err == nil {
+		return ingestion, nil
+	}
+
+	pgError, ok := err.(*pq.Error)
+	if ok && pgError.Code == "23505" {
+		return nil, ErrIngestionAlreadyExists
+	}
+
+	return nil, err
+}
+
+func (r *IngestionsRepository) Exists(path string, size int64, ctx context.Context) (bool, error) {
+	ctx, span := r.tracer.Start(ctx, "ingestions.repository.exists")
+	defer span.End()
+
+	var exists bool
+	err := r.db.GetContext(ctx, &exists, "SELECT EXISTS (SELECT 1 FROM ingestions WHERE path = $1 AND size = $2)", path, size)
+	if err != nil {
+		return false, errors.Join(err, ErrFailedToGetIngestion)
+	}
+
+	return exists, nil
+}
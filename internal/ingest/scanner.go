@@ -0,0 +1,118 @@
+package ingest
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	videoExtensions = map[string]bool{
+		".mp4":  true,
+		".m4v":  true,
+		".mkv":  true,
+		".mov":  true,
+		".avi":  true,
+		".webm": true,
+		".ts":   true,
+	}
+	subtitleExtensions = map[string]bool{
+		".srt": true,
+		".smi": true,
+		".vtt": true,
+		".ass": true,
+	}
+)
+
+type Sidecar struct {
+	Path     string
+	Language *string
+}
+
+type MediaFile struct {
+	Path       string
+	Size       int64
+	ModifiedAt time.Time
+	Episode    *EpisodeInfo
+	Sidecars   []*Sidecar
+}
+
+// Name is the file name without its extension.
+func (m *MediaFile) Name() string {
+	base := filepath.Base(m.Path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// ScanLibrary walks root and pairs every video with the subtitle files next to
+// it that share its name, e.g. "Show.S01E02.mkv" with "Show.S01E02.srt" or
+// "Show.S01E02.ko.smi".
+func ScanLibrary(root string) ([]*MediaFile, error) {
+	var videoPaths []string
+	sidecarPaths := make(map[string][]string)
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		extension := strings.ToLower(filepath.Ext(path))
+		if videoExtensions[extension] {
+			videoPaths = append(videoPaths, path)
+		} else if subtitleExtensions[extension] {
+			directory := filepath.Dir(path)
+			sidecarPaths[directory] = append(sidecarPaths[directory], path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(videoPaths)
+
+	var mediaFiles []*MediaFile
+	for _, videoPath := range videoPaths {
+		info, err := os.Stat(videoPath)
+		if err != nil {
+			return nil, err
+		}
+
+		mediaFile := &MediaFile{
+			Path:       videoPath,
+			Size:       info.Size(),
+			ModifiedAt: info.ModTime(),
+		}
+		mediaFile.Episode = ParseEpisode(mediaFile.Name())
+		mediaFile.Sidecars = matchSidecars(mediaFile.Name(), sidecarPaths[filepath.Dir(videoPath)])
+
+		mediaFiles = append(mediaFiles, mediaFile)
+	}
+
+	return mediaFiles, nil
+}
+
+func matchSidecars(videoName string, candidates []string) []*Sidecar {
+	var sidecars []*Sidecar
+	for _, candidate := range candidates {
+		base := filepath.Base(candidate)
+		name := strings.TrimSuffix(base, filepath.Ext(base))
+		if name == videoName {
+			sidecars = append(sidecars, &Sidecar{Path: candidate})
+			continue
+		}
+
+		language, found := strings.CutPrefix(name, videoName+".")
+		if found && language != "" && !strings.Contains(language, ".") {
+			sidecars = append(sidecars, &Sidecar{Path: candidate, Language: &language})
+		}
+	}
+
+	return sidecars
+}
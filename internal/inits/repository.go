@@ -57,8 +57,9 @@ func (r *InitsRepository) Insert(init *DbInit, ctx context.Context) (*DbInit, er
 		return init, nil
 	}
 
-	pgError := err.(*pq.Error)
-	if pgError.Code == "23505" {
+	// Errors such as a cancelled context do not come from Postgres.
+	var pgError *pq.Error
+	if errors.As(err, &pgError) && pgError.Code == "23505" {
 		return nil, ErrInitAlreadyExists
 	}
 
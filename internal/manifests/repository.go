@@ -32,6 +32,8 @@ func NewManifestsRepository(dependencies *app.Dependencies) *ManifestsRepository
 }
 
 func (r *ManifestsRepository) Insert(manifest *DbManifest, ctx context.Context) (*DbManifest, error) {
+	ctx, span := r.tracer.Start(ctx, "manifests.repository.insert")
+	defer span.End()
 	r.logger.Debug().Str("videoId", manifest.VideoId.String()).Msg("Inserting manifest")
 
 	_, err := r.db.NamedExecContext(ctx, "INSERT INTO manifests (id, video_id, meta) VALUES (:id,:video_id, :meta)", manifest)
@@ -39,8 +41,9 @@ func (r *ManifestsRepository) Insert(manifest *DbManifest, ctx context.Context)
 		return manifest, nil
 	}
 
-	pgError := err.(*pq.Error)
-	if pgError.Code == "23505" {
+	// Errors such as a cancelled context do not come from Postgres.
+	var pgError *pq.Error
+	if errors.As(err, &pgError) && pgError.Code == "23505" {
 		return nil, ErrManifestAlreadyExists
 	}
 
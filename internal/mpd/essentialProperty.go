@@ -0,0 +1,10 @@
+package mpd
+
+const (
+	ThumbnailTileSchemeIdUri = "http://dashif.org/guidelines/thumbnail_tile"
+)
+
+type EssentialProperty struct {
+	SchemeIdUri string `xml:"schemeIdUri,attr" json:"schemeIdUri,omitempty"`
+	Value       string `xml:"value,attr" json:"value,omitempty"`
+}
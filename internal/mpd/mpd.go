@@ -1,6 +1,17 @@
 package mpd
 
-import "encoding/xml"
+import (
+	"encoding/xml"
+	"errors"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+var (
+	ErrInvalidDuration = errors.New("invalid duration")
+	durationPattern    = regexp.MustCompile(`^PT(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?$`)
+)
 
 type MPD struct {
 	XMLNS                     string    `xml:"xmlns,attr" json:"xmlns,omitempty"`
@@ -65,3 +76,27 @@ func (m *MPD) GetChunkDuration() (int64, error) {
 
 	return chunkDuration, nil
 }
+
+// GetDurationMs parses mediaPresentationDuration, which ffmpeg writes as an
+// ISO 8601 duration such as "PT0H24M5.120S".
+func (m *MPD) GetDurationMs() (int64, error) {
+	matches := durationPattern.FindStringSubmatch(m.MediaPresentationDuration)
+	if matches == nil || m.MediaPresentationDuration == "PT" {
+		return 0, ErrInvalidDuration
+	}
+
+	var durationMs float64
+	for i, multiplier := range []float64{3600000, 60000, 1000} {
+		if matches[i+1] == "" {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(matches[i+1], 64)
+		if err != nil {
+			return 0, errors.Join(err, ErrInvalidDuration)
+		}
+		durationMs += value * multiplier
+	}
+
+	return int64(math.Round(durationMs)), nil
+}
@@ -0,0 +1,39 @@
+package mpd_test
+
+import (
+	"dewarrum/vocabulary-leveling/internal/mpd"
+	"testing"
+)
+
+func TestGetDurationMs(t *testing.T) {
+	cases := map[string]int64{
+		"PT0H24M5.120S": 1445120,
+		"PT1H0M0S":      3600000,
+		"PT30.5S":       30500,
+		"PT2M":          120000,
+	}
+
+	for duration, expected := range cases {
+		manifest := &mpd.MPD{MediaPresentationDuration: duration}
+
+		actual, err := manifest.GetDurationMs()
+		if err != nil {
+			t.Error(err)
+		}
+
+		if actual != expected {
+			t.Errorf("Expected duration of %s to be %d, but got %d", duration, expected, actual)
+		}
+	}
+}
+
+func TestGetDurationMsInvalid(t *testing.T) {
+	for _, duration := range []string{"", "PT", "24:05"} {
+		manifest := &mpd.MPD{MediaPresentationDuration: duration}
+
+		_, err := manifest.GetDurationMs()
+		if err == nil {
+			t.Errorf("Expected duration %q to be rejected", duration)
+		}
+	}
+}
@@ -11,6 +11,10 @@ func (p *Period) getChunkDuration() (int64, error) {
 	var chunkDuration int64
 	for _, adaptationSet := range p.AdaptationSets {
 		for _, representation := range adaptationSet.Representations {
+			if representation.SegmentTemplate == nil {
+				continue
+			}
+
 			duration, err := representation.SegmentTemplate.getChunkDuration()
 			if err != nil {
 				return int64(0), err
@@ -15,6 +15,7 @@ type Representation struct {
 	SegmentTemplate           *SegmentTemplate           `xml:"SegmentTemplate,omitempty" json:"segmentTemplate,omitempty"`
 	BaseUrl                   string                     `xml:"BaseURL,omitempty" json:"baseUrl,omitempty"`
 	AudioChannelConfiguration *AudioChannelConfiguration `xml:"AudioChannelConfiguration,omitempty" json:"audioChannelConfiguration,omitempty"`
+	EssentialProperty         *EssentialProperty         `xml:"EssentialProperty,omitempty" json:"essentialProperty,omitempty"`
 }
 
 type AudioChannelConfiguration struct {
@@ -1,9 +1,15 @@
 package mpd
 
 type SegmentList struct {
-	Timescale      string          `xml:"timescale,attr,omitempty" json:"timescale,omitempty"`
-	Duration       string          `xml:"duration,attr,omitempty" json:"duration,omitempty"`
-	StartNumber    string          `xml:"startNumber,attr,omitempty" json:"startNumber,omitempty"`
-	Initialization *Initialization `xml:"Initialization,omitempty" json:"initialization,omitempty"`
-	Segments       []*Segment      `xml:"SegmentURL,omitempty" json:"segments,omitempty"`
+	Timescale string `xml:"timescale,attr,omitempty" json:"timescale,omitempty"`
+	Duration  string `xml:"duration,attr,omitempty" json:"duration,omitempty"`
+	// PresentationTimeOffset is the media time, in the timescale, at which
+	// the period starts.
+	PresentationTimeOffset string          `xml:"presentationTimeOffset,attr,omitempty" json:"presentationTimeOffset,omitempty"`
+	StartNumber            string          `xml:"startNumber,attr,omitempty" json:"startNumber,omitempty"`
+	Initialization         *Initialization `xml:"Initialization,omitempty" json:"initialization,omitempty"`
+	// SegmentTimeline places every segment at its own media time, instead of
+	// one after the other every Duration from the start of the period.
+	SegmentTimeline *SegmentTimeline `xml:"SegmentTimeline,omitempty" json:"segmentTimeline,omitempty"`
+	Segments        []*Segment       `xml:"SegmentURL,omitempty" json:"segments,omitempty"`
 }
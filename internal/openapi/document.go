@@ -0,0 +1,184 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+const Version = "3.0.3"
+
+// Document is the subset of an OpenAPI 3 document the server describes itself
+// with. Maps are serialized with sorted keys, so the same routes always
+// produce the same document.
+type Document struct {
+	OpenApi    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+
+	names map[reflect.Type]string
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// PathItem maps lowercase HTTP methods to their operations.
+type PathItem map[string]*Operation
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+type Operation struct {
+	OperationId string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Headers     map[string]*Header    `json:"headers,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Schema *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+func NewDocument(title string, version string) *Document {
+	return &Document{
+		OpenApi:    Version,
+		Info:       Info{Title: title, Version: version},
+		Paths:      make(map[string]*PathItem),
+		Components: Components{Schemas: make(map[string]*Schema)},
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+// Encode returns the indented JSON of the document, as it is committed for the
+// web client.
+func (d *Document) Encode() ([]byte, error) {
+	encoded, err := json.MarshalIndent(d, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(encoded, '\n'), nil
+}
+
+var routeParameterRegex = regexp.MustCompile(`:(\w+)`)
+
+// PathOf converts a fiber route like /videos/:videoId into the OpenAPI
+// /videos/{videoId}.
+func PathOf(route string) string {
+	path := routeParameterRegex.ReplaceAllString(route, "{$1}")
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+
+	return path
+}
+
+// Add describes the operation served for method on the fiber route.
+func (d *Document) Add(method string, route string, operation *Operation) {
+	path := PathOf(route)
+	item, ok := d.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		d.Paths[path] = item
+	}
+
+	(*item)[strings.ToLower(method)] = operation
+}
+
+// Operation returns the operation described for method on the fiber route, or
+// nil when there is none.
+func (d *Document) Operation(method string, route string) *Operation {
+	item, ok := d.Paths[PathOf(route)]
+	if !ok {
+		return nil
+	}
+
+	return (*item)[strings.ToLower(method)]
+}
+
+func JsonContent(schema *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: schema}}
+}
+
+// JsonResponse is a response with a JSON body.
+func JsonResponse(description string, schema *Schema) *Response {
+	return &Response{Description: description, Content: JsonContent(schema)}
+}
+
+// ProblemResponse is a response with an RFC 7807 problem+json body.
+func ProblemResponse(description string, schema *Schema) *Response {
+	return &Response{
+		Description: description,
+		Content:     map[string]*MediaType{"application/problem+json": {Schema: schema}},
+	}
+}
+
+// EmptyResponse is a response without a body.
+func EmptyResponse(description string) *Response {
+	return &Response{Description: description}
+}
+
+// RedirectResponse is a response that points the client elsewhere with the
+// Location header.
+func RedirectResponse(description string) *Response {
+	return &Response{
+		Description: description,
+		Headers:     map[string]*Header{"Location": {Schema: String()}},
+	}
+}
+
+// JsonBody is a required request body in JSON.
+func JsonBody(schema *Schema) *RequestBody {
+	return &RequestBody{Required: true, Content: JsonContent(schema)}
+}
+
+// MultipartBody is a required multipart/form-data request body with the given
+// fields.
+func MultipartBody(schema *Schema) *RequestBody {
+	return &RequestBody{
+		Required: true,
+		Content:  map[string]*MediaType{"multipart/form-data": {Schema: schema}},
+	}
+}
+
+func PathParameter(name string, schema *Schema) *Parameter {
+	return &Parameter{Name: name, In: "path", Required: true, Schema: schema}
+}
+
+func QueryParameter(name string, schema *Schema, required bool) *Parameter {
+	return &Parameter{Name: name, In: "query", Required: required, Schema: schema}
+}
+
+func HeaderParameter(name string, schema *Schema, required bool) *Parameter {
+	return &Parameter{Name: name, In: "header", Required: required, Schema: schema}
+}
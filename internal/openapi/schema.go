@@ -0,0 +1,179 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+}
+
+func String() *Schema {
+	return &Schema{Type: "string"}
+}
+
+func Uuid() *Schema {
+	return &Schema{Type: "string", Format: "uuid"}
+}
+
+func Binary() *Schema {
+	return &Schema{Type: "string", Format: "binary"}
+}
+
+func Integer() *Schema {
+	return &Schema{Type: "integer"}
+}
+
+func Boolean() *Schema {
+	return &Schema{Type: "boolean"}
+}
+
+func Enum(values ...string) *Schema {
+	return &Schema{Type: "string", Enum: values}
+}
+
+func Array(items *Schema) *Schema {
+	return &Schema{Type: "array", Items: items}
+}
+
+// Object is an object schema with the given properties, of which the required
+// ones are listed.
+func Object(properties map[string]*Schema, required ...string) *Schema {
+	return &Schema{Type: "object", Properties: properties, Required: required}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// SchemaOf describes the JSON encoding of value. Named structs are added to
+// the components under their name without the Dto prefix and referenced from
+// the returned schema.
+func (d *Document) SchemaOf(value any) *Schema {
+	return d.schemaOf(reflect.TypeOf(value))
+}
+
+// NamedSchemaOf is SchemaOf for structs whose name does not suit the contract,
+// such as the ones of other modules. The name sticks to the struct wherever it
+// is referenced from afterwards.
+func (d *Document) NamedSchemaOf(name string, value any) *Schema {
+	t := reflect.TypeOf(value)
+	d.names[t] = name
+	return d.component(name, t)
+}
+
+func (d *Document) schemaOf(t reflect.Type) *Schema {
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return d.schemaOf(t.Elem())
+	case reflect.String:
+		return String()
+	case reflect.Bool:
+		return Boolean()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Integer()
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return Array(d.schemaOf(t.Elem()))
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.objectOf(t)
+		}
+		if name, ok := d.names[t]; ok {
+			return d.component(name, t)
+		}
+		return d.component(strings.TrimPrefix(t.Name(), "Dto"), t)
+	default:
+		return &Schema{}
+	}
+}
+
+func (d *Document) component(name string, t reflect.Type) *Schema {
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+	if _, ok := d.Components.Schemas[name]; ok {
+		return ref
+	}
+
+	// Registered before the properties are described, so that recursive
+	// types end up referencing themselves.
+	d.Components.Schemas[name] = &Schema{}
+	*d.Components.Schemas[name] = *d.objectOf(t)
+
+	return ref
+}
+
+func (d *Document) objectOf(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	d.addFields(schema, t)
+	return schema
+}
+
+// addFields adds the fields of t to schema the way encoding/json encodes
+// them. Embedded structs contribute their fields directly, and a format tag
+// sets the format of a field, such as uuid for ids.
+func (d *Document) addFields(schema *Schema, t reflect.Type) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			d.addFields(schema, field.Type)
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		// Pointers are how the DTOs tell that a value may be missing. Slices
+		// and maps are always made before they are encoded.
+		property := d.schemaOf(field.Type)
+		if format := field.Tag.Get("format"); format != "" && property.Ref == "" {
+			property.Format = format
+		}
+		if field.Type.Kind() == reflect.Pointer && !strings.Contains(options, "omitempty") {
+			property = nullable(property)
+		}
+
+		schema.Properties[name] = property
+		if !strings.Contains(options, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// nullable allows null in place of schema. A reference cannot have siblings
+// in OpenAPI 3.0, so it is wrapped.
+func nullable(schema *Schema) *Schema {
+	if schema.Ref != "" {
+		return &Schema{Nullable: true, AllOf: []*Schema{schema}}
+	}
+
+	result := *schema
+	result.Nullable = true
+	return &result
+}
@@ -0,0 +1,98 @@
+package openapi_test
+
+import (
+	"dewarrum/vocabulary-leveling/internal/openapi"
+	"slices"
+	"testing"
+	"time"
+)
+
+type DtoItem struct {
+	Id        string    `json:"id" format:"uuid"`
+	Note      *string   `json:"note"`
+	Url       string    `json:"url,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	hidden    string
+}
+
+type DtoItemDetail struct {
+	DtoItem
+	Children []*DtoItem `json:"children"`
+}
+
+func TestSchemaOfRegistersComponentWithoutDtoPrefix(t *testing.T) {
+	document := openapi.NewDocument("test", "1")
+
+	schema := document.SchemaOf(DtoItem{})
+	if schema.Ref != "#/components/schemas/Item" {
+		t.Errorf("Expected a reference to Item, but got %+v", schema)
+	}
+
+	item, ok := document.Components.Schemas["Item"]
+	if !ok {
+		t.Fatal("Expected Item to be registered")
+	}
+
+	if len(item.Properties) != 4 {
+		t.Errorf("Expected %d properties, but got %d", 4, len(item.Properties))
+	}
+
+	if item.Properties["id"].Format != "uuid" {
+		t.Errorf("Expected id to be a uuid, but got %+v", item.Properties["id"])
+	}
+
+	if item.Properties["createdAt"].Format != "date-time" {
+		t.Errorf("Expected createdAt to be a date-time, but got %+v", item.Properties["createdAt"])
+	}
+}
+
+func TestSchemaOfMarksPointersNullableAndOmitEmptyOptional(t *testing.T) {
+	document := openapi.NewDocument("test", "1")
+	document.SchemaOf(DtoItem{})
+	item := document.Components.Schemas["Item"]
+
+	if !item.Properties["note"].Nullable {
+		t.Error("Expected note to be nullable")
+	}
+
+	if !slices.Equal(item.Required, []string{"id", "note", "createdAt"}) {
+		t.Errorf("Expected id, note and createdAt to be required, but got %v", item.Required)
+	}
+}
+
+func TestSchemaOfFlattensEmbeddedStructs(t *testing.T) {
+	document := openapi.NewDocument("test", "1")
+	document.SchemaOf(DtoItemDetail{})
+	detail := document.Components.Schemas["ItemDetail"]
+
+	if _, ok := detail.Properties["id"]; !ok {
+		t.Errorf("Expected the fields of Item to be inlined, but got %v", detail.Properties)
+	}
+
+	if detail.Properties["children"].Items.Ref != "#/components/schemas/Item" {
+		t.Errorf("Expected children to reference Item, but got %+v", detail.Properties["children"].Items)
+	}
+}
+
+func TestNamedSchemaOfOverridesTheName(t *testing.T) {
+	document := openapi.NewDocument("test", "1")
+	document.NamedSchemaOf("Entry", DtoItem{})
+
+	schema := document.SchemaOf(DtoItemDetail{})
+	children := document.Components.Schemas["ItemDetail"].Properties["children"]
+	if schema.Ref == "" || children.Items.Ref != "#/components/schemas/Entry" {
+		t.Errorf("Expected children to reference Entry, but got %+v", children.Items)
+	}
+}
+
+func TestPathOfConvertsRouteParameters(t *testing.T) {
+	path := openapi.PathOf("/api/subtitles/:trackId/:sequence/clip.:format")
+	if path != "/api/subtitles/{trackId}/{sequence}/clip.{format}" {
+		t.Errorf("Expected route parameters to be converted, but got %s", path)
+	}
+
+	path = openapi.PathOf("/api/admin/videos/uploads/")
+	if path != "/api/admin/videos/uploads" {
+		t.Errorf("Expected the trailing slash to be trimmed, but got %s", path)
+	}
+}
@@ -0,0 +1,263 @@
+package openapi
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// typescriptRuntime is the part of the client every operation goes through.
+const typescriptRuntime = `export class ApiRequestError extends Error {
+	constructor(
+		public readonly status: number,
+		public readonly body: unknown
+	) {
+		super(` + "`Request failed with status ${status}`" + `);
+	}
+}
+
+async function request<T>(
+	method: string,
+	path: string,
+	query: Record<string, string | number | boolean | null | undefined>,
+	init: RequestInit
+): Promise<T> {
+	const search = new URLSearchParams();
+	for (const [key, value] of Object.entries(query)) {
+		if (value !== undefined && value !== null && value !== '') {
+			search.set(key, String(value));
+		}
+	}
+
+	const url = search.toString() ? ` + "`${path}?${search.toString()}`" + ` : path;
+	const response = await fetch(url, { ...init, method });
+	if (!response.ok) {
+		const body = await response.json().catch(() => null);
+		throw new ApiRequestError(response.status, body);
+	}
+
+	if (response.status === 204 || response.headers.get('Content-Length') === '0') {
+		return undefined as T;
+	}
+
+	return (await response.json()) as T;
+}
+`
+
+// TypeScript returns a client for the web app with a type for every component
+// and a function for every operation that is called with fetch. Operations
+// driven by headers or answered with redirects are left to the browser.
+func (d *Document) TypeScript() []byte {
+	var b strings.Builder
+	b.WriteString("// Code generated by `go run ./cmd/openapi`. DO NOT EDIT.\n")
+
+	for _, name := range sortedKeys(d.Components.Schemas) {
+		fmt.Fprintf(&b, "\nexport type %s = %s;\n", name, typescriptType(d.Components.Schemas[name], ""))
+	}
+
+	b.WriteString("\n")
+	b.WriteString(typescriptRuntime)
+
+	for _, path := range sortedKeys(d.Paths) {
+		item := *d.Paths[path]
+		for _, method := range sortedKeys(item) {
+			writeTypescriptFunction(&b, method, path, item[method])
+		}
+	}
+
+	return []byte(b.String())
+}
+
+func writeTypescriptFunction(b *strings.Builder, method string, path string, operation *Operation) {
+	result, ok := typescriptResult(operation)
+	if !ok {
+		return
+	}
+
+	var pathParameters, queryParameters []*Parameter
+	for _, parameter := range operation.Parameters {
+		switch parameter.In {
+		case "path":
+			pathParameters = append(pathParameters, parameter)
+		case "query":
+			queryParameters = append(queryParameters, parameter)
+		default:
+			return
+		}
+	}
+
+	var arguments []string
+	if len(pathParameters)+len(queryParameters) > 0 {
+		var fields []string
+		optional := true
+		for _, parameter := range slices.Concat(pathParameters, queryParameters) {
+			separator := "?: "
+			if parameter.Required {
+				separator = ": "
+				optional = false
+			}
+			fields = append(fields, parameter.Name+separator+typescriptInline(parameter.Schema))
+		}
+
+		argument := "params: { " + strings.Join(fields, "; ") + " }"
+		if optional {
+			argument += " = {}"
+		}
+		arguments = append(arguments, argument)
+	}
+
+	init := "{}"
+	if operation.RequestBody != nil {
+		if media, ok := operation.RequestBody.Content["application/json"]; ok {
+			argument := "body: " + typescriptInline(media.Schema)
+			if !operation.RequestBody.Required {
+				argument = "body?: " + typescriptInline(media.Schema)
+			}
+			arguments = append(arguments, argument)
+			init = "{\n\t\tbody: JSON.stringify(body),\n\t\theaders: { 'Content-Type': 'application/json' }\n\t}"
+		} else if _, ok := operation.RequestBody.Content["multipart/form-data"]; ok {
+			arguments = append(arguments, "body: FormData")
+			init = "{ body }"
+		} else {
+			return
+		}
+	}
+
+	url := "'" + path + "'"
+	if len(pathParameters) > 0 {
+		url = path
+		for _, parameter := range pathParameters {
+			url = strings.ReplaceAll(url, "{"+parameter.Name+"}", "${encodeURIComponent(params."+parameter.Name+")}")
+		}
+		url = "`" + url + "`"
+	}
+
+	query := "{}"
+	if len(queryParameters) > 0 {
+		var fields []string
+		for _, parameter := range queryParameters {
+			fields = append(fields, parameter.Name+": params."+parameter.Name)
+		}
+		query = "{ " + strings.Join(fields, ", ") + " }"
+	}
+
+	if operation.Summary != "" {
+		fmt.Fprintf(b, "\n/** %s */", operation.Summary)
+	}
+	fmt.Fprintf(b, "\nexport function %s(%s): Promise<%s> {\n", operation.OperationId, strings.Join(arguments, ", "), result)
+	fmt.Fprintf(b, "\treturn request('%s', %s, %s, %s);\n}\n", strings.ToUpper(method), url, query, init)
+}
+
+// typescriptResult is the type an operation resolves to, taken from its
+// successful response. Operations answered with anything but JSON or an empty
+// body have none.
+func typescriptResult(operation *Operation) (string, bool) {
+	for _, status := range sortedKeys(operation.Responses) {
+		if !strings.HasPrefix(status, "2") {
+			continue
+		}
+
+		response := operation.Responses[status]
+		if len(response.Content) == 0 {
+			return "void", true
+		}
+
+		media, ok := response.Content["application/json"]
+		if !ok {
+			return "", false
+		}
+
+		return typescriptInline(media.Schema), true
+	}
+
+	return "", false
+}
+
+// typescriptInline is typescriptType on a single line, for the signatures of
+// the operations.
+func typescriptInline(schema *Schema) string {
+	return strings.Join(strings.Fields(typescriptType(schema, "")), " ")
+}
+
+func typescriptType(schema *Schema, indent string) string {
+	var result string
+	switch {
+	case schema.Ref != "":
+		result = strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+	case len(schema.AllOf) == 1:
+		result = typescriptType(schema.AllOf[0], indent)
+	case len(schema.AllOf) > 1:
+		types := make([]string, len(schema.AllOf))
+		for i, item := range schema.AllOf {
+			types[i] = typescriptType(item, indent)
+		}
+		result = strings.Join(types, " & ")
+	case len(schema.Enum) > 0:
+		values := make([]string, len(schema.Enum))
+		for i, value := range schema.Enum {
+			values[i] = "'" + value + "'"
+		}
+		result = strings.Join(values, " | ")
+	case schema.Type == "string" && schema.Format == "binary":
+		result = "Blob"
+	case schema.Type == "string":
+		result = "string"
+	case schema.Type == "integer" || schema.Type == "number":
+		result = "number"
+	case schema.Type == "boolean":
+		result = "boolean"
+	case schema.Type == "array":
+		result = typescriptType(schema.Items, indent) + "[]"
+		if strings.Contains(result, " ") {
+			result = "(" + strings.TrimSuffix(result, "[]") + ")[]"
+		}
+	case schema.Type == "object" && schema.AdditionalProperties != nil:
+		result = "Record<string, " + typescriptType(schema.AdditionalProperties, indent) + ">"
+	case schema.Type == "object" && len(schema.Properties) > 0:
+		result = typescriptObject(schema, indent)
+	case schema.Type == "object":
+		result = "Record<string, unknown>"
+	default:
+		result = "unknown"
+	}
+
+	if schema.Nullable {
+		result += " | null"
+	}
+
+	return result
+}
+
+// typescriptObject lists the properties in the order of the schema's required
+// ones, which is the order of the struct fields, followed by the others.
+func typescriptObject(schema *Schema, indent string) string {
+	names := slices.Clone(schema.Required)
+	for _, name := range sortedKeys(schema.Properties) {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("{\n")
+	for _, name := range names {
+		separator := "?: "
+		if slices.Contains(schema.Required, name) {
+			separator = ": "
+		}
+		fmt.Fprintf(&b, "%s\t%s%s%s;\n", indent, name, separator, typescriptType(schema.Properties[name], indent+"\t"))
+	}
+	b.WriteString(indent + "}")
+
+	return b.String()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	return keys
+}
@@ -0,0 +1,39 @@
+package series
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/storage"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+)
+
+const (
+	FailedToUpload = "failed to upload"
+)
+
+type FileStorage struct {
+	objectStore storage.ObjectStore
+}
+
+func NewFileStorage(objectStore storage.ObjectStore) *FileStorage {
+	return &FileStorage{
+		objectStore: objectStore,
+	}
+}
+
+func (f *FileStorage) UploadPoster(seriesId uuid.UUID, body io.Reader, contentType string, ctx context.Context) (string, error) {
+	key := fmt.Sprintf("series/%s/poster", seriesId)
+	err := f.objectStore.Put(key, body, contentType, ctx)
+	if err != nil {
+		return "", errors.Join(err, errors.New(FailedToUpload))
+	}
+
+	return key, nil
+}
+
+func (f *FileStorage) PresignObject(key string, ctx context.Context) (string, error) {
+	return f.objectStore.PresignGet(key, storage.PresignOptions{}, ctx)
+}
@@ -0,0 +1,171 @@
+package series
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrSeriesAlreadyExists = errors.New("series already exists")
+	ErrFailedToGetSeries   = errors.New("failed to get series")
+	ErrFailedToSaveSeries  = errors.New("failed to save series")
+)
+
+type DbSeries struct {
+	Id             uuid.UUID      `db:"id"`
+	Name           string         `db:"name"`
+	Description    *string        `db:"description"`
+	Genres         pq.StringArray `db:"genres"`
+	PosterLocation *string        `db:"poster_location"`
+	CreatedAt      time.Time      `db:"created_at"`
+}
+
+func NewDbSeries(name string, description *string, genres []string) *DbSeries {
+	if genres == nil {
+		genres = []string{}
+	}
+
+	return &DbSeries{
+		Id:          uuid.New(),
+		Name:        name,
+		Description: description,
+		Genres:      genres,
+		CreatedAt:   time.Now().In(time.UTC),
+	}
+}
+
+type SeriesRepository struct {
+	db     *sqlx.DB
+	logger zerolog.Logger
+	tracer trace.Tracer
+}
+
+func NewSeriesRepository(dependencies *app.Dependencies) *SeriesRepository {
+	return &SeriesRepository{
+		db:     dependencies.Postgres,
+		logger: dependencies.Logger,
+		tracer: dependencies.Tracer,
+	}
+}
+
+func (r *SeriesRepository) Insert(series *DbSeries, ctx context.Context) (*DbSeries, error) {
+	ctx, span := r.tracer.Start(ctx, "series.repository.insert")
+	defer span.End()
+	r.logger.Debug().Str("seriesId", series.Id.String()).Msg("Inserting series")
+
+	_, err := r.db.NamedExecContext(ctx, "INSERT INTO series (id, name, description, genres, poster_location, created_at) VALUES (:id, :name, :description, :genres, :poster_location, :created_at)", series)
+	if err == nil {
+		return series, nil
+	}
+
+	pgError, ok := err.(*pq.Error)
+	if ok && pgError.Code == "23505" {
+		return nil, ErrSeriesAlreadyExists
+	}
+
+	return nil, errors.Join(err, ErrFailedToSaveSeries)
+}
+
+func (r *SeriesRepository) Update(series *DbSeries, ctx context.Context) error {
+	ctx, span := r.tracer.Start(ctx, "series.repository.update")
+	defer span.End()
+	r.logger.Debug().Str("seriesId", series.Id.String()).Msg("Updating series")
+
+	_, err := r.db.NamedExecContext(ctx, "UPDATE series SET name = :name, description = :description, genres = :genres, poster_location = :poster_location WHERE id = :id", series)
+	if err == nil {
+		return nil
+	}
+
+	pgError, ok := err.(*pq.Error)
+	if ok && pgError.Code == "23505" {
+		return ErrSeriesAlreadyExists
+	}
+
+	return errors.Join(err, ErrFailedToSaveSeries)
+}
+
+func (r *SeriesRepository) Delete(id uuid.UUID, ctx context.Context) error {
+	ctx, span := r.tracer.Start(ctx, "series.repository.delete")
+	defer span.End()
+	r.logger.Debug().Str("seriesId", id.String()).Msg("Deleting series")
+
+	_, err := r.db.ExecContext(ctx, "DELETE FROM series WHERE id = $1", id)
+	if err != nil {
+		return errors.Join(err, ErrFailedToSaveSeries)
+	}
+
+	return nil
+}
+
+func (r *SeriesRepository) GetById(id uuid.UUID, ctx context.Context) (*DbSeries, error) {
+	ctx, span := r.tracer.Start(ctx, "series.repository.getById")
+	defer span.End()
+	r.logger.Debug().Str("seriesId", id.String()).Msg("Searching series by id")
+
+	var series DbSeries
+	err := r.db.GetContext(ctx, &series, "SELECT * FROM series WHERE id = $1 LIMIT 1", id)
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToGetSeries)
+	}
+
+	return &series, nil
+}
+
+func (r *SeriesRepository) GetByName(name string, ctx context.Context) (*DbSeries, error) {
+	ctx, span := r.tracer.Start(ctx, "series.repository.getByName")
+	defer span.End()
+	r.logger.Debug().Str("name", name).Msg("Searching series by name")
+
+	var series DbSeries
+	err := r.db.GetContext(ctx, &series, "SELECT * FROM series WHERE name = $1 LIMIT 1", name)
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToGetSeries)
+	}
+
+	return &series, nil
+}
+
+func (r *SeriesRepository) GetManyByIds(ids []uuid.UUID, ctx context.Context) ([]*DbSeries, error) {
+	ctx, span := r.tracer.Start(ctx, "series.repository.getManyByIds")
+	defer span.End()
+	r.logger.Debug().Msg("Searching series by ids")
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	query, args, err := sqlx.In("SELECT * FROM series WHERE id IN (?)", ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var series []*DbSeries
+	err = r.db.SelectContext(ctx, &series, r.db.Rebind(query), args...)
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToGetSeries)
+	}
+
+	return series, nil
+}
+
+func (r *SeriesRepository) GetAll(ctx context.Context) ([]*DbSeries, error) {
+	ctx, span := r.tracer.Start(ctx, "series.repository.getAll")
+	defer span.End()
+	r.logger.Debug().Msg("Listing series")
+
+	var series []*DbSeries
+	err := r.db.SelectContext(ctx, &series, "SELECT * FROM series ORDER BY name")
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToGetSeries)
+	}
+
+	return series, nil
+}
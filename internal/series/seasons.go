@@ -0,0 +1,168 @@
+package series
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrSeasonAlreadyExists = errors.New("season already exists")
+	ErrFailedToGetSeasons  = errors.New("failed to get seasons")
+	ErrFailedToSaveSeason  = errors.New("failed to save season")
+)
+
+type DbSeason struct {
+	Id          uuid.UUID `db:"id"`
+	SeriesId    uuid.UUID `db:"series_id"`
+	Number      int       `db:"number"`
+	AirYear     *int      `db:"air_year"`
+	Description *string   `db:"description"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+func NewDbSeason(seriesId uuid.UUID, number int, airYear *int, description *string) *DbSeason {
+	return &DbSeason{
+		Id:          uuid.New(),
+		SeriesId:    seriesId,
+		Number:      number,
+		AirYear:     airYear,
+		Description: description,
+		CreatedAt:   time.Now().In(time.UTC),
+	}
+}
+
+type SeasonsRepository struct {
+	db     *sqlx.DB
+	logger zerolog.Logger
+	tracer trace.Tracer
+}
+
+func NewSeasonsRepository(dependencies *app.Dependencies) *SeasonsRepository {
+	return &SeasonsRepository{
+		db:     dependencies.Postgres,
+		logger: dependencies.Logger,
+		tracer: dependencies.Tracer,
+	}
+}
+
+func (r *SeasonsRepository) Insert(season *DbSeason, ctx context.Context) (*DbSeason, error) {
+	ctx, span := r.tracer.Start(ctx, "seasons.repository.insert")
+	defer span.End()
+	r.logger.Debug().Str("seriesId", season.SeriesId.String()).Int("number", season.Number).Msg("Inserting season")
+
+	_, err := r.db.NamedExecContext(ctx, "INSERT INTO seasons (id, series_id, number, air_year, description, created_at) VALUES (:id, :series_id, :number, :air_year, :description, :created_at)", season)
+	if err == nil {
+		return season, nil
+	}
+
+	pgError, ok := err.(*pq.Error)
+	if ok && pgError.Code == "23505" {
+		return nil, ErrSeasonAlreadyExists
+	}
+
+	return nil, errors.Join(err, ErrFailedToSaveSeason)
+}
+
+func (r *SeasonsRepository) Update(season *DbSeason, ctx context.Context) error {
+	ctx, span := r.tracer.Start(ctx, "seasons.repository.update")
+	defer span.End()
+	r.logger.Debug().Str("seasonId", season.Id.String()).Msg("Updating season")
+
+	_, err := r.db.NamedExecContext(ctx, "UPDATE seasons SET number = :number, air_year = :air_year, description = :description WHERE id = :id", season)
+	if err == nil {
+		return nil
+	}
+
+	pgError, ok := err.(*pq.Error)
+	if ok && pgError.Code == "23505" {
+		return ErrSeasonAlreadyExists
+	}
+
+	return errors.Join(err, ErrFailedToSaveSeason)
+}
+
+func (r *SeasonsRepository) Delete(id uuid.UUID, ctx context.Context) error {
+	ctx, span := r.tracer.Start(ctx, "seasons.repository.delete")
+	defer span.End()
+	r.logger.Debug().Str("seasonId", id.String()).Msg("Deleting season")
+
+	_, err := r.db.ExecContext(ctx, "DELETE FROM seasons WHERE id = $1", id)
+	if err != nil {
+		return errors.Join(err, ErrFailedToSaveSeason)
+	}
+
+	return nil
+}
+
+func (r *SeasonsRepository) GetById(id uuid.UUID, ctx context.Context) (*DbSeason, error) {
+	ctx, span := r.tracer.Start(ctx, "seasons.repository.getById")
+	defer span.End()
+	r.logger.Debug().Str("seasonId", id.String()).Msg("Searching season by id")
+
+	var season DbSeason
+	err := r.db.GetContext(ctx, &season, "SELECT * FROM seasons WHERE id = $1 LIMIT 1", id)
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToGetSeasons)
+	}
+
+	return &season, nil
+}
+
+func (r *SeasonsRepository) GetBySeriesIdAndNumber(seriesId uuid.UUID, number int, ctx context.Context) (*DbSeason, error) {
+	ctx, span := r.tracer.Start(ctx, "seasons.repository.getBySeriesIdAndNumber")
+	defer span.End()
+	r.logger.Debug().Str("seriesId", seriesId.String()).Int("number", number).Msg("Searching season by number")
+
+	var season DbSeason
+	err := r.db.GetContext(ctx, &season, "SELECT * FROM seasons WHERE series_id = $1 AND number = $2 LIMIT 1", seriesId, number)
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToGetSeasons)
+	}
+
+	return &season, nil
+}
+
+func (r *SeasonsRepository) GetBySeriesId(seriesId uuid.UUID, ctx context.Context) ([]*DbSeason, error) {
+	ctx, span := r.tracer.Start(ctx, "seasons.repository.getBySeriesId")
+	defer span.End()
+	r.logger.Debug().Str("seriesId", seriesId.String()).Msg("Searching seasons by series id")
+
+	var seasons []*DbSeason
+	err := r.db.SelectContext(ctx, &seasons, "SELECT * FROM seasons WHERE series_id = $1 ORDER BY number", seriesId)
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToGetSeasons)
+	}
+
+	return seasons, nil
+}
+
+func (r *SeasonsRepository) GetManyByIds(ids []uuid.UUID, ctx context.Context) ([]*DbSeason, error) {
+	ctx, span := r.tracer.Start(ctx, "seasons.repository.getManyByIds")
+	defer span.End()
+	r.logger.Debug().Msg("Searching seasons by ids")
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	query, args, err := sqlx.In("SELECT * FROM seasons WHERE id IN (?)", ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var seasons []*DbSeason
+	err = r.db.SelectContext(ctx, &seasons, r.db.Rebind(query), args...)
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToGetSeasons)
+	}
+
+	return seasons, nil
+}
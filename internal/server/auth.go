@@ -12,7 +12,7 @@ func (s *Server) SignIn(router fiber.Router) {
 	router.Get("/sign-in", func(c *fiber.Ctx) error {
 		auth, err := s.NewAuthenticationService(c)
 		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(map[string]string{"error": err.Error()})
+			return internalError(err)
 		}
 		defer auth.Close()
 
@@ -25,7 +25,7 @@ func (s *Server) SignIn(router fiber.Router) {
 
 		signInUrl, err := auth.LogtoClient.SignIn(fmt.Sprintf("%s://%s/auth/callback", c.Protocol(), c.Hostname()))
 		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(map[string]string{"error": err.Error()})
+			return internalError(err)
 		}
 
 		return c.Status(http.StatusTemporaryRedirect).Redirect(signInUrl)
@@ -36,7 +36,7 @@ func (s *Server) SignInCallback(router fiber.Router) {
 	router.Get("/callback", func(c *fiber.Ctx) error {
 		auth, err := s.NewAuthenticationService(c)
 		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(map[string]string{"error": err.Error()})
+			return internalError(err)
 		}
 		defer auth.Close()
 
@@ -44,13 +44,13 @@ func (s *Server) SignInCallback(router fiber.Router) {
 		err = fasthttpadaptor.ConvertRequest(c.Context(), &r, true)
 		if err != nil {
 			s.Logger.Error().Err(err).Msg("Failed to convert request")
-			return c.Status(http.StatusInternalServerError).JSON(map[string]string{"error": err.Error()})
+			return internalError(err)
 		}
 
 		err = auth.LogtoClient.HandleSignInCallback(&r)
 		if err != nil {
 			s.Logger.Error().Err(err).Msg("Failed to handle sign in callback")
-			return c.Status(http.StatusInternalServerError).JSON(map[string]string{"error": err.Error()})
+			return internalError(err)
 		}
 
 		backUrl := auth.Session.Get("backUrl").(string)
@@ -66,7 +66,7 @@ func (s *Server) SignOut(router fiber.Router) {
 	router.Get("/sign-out", func(c *fiber.Ctx) error {
 		auth, err := s.NewAuthenticationService(c)
 		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(map[string]string{"error": err.Error()})
+			return internalError(err)
 		}
 		defer auth.Close()
 
@@ -74,7 +74,7 @@ func (s *Server) SignOut(router fiber.Router) {
 
 		redirectUri, err := auth.LogtoClient.SignOut(fmt.Sprintf("%s://%s", c.Protocol(), c.Hostname()))
 		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(map[string]string{"error": err.Error()})
+			return internalError(err)
 		}
 
 		return c.Status(http.StatusTemporaryRedirect).Redirect(redirectUri)
@@ -85,13 +85,13 @@ func (s *Server) Profile(router fiber.Router) {
 	router.Get("/profile", func(c *fiber.Ctx) error {
 		auth, err := s.NewAuthenticationService(c)
 		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(map[string]string{"error": err.Error()})
+			return internalError(err)
 		}
 		defer auth.Close()
 
 		idTokenClaims, err := auth.LogtoClient.GetIdTokenClaims()
 		if err != nil {
-			return c.Status(http.StatusUnauthorized).JSON(map[string]string{"error": err.Error()})
+			return newProblem(http.StatusUnauthorized, CodeUnauthorized, "not signed in").withCause(err)
 		}
 
 		return c.Status(http.StatusOK).JSON(idTokenClaims)
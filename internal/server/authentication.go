@@ -1,9 +1,8 @@
 package server
 
 import (
-	"errors"
+	"dewarrum/vocabulary-leveling/internal/config"
 	"net/http"
-	"os"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/session"
@@ -11,28 +10,13 @@ import (
 	"github.com/rs/zerolog"
 )
 
-func newLogtoConfig() (*client.LogtoConfig, error) {
-	endpoint := os.Getenv("LOGTO_ENDPOINT")
-	if endpoint == "" {
-		return nil, errors.New("LOGTO_ENDPOINT is required")
-	}
-
-	appId := os.Getenv("LOGTO_APP_ID")
-	if appId == "" {
-		return nil, errors.New("LOGTO_APP_ID is required")
-	}
-
-	appSecret := os.Getenv("LOGTO_APP_SECRET")
-	if appSecret == "" {
-		return nil, errors.New("LOGTO_APP_SECRET is required")
-	}
-
+func newLogtoConfig(logtoConfig config.Logto) *client.LogtoConfig {
 	return &client.LogtoConfig{
-		Endpoint:  endpoint,
-		AppId:     appId,
-		AppSecret: appSecret,
+		Endpoint:  logtoConfig.Endpoint,
+		AppId:     logtoConfig.AppId,
+		AppSecret: logtoConfig.AppSecret,
 		Scopes:    []string{"roles"},
-	}, nil
+	}
 }
 
 type AuthenticationService struct {
@@ -67,11 +51,11 @@ func (s *Server) RequireAuthenticationMiddleware() func(c *fiber.Ctx) error {
 	return func(c *fiber.Ctx) error {
 		auth, err := s.NewAuthenticationService(c)
 		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(map[string]string{"error": err.Error()})
+			return internalError(err)
 		}
 
 		if !auth.LogtoClient.IsAuthenticated() {
-			return c.Status(http.StatusUnauthorized).JSON(map[string]string{"error": "unauthorized"})
+			return newProblem(http.StatusUnauthorized, CodeUnauthorized, "not signed in")
 		}
 
 		return c.Next()
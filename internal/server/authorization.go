@@ -10,12 +10,12 @@ func (s *Server) RequireAuthorizationMiddleware(requiredRole string) func(c *fib
 	return func(c *fiber.Ctx) error {
 		auth, err := s.NewAuthenticationService(c)
 		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(map[string]string{"error": err.Error()})
+			return internalError(err)
 		}
 
 		claims, err := auth.LogtoClient.GetIdTokenClaims()
 		if err != nil {
-			return c.Status(http.StatusUnauthorized).JSON(map[string]string{"error": err.Error()})
+			return newProblem(http.StatusUnauthorized, CodeUnauthorized, "not signed in").withCause(err)
 		}
 
 		for _, role := range claims.Roles {
@@ -26,6 +26,6 @@ func (s *Server) RequireAuthorizationMiddleware(requiredRole string) func(c *fib
 
 		s.Logger.Info().Any("roles", claims.Roles).Str("requiredRole", requiredRole).Msg("User does not have required role")
 
-		return c.Status(http.StatusForbidden).JSON(map[string]string{"error": "forbidden"})
+		return newProblem(http.StatusForbidden, CodeForbidden, "the "+requiredRole+" role is required")
 	}
 }
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	HealthzPath = "/healthz"
+	ReadyzPath  = "/readyz"
+)
+
+// Healthz is the liveness probe. It only tells that the process serves
+// requests, a dependency being down must not get the pod restarted.
+func (s *Server) Healthz(router fiber.Router) {
+	router.Get(HealthzPath, func(c *fiber.Ctx) error {
+		return c.Status(http.StatusOK).JSON(map[string]string{"status": "ok"})
+	})
+}
+
+// Readyz is the readiness probe. It fails while any dependency is unhealthy so
+// that no traffic is routed to the pod. It is not authenticated, so the
+// dependencies are only listed by HealthStatus.
+func (s *Server) Readyz(router fiber.Router) {
+	router.Get(ReadyzPath, func(c *fiber.Ctx) error {
+		report := s.Health.Check(c.Context())
+		status := http.StatusOK
+		if !report.Healthy {
+			status = http.StatusServiceUnavailable
+		}
+
+		return c.Status(status).JSON(map[string]string{"status": report.Status()})
+	})
+}
+
+// HealthStatus lists the latency and error of every dependency check.
+func (s *Server) HealthStatus(router fiber.Router) {
+	router.Get("/health", func(c *fiber.Ctx) error {
+		return c.Status(http.StatusOK).JSON(s.Health.Check(c.Context()))
+	})
+}
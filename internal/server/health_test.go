@@ -0,0 +1,54 @@
+package server_test
+
+import (
+	"dewarrum/vocabulary-leveling/internal/server"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func getReadyz(t *testing.T, srv *testServer) (int, map[string]any) {
+	app := fiber.New()
+	srv.Readyz(app)
+
+	response, err := app.Test(httptest.NewRequest(http.MethodGet, server.ReadyzPath, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body map[string]any
+	err = json.NewDecoder(response.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return response.StatusCode, body
+}
+
+func TestReadyzAnswersWithTheStatusOnly(t *testing.T) {
+	srv := newTestServer(t)
+
+	status, body := getReadyz(t, srv)
+	if status != http.StatusOK {
+		t.Errorf("Expected status %d, but got %d", http.StatusOK, status)
+	}
+	if len(body) != 1 || body["status"] != "ok" {
+		t.Errorf("Expected only the status ok, but got %v", body)
+	}
+}
+
+func TestReadyzDoesNotListTheUnhealthyDependencies(t *testing.T) {
+	srv := newTestServer(t)
+	srv.bus.Close()
+
+	status, body := getReadyz(t, srv)
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, but got %d", http.StatusServiceUnavailable, status)
+	}
+	if len(body) != 1 || body["status"] != "unavailable" {
+		t.Errorf("Expected only the status unavailable, but got %v", body)
+	}
+}
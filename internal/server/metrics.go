@@ -0,0 +1,19 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+)
+
+const (
+	MetricsPath = "/metrics"
+)
+
+// Metrics serves the Prometheus scrape endpoint. It is registered on a listener
+// that is not exposed to the public rather than behind authentication, so
+// that scrapers do not need a session.
+func (s *Server) Metrics(router fiber.Router, handler http.Handler) {
+	router.Get(MetricsPath, adaptor.HTTPHandler(handler))
+}
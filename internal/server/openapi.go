@@ -0,0 +1,415 @@
+package server
+
+import (
+	"dewarrum/vocabulary-leveling/internal/clips"
+	"dewarrum/vocabulary-leveling/internal/health"
+	"dewarrum/vocabulary-leveling/internal/openapi"
+	"dewarrum/vocabulary-leveling/internal/videos"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/logto-io/go/core"
+)
+
+const OpenApiPath = "/api/openapi.json"
+
+// OpenApi serves the document. It is registered ahead of the /api group so
+// that the contract can be fetched without signing in.
+func (s *Server) OpenApi(router fiber.Router) {
+	document := ApiDocument()
+	router.Get(OpenApiPath, func(c *fiber.Ctx) error {
+		return c.Status(http.StatusOK).JSON(document)
+	})
+}
+
+// CheckApiDocument lists the /api and /auth routes that ApiDocument does not
+// describe, so that a handler cannot be added without updating the contract.
+func CheckApiDocument(document *openapi.Document, routes []fiber.Route) error {
+	gets := make(map[string]bool)
+	for _, route := range routes {
+		if route.Method == fiber.MethodGet {
+			gets[route.Path] = true
+		}
+	}
+
+	var errs []error
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, "/api/") && !strings.HasPrefix(route.Path, "/auth/") {
+			continue
+		}
+
+		// fiber serves HEAD for every GET route on its own.
+		if route.Method == fiber.MethodHead && gets[route.Path] {
+			continue
+		}
+
+		if document.Operation(route.Method, route.Path) == nil {
+			errs = append(errs, fmt.Errorf("%s %s is not described in the api document", route.Method, route.Path))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ApiDocument describes the routes registered by the api binary. The web
+// client is generated from it, run `go run ./cmd/openapi` after changing a
+// route or one of the DTOs.
+func ApiDocument() *openapi.Document {
+	document := openapi.NewDocument("Vocabulary Leveling", "0.0.1")
+	problemSchema := document.NamedSchemaOf("Problem", Problem{})
+
+	withErrors := func(responses map[string]*openapi.Response) map[string]*openapi.Response {
+		responses["default"] = openapi.ProblemResponse("The request failed", problemSchema)
+		return responses
+	}
+	duplicateSchema := &openapi.Schema{AllOf: []*openapi.Schema{problemSchema, document.SchemaOf(DtoDuplicateVideo{})}}
+
+	document.Add(fiber.MethodGet, OpenApiPath, &openapi.Operation{
+		OperationId: "getOpenApi",
+		Tags:        []string{"meta"},
+		Responses: map[string]*openapi.Response{
+			"200": openapi.JsonResponse("This document", &openapi.Schema{Type: "object"}),
+		},
+	})
+
+	document.Add(fiber.MethodGet, "/api/subtitles/search", &openapi.Operation{
+		OperationId: "searchSubtitles",
+		Tags:        []string{"subtitles"},
+		Parameters: []*openapi.Parameter{
+			openapi.QueryParameter("query", openapi.String(), true),
+		},
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": openapi.JsonResponse("The subtitles matching the query", openapi.Array(document.SchemaOf(DtoSubtitle{}))),
+		}),
+	})
+
+	document.Add(fiber.MethodGet, "/api/subtitles/:trackId/:sequence/clip.:format", &openapi.Operation{
+		OperationId: "getSubtitleClip",
+		Summary:     "Redirects to the clip of a subtitle, rendering it first when needed",
+		Tags:        []string{"subtitles"},
+		Parameters: []*openapi.Parameter{
+			openapi.PathParameter("trackId", openapi.Uuid()),
+			openapi.PathParameter("sequence", openapi.Integer()),
+			openapi.PathParameter("format", openapi.Enum(clips.Extensions()...)),
+			openapi.QueryParameter("burnIn", openapi.Boolean(), false),
+		},
+		Responses: withErrors(map[string]*openapi.Response{
+			"302": openapi.RedirectResponse("The rendered clip"),
+			"202": {
+				Description: "The clip is rendering, ask again after Retry-After",
+				Headers: map[string]*openapi.Header{
+					"Retry-After": {Schema: openapi.Integer()},
+					"Location":    {Schema: openapi.String()},
+				},
+			},
+		}),
+	})
+
+	document.Add(fiber.MethodGet, "/api/videos/manifest.mpd", &openapi.Operation{
+		OperationId: "getVideoManifest",
+		Summary:     "DASH manifest of the part of the video around a subtitle",
+		Tags:        []string{"videos"},
+		Parameters: []*openapi.Parameter{
+			openapi.QueryParameter("subtitleId", openapi.String(), true),
+		},
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": {
+				Description: "The manifest",
+				Content:     map[string]*openapi.MediaType{"application/dash+xml": {Schema: openapi.String()}},
+			},
+		}),
+	})
+
+	document.Add(fiber.MethodGet, "/api/videos", &openapi.Operation{
+		OperationId: "listVideos",
+		Tags:        []string{"videos"},
+		Parameters: []*openapi.Parameter{
+			openapi.QueryParameter("sort", openapi.Enum(videos.SortNewest, videos.SortName, videos.SortDuration), false),
+			openapi.QueryParameter("limit", openapi.Integer(), false),
+			openapi.QueryParameter("cursor", openapi.String(), false),
+			openapi.QueryParameter("seriesId", openapi.Uuid(), false),
+			openapi.QueryParameter("genre", openapi.String(), false),
+			openapi.QueryParameter("status", openapi.Enum(videos.VideoStatuses...), false),
+			openapi.QueryParameter("language", openapi.String(), false),
+		},
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": openapi.JsonResponse("A page of videos", document.SchemaOf(DtoVideoPage{})),
+		}),
+	})
+
+	document.Add(fiber.MethodGet, "/api/videos/:videoId", &openapi.Operation{
+		OperationId: "getVideo",
+		Tags:        []string{"videos"},
+		Parameters:  []*openapi.Parameter{openapi.PathParameter("videoId", openapi.Uuid())},
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": openapi.JsonResponse("The video", document.SchemaOf(DtoVideoDetail{})),
+		}),
+	})
+
+	document.Add(fiber.MethodGet, "/api/series", &openapi.Operation{
+		OperationId: "listSeries",
+		Tags:        []string{"series"},
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": openapi.JsonResponse("Every series", openapi.Array(document.SchemaOf(DtoSeries{}))),
+		}),
+	})
+
+	document.Add(fiber.MethodGet, "/api/series/:seriesId", &openapi.Operation{
+		OperationId: "getSeries",
+		Tags:        []string{"series"},
+		Parameters:  []*openapi.Parameter{openapi.PathParameter("seriesId", openapi.Uuid())},
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": openapi.JsonResponse("The series with its seasons and episodes", document.SchemaOf(DtoSeries{})),
+		}),
+	})
+
+	document.Add(fiber.MethodPost, "/api/admin/videos/upload", &openapi.Operation{
+		OperationId: "uploadVideo",
+		Tags:        []string{"admin"},
+		RequestBody: openapi.MultipartBody(openapi.Object(map[string]*openapi.Schema{
+			"video":             openapi.Binary(),
+			"videoName":         openapi.String(),
+			"subtitles":         openapi.Binary(),
+			"subtitlesLanguage": openapi.String(),
+			"extractSubtitles":  openapi.Boolean(),
+		}, "video", "videoName")),
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": openapi.JsonResponse("The video is being exported", document.SchemaOf(DtoExportingVideo{})),
+			"409": openapi.ProblemResponse("The video has already been uploaded", duplicateSchema),
+		}),
+	})
+
+	document.Add(fiber.MethodPost, "/api/admin/videos", &openapi.Operation{
+		OperationId: "createVideo",
+		Summary:     "Registers a video and returns the URLs to upload its files to",
+		Tags:        []string{"admin"},
+		RequestBody: openapi.JsonBody(document.SchemaOf(DtoCreateVideoRequest{})),
+		Responses: withErrors(map[string]*openapi.Response{
+			"201": openapi.JsonResponse("The video was registered", document.SchemaOf(DtoCreateVideoResponse{})),
+		}),
+	})
+
+	document.Add(fiber.MethodPost, "/api/admin/videos/:videoId/complete", &openapi.Operation{
+		OperationId: "completeVideo",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{openapi.PathParameter("videoId", openapi.Uuid())},
+		RequestBody: &openapi.RequestBody{Content: openapi.JsonContent(document.SchemaOf(DtoCompleteVideoRequest{}))},
+		Responses: withErrors(map[string]*openapi.Response{
+			"202": openapi.JsonResponse("The video is being exported", document.SchemaOf(DtoExportingVideo{})),
+		}),
+	})
+
+	document.Add(fiber.MethodPut, "/api/admin/videos/:videoId", &openapi.Operation{
+		OperationId: "updateVideo",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{openapi.PathParameter("videoId", openapi.Uuid())},
+		RequestBody: openapi.JsonBody(document.SchemaOf(DtoVideoCatalogueRequest{})),
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": openapi.JsonResponse("The catalogued video", document.SchemaOf(DtoEpisode{})),
+		}),
+	})
+
+	document.Add(fiber.MethodPut, "/api/admin/videos/:videoId/poster", &openapi.Operation{
+		OperationId: "uploadVideoPoster",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{openapi.PathParameter("videoId", openapi.Uuid())},
+		RequestBody: openapi.MultipartBody(openapi.Object(map[string]*openapi.Schema{"poster": openapi.Binary()}, "poster")),
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": openapi.JsonResponse("The video with its new poster", document.SchemaOf(DtoEpisode{})),
+		}),
+	})
+
+	document.Add(fiber.MethodGet, "/api/admin/videos/:videoId/subtitle-tracks", &openapi.Operation{
+		OperationId: "listSubtitleTracks",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{openapi.PathParameter("videoId", openapi.Uuid())},
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": openapi.JsonResponse("The subtitle tracks of the video", openapi.Array(document.SchemaOf(DtoSubtitleTrack{}))),
+		}),
+	})
+
+	addTusOperations(document, withErrors)
+
+	document.Add(fiber.MethodPost, "/api/admin/series", &openapi.Operation{
+		OperationId: "createSeries",
+		Tags:        []string{"admin"},
+		RequestBody: openapi.JsonBody(document.SchemaOf(DtoSeriesRequest{})),
+		Responses: withErrors(map[string]*openapi.Response{
+			"201": openapi.JsonResponse("The created series", document.SchemaOf(DtoSeries{})),
+		}),
+	})
+
+	document.Add(fiber.MethodPut, "/api/admin/series/:seriesId", &openapi.Operation{
+		OperationId: "updateSeries",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{openapi.PathParameter("seriesId", openapi.Uuid())},
+		RequestBody: openapi.JsonBody(document.SchemaOf(DtoSeriesRequest{})),
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": openapi.JsonResponse("The updated series", document.SchemaOf(DtoSeries{})),
+		}),
+	})
+
+	document.Add(fiber.MethodDelete, "/api/admin/series/:seriesId", &openapi.Operation{
+		OperationId: "deleteSeries",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{openapi.PathParameter("seriesId", openapi.Uuid())},
+		Responses: withErrors(map[string]*openapi.Response{
+			"204": openapi.EmptyResponse("The series was deleted"),
+		}),
+	})
+
+	document.Add(fiber.MethodPut, "/api/admin/series/:seriesId/poster", &openapi.Operation{
+		OperationId: "uploadSeriesPoster",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{openapi.PathParameter("seriesId", openapi.Uuid())},
+		RequestBody: openapi.MultipartBody(openapi.Object(map[string]*openapi.Schema{"poster": openapi.Binary()}, "poster")),
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": openapi.JsonResponse("The series with its new poster", document.SchemaOf(DtoSeries{})),
+		}),
+	})
+
+	document.Add(fiber.MethodPost, "/api/admin/series/:seriesId/seasons", &openapi.Operation{
+		OperationId: "createSeason",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{openapi.PathParameter("seriesId", openapi.Uuid())},
+		RequestBody: openapi.JsonBody(document.SchemaOf(DtoSeasonRequest{})),
+		Responses: withErrors(map[string]*openapi.Response{
+			"201": openapi.JsonResponse("The created season", document.SchemaOf(DtoSeason{})),
+		}),
+	})
+
+	document.Add(fiber.MethodPut, "/api/admin/seasons/:seasonId", &openapi.Operation{
+		OperationId: "updateSeason",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{openapi.PathParameter("seasonId", openapi.Uuid())},
+		RequestBody: openapi.JsonBody(document.SchemaOf(DtoSeasonRequest{})),
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": openapi.JsonResponse("The updated season", document.SchemaOf(DtoSeason{})),
+		}),
+	})
+
+	document.Add(fiber.MethodDelete, "/api/admin/seasons/:seasonId", &openapi.Operation{
+		OperationId: "deleteSeason",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{openapi.PathParameter("seasonId", openapi.Uuid())},
+		Responses: withErrors(map[string]*openapi.Response{
+			"204": openapi.EmptyResponse("The season was deleted"),
+		}),
+	})
+
+	document.NamedSchemaOf("DependencyStatus", health.Status{})
+	document.Add(fiber.MethodGet, "/api/admin/health", &openapi.Operation{
+		OperationId: "getHealth",
+		Tags:        []string{"admin"},
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": openapi.JsonResponse("The status of every dependency", document.NamedSchemaOf("HealthReport", health.Report{})),
+		}),
+	})
+
+	addAuthOperations(document, withErrors)
+
+	return document
+}
+
+// addTusOperations describes the tus endpoints. Their requests and responses
+// are carried by headers, see https://tus.io/protocols/resumable-upload.
+func addTusOperations(document *openapi.Document, withErrors func(map[string]*openapi.Response) map[string]*openapi.Response) {
+	tusResumable := openapi.HeaderParameter("Tus-Resumable", openapi.Enum(tusVersion), true)
+	uploadId := openapi.PathParameter("uploadId", openapi.Uuid())
+
+	document.Add(fiber.MethodOptions, "/api/admin/videos/uploads/", &openapi.Operation{
+		OperationId: "getTusCapabilities",
+		Tags:        []string{"admin"},
+		Responses: map[string]*openapi.Response{
+			"204": openapi.EmptyResponse("The supported versions and extensions in the Tus-* headers"),
+		},
+	})
+
+	document.Add(fiber.MethodPost, "/api/admin/videos/uploads/", &openapi.Operation{
+		OperationId: "createTusUpload",
+		Tags:        []string{"admin"},
+		Parameters: []*openapi.Parameter{
+			tusResumable,
+			openapi.HeaderParameter("Upload-Length", openapi.Integer(), true),
+			openapi.HeaderParameter("Upload-Metadata", openapi.String(), true),
+		},
+		Responses: withErrors(map[string]*openapi.Response{
+			"201": openapi.RedirectResponse("The upload was created at Location"),
+		}),
+	})
+
+	document.Add(fiber.MethodHead, "/api/admin/videos/uploads/:uploadId", &openapi.Operation{
+		OperationId: "getTusUploadOffset",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{tusResumable, uploadId},
+		Responses: map[string]*openapi.Response{
+			"200": openapi.EmptyResponse("The progress of the upload in the Upload-Offset header"),
+			"404": openapi.EmptyResponse("There is no such upload"),
+			"423": openapi.EmptyResponse("Another request is writing to the upload"),
+		},
+	})
+
+	document.Add(fiber.MethodPatch, "/api/admin/videos/uploads/:uploadId", &openapi.Operation{
+		OperationId: "appendTusUpload",
+		Tags:        []string{"admin"},
+		Parameters: []*openapi.Parameter{
+			tusResumable,
+			uploadId,
+			openapi.HeaderParameter("Upload-Offset", openapi.Integer(), true),
+		},
+		RequestBody: &openapi.RequestBody{
+			Required: true,
+			Content:  map[string]*openapi.MediaType{"application/offset+octet-stream": {Schema: openapi.Binary()}},
+		},
+		Responses: withErrors(map[string]*openapi.Response{
+			"204": openapi.EmptyResponse("The chunk was appended, the new offset is in the Upload-Offset header"),
+		}),
+	})
+
+	document.Add(fiber.MethodDelete, "/api/admin/videos/uploads/:uploadId", &openapi.Operation{
+		OperationId: "terminateTusUpload",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{tusResumable, uploadId},
+		Responses: withErrors(map[string]*openapi.Response{
+			"204": openapi.EmptyResponse("The upload was terminated"),
+		}),
+	})
+}
+
+func addAuthOperations(document *openapi.Document, withErrors func(map[string]*openapi.Response) map[string]*openapi.Response) {
+	document.Add(fiber.MethodGet, "/auth/profile", &openapi.Operation{
+		OperationId: "getProfile",
+		Tags:        []string{"auth"},
+		Responses: withErrors(map[string]*openapi.Response{
+			"200": openapi.JsonResponse("The claims of the signed in user", document.NamedSchemaOf("Profile", core.IdTokenClaims{})),
+		}),
+	})
+
+	document.Add(fiber.MethodGet, "/auth/sign-in", &openapi.Operation{
+		OperationId: "signIn",
+		Tags:        []string{"auth"},
+		Parameters:  []*openapi.Parameter{openapi.QueryParameter("backUrl", openapi.String(), false)},
+		Responses: withErrors(map[string]*openapi.Response{
+			"307": openapi.RedirectResponse("The sign in page of the identity provider"),
+		}),
+	})
+
+	document.Add(fiber.MethodGet, "/auth/callback", &openapi.Operation{
+		OperationId: "signInCallback",
+		Tags:        []string{"auth"},
+		Responses: withErrors(map[string]*openapi.Response{
+			"307": openapi.RedirectResponse("The page the sign in started from"),
+		}),
+	})
+
+	document.Add(fiber.MethodGet, "/auth/sign-out", &openapi.Operation{
+		OperationId: "signOut",
+		Tags:        []string{"auth"},
+		Responses: withErrors(map[string]*openapi.Response{
+			"307": openapi.RedirectResponse("The sign out page of the identity provider"),
+		}),
+	})
+}
@@ -0,0 +1,60 @@
+package server_test
+
+import (
+	"bytes"
+	"dewarrum/vocabulary-leveling/internal/server"
+	"dewarrum/vocabulary-leveling/internal/storage"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestApiDocumentIsUpToDate(t *testing.T) {
+	document := server.ApiDocument()
+	encoded, err := document.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	generated := map[string][]byte{
+		"openapi.json": encoded,
+		"client.ts":    document.TypeScript(),
+	}
+
+	for name, expected := range generated {
+		committed, err := os.ReadFile(filepath.Join("../../web/src/lib/api", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(committed, expected) {
+			t.Errorf("Expected web/src/lib/api/%s to match the routes, run `go run ./cmd/openapi` to update it", name)
+		}
+	}
+}
+
+func TestApiDocumentDescribesEveryRoute(t *testing.T) {
+	srv := &server.Server{}
+	app := fiber.New()
+	srv.Routes(app, server.RouteOptions{
+		Tus:        true,
+		LocalStore: storage.NewLocalStore(t.TempDir(), "http://localhost/storage", []byte("secret")),
+	})
+
+	err := server.CheckApiDocument(server.ApiDocument(), app.GetRoutes(true))
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCheckApiDocumentReportsUndescribedRoutes(t *testing.T) {
+	app := fiber.New()
+	app.Get("/api/undescribed", func(c *fiber.Ctx) error { return nil })
+
+	err := server.CheckApiDocument(server.ApiDocument(), app.GetRoutes(true))
+	if err == nil {
+		t.Error("Expected the undescribed route to be reported")
+	}
+}
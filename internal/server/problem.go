@@ -0,0 +1,182 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Codes tell the clients apart the problems that share a status. They are part
+// of the API contract and must not change once released.
+const (
+	CodeInternalError         = "internal_error"
+	CodeUnauthorized          = "unauthorized"
+	CodeForbidden             = "forbidden"
+	CodeInvalidQuery          = "invalid_query"
+	CodeInvalidParameter      = "invalid_parameter"
+	CodeInvalidHeader         = "invalid_header"
+	CodeInvalidBody           = "invalid_body"
+	CodeUnsupportedMediaType  = "unsupported_media_type"
+	CodeUnsupportedFormat     = "unsupported_format"
+	CodeUnsupportedTusVersion = "unsupported_tus_version"
+	CodeSubtitleNotFound      = "subtitle_not_found"
+	CodeVideoNotFound         = "video_not_found"
+	CodeSeriesNotFound        = "series_not_found"
+	CodeSeasonNotFound        = "season_not_found"
+	CodeUploadNotFound        = "upload_not_found"
+	CodeObjectNotFound        = "object_not_found"
+	CodeVideoNotReady         = "video_not_ready"
+	CodeVideoNotUploaded      = "video_not_uploaded"
+	CodeVideoAlreadyCompleted = "video_already_completed"
+	CodeSubtitlesNotUploaded  = "subtitles_not_uploaded"
+	CodeDuplicateVideo        = "duplicate_video"
+	CodeSeriesAlreadyExists   = "series_already_exists"
+	CodeSeasonAlreadyExists   = "season_already_exists"
+	CodeUploadTooLarge        = "upload_too_large"
+	CodeUploadOffsetMismatch  = "upload_offset_mismatch"
+	CodeUploadCompleted       = "upload_completed"
+	CodeUploadLocked          = "upload_locked"
+	CodeInvalidSignature      = "invalid_signature"
+	CodeClipRendererBusy      = "clip_renderer_busy"
+)
+
+// Problem is an RFC 7807 problem detail. Handlers return it as their error
+// and ErrorHandler renders it. The cause is only logged, never sent.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Code     string `json:"code"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	// Extensions are additional members of the problem, such as the video
+	// an upload duplicates.
+	Extensions map[string]any `json:"-"`
+
+	cause error
+}
+
+func newProblem(status int, code string, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Code:   code,
+		Detail: detail,
+	}
+}
+
+func badRequest(code string, detail string) *Problem {
+	return newProblem(http.StatusBadRequest, code, detail)
+}
+
+func notFound(code string, detail string) *Problem {
+	return newProblem(http.StatusNotFound, code, detail)
+}
+
+func conflict(code string, detail string) *Problem {
+	return newProblem(http.StatusConflict, code, detail)
+}
+
+func internalError(err error) *Problem {
+	return newProblem(http.StatusInternalServerError, CodeInternalError, "the request could not be processed").withCause(err)
+}
+
+// notFoundOr is the problem for an error of a repository lookup, sql.ErrNoRows
+// meaning that the entity does not exist.
+func notFoundOr(err error, code string, detail string) *Problem {
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound(code, detail).withCause(err)
+	}
+
+	return internalError(err)
+}
+
+// withCause attaches the error the problem originates from, for the logs.
+func (p *Problem) withCause(err error) *Problem {
+	p.cause = err
+	return p
+}
+
+// withMembers adds the fields of a DTO as extensions, so that the api document
+// can describe them.
+func (p *Problem) withMembers(members any) *Problem {
+	encoded, err := json.Marshal(members)
+	if err == nil {
+		err = json.Unmarshal(encoded, &p.Extensions)
+	}
+	if err != nil {
+		p.cause = errors.Join(p.cause, err)
+	}
+	return p
+}
+
+func (p *Problem) Error() string {
+	if p.cause != nil {
+		return p.Code + ": " + p.cause.Error()
+	}
+
+	return p.Code + ": " + p.Detail
+}
+
+func (p *Problem) Unwrap() error {
+	return p.cause
+}
+
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem
+	encoded, err := json.Marshal((*problem)(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return encoded, err
+	}
+
+	members := make(map[string]any, len(p.Extensions))
+	for name, value := range p.Extensions {
+		members[name] = value
+	}
+	err = json.Unmarshal(encoded, &members)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(members)
+}
+
+// ErrorHandler renders the errors returned by handlers as problems. Errors
+// that are not problems are internal errors, apart from the ones of fiber
+// itself, such as for unknown routes or too large bodies.
+func (s *Server) ErrorHandler(c *fiber.Ctx, err error) error {
+	var problem *Problem
+	var fiberError *fiber.Error
+	switch {
+	case errors.As(err, &problem):
+	case errors.As(err, &fiberError):
+		code := strings.ToLower(strings.ReplaceAll(http.StatusText(fiberError.Code), " ", "_"))
+		problem = newProblem(fiberError.Code, code, fiberError.Message)
+	default:
+		problem = internalError(err)
+	}
+
+	rendered := *problem
+	rendered.Instance = c.Path()
+
+	event := s.Logger.Debug()
+	if problem.Status >= http.StatusInternalServerError {
+		event = s.Logger.Error()
+	}
+	event.Err(err).
+		Str("method", c.Method()).
+		Str("path", c.Path()).
+		Int("status", problem.Status).
+		Str("code", problem.Code).
+		Msg("Request failed")
+
+	return c.Status(problem.Status).JSON(&rendered, ProblemContentType)
+}
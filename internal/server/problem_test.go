@@ -0,0 +1,142 @@
+package server_test
+
+import (
+	"database/sql"
+	"dewarrum/vocabulary-leveling/internal/server"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+func newProblemApp() *fiber.App {
+	srv := &server.Server{Logger: zerolog.Nop()}
+	app := fiber.New(fiber.Config{ErrorHandler: srv.ErrorHandler})
+	srv.SubtitlesSearch(app)
+	srv.SeriesUpdate(app)
+	app.Get("/failing", func(c *fiber.Ctx) error {
+		return errors.New(`pq: relation "videos" does not exist`)
+	})
+
+	return app
+}
+
+func TestErrorHandlerRendersProblems(t *testing.T) {
+	tests := []struct {
+		path   string
+		method string
+		status int
+		code   string
+	}{
+		{"/subtitles/search", http.MethodGet, http.StatusBadRequest, server.CodeInvalidQuery},
+		{"/series/42", http.MethodPut, http.StatusBadRequest, server.CodeInvalidParameter},
+		{"/failing", http.MethodGet, http.StatusInternalServerError, server.CodeInternalError},
+		{"/unknown", http.MethodGet, http.StatusNotFound, "not_found"},
+	}
+
+	app := newProblemApp()
+	for _, test := range tests {
+		response, err := app.Test(httptest.NewRequest(test.method, test.path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if response.StatusCode != test.status {
+			t.Errorf("Expected %s %s to respond with %d, got %d", test.method, test.path, test.status, response.StatusCode)
+		}
+		if contentType := response.Header.Get(fiber.HeaderContentType); contentType != server.ProblemContentType {
+			t.Errorf("Expected %s %s to respond with %s, got %s", test.method, test.path, server.ProblemContentType, contentType)
+		}
+
+		var problem server.Problem
+		err = json.NewDecoder(response.Body).Decode(&problem)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if problem.Status != test.status || problem.Code != test.code || problem.Instance != test.path {
+			t.Errorf("Expected %s %s to be described as %d %s, got %+v", test.method, test.path, test.status, test.code, problem)
+		}
+	}
+}
+
+func TestErrorHandlerRendersMissingRows(t *testing.T) {
+	subtitleQuery := "SELECT .* FROM subtitles WHERE id = \\$1"
+	tests := []struct {
+		name   string
+		expect func(mock sqlmock.Sqlmock)
+		status int
+		code   string
+	}{
+		{
+			name: "subtitle",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(subtitleQuery).WillReturnError(sql.ErrNoRows)
+			},
+			status: http.StatusNotFound,
+			code:   server.CodeSubtitleNotFound,
+		},
+		{
+			name: "manifest",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(subtitleQuery).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "video_id"}).AddRow(uuid.New(), uuid.New()))
+				mock.ExpectQuery("SELECT \\* FROM manifests WHERE video_id = \\$1").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			},
+			status: http.StatusConflict,
+			code:   server.CodeVideoNotReady,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := newTestServer(t)
+			app := fiber.New(fiber.Config{ErrorHandler: srv.ErrorHandler})
+			srv.VideosManifest(app)
+			test.expect(srv.mock)
+
+			path := "/videos/manifest.mpd?subtitleId=" + uuid.NewString()
+			response, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var problem server.Problem
+			err = json.NewDecoder(response.Body).Decode(&problem)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if response.StatusCode != test.status || problem.Code != test.code {
+				t.Errorf("Expected %d %s, got %d %+v", test.status, test.code, response.StatusCode, problem)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerHidesInternalCauses(t *testing.T) {
+	response, err := newProblemApp().Test(httptest.NewRequest(http.MethodGet, "/failing", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body map[string]any
+	err = json.NewDecoder(response.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, value := range body {
+		if text, ok := value.(string); ok && strings.Contains(text, "relation") {
+			t.Errorf("Expected the cause to stay out of the response, got %s %q", name, text)
+		}
+	}
+}
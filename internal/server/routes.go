@@ -0,0 +1,74 @@
+package server
+
+import (
+	"dewarrum/vocabulary-leveling/internal/storage"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+)
+
+type RouteOptions struct {
+	CorsOrigins []string
+	// Tus serves resumable uploads, which require the s3 storage backend.
+	Tus bool
+	// LocalStore is served under /storage when files are kept on disk.
+	LocalStore *storage.LocalStore
+}
+
+// Routes registers the routes of the api binary. ApiDocument has to describe
+// every /api and /auth route registered here, see CheckApiDocument.
+func (s *Server) Routes(app *fiber.App, options RouteOptions) {
+	corsOrigins := strings.Join(options.CorsOrigins, ",")
+
+	s.Healthz(app)
+	s.Readyz(app)
+	s.OpenApi(app)
+
+	api := app.Group("/api", s.RequireAuthenticationMiddleware())
+	api.Use(cors.New(cors.Config{
+		AllowOrigins:  corsOrigins,
+		ExposeHeaders: "Location,Upload-Offset,Upload-Length,Tus-Resumable,Tus-Version,Tus-Extension,Tus-Max-Size",
+	}))
+
+	s.VideosManifest(api)
+	s.SubtitlesSearch(api)
+	s.SubtitlesClip(api)
+	s.VideosList(api)
+	s.VideosDetail(api)
+	s.SeriesList(api)
+	s.SeriesDetail(api)
+
+	adminApi := api.Group("/admin", s.RequireAuthorizationMiddleware("Admin"))
+	s.VideosUpload(adminApi)
+	s.VideosSubtitleTracks(adminApi)
+	if options.Tus {
+		s.VideosTusUpload(adminApi)
+	}
+	s.VideosCreate(adminApi)
+	s.VideosComplete(adminApi)
+	s.VideosUpdate(adminApi)
+	s.VideosPosterUpload(adminApi)
+	s.SeriesCreate(adminApi)
+	s.SeriesUpdate(adminApi)
+	s.SeriesDelete(adminApi)
+	s.SeriesPosterUpload(adminApi)
+	s.SeasonsCreate(adminApi)
+	s.SeasonsUpdate(adminApi)
+	s.SeasonsDelete(adminApi)
+	s.HealthStatus(adminApi)
+
+	if options.LocalStore != nil {
+		storageApi := app.Group("/storage", cors.New(cors.Config{
+			AllowOrigins: corsOrigins,
+			AllowMethods: "GET,HEAD,PUT",
+		}))
+		s.LocalStorage(storageApi, options.LocalStore)
+	}
+
+	authApi := app.Group("/auth")
+	s.Profile(authApi)
+	s.SignIn(authApi)
+	s.SignInCallback(authApi)
+	s.SignOut(authApi)
+}
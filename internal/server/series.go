@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/series"
+	"dewarrum/vocabulary-leveling/internal/videos"
+	"time"
+)
+
+type DtoSeries struct {
+	Id          string       `json:"id"`
+	Name        string       `json:"name"`
+	Description *string      `json:"description"`
+	Genres      []string     `json:"genres"`
+	PosterUrl   string       `json:"posterUrl,omitempty"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	Seasons     []*DtoSeason `json:"seasons,omitempty"`
+}
+
+type DtoSeason struct {
+	Id          string        `json:"id"`
+	SeriesId    string        `json:"seriesId"`
+	Number      int           `json:"number"`
+	AirYear     *int          `json:"airYear"`
+	Description *string       `json:"description"`
+	Episodes    []*DtoEpisode `json:"episodes"`
+}
+
+type DtoEpisode struct {
+	Id            string   `json:"id"`
+	Name          string   `json:"name"`
+	EpisodeNumber *int     `json:"episodeNumber"`
+	AirYear       *int     `json:"airYear"`
+	Description   *string  `json:"description"`
+	Genres        []string `json:"genres"`
+	PosterUrl     string   `json:"posterUrl,omitempty"`
+}
+
+type DtoSeriesRequest struct {
+	Name        string   `json:"name"`
+	Description *string  `json:"description"`
+	Genres      []string `json:"genres"`
+}
+
+type DtoSeasonRequest struct {
+	Number      int     `json:"number"`
+	AirYear     *int    `json:"airYear"`
+	Description *string `json:"description"`
+}
+
+type DtoVideoCatalogueRequest struct {
+	Name          string   `json:"name"`
+	SeasonId      *string  `json:"seasonId"`
+	EpisodeNumber *int     `json:"episodeNumber"`
+	AirYear       *int     `json:"airYear"`
+	Description   *string  `json:"description"`
+	Genres        []string `json:"genres"`
+}
+
+func (s *Server) mapSeriesToDto(dbSeries *series.DbSeries, ctx context.Context) (*DtoSeries, error) {
+	dtoSeries := &DtoSeries{
+		Id:          dbSeries.Id.String(),
+		Name:        dbSeries.Name,
+		Description: dbSeries.Description,
+		Genres:      dbSeries.Genres,
+		CreatedAt:   dbSeries.CreatedAt,
+	}
+
+	if dbSeries.PosterLocation != nil {
+		posterUrl, err := s.Series.FileStorage.PresignObject(*dbSeries.PosterLocation, ctx)
+		if err != nil {
+			return nil, err
+		}
+		dtoSeries.PosterUrl = posterUrl
+	}
+
+	return dtoSeries, nil
+}
+
+func mapSeasonToDto(dbSeason *series.DbSeason) *DtoSeason {
+	return &DtoSeason{
+		Id:          dbSeason.Id.String(),
+		SeriesId:    dbSeason.SeriesId.String(),
+		Number:      dbSeason.Number,
+		AirYear:     dbSeason.AirYear,
+		Description: dbSeason.Description,
+		Episodes:    []*DtoEpisode{},
+	}
+}
+
+func (s *Server) mapEpisodeToDto(dbVideo *videos.DbVideo, ctx context.Context) (*DtoEpisode, error) {
+	dtoEpisode := &DtoEpisode{
+		Id:            dbVideo.Id.String(),
+		Name:          dbVideo.Name,
+		EpisodeNumber: dbVideo.EpisodeNumber,
+		AirYear:       dbVideo.AirYear,
+		Description:   dbVideo.Description,
+		Genres:        dbVideo.Genres,
+	}
+
+	if dbVideo.PosterLocation != nil {
+		posterUrl, err := s.Videos.FileStorage.PresignObject(*dbVideo.PosterLocation, ctx)
+		if err != nil {
+			return nil, err
+		}
+		dtoEpisode.PosterUrl = posterUrl
+	}
+
+	return dtoEpisode, nil
+}
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"dewarrum/vocabulary-leveling/internal/series"
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func (s *Server) SeriesCreate(router fiber.Router) {
+	router.Post("/series", func(c *fiber.Ctx) error {
+		var request DtoSeriesRequest
+		err := c.BodyParser(&request)
+		if err != nil {
+			return badRequest(CodeInvalidBody, "the body could not be parsed").withCause(err)
+		}
+
+		if request.Name == "" {
+			return badRequest(CodeInvalidBody, "name is required")
+		}
+
+		dbSeries, err := s.Series.Repository.Insert(series.NewDbSeries(request.Name, request.Description, request.Genres), c.Context())
+		if errors.Is(err, series.ErrSeriesAlreadyExists) {
+			return conflict(CodeSeriesAlreadyExists, series.ErrSeriesAlreadyExists.Error())
+		}
+		if err != nil {
+			return internalError(err)
+		}
+
+		dtoSeries, err := s.mapSeriesToDto(dbSeries, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		return c.Status(http.StatusCreated).JSON(dtoSeries)
+	})
+}
+
+func (s *Server) SeriesUpdate(router fiber.Router) {
+	router.Put("/series/:seriesId", func(c *fiber.Ctx) error {
+		seriesId, err := uuid.Parse(c.Params("seriesId"))
+		if err != nil {
+			return badRequest(CodeInvalidParameter, "seriesId must be a valid uuid")
+		}
+
+		var request DtoSeriesRequest
+		err = c.BodyParser(&request)
+		if err != nil {
+			return badRequest(CodeInvalidBody, "the body could not be parsed").withCause(err)
+		}
+
+		if request.Name == "" {
+			return badRequest(CodeInvalidBody, "name is required")
+		}
+
+		dbSeries, err := s.Series.Repository.GetById(seriesId, c.Context())
+		if err != nil {
+			return notFoundOr(err, CodeSeriesNotFound, "series not found")
+		}
+
+		dbSeries.Name = request.Name
+		dbSeries.Description = request.Description
+		dbSeries.Genres = request.Genres
+		if dbSeries.Genres == nil {
+			dbSeries.Genres = []string{}
+		}
+
+		err = s.Series.Repository.Update(dbSeries, c.Context())
+		if errors.Is(err, series.ErrSeriesAlreadyExists) {
+			return conflict(CodeSeriesAlreadyExists, series.ErrSeriesAlreadyExists.Error())
+		}
+		if err != nil {
+			return internalError(err)
+		}
+
+		dtoSeries, err := s.mapSeriesToDto(dbSeries, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		return c.Status(http.StatusOK).JSON(dtoSeries)
+	})
+}
+
+// SeriesDelete removes the series and its seasons. Episodes are kept and
+// simply lose their season reference.
+func (s *Server) SeriesDelete(router fiber.Router) {
+	router.Delete("/series/:seriesId", func(c *fiber.Ctx) error {
+		seriesId, err := uuid.Parse(c.Params("seriesId"))
+		if err != nil {
+			return badRequest(CodeInvalidParameter, "seriesId must be a valid uuid")
+		}
+
+		err = s.Series.Repository.Delete(seriesId, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		return c.SendStatus(http.StatusNoContent)
+	})
+}
+
+func (s *Server) SeriesPosterUpload(router fiber.Router) {
+	router.Put("/series/:seriesId/poster", func(c *fiber.Ctx) error {
+		seriesId, err := uuid.Parse(c.Params("seriesId"))
+		if err != nil {
+			return badRequest(CodeInvalidParameter, "seriesId must be a valid uuid")
+		}
+
+		posterHeader, err := c.FormFile("poster")
+		if err != nil {
+			return badRequest(CodeInvalidBody, "poster is required").withCause(err)
+		}
+
+		posterFile, err := posterHeader.Open()
+		if err != nil {
+			return internalError(err)
+		}
+		defer posterFile.Close()
+
+		dbSeries, err := s.Series.Repository.GetById(seriesId, c.Context())
+		if err != nil {
+			return notFoundOr(err, CodeSeriesNotFound, "series not found")
+		}
+
+		posterLocation, err := s.Series.FileStorage.UploadPoster(seriesId, posterFile, posterHeader.Header.Get("Content-Type"), c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		dbSeries.PosterLocation = &posterLocation
+		err = s.Series.Repository.Update(dbSeries, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		dtoSeries, err := s.mapSeriesToDto(dbSeries, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		return c.Status(http.StatusOK).JSON(dtoSeries)
+	})
+}
+
+func (s *Server) SeasonsCreate(router fiber.Router) {
+	router.Post("/series/:seriesId/seasons", func(c *fiber.Ctx) error {
+		seriesId, err := uuid.Parse(c.Params("seriesId"))
+		if err != nil {
+			return badRequest(CodeInvalidParameter, "seriesId must be a valid uuid")
+		}
+
+		var request DtoSeasonRequest
+		err = c.BodyParser(&request)
+		if err != nil {
+			return badRequest(CodeInvalidBody, "the body could not be parsed").withCause(err)
+		}
+
+		if request.Number <= 0 {
+			return badRequest(CodeInvalidBody, "number must be positive")
+		}
+
+		_, err = s.Series.Repository.GetById(seriesId, c.Context())
+		if err != nil {
+			return notFoundOr(err, CodeSeriesNotFound, "series not found")
+		}
+
+		dbSeason, err := s.Series.Seasons.Insert(series.NewDbSeason(seriesId, request.Number, request.AirYear, request.Description), c.Context())
+		if errors.Is(err, series.ErrSeasonAlreadyExists) {
+			return conflict(CodeSeasonAlreadyExists, series.ErrSeasonAlreadyExists.Error())
+		}
+		if err != nil {
+			return internalError(err)
+		}
+
+		return c.Status(http.StatusCreated).JSON(mapSeasonToDto(dbSeason))
+	})
+}
+
+func (s *Server) SeasonsUpdate(router fiber.Router) {
+	router.Put("/seasons/:seasonId", func(c *fiber.Ctx) error {
+		seasonId, err := uuid.Parse(c.Params("seasonId"))
+		if err != nil {
+			return badRequest(CodeInvalidParameter, "seasonId must be a valid uuid")
+		}
+
+		var request DtoSeasonRequest
+		err = c.BodyParser(&request)
+		if err != nil {
+			return badRequest(CodeInvalidBody, "the body could not be parsed").withCause(err)
+		}
+
+		if request.Number <= 0 {
+			return badRequest(CodeInvalidBody, "number must be positive")
+		}
+
+		dbSeason, err := s.Series.Seasons.GetById(seasonId, c.Context())
+		if err != nil {
+			return notFoundOr(err, CodeSeasonNotFound, "season not found")
+		}
+
+		dbSeason.Number = request.Number
+		dbSeason.AirYear = request.AirYear
+		dbSeason.Description = request.Description
+
+		err = s.Series.Seasons.Update(dbSeason, c.Context())
+		if errors.Is(err, series.ErrSeasonAlreadyExists) {
+			return conflict(CodeSeasonAlreadyExists, series.ErrSeasonAlreadyExists.Error())
+		}
+		if err != nil {
+			return internalError(err)
+		}
+
+		return c.Status(http.StatusOK).JSON(mapSeasonToDto(dbSeason))
+	})
+}
+
+func (s *Server) SeasonsDelete(router fiber.Router) {
+	router.Delete("/seasons/:seasonId", func(c *fiber.Ctx) error {
+		seasonId, err := uuid.Parse(c.Params("seasonId"))
+		if err != nil {
+			return badRequest(CodeInvalidParameter, "seasonId must be a valid uuid")
+		}
+
+		err = s.Series.Seasons.Delete(seasonId, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		return c.SendStatus(http.StatusNoContent)
+	})
+}
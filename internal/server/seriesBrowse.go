@@ -0,0 +1,82 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func (s *Server) SeriesList(router fiber.Router) {
+	router.Get("/series", func(c *fiber.Ctx) error {
+		dbSeries, err := s.Series.Repository.GetAll(c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		dtoSeries := make([]*DtoSeries, len(dbSeries))
+		for i, item := range dbSeries {
+			dtoSeries[i], err = s.mapSeriesToDto(item, c.Context())
+			if err != nil {
+				return internalError(err)
+			}
+		}
+
+		return c.Status(http.StatusOK).JSON(dtoSeries)
+	})
+}
+
+// SeriesDetail returns a series together with its seasons and the episodes
+// that belong to each of them.
+func (s *Server) SeriesDetail(router fiber.Router) {
+	router.Get("/series/:seriesId", func(c *fiber.Ctx) error {
+		seriesId, err := uuid.Parse(c.Params("seriesId"))
+		if err != nil {
+			return badRequest(CodeInvalidParameter, "seriesId must be a valid uuid")
+		}
+
+		dbSeries, err := s.Series.Repository.GetById(seriesId, c.Context())
+		if err != nil {
+			return notFoundOr(err, CodeSeriesNotFound, "series not found")
+		}
+
+		dbSeasons, err := s.Series.Seasons.GetBySeriesId(seriesId, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		seasonIds := make([]uuid.UUID, len(dbSeasons))
+		for i, season := range dbSeasons {
+			seasonIds[i] = season.Id
+		}
+
+		dbVideos, err := s.Videos.Repository.GetManyBySeasonIds(seasonIds, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		dtoSeries, err := s.mapSeriesToDto(dbSeries, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		seasonMap := make(map[uuid.UUID]*DtoSeason)
+		dtoSeries.Seasons = make([]*DtoSeason, len(dbSeasons))
+		for i, season := range dbSeasons {
+			dtoSeries.Seasons[i] = mapSeasonToDto(season)
+			seasonMap[season.Id] = dtoSeries.Seasons[i]
+		}
+
+		for _, video := range dbVideos {
+			dtoEpisode, err := s.mapEpisodeToDto(video, c.Context())
+			if err != nil {
+				return internalError(err)
+			}
+
+			dtoSeason := seasonMap[*video.SeasonId]
+			dtoSeason.Episodes = append(dtoSeason.Episodes, dtoEpisode)
+		}
+
+		return c.Status(http.StatusOK).JSON(dtoSeries)
+	})
+}
@@ -0,0 +1,212 @@
+package server_test
+
+import (
+	"bytes"
+	"dewarrum/vocabulary-leveling/internal/server"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	seriesColumns = []string{"id", "name", "description", "genres", "poster_location", "created_at"}
+	seasonColumns = []string{"id", "series_id", "number", "air_year", "description", "created_at"}
+)
+
+func newSeriesApp(t *testing.T) (*testServer, *fiber.App) {
+	srv := newTestServer(t)
+	app := fiber.New(fiber.Config{ErrorHandler: srv.ErrorHandler})
+	srv.SeriesList(app)
+	srv.SeriesDetail(app)
+	srv.SeriesCreate(app)
+	srv.SeriesUpdate(app)
+	srv.SeriesDelete(app)
+	srv.SeasonsCreate(app)
+	srv.SeasonsUpdate(app)
+	srv.SeasonsDelete(app)
+
+	return srv, app
+}
+
+func sendJson(t *testing.T, app *fiber.App, method string, path string, body any, into any) *http.Response {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	request := httptest.NewRequest(method, path, reader)
+	request.Header.Set("Content-Type", "application/json")
+	response, err := app.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if into != nil {
+		err = json.NewDecoder(response.Body).Decode(into)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return response
+}
+
+func TestSeriesDetailGroupsEpisodesBySeason(t *testing.T) {
+	srv, app := newSeriesApp(t)
+
+	seriesId, firstSeasonId, secondSeasonId := uuid.New(), uuid.New(), uuid.New()
+	now := time.Now()
+	srv.mock.ExpectQuery("SELECT \\* FROM series WHERE id = \\$1").WithArgs(seriesId).
+		WillReturnRows(sqlmock.NewRows(seriesColumns).AddRow(seriesId, "Reply 1988", nil, "{drama}", nil, now))
+	srv.mock.ExpectQuery("SELECT \\* FROM seasons WHERE series_id = \\$1 ORDER BY number").WithArgs(seriesId).
+		WillReturnRows(sqlmock.NewRows(seasonColumns).
+			AddRow(firstSeasonId, seriesId, 1, 2015, nil, now).
+			AddRow(secondSeasonId, seriesId, 2, nil, nil, now))
+	srv.mock.ExpectQuery("SELECT .* FROM videos WHERE season_id IN \\(\\$1, \\$2\\)").WithArgs(firstSeasonId, secondSeasonId).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "season_id", "episode_number", "genres", "status", "created_at"}).
+			AddRow(uuid.New(), "Episode 1", firstSeasonId, 1, "{}", "ready", now).
+			AddRow(uuid.New(), "Episode 2", firstSeasonId, 2, "{}", "ready", now))
+
+	var dtoSeries server.DtoSeries
+	response := sendJson(t, app, http.MethodGet, fmt.Sprintf("/series/%s", seriesId), nil, &dtoSeries)
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	if len(dtoSeries.Seasons) != 2 {
+		t.Fatalf("Expected 2 seasons, but got %d", len(dtoSeries.Seasons))
+	}
+	if first := dtoSeries.Seasons[0]; first.Number != 1 || len(first.Episodes) != 2 || first.Episodes[0].Name != "Episode 1" {
+		t.Errorf("Expected the first season to hold both episodes in order, but got %+v", first)
+	}
+	if second := dtoSeries.Seasons[1]; second.Episodes == nil || len(second.Episodes) != 0 {
+		t.Errorf("Expected the second season to have an empty list of episodes, but got %+v", second.Episodes)
+	}
+}
+
+func TestSeriesDetailReportsUnknownSeries(t *testing.T) {
+	srv, app := newSeriesApp(t)
+
+	seriesId := uuid.New()
+	srv.mock.ExpectQuery("SELECT \\* FROM series WHERE id = \\$1").WithArgs(seriesId).WillReturnRows(sqlmock.NewRows(seriesColumns))
+
+	var problem server.Problem
+	response := sendJson(t, app, http.MethodGet, fmt.Sprintf("/series/%s", seriesId), nil, &problem)
+	if response.StatusCode != http.StatusNotFound || problem.Code != server.CodeSeriesNotFound {
+		t.Errorf("Expected %d %s, but got %d %s", http.StatusNotFound, server.CodeSeriesNotFound, response.StatusCode, problem.Code)
+	}
+}
+
+func TestSeriesCreateRejectsTakenNames(t *testing.T) {
+	srv, app := newSeriesApp(t)
+
+	srv.mock.ExpectExec("INSERT INTO series").WillReturnError(&pq.Error{Code: "23505"})
+
+	var problem server.Problem
+	response := sendJson(t, app, http.MethodPost, "/series", server.DtoSeriesRequest{Name: "Reply 1988"}, &problem)
+	if response.StatusCode != http.StatusConflict || problem.Code != server.CodeSeriesAlreadyExists {
+		t.Errorf("Expected %d %s, but got %d %s", http.StatusConflict, server.CodeSeriesAlreadyExists, response.StatusCode, problem.Code)
+	}
+
+	response = sendJson(t, app, http.MethodPost, "/series", server.DtoSeriesRequest{}, &problem)
+	if response.StatusCode != http.StatusBadRequest || problem.Code != server.CodeInvalidBody {
+		t.Errorf("Expected %d %s, but got %d %s", http.StatusBadRequest, server.CodeInvalidBody, response.StatusCode, problem.Code)
+	}
+}
+
+func TestSeriesUpdateClearsGenres(t *testing.T) {
+	srv, app := newSeriesApp(t)
+
+	seriesId := uuid.New()
+	srv.mock.ExpectQuery("SELECT \\* FROM series WHERE id = \\$1").WithArgs(seriesId).
+		WillReturnRows(sqlmock.NewRows(seriesColumns).AddRow(seriesId, "Reply 1988", nil, "{drama}", nil, time.Now()))
+	srv.mock.ExpectExec("UPDATE series SET").WithArgs("Reply 1994", nil, "{}", nil, seriesId).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	var dtoSeries server.DtoSeries
+	response := sendJson(t, app, http.MethodPut, fmt.Sprintf("/series/%s", seriesId), server.DtoSeriesRequest{Name: "Reply 1994"}, &dtoSeries)
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+	if dtoSeries.Name != "Reply 1994" || dtoSeries.Genres == nil || len(dtoSeries.Genres) != 0 {
+		t.Errorf("Expected the renamed series without genres, but got %+v", dtoSeries)
+	}
+}
+
+func TestSeasonsCreateRequiresTheSeries(t *testing.T) {
+	srv, app := newSeriesApp(t)
+
+	seriesId := uuid.New()
+	var problem server.Problem
+	response := sendJson(t, app, http.MethodPost, fmt.Sprintf("/series/%s/seasons", seriesId), server.DtoSeasonRequest{Number: 0}, &problem)
+	if response.StatusCode != http.StatusBadRequest || problem.Code != server.CodeInvalidBody {
+		t.Errorf("Expected %d %s, but got %d %s", http.StatusBadRequest, server.CodeInvalidBody, response.StatusCode, problem.Code)
+	}
+
+	srv.mock.ExpectQuery("SELECT \\* FROM series WHERE id = \\$1").WithArgs(seriesId).WillReturnRows(sqlmock.NewRows(seriesColumns))
+	response = sendJson(t, app, http.MethodPost, fmt.Sprintf("/series/%s/seasons", seriesId), server.DtoSeasonRequest{Number: 1}, &problem)
+	if response.StatusCode != http.StatusNotFound || problem.Code != server.CodeSeriesNotFound {
+		t.Errorf("Expected %d %s, but got %d %s", http.StatusNotFound, server.CodeSeriesNotFound, response.StatusCode, problem.Code)
+	}
+}
+
+func TestSeasonsCreateRejectsTakenNumbers(t *testing.T) {
+	srv, app := newSeriesApp(t)
+
+	seriesId := uuid.New()
+	srv.mock.ExpectQuery("SELECT \\* FROM series WHERE id = \\$1").WithArgs(seriesId).
+		WillReturnRows(sqlmock.NewRows(seriesColumns).AddRow(seriesId, "Reply 1988", nil, "{}", nil, time.Now()))
+	srv.mock.ExpectExec("INSERT INTO seasons").WillReturnError(&pq.Error{Code: "23505"})
+
+	var problem server.Problem
+	response := sendJson(t, app, http.MethodPost, fmt.Sprintf("/series/%s/seasons", seriesId), server.DtoSeasonRequest{Number: 1}, &problem)
+	if response.StatusCode != http.StatusConflict || problem.Code != server.CodeSeasonAlreadyExists {
+		t.Errorf("Expected %d %s, but got %d %s", http.StatusConflict, server.CodeSeasonAlreadyExists, response.StatusCode, problem.Code)
+	}
+}
+
+func TestSeasonsUpdateChangesTheSeason(t *testing.T) {
+	srv, app := newSeriesApp(t)
+
+	seriesId, seasonId := uuid.New(), uuid.New()
+	srv.mock.ExpectQuery("SELECT \\* FROM seasons WHERE id = \\$1").WithArgs(seasonId).
+		WillReturnRows(sqlmock.NewRows(seasonColumns).AddRow(seasonId, seriesId, 1, nil, nil, time.Now()))
+	srv.mock.ExpectExec("UPDATE seasons SET").WithArgs(2, 2016, nil, seasonId).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	airYear := 2016
+	var dtoSeason server.DtoSeason
+	response := sendJson(t, app, http.MethodPut, fmt.Sprintf("/seasons/%s", seasonId), server.DtoSeasonRequest{Number: 2, AirYear: &airYear}, &dtoSeason)
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+	if dtoSeason.Number != 2 || dtoSeason.AirYear == nil || *dtoSeason.AirYear != 2016 || dtoSeason.SeriesId != seriesId.String() {
+		t.Errorf("Expected season 2 of %s aired in 2016, but got %+v", seriesId, dtoSeason)
+	}
+}
+
+func TestSeriesAndSeasonsDelete(t *testing.T) {
+	srv, app := newSeriesApp(t)
+
+	seriesId, seasonId := uuid.New(), uuid.New()
+	srv.mock.ExpectExec("DELETE FROM series WHERE id = \\$1").WithArgs(seriesId).WillReturnResult(sqlmock.NewResult(0, 1))
+	srv.mock.ExpectExec("DELETE FROM seasons WHERE id = \\$1").WithArgs(seasonId).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	for _, path := range []string{fmt.Sprintf("/series/%s", seriesId), fmt.Sprintf("/seasons/%s", seasonId)} {
+		response := sendJson(t, app, http.MethodDelete, path, nil, nil)
+		if response.StatusCode != http.StatusNoContent {
+			t.Errorf("Expected %s to be deleted with status %d, but got %d", path, http.StatusNoContent, response.StatusCode)
+		}
+	}
+}
@@ -4,9 +4,14 @@ import (
 	"context"
 	"dewarrum/vocabulary-leveling/internal/app"
 	"dewarrum/vocabulary-leveling/internal/chunks"
+	"dewarrum/vocabulary-leveling/internal/clips"
+	"dewarrum/vocabulary-leveling/internal/config"
+	"dewarrum/vocabulary-leveling/internal/health"
 	"dewarrum/vocabulary-leveling/internal/inits"
 	"dewarrum/vocabulary-leveling/internal/manifests"
+	"dewarrum/vocabulary-leveling/internal/series"
 	"dewarrum/vocabulary-leveling/internal/subtitles"
+	"dewarrum/vocabulary-leveling/internal/uploads"
 	"dewarrum/vocabulary-leveling/internal/videos"
 
 	"github.com/gofiber/fiber/v2/middleware/session"
@@ -18,11 +23,16 @@ import (
 type Server struct {
 	Videos    *VideoContext
 	Subtitles *SubtitleContext
+	Series    *SeriesContext
+	Clips     *clips.Renderer
+	Uploads   *uploads.Receiver
+	Health    *health.Checker
 
 	ChunksRepository    *chunks.ChunksRepository
 	InitsRepository     *inits.InitsRepository
 	ManifestsRepository *manifests.ManifestsRepository
 
+	Config       *config.Config
 	LogtoConfig  *client.LogtoConfig
 	SessionStore *session.Store
 
@@ -31,10 +41,17 @@ type Server struct {
 }
 
 type SubtitleContext struct {
-	Repository     *subtitles.SubtitlesRepository
-	Messages       *subtitles.MessageQueue
-	FullTextSearch *subtitles.FullTextSearch
-	FileStorage    *subtitles.FileStorage
+	Repository  *subtitles.SubtitlesRepository
+	Tracks      *subtitles.SubtitleTracksRepository
+	Messages    *subtitles.MessageQueue
+	SearchIndex subtitles.SearchIndex
+	FileStorage *subtitles.FileStorage
+}
+
+type SeriesContext struct {
+	Repository  *series.SeriesRepository
+	Seasons     *series.SeasonsRepository
+	FileStorage *series.FileStorage
 }
 
 type VideoContext struct {
@@ -54,18 +71,18 @@ func NewServer(dependencies *app.Dependencies, ctx context.Context) (*Server, er
 		return nil, err
 	}
 
-	logtoConfig, err := newLogtoConfig()
-	if err != nil {
-		return nil, err
-	}
-
 	return &Server{
 		Videos:              videoContext,
 		Subtitles:           subtitleContext,
+		Series:              newSeriesContext(dependencies),
+		Clips:               clips.NewRenderer(dependencies),
+		Uploads:             uploads.NewReceiver(dependencies),
+		Health:              health.NewChecker(dependencies),
 		ChunksRepository:    chunks.NewChunksRepository(dependencies),
 		InitsRepository:     inits.NewInitsRepository(dependencies),
 		ManifestsRepository: manifests.NewManifestsRepository(dependencies),
-		LogtoConfig:         logtoConfig,
+		Config:              dependencies.Config,
+		LogtoConfig:         newLogtoConfig(dependencies.Config.Logto),
 		SessionStore:        dependencies.SessionStore,
 		Logger:              dependencies.Logger,
 		Tracer:              dependencies.Tracer,
@@ -78,16 +95,17 @@ func newSubtitleContext(dependencies *app.Dependencies, ctx context.Context) (*S
 		return nil, err
 	}
 
-	subtitlesFullTextSearch, err := subtitles.NewFullTextSearch(dependencies.ElasticsearchClient, ctx)
+	subtitlesSearchIndex, err := subtitles.NewSearchIndex(dependencies, ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	return &SubtitleContext{
-		Repository:     subtitles.NewSubtitlesRepository(dependencies),
-		Messages:       subtitlesMessages,
-		FullTextSearch: subtitlesFullTextSearch,
-		FileStorage:    subtitles.NewFileStorage(dependencies.S3Client, dependencies.S3PresignClient),
+		Repository:  subtitles.NewSubtitlesRepository(dependencies),
+		Tracks:      subtitles.NewSubtitleTracksRepository(dependencies),
+		Messages:    subtitlesMessages,
+		SearchIndex: subtitlesSearchIndex,
+		FileStorage: subtitles.NewFileStorage(dependencies.ObjectStore),
 	}, nil
 }
 
@@ -100,6 +118,14 @@ func newVideoContext(dependencies *app.Dependencies) (*VideoContext, error) {
 	return &VideoContext{
 		Repository:  videos.NewVideosRepository(dependencies),
 		Messages:    videosMessages,
-		FileStorage: videos.NewFileStorage(dependencies.S3Client, dependencies.S3PresignClient),
+		FileStorage: videos.NewFileStorage(dependencies.ObjectStore),
 	}, nil
 }
+
+func newSeriesContext(dependencies *app.Dependencies) *SeriesContext {
+	return &SeriesContext{
+		Repository:  series.NewSeriesRepository(dependencies),
+		Seasons:     series.NewSeasonsRepository(dependencies),
+		FileStorage: series.NewFileStorage(dependencies.ObjectStore),
+	}
+}
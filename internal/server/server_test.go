@@ -0,0 +1,93 @@
+package server_test
+
+import (
+	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/bus"
+	"dewarrum/vocabulary-leveling/internal/health"
+	"dewarrum/vocabulary-leveling/internal/inits"
+	"dewarrum/vocabulary-leveling/internal/manifests"
+	"dewarrum/vocabulary-leveling/internal/series"
+	"dewarrum/vocabulary-leveling/internal/server"
+	"dewarrum/vocabulary-leveling/internal/storage"
+	"dewarrum/vocabulary-leveling/internal/subtitles"
+	"dewarrum/vocabulary-leveling/internal/videos"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// testServer is a server backed by a mocked database, a memory bus and a
+// local object store.
+type testServer struct {
+	*server.Server
+	mock  sqlmock.Sqlmock
+	bus   *bus.MemoryBus
+	store *storage.LocalStore
+}
+
+func newTestServer(t *testing.T) *testServer {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	memoryBus := bus.NewMemoryBus()
+	t.Cleanup(func() { memoryBus.Close() })
+
+	dependencies := &app.Dependencies{
+		Postgres:    sqlx.NewDb(db, "postgres"),
+		Bus:         memoryBus,
+		ObjectStore: storage.NewLocalStore(t.TempDir(), "http://localhost/storage", []byte("secret")),
+		Logger:      zerolog.Nop(),
+		Tracer:      noop.NewTracerProvider().Tracer(""),
+		Meter:       metricnoop.NewMeterProvider().Meter(""),
+	}
+
+	videoMessages, err := videos.NewMessageQueue(dependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subtitleMessages, err := subtitles.NewMessageQueue(dependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testServer{
+		Server: &server.Server{
+			Videos: &server.VideoContext{
+				Repository:  videos.NewVideosRepository(dependencies),
+				Messages:    videoMessages,
+				FileStorage: videos.NewFileStorage(dependencies.ObjectStore),
+			},
+			Subtitles: &server.SubtitleContext{
+				Repository:  subtitles.NewSubtitlesRepository(dependencies),
+				Tracks:      subtitles.NewSubtitleTracksRepository(dependencies),
+				Messages:    subtitleMessages,
+				FileStorage: subtitles.NewFileStorage(dependencies.ObjectStore),
+			},
+			Series: &server.SeriesContext{
+				Repository:  series.NewSeriesRepository(dependencies),
+				Seasons:     series.NewSeasonsRepository(dependencies),
+				FileStorage: series.NewFileStorage(dependencies.ObjectStore),
+			},
+			Health:              health.NewChecker(dependencies),
+			InitsRepository:     inits.NewInitsRepository(dependencies),
+			ManifestsRepository: manifests.NewManifestsRepository(dependencies),
+			Logger:              dependencies.Logger,
+			Tracer:              dependencies.Tracer,
+		},
+		mock:  mock,
+		bus:   memoryBus,
+		store: dependencies.ObjectStore.(*storage.LocalStore),
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"bytes"
+	"dewarrum/vocabulary-leveling/internal/storage"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// LocalStorage serves the URLs presigned by storage.LocalStore. Requests are
+// authorized by the signature in the query string rather than the session, so
+// the routes must be registered outside of the authenticated api group.
+func (s *Server) LocalStorage(router fiber.Router, store *storage.LocalStore) {
+	serve := func(c *fiber.Ctx) error {
+		key, err := url.PathUnescape(c.Params("*"))
+		if err != nil {
+			return badRequest(CodeInvalidParameter, "the object key could not be unescaped").withCause(err)
+		}
+
+		query, err := url.ParseQuery(string(c.Context().QueryArgs().QueryString()))
+		if err != nil {
+			return badRequest(CodeInvalidQuery, "the query string could not be parsed").withCause(err)
+		}
+
+		options, err := store.Verify(http.MethodGet, key, query)
+		if err != nil {
+			return newProblem(http.StatusForbidden, CodeInvalidSignature, storage.ErrInvalidSignature.Error()).withCause(err)
+		}
+
+		objectPath, err := store.Path(key)
+		if err != nil {
+			return badRequest(CodeInvalidParameter, storage.ErrInvalidKey.Error()).withCause(err)
+		}
+
+		file, err := os.Open(objectPath)
+		if errors.Is(err, os.ErrNotExist) {
+			return notFound(CodeObjectNotFound, "object not found").withCause(err)
+		}
+		if err != nil {
+			return internalError(err)
+		}
+		defer file.Close()
+
+		stat, err := file.Stat()
+		if err != nil {
+			return internalError(err)
+		}
+
+		// http.ServeContent takes care of Range and conditional requests, which
+		// the video player relies on for seeking.
+		handler := fasthttpadaptor.NewFastHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if contentType := store.ContentType(key); contentType != "" {
+				w.Header().Set("Content-Type", contentType)
+			} else {
+				w.Header().Set("Content-Type", "application/octet-stream")
+			}
+			if options.Filename != "" {
+				w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": options.Filename}))
+			}
+
+			http.ServeContent(w, r, "", stat.ModTime(), file)
+		}))
+		handler(c.Context())
+
+		return nil
+	}
+
+	router.Get("/*", serve)
+	router.Head("/*", serve)
+
+	router.Put("/*", func(c *fiber.Ctx) error {
+		key, err := url.PathUnescape(c.Params("*"))
+		if err != nil {
+			return badRequest(CodeInvalidParameter, "the object key could not be unescaped").withCause(err)
+		}
+
+		query, err := url.ParseQuery(string(c.Context().QueryArgs().QueryString()))
+		if err != nil {
+			return badRequest(CodeInvalidQuery, "the query string could not be parsed").withCause(err)
+		}
+
+		options, err := store.Verify(http.MethodPut, key, query)
+		if err != nil {
+			return newProblem(http.StatusForbidden, CodeInvalidSignature, storage.ErrInvalidSignature.Error()).withCause(err)
+		}
+
+		contentType := c.Get(fiber.HeaderContentType)
+		if options.ContentType != "" && !strings.EqualFold(contentType, options.ContentType) {
+			return newProblem(http.StatusForbidden, CodeInvalidSignature, "content type does not match the presigned url")
+		}
+
+		// Small bodies are read up front even when request body streaming is
+		// enabled, in which case there is no stream to read from.
+		var body io.Reader = bytes.NewReader(c.Body())
+		if stream := c.Context().RequestBodyStream(); stream != nil {
+			body = stream
+		}
+
+		err = store.Put(key, body, contentType, c.Context())
+		if errors.Is(err, storage.ErrInvalidKey) {
+			return badRequest(CodeInvalidParameter, storage.ErrInvalidKey.Error()).withCause(err)
+		}
+		if err != nil {
+			return internalError(err)
+		}
+
+		return c.SendStatus(http.StatusOK)
+	})
+}
@@ -1,9 +1,23 @@
 package server
 
+import "dewarrum/vocabulary-leveling/internal/subtitles"
+
+// DtoSubtitle has the text of a subtitle as shown on screen, its utterances
+// split that text by speaker and leave out the sound descriptions.
 type DtoSubtitle struct {
-	Id        string `json:"id"`
-	VideoName string `json:"videoName"`
-	StartMs   int64  `json:"startMs"`
-	EndMs     int64  `json:"endMs"`
-	Text      string `json:"text"`
+	Id            string                  `json:"id"`
+	VideoId       string                  `json:"videoId"`
+	VideoName     string                  `json:"videoName"`
+	SeriesId      *string                 `json:"seriesId,omitempty"`
+	SeriesName    *string                 `json:"seriesName,omitempty"`
+	SeasonId      *string                 `json:"seasonId,omitempty"`
+	SeasonNumber  *int                    `json:"seasonNumber,omitempty"`
+	EpisodeNumber *int                    `json:"episodeNumber,omitempty"`
+	AirYear       *int                    `json:"airYear,omitempty"`
+	StartMs       int64                   `json:"startMs"`
+	EndMs         int64                   `json:"endMs"`
+	Text          string                  `json:"text"`
+	Utterances    []*subtitles.Utterance  `json:"utterances,omitempty"`
+	Style         *subtitles.CaptionStyle `json:"style,omitempty"`
+	ThumbnailUrl  string                  `json:"thumbnailUrl,omitempty"`
 }
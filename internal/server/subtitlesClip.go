@@ -0,0 +1,65 @@
+package server
+
+import (
+	"dewarrum/vocabulary-leveling/internal/clips"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// clipRetryAfter is the number of seconds clients are asked to wait before
+// asking again for a clip that is rendering.
+const clipRetryAfter = "5"
+
+// SubtitlesClip serves /subtitles/:trackId/:sequence/clip.:format, the path
+// segments together forming the subtitle id. Subtitles exported before tracks
+// existed use the video id in place of the track id. A clip that takes long to
+// render is answered with 202 Accepted, the client asks again after
+// Retry-After.
+func (s *Server) SubtitlesClip(router fiber.Router) {
+	router.Get("/subtitles/:trackId/:sequence/clip.:format", func(c *fiber.Ctx) error {
+		format, err := clips.ParseFormat(c.Params("format"))
+		if err != nil {
+			return badRequest(CodeUnsupportedFormat, err.Error())
+		}
+
+		subtitleId := fmt.Sprintf("%s/%s", c.Params("trackId"), c.Params("sequence"))
+		subtitle, err := s.Subtitles.Repository.GetById(subtitleId, c.Context())
+		if err != nil {
+			return notFoundOr(err, CodeSubtitleNotFound, "subtitle not found")
+		}
+
+		startMs, endMs := ExtendRange(subtitle.StartMs, subtitle.EndMs, subtitle.EndMs-subtitle.StartMs+2*s.Config.Clips.PaddingMs)
+
+		clip := &clips.Clip{
+			VideoId:    subtitle.VideoId,
+			SubtitleId: subtitle.Id,
+			Sequence:   subtitle.Sequence,
+			StartMs:    startMs,
+			EndMs:      endMs,
+			Text:       subtitle.RawText,
+			TextFrom:   subtitle.StartMs,
+			TextTo:     subtitle.EndMs,
+			BurnIn:     c.QueryBool("burnIn"),
+			Format:     format,
+		}
+
+		url, err := s.Clips.Render(clip, c.UserContext())
+		if errors.Is(err, clips.ErrRenderPending) {
+			c.Set(fiber.HeaderRetryAfter, clipRetryAfter)
+			c.Set(fiber.HeaderLocation, c.OriginalURL())
+			return c.SendStatus(http.StatusAccepted)
+		}
+		if errors.Is(err, clips.ErrRendererBusy) {
+			c.Set(fiber.HeaderRetryAfter, clipRetryAfter)
+			return newProblem(http.StatusServiceUnavailable, CodeClipRendererBusy, "too many clips are rendering, try again later")
+		}
+		if err != nil {
+			return internalError(err)
+		}
+
+		return c.Redirect(url, http.StatusFound)
+	})
+}
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/series"
 	"dewarrum/vocabulary-leveling/internal/subtitles"
 	"dewarrum/vocabulary-leveling/internal/videos"
 
@@ -12,12 +14,12 @@ func (s *Server) SubtitlesSearch(router fiber.Router) {
 	router.Get("/subtitles/search", func(c *fiber.Ctx) error {
 		query := c.Query("query")
 		if query == "" {
-			return c.Status(400).JSON(map[string]string{"error": "query is required"})
+			return badRequest(CodeInvalidQuery, "query is required")
 		}
 
-		ftsSubtitles, err := s.Subtitles.FullTextSearch.Search(query, c.Context())
+		ftsSubtitles, err := s.Subtitles.SearchIndex.Search(query, c.Context())
 		if err != nil {
-			return c.Status(500).JSON(map[string]string{"error": err.Error()})
+			return internalError(err)
 		}
 
 		if len(ftsSubtitles) == 0 {
@@ -31,22 +33,57 @@ func (s *Server) SubtitlesSearch(router fiber.Router) {
 
 		dbSubtitles, err := s.Subtitles.Repository.GetManyByIds(subtitleIds, c.Context())
 		if err != nil {
-			return c.Status(500).JSON(map[string]string{"error": err.Error()})
+			return internalError(err)
 		}
+		s.purgeStale(subtitleIds, dbSubtitles, c.Context())
 
 		videoIds := getVideosIds(dbSubtitles)
 
 		videos, err := s.Videos.Repository.GetManyByIds(videoIds, c.Context())
 		if err != nil {
-			return c.Status(500).JSON(map[string]string{"error": err.Error()})
+			return internalError(err)
 		}
 
-		dtoSubtitles := mapToDto(dbSubtitles, getVideoMap(videos))
+		dtoSubtitles, err := mapToDto(dbSubtitles, getVideoMap(videos))
+		if err != nil {
+			return internalError(err)
+		}
+
+		err = s.attachEpisodeContext(dtoSubtitles, videos, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
 
-		return c.Status(200).JSON(dtoSubtitles)
+		err = s.presignThumbnails(dtoSubtitles, dbSubtitles, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		return c.Status(200).JSON(withoutMissing(dtoSubtitles))
 	})
 }
 
+// purgeStale removes the subtitles found in the search index that are no
+// longer stored, such as those of the duplicate tracks deleted by migration
+// 000018. They are left out of the results either way.
+func (s *Server) purgeStale(subtitleIds []string, dbSubtitles []*subtitles.DbSubtitle, ctx context.Context) {
+	stored := make(map[string]bool, len(dbSubtitles))
+	for _, subtitle := range dbSubtitles {
+		stored[subtitle.Id] = true
+	}
+
+	for _, id := range subtitleIds {
+		if stored[id] {
+			continue
+		}
+
+		err := s.Subtitles.SearchIndex.Delete(id, ctx)
+		if err != nil {
+			s.Logger.Warn().Err(err).Str("subtitleId", id).Msg("Failed to purge stale subtitle from the search index")
+		}
+	}
+}
+
 func getVideosIds(subtitles []*subtitles.DbSubtitle) []uuid.UUID {
 	set := make(map[uuid.UUID]bool)
 	videoIds := make([]uuid.UUID, len(subtitles))
@@ -60,6 +97,19 @@ func getVideosIds(subtitles []*subtitles.DbSubtitle) []uuid.UUID {
 	return videoIds
 }
 
+// withoutMissing drops the subtitles that could not be mapped, either because
+// their video is gone or because it is a duplicate of another video.
+func withoutMissing(dtoSubtitles []*DtoSubtitle) []*DtoSubtitle {
+	result := make([]*DtoSubtitle, 0, len(dtoSubtitles))
+	for _, dtoSubtitle := range dtoSubtitles {
+		if dtoSubtitle != nil {
+			result = append(result, dtoSubtitle)
+		}
+	}
+
+	return result
+}
+
 func getVideoMap(dbVideos []*videos.DbVideo) map[uuid.UUID]*videos.DbVideo {
 	videoMap := make(map[uuid.UUID]*videos.DbVideo)
 	for _, video := range dbVideos {
@@ -68,21 +118,120 @@ func getVideoMap(dbVideos []*videos.DbVideo) map[uuid.UUID]*videos.DbVideo {
 	return videoMap
 }
 
-func mapToDto(subtitles []*subtitles.DbSubtitle, dbVideoMap map[uuid.UUID]*videos.DbVideo) []*DtoSubtitle {
+func mapToDto(subtitles []*subtitles.DbSubtitle, dbVideoMap map[uuid.UUID]*videos.DbVideo) ([]*DtoSubtitle, error) {
 	dtoSubtitles := make([]*DtoSubtitle, len(subtitles))
 	for i, subtitle := range subtitles {
 		dbVideo, ok := dbVideoMap[subtitle.VideoId]
-		if !ok {
+		if !ok || dbVideo.DuplicateOf != nil {
 			continue
 		}
 
+		utterances, err := subtitle.GetUtterances()
+		if err != nil {
+			return nil, err
+		}
+		style, err := subtitle.GetStyle()
+		if err != nil {
+			return nil, err
+		}
+
 		dtoSubtitles[i] = &DtoSubtitle{
-			Id:        subtitle.Id,
-			VideoName: dbVideo.Name,
-			StartMs:   subtitle.StartMs,
-			EndMs:     subtitle.EndMs,
-			Text:      subtitle.Text,
+			Id:            subtitle.Id,
+			VideoId:       dbVideo.Id.String(),
+			VideoName:     dbVideo.Name,
+			EpisodeNumber: dbVideo.EpisodeNumber,
+			AirYear:       dbVideo.AirYear,
+			StartMs:       subtitle.StartMs,
+			EndMs:         subtitle.EndMs,
+			Text:          subtitle.RawText,
+			Utterances:    utterances,
+			Style:         style,
+		}
+	}
+	return dtoSubtitles, nil
+}
+
+// attachEpisodeContext fills in the series and season of every subtitle whose
+// video has been catalogued as an episode.
+func (s *Server) attachEpisodeContext(dtoSubtitles []*DtoSubtitle, dbVideos []*videos.DbVideo, ctx context.Context) error {
+	var seasonIds []uuid.UUID
+	for _, video := range dbVideos {
+		if video.SeasonId != nil {
+			seasonIds = append(seasonIds, *video.SeasonId)
 		}
 	}
-	return dtoSubtitles
+
+	if len(seasonIds) == 0 {
+		return nil
+	}
+
+	dbSeasons, err := s.Series.Seasons.GetManyByIds(seasonIds, ctx)
+	if err != nil {
+		return err
+	}
+
+	seasonMap := make(map[uuid.UUID]*series.DbSeason)
+	seriesIds := make([]uuid.UUID, len(dbSeasons))
+	for i, season := range dbSeasons {
+		seasonMap[season.Id] = season
+		seriesIds[i] = season.SeriesId
+	}
+
+	dbSeries, err := s.Series.Repository.GetManyByIds(seriesIds, ctx)
+	if err != nil {
+		return err
+	}
+
+	seriesMap := make(map[uuid.UUID]*series.DbSeries)
+	for _, item := range dbSeries {
+		seriesMap[item.Id] = item
+	}
+
+	videoMap := getVideoMap(dbVideos)
+	for _, dtoSubtitle := range dtoSubtitles {
+		if dtoSubtitle == nil {
+			continue
+		}
+
+		video := videoMap[uuid.MustParse(dtoSubtitle.VideoId)]
+		if video.SeasonId == nil {
+			continue
+		}
+
+		season, ok := seasonMap[*video.SeasonId]
+		if !ok {
+			continue
+		}
+
+		seasonId := season.Id.String()
+		dtoSubtitle.SeasonId = &seasonId
+		dtoSubtitle.SeasonNumber = &season.Number
+		if dtoSubtitle.AirYear == nil {
+			dtoSubtitle.AirYear = season.AirYear
+		}
+
+		if seriesItem, ok := seriesMap[season.SeriesId]; ok {
+			seriesId := seriesItem.Id.String()
+			dtoSubtitle.SeriesId = &seriesId
+			dtoSubtitle.SeriesName = &seriesItem.Name
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) presignThumbnails(dtoSubtitles []*DtoSubtitle, dbSubtitles []*subtitles.DbSubtitle, ctx context.Context) error {
+	for i, subtitle := range dbSubtitles {
+		if dtoSubtitles[i] == nil || subtitle.ThumbnailLocation == nil {
+			continue
+		}
+
+		thumbnailUrl, err := s.Subtitles.FileStorage.PresignObject(*subtitle.ThumbnailLocation, ctx)
+		if err != nil {
+			return err
+		}
+		dtoSubtitles[i].ThumbnailUrl = thumbnailUrl
+	}
+
+	return nil
 }
@@ -0,0 +1,114 @@
+package server_test
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/server"
+	"dewarrum/vocabulary-leveling/internal/subtitles"
+	"dewarrum/vocabulary-leveling/internal/videos"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// foundIndex finds the same subtitles for every query.
+type foundIndex []*subtitles.FtsSubtitle
+
+func (i foundIndex) Insert(*subtitles.FtsSubtitle, context.Context) error { return nil }
+
+func (i foundIndex) Delete(string, context.Context) error { return nil }
+
+func (i foundIndex) Search(string, context.Context) ([]*subtitles.FtsSubtitle, error) {
+	return i, nil
+}
+
+func TestSubtitlesSearchShowsTheTextAsShownOnScreen(t *testing.T) {
+	srv := newTestServer(t)
+	app := fiber.New(fiber.Config{ErrorHandler: srv.ErrorHandler})
+	srv.SubtitlesSearch(app)
+
+	videoId := uuid.New()
+	subtitleId := fmt.Sprintf("%s/1", videoId)
+	srv.Subtitles.SearchIndex = foundIndex{subtitles.NewFtsSubtitle(subtitleId, videoId, 1, "안녕\n잘 가")}
+
+	srv.mock.ExpectQuery("SELECT .* FROM subtitles WHERE id IN").
+		WithArgs(subtitleId).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "video_id", "sequence", "start_ms", "end_ms", "text", "raw_text", "utterances", "non_speech", "style"}).
+			AddRow(subtitleId, videoId, 1, 1000, 2000, "안녕\n잘 가", "민수: 안녕\n- 잘 가", `[{"speaker":"민수","text":"안녕"},{"text":"잘 가"}]`, false, `{"italic":true}`))
+	srv.mock.ExpectQuery("SELECT .* FROM videos WHERE id IN").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "status", "genres", "created_at"}).
+			AddRow(videoId, "Episode 1", videos.VideoStatusReady, "{}", time.Now()))
+
+	var found []*server.DtoSubtitle
+	response := sendJson(t, app, http.MethodGet, "/subtitles/search?query=안녕", nil, &found)
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+	if len(found) != 1 {
+		t.Fatalf("Expected 1 subtitle, but got %d", len(found))
+	}
+
+	subtitle := found[0]
+	if subtitle.Text != "민수: 안녕\n- 잘 가" {
+		t.Errorf("Expected the raw text, but got %q", subtitle.Text)
+	}
+	if len(subtitle.Utterances) != 2 || subtitle.Utterances[0].Speaker != "민수" || subtitle.Utterances[1].Text != "잘 가" {
+		t.Errorf("Expected the utterances of the subtitle, but got %+v", subtitle.Utterances)
+	}
+	if subtitle.Style == nil || !subtitle.Style.Italic {
+		t.Errorf("Expected the subtitle to be italic, but got %+v", subtitle.Style)
+	}
+}
+
+// staleIndex finds the same subtitles for every query and remembers what was
+// deleted.
+type staleIndex struct {
+	foundIndex
+	deleted []string
+}
+
+func (i *staleIndex) Delete(id string, ctx context.Context) error {
+	i.deleted = append(i.deleted, id)
+	return nil
+}
+
+func TestSubtitlesSearchPurgesSubtitlesThatAreNoLongerStored(t *testing.T) {
+	srv := newTestServer(t)
+	app := fiber.New(fiber.Config{ErrorHandler: srv.ErrorHandler})
+	srv.SubtitlesSearch(app)
+
+	videoId := uuid.New()
+	storedId := fmt.Sprintf("%s/1", videoId)
+	// A subtitle of a duplicate track deleted by migration 000018.
+	staleId := fmt.Sprintf("%s/1", uuid.New())
+	index := &staleIndex{foundIndex: foundIndex{
+		subtitles.NewFtsSubtitle(storedId, videoId, 1, "안녕"),
+		subtitles.NewFtsSubtitle(staleId, videoId, 1, "안녕"),
+	}}
+	srv.Subtitles.SearchIndex = index
+
+	srv.mock.ExpectQuery("SELECT .* FROM subtitles WHERE id IN").
+		WithArgs(storedId, staleId).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "video_id", "sequence", "start_ms", "end_ms", "text", "raw_text", "utterances", "non_speech", "style"}).
+			AddRow(storedId, videoId, 1, 1000, 2000, "안녕", "안녕", nil, false, nil))
+	srv.mock.ExpectQuery("SELECT .* FROM videos WHERE id IN").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "status", "genres", "created_at"}).
+			AddRow(videoId, "Episode 1", videos.VideoStatusReady, "{}", time.Now()))
+
+	var found []*server.DtoSubtitle
+	response := sendJson(t, app, http.MethodGet, "/subtitles/search?query=안녕", nil, &found)
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	if len(found) != 1 || found[0].Id != storedId {
+		t.Errorf("Expected only the stored subtitle, but got %+v", found)
+	}
+	if len(index.deleted) != 1 || index.deleted[0] != staleId {
+		t.Errorf("Expected the stale subtitle to be purged, but got %v", index.deleted)
+	}
+}
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/videos"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultVideosPageSize = 20
+	maxVideosPageSize     = 100
+)
+
+type DtoVideo struct {
+	Id            string    `json:"id"`
+	Name          string    `json:"name"`
+	Status        string    `json:"status"`
+	DurationMs    *int64    `json:"durationMs"`
+	SeasonId      *string   `json:"seasonId"`
+	EpisodeNumber *int      `json:"episodeNumber"`
+	AirYear       *int      `json:"airYear"`
+	Description   *string   `json:"description"`
+	Genres        []string  `json:"genres"`
+	PosterUrl     string    `json:"posterUrl,omitempty"`
+	DuplicateOf   *string   `json:"duplicateOf,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type DtoVideoPage struct {
+	Items      []*DtoVideo `json:"items"`
+	NextCursor *string     `json:"nextCursor"`
+}
+
+type DtoVideoDetail struct {
+	DtoVideo
+	SeriesId       *string             `json:"seriesId,omitempty"`
+	SeriesName     *string             `json:"seriesName,omitempty"`
+	SeasonNumber   *int                `json:"seasonNumber,omitempty"`
+	SubtitleCount  int64               `json:"subtitleCount"`
+	SubtitleTracks []*DtoSubtitleTrack `json:"subtitleTracks"`
+}
+
+// VideosList pages through the videos of the library, those that are ready to
+// watch unless another status is asked for. Pages are addressed with an opaque
+// cursor taken from the previous response, so inserts between requests never
+// shift or duplicate items.
+func (s *Server) VideosList(router fiber.Router) {
+	router.Get("/videos", func(c *fiber.Ctx) error {
+		sort := c.Query("sort", videos.SortNewest)
+
+		limit := c.QueryInt("limit", defaultVideosPageSize)
+		if limit < 1 || limit > maxVideosPageSize {
+			return badRequest(CodeInvalidQuery, "limit must be between 1 and 100")
+		}
+
+		// Only the videos ready to watch are listed unless asked otherwise.
+		status := c.Query("status", videos.VideoStatusReady)
+		if !slices.Contains(videos.VideoStatuses, status) {
+			return badRequest(CodeInvalidQuery, "status must be one of "+strings.Join(videos.VideoStatuses, ", "))
+		}
+		filter := &videos.ListFilter{
+			Genre:    optionalQuery(c, "genre"),
+			Status:   &status,
+			Language: optionalQuery(c, "language"),
+		}
+
+		if seriesId := c.Query("seriesId"); seriesId != "" {
+			parsedSeriesId, err := uuid.Parse(seriesId)
+			if err != nil {
+				return badRequest(CodeInvalidQuery, "seriesId must be a valid uuid")
+			}
+			filter.SeriesId = &parsedSeriesId
+		}
+
+		var cursor *videos.Cursor
+		if value := c.Query("cursor"); value != "" {
+			decodedCursor, err := videos.DecodeCursor(value)
+			if err != nil {
+				return badRequest(CodeInvalidQuery, videos.ErrInvalidCursor.Error()).withCause(err)
+			}
+			cursor = decodedCursor
+		}
+
+		dbVideos, nextCursor, err := s.Videos.Repository.List(filter, sort, cursor, limit, c.Context())
+		if errors.Is(err, videos.ErrUnsupportedSort) {
+			return badRequest(CodeInvalidQuery, videos.ErrUnsupportedSort.Error()).withCause(err)
+		}
+		if errors.Is(err, videos.ErrInvalidCursor) {
+			return badRequest(CodeInvalidQuery, videos.ErrInvalidCursor.Error()).withCause(err)
+		}
+		if err != nil {
+			return internalError(err)
+		}
+
+		page := &DtoVideoPage{Items: make([]*DtoVideo, len(dbVideos))}
+		for i, video := range dbVideos {
+			page.Items[i], err = s.mapVideoToDto(video, c.Context())
+			if err != nil {
+				return internalError(err)
+			}
+		}
+
+		if nextCursor != nil {
+			encodedCursor, err := nextCursor.Encode()
+			if err != nil {
+				return internalError(err)
+			}
+			page.NextCursor = &encodedCursor
+		}
+
+		return c.Status(http.StatusOK).JSON(page)
+	})
+}
+
+func (s *Server) VideosDetail(router fiber.Router) {
+	router.Get("/videos/:videoId", func(c *fiber.Ctx) error {
+		videoId, err := uuid.Parse(c.Params("videoId"))
+		if err != nil {
+			return badRequest(CodeInvalidParameter, "videoId must be a valid uuid")
+		}
+
+		dbVideo, err := s.Videos.Repository.GetById(videoId, c.Context())
+		if err != nil {
+			return notFoundOr(err, CodeVideoNotFound, "video not found")
+		}
+
+		dtoVideo, err := s.mapVideoToDto(dbVideo, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		detail := &DtoVideoDetail{DtoVideo: *dtoVideo}
+
+		detail.SubtitleCount, err = s.Subtitles.Repository.CountByVideoId(videoId, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		dbTracks, err := s.Subtitles.Tracks.GetByVideoId(videoId, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		detail.SubtitleTracks = make([]*DtoSubtitleTrack, len(dbTracks))
+		for i, track := range dbTracks {
+			detail.SubtitleTracks[i] = &DtoSubtitleTrack{
+				Id:          track.Id.String(),
+				Source:      track.Source,
+				Status:      track.Status,
+				Language:    track.Language,
+				StreamIndex: track.StreamIndex,
+				Codec:       track.Codec,
+				CreatedAt:   track.CreatedAt,
+			}
+		}
+
+		if dbVideo.SeasonId != nil {
+			dbSeason, err := s.Series.Seasons.GetById(*dbVideo.SeasonId, c.Context())
+			if err != nil {
+				return internalError(err)
+			}
+
+			dbSeries, err := s.Series.Repository.GetById(dbSeason.SeriesId, c.Context())
+			if err != nil {
+				return internalError(err)
+			}
+
+			seriesId := dbSeries.Id.String()
+			detail.SeriesId = &seriesId
+			detail.SeriesName = &dbSeries.Name
+			detail.SeasonNumber = &dbSeason.Number
+		}
+
+		return c.Status(http.StatusOK).JSON(detail)
+	})
+}
+
+func (s *Server) mapVideoToDto(dbVideo *videos.DbVideo, ctx context.Context) (*DtoVideo, error) {
+	dtoVideo := &DtoVideo{
+		Id:            dbVideo.Id.String(),
+		Name:          dbVideo.Name,
+		Status:        dbVideo.Status,
+		DurationMs:    dbVideo.DurationMs,
+		EpisodeNumber: dbVideo.EpisodeNumber,
+		AirYear:       dbVideo.AirYear,
+		Description:   dbVideo.Description,
+		Genres:        dbVideo.Genres,
+		CreatedAt:     dbVideo.CreatedAt,
+	}
+
+	if dbVideo.SeasonId != nil {
+		seasonId := dbVideo.SeasonId.String()
+		dtoVideo.SeasonId = &seasonId
+	}
+
+	if dbVideo.DuplicateOf != nil {
+		duplicateOf := dbVideo.DuplicateOf.String()
+		dtoVideo.DuplicateOf = &duplicateOf
+	}
+
+	if dbVideo.PosterLocation != nil {
+		posterUrl, err := s.Videos.FileStorage.PresignObject(*dbVideo.PosterLocation, ctx)
+		if err != nil {
+			return nil, err
+		}
+		dtoVideo.PosterUrl = posterUrl
+	}
+
+	return dtoVideo, nil
+}
+
+func optionalQuery(c *fiber.Ctx, key string) *string {
+	value := c.Query(key)
+	if value == "" {
+		return nil
+	}
+
+	return &value
+}
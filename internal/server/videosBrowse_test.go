@@ -0,0 +1,74 @@
+package server_test
+
+import (
+	"dewarrum/vocabulary-leveling/internal/server"
+	"dewarrum/vocabulary-leveling/internal/videos"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestVideosListListsReadyVideosUnlessAskedOtherwise(t *testing.T) {
+	srv := newTestServer(t)
+	app := fiber.New(fiber.Config{ErrorHandler: srv.ErrorHandler})
+	srv.VideosList(app)
+
+	cases := []struct {
+		path   string
+		status string
+	}{
+		{path: "/videos", status: videos.VideoStatusReady},
+		{path: "/videos?status=processing", status: videos.VideoStatusProcessing},
+	}
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			srv.mock.ExpectQuery("SELECT .* FROM videos v .* WHERE v.status = \\$1 ORDER BY").
+				WithArgs(c.status, 21).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name", "status", "genres", "created_at"}).
+					AddRow(uuid.New(), "Episode 1", c.status, "{}", time.Now()))
+
+			var page server.DtoVideoPage
+			response := sendJson(t, app, http.MethodGet, c.path, nil, &page)
+			if response.StatusCode != http.StatusOK {
+				t.Fatalf("Expected status %d, but got %d", http.StatusOK, response.StatusCode)
+			}
+			if len(page.Items) != 1 || page.Items[0].Status != c.status {
+				t.Errorf("Expected the %s video, but got %+v", c.status, page.Items)
+			}
+		})
+	}
+}
+
+func TestVideosListRejectsUnknownStatuses(t *testing.T) {
+	srv := newTestServer(t)
+	app := fiber.New(fiber.Config{ErrorHandler: srv.ErrorHandler})
+	srv.VideosList(app)
+
+	response := sendJson(t, app, http.MethodGet, "/videos?status=deleted", nil, nil)
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status %d, but got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestVideosListFiltersReadyVideosByGenre(t *testing.T) {
+	srv := newTestServer(t)
+	app := fiber.New(fiber.Config{ErrorHandler: srv.ErrorHandler})
+	srv.VideosList(app)
+
+	srv.mock.ExpectQuery("WHERE \\(\\$1 = ANY\\(v.genres\\) OR \\$1 = ANY\\(se.genres\\)\\) AND v.status = \\$2").
+		WithArgs("drama", videos.VideoStatusReady, 21).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	var page server.DtoVideoPage
+	response := sendJson(t, app, http.MethodGet, "/videos?genre=drama", nil, &page)
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+	if len(page.Items) != 0 || page.NextCursor != nil {
+		t.Errorf("Expected an empty last page, but got %+v", page)
+	}
+}
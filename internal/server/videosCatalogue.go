@@ -0,0 +1,119 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// VideosUpdate edits the catalogue metadata of a video, including the season
+// it belongs to and its episode number.
+func (s *Server) VideosUpdate(router fiber.Router) {
+	router.Put("/videos/:videoId", func(c *fiber.Ctx) error {
+		videoId, err := uuid.Parse(c.Params("videoId"))
+		if err != nil {
+			return badRequest(CodeInvalidParameter, "videoId must be a valid uuid")
+		}
+
+		var request DtoVideoCatalogueRequest
+		err = c.BodyParser(&request)
+		if err != nil {
+			return badRequest(CodeInvalidBody, "the body could not be parsed").withCause(err)
+		}
+
+		if request.Name == "" {
+			return badRequest(CodeInvalidBody, "name is required")
+		}
+
+		var seasonId *uuid.UUID
+		if request.SeasonId != nil {
+			parsedSeasonId, err := uuid.Parse(*request.SeasonId)
+			if err != nil {
+				return badRequest(CodeInvalidBody, "seasonId must be a valid uuid")
+			}
+
+			_, err = s.Series.Seasons.GetById(parsedSeasonId, c.Context())
+			if errors.Is(err, sql.ErrNoRows) {
+				return badRequest(CodeSeasonNotFound, "season not found").withCause(err)
+			}
+			if err != nil {
+				return internalError(err)
+			}
+
+			seasonId = &parsedSeasonId
+		}
+
+		dbVideo, err := s.Videos.Repository.GetById(videoId, c.Context())
+		if err != nil {
+			return notFoundOr(err, CodeVideoNotFound, "video not found")
+		}
+
+		dbVideo.Name = request.Name
+		dbVideo.SeasonId = seasonId
+		dbVideo.EpisodeNumber = request.EpisodeNumber
+		dbVideo.AirYear = request.AirYear
+		dbVideo.Description = request.Description
+		dbVideo.Genres = request.Genres
+		if dbVideo.Genres == nil {
+			dbVideo.Genres = []string{}
+		}
+
+		err = s.Videos.Repository.UpdateCatalogue(dbVideo, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		dtoEpisode, err := s.mapEpisodeToDto(dbVideo, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		return c.Status(http.StatusOK).JSON(dtoEpisode)
+	})
+}
+
+func (s *Server) VideosPosterUpload(router fiber.Router) {
+	router.Put("/videos/:videoId/poster", func(c *fiber.Ctx) error {
+		videoId, err := uuid.Parse(c.Params("videoId"))
+		if err != nil {
+			return badRequest(CodeInvalidParameter, "videoId must be a valid uuid")
+		}
+
+		posterHeader, err := c.FormFile("poster")
+		if err != nil {
+			return badRequest(CodeInvalidBody, "poster is required").withCause(err)
+		}
+
+		posterFile, err := posterHeader.Open()
+		if err != nil {
+			return internalError(err)
+		}
+		defer posterFile.Close()
+
+		dbVideo, err := s.Videos.Repository.GetById(videoId, c.Context())
+		if err != nil {
+			return notFoundOr(err, CodeVideoNotFound, "video not found")
+		}
+
+		posterLocation, err := s.Videos.FileStorage.UploadPoster(videoId, posterFile, posterHeader.Header.Get("Content-Type"), c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		err = s.Videos.Repository.UpdatePosterLocation(videoId, posterLocation, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+		dbVideo.PosterLocation = &posterLocation
+
+		dtoEpisode, err := s.mapEpisodeToDto(dbVideo, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		return c.Status(http.StatusOK).JSON(dtoEpisode)
+	})
+}
@@ -3,16 +3,24 @@ package server
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"dewarrum/vocabulary-leveling/internal/chunks"
 	"dewarrum/vocabulary-leveling/internal/inits"
 	"dewarrum/vocabulary-leveling/internal/mpd"
 	"dewarrum/vocabulary-leveling/internal/utils"
+	"dewarrum/vocabulary-leveling/internal/videos"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	thumbnailAdaptationSetId = "2"
+	thumbnailTileBandwidth   = "20000"
 )
 
 func insertPresignedChunkStreams(segmentList *mpd.SegmentList, presignedUrls []string) error {
@@ -93,41 +101,120 @@ func (s *Server) insertSegmentList(representation *mpd.Representation, chunks []
 	return nil
 }
 
+// ThumbnailSegmentList lists the thumbnail tiles of a period that starts at
+// periodStartMs. The timeline places every tile at the media time it covers,
+// tiles do not start with the period, and the offset maps the start of the
+// period to its media time, which is where the first video chunk starts.
+func ThumbnailSegmentList(tiles []*chunks.DbChunk, presignedTiles []string, periodStartMs int64) (*mpd.SegmentList, error) {
+	segmentList := &mpd.SegmentList{
+		Timescale:              "1000",
+		PresentationTimeOffset: fmt.Sprintf("%d", periodStartMs),
+		SegmentTimeline:        &mpd.SegmentTimeline{SegmentTimelineEntries: make([]*mpd.SegmentTimelineEntry, len(tiles))},
+		Segments:               make([]*mpd.Segment, len(tiles)),
+	}
+	for i, tile := range tiles {
+		segmentList.SegmentTimeline.SegmentTimelineEntries[i] = &mpd.SegmentTimelineEntry{
+			Timestamp: fmt.Sprintf("%d", tile.StartMs),
+			Duration:  fmt.Sprintf("%d", tile.EndMs-tile.StartMs),
+		}
+	}
+
+	err := insertPresignedChunkStreams(segmentList, presignedTiles)
+	if err != nil {
+		return nil, err
+	}
+
+	return segmentList, nil
+}
+
+func (s *Server) insertThumbnailAdaptationSet(manifest *mpd.MPD, videoId uuid.UUID, startMs int64, endMs int64, periodStartMs int64, ctx context.Context) error {
+	if len(manifest.Periods) < 1 {
+		return nil
+	}
+
+	dbTiles, err := s.ChunksRepository.GetOverlapping(videoId, chunks.ThumbnailTilesRepresentationId, startMs, endMs, ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(dbTiles) == 0 {
+		return nil
+	}
+
+	presignedTiles, err := s.presignChunks(dbTiles, ctx)
+	if err != nil {
+		return err
+	}
+
+	segmentList, err := ThumbnailSegmentList(dbTiles, presignedTiles, periodStartMs)
+	if err != nil {
+		return err
+	}
+
+	adaptationSet := &mpd.AdaptationSet{
+		Id:          thumbnailAdaptationSetId,
+		MimeType:    "image/jpeg",
+		ContentType: "image",
+		Representations: []*mpd.Representation{
+			{
+				ID:          chunks.ThumbnailTilesRepresentationId,
+				MimeType:    "image/jpeg",
+				Bandwidth:   thumbnailTileBandwidth,
+				Width:       fmt.Sprintf("%d", videos.ThumbnailWidth*videos.ThumbnailTileColumns),
+				Height:      fmt.Sprintf("%d", videos.ThumbnailHeight*videos.ThumbnailTileRows),
+				SegmentList: segmentList,
+				EssentialProperty: &mpd.EssentialProperty{
+					SchemeIdUri: mpd.ThumbnailTileSchemeIdUri,
+					Value:       fmt.Sprintf("%dx%d", videos.ThumbnailTileColumns, videos.ThumbnailTileRows),
+				},
+			},
+		},
+	}
+
+	period := manifest.Periods[0]
+	period.AdaptationSets = append(period.AdaptationSets, adaptationSet)
+
+	return nil
+}
+
 func (s *Server) VideosManifest(router fiber.Router) {
 	router.Get("/videos/manifest.mpd", func(c *fiber.Ctx) error {
 		subtitleId := c.Query("subtitleId")
 		if subtitleId == "" {
-			return c.Status(http.StatusBadRequest).JSON(map[string]string{"error": "subtitleId is required"})
+			return badRequest(CodeInvalidQuery, "subtitleId is required")
 		}
 
 		subtitle, err := s.Subtitles.Repository.GetById(subtitleId, c.Context())
 		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(map[string]string{"error": err.Error()})
+			return notFoundOr(err, CodeSubtitleNotFound, "subtitle not found")
 		}
 		videoId := subtitle.VideoId
 
 		dbManifest, err := s.ManifestsRepository.GetByVideoId(videoId, c.Context())
+		if errors.Is(err, sql.ErrNoRows) {
+			return conflict(CodeVideoNotReady, "the video has not been processed yet").withCause(err)
+		}
 		if err != nil {
-			c.Status(http.StatusInternalServerError).JSON(map[string]string{"error": err.Error()})
-			return err
+			return internalError(err)
 		}
 
 		manifestMeta, err := dbManifest.GetMeta()
 		if err != nil {
-			c.Status(http.StatusInternalServerError).JSON(map[string]string{"error": err.Error()})
-			return err
+			return internalError(err)
 		}
 
 		dbInits, err := s.InitsRepository.GetByVideoId(videoId, c.Context())
 		if err != nil {
-			c.Status(http.StatusInternalServerError).JSON(map[string]string{"error": err.Error()})
-			return err
+			return internalError(err)
+		}
+		if len(dbInits) < 2 {
+			return conflict(CodeVideoNotReady, "the video has not been processed yet")
 		}
 
 		chunkDuration, err := manifestMeta.GetChunkDuration()
 		s.Logger.Debug().Int64("chunkDuration", chunkDuration).Msg("Chunk duration")
 		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(map[string]string{"error": err.Error()})
+			return internalError(err)
 		}
 
 		s.Logger.Debug().Int64("startMs", subtitle.StartMs).Int64("endMs", subtitle.EndMs).Msg("Subtitle range")
@@ -138,20 +225,39 @@ func (s *Server) VideosManifest(router fiber.Router) {
 
 		dbChunks, err := s.ChunksRepository.GetMany(videoId, startMs, endMs, c.Context())
 		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(map[string]string{"error": err.Error()})
+			return internalError(err)
 		}
 
 		dbVideoChunks := utils.Filter(dbChunks, func(chunk *chunks.DbChunk) bool { return chunk.RepresentationId == "0" })
 		dbAudioChunks := utils.Filter(dbChunks, func(chunk *chunks.DbChunk) bool { return chunk.RepresentationId == "1" })
 
-		s.insertSegmentList(manifestMeta.GetRepresentation("0"), dbVideoChunks, dbInits[0], 1, c.Context())
-		s.insertSegmentList(manifestMeta.GetRepresentation("1"), dbAudioChunks, dbInits[1], 1, c.Context())
+		err = s.insertSegmentList(manifestMeta.GetRepresentation("0"), dbVideoChunks, dbInits[0], 1, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		err = s.insertSegmentList(manifestMeta.GetRepresentation("1"), dbAudioChunks, dbInits[1], 1, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		periodStartMs := startMs
+		for i, chunk := range dbVideoChunks {
+			if i == 0 || chunk.StartMs < periodStartMs {
+				periodStartMs = chunk.StartMs
+			}
+		}
+
+		err = s.insertThumbnailAdaptationSet(manifestMeta, videoId, startMs, endMs, periodStartMs, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
 
-		c.Set("Content-Type", "application/dash+xml")
 		serialized, err := manifestMeta.Serialize()
 		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(map[string]string{"error": err.Error()})
+			return internalError(err)
 		}
+		c.Set("Content-Type", "application/dash+xml")
 
 		serialized = bytes.Replace(serialized, []byte("&amp;"), []byte("&"), -1)
 		return c.Status(http.StatusOK).Send(serialized)
@@ -1,7 +1,11 @@
 package server_test
 
 import (
+	"dewarrum/vocabulary-leveling/internal/chunks"
+	"dewarrum/vocabulary-leveling/internal/mpd"
 	"dewarrum/vocabulary-leveling/internal/server"
+	"dewarrum/vocabulary-leveling/internal/videos"
+	"strconv"
 	"testing"
 )
 
@@ -52,3 +56,53 @@ func TestExtendRangeWhenStartIsZero(t *testing.T) {
 		t.Errorf("Expected endMs to be %d, but got %d", 2000, e)
 	}
 }
+
+// fetchedTile is the tile a player fetches to show the thumbnail at
+// presentationMs into the period. It maps the presentation time to media time
+// with the offset and looks it up in the timeline.
+func fetchedTile(t *testing.T, segmentList *mpd.SegmentList, presentationMs int64) string {
+	offset, err := strconv.ParseInt(segmentList.PresentationTimeOffset, 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mediaMs := presentationMs + offset
+	for i, entry := range segmentList.SegmentTimeline.SegmentTimelineEntries {
+		start, _ := strconv.ParseInt(entry.Timestamp, 10, 64)
+		duration, _ := strconv.ParseInt(entry.Duration, 10, 64)
+		if start <= mediaMs && mediaMs < start+duration {
+			return segmentList.Segments[i].Media
+		}
+	}
+
+	return ""
+}
+
+func TestThumbnailSegmentListShowsTheTilesOfThePeriod(t *testing.T) {
+	tiles := []*chunks.DbChunk{
+		{Sequence: 3, StartMs: 2 * videos.ThumbnailTileMs, EndMs: 3 * videos.ThumbnailTileMs},
+		{Sequence: 4, StartMs: 3 * videos.ThumbnailTileMs, EndMs: 4 * videos.ThumbnailTileMs},
+	}
+
+	// The period starts 4s into the third tile.
+	periodStartMs := int64(2*videos.ThumbnailTileMs + 4000)
+	segmentList, err := server.ThumbnailSegmentList(tiles, []string{"tile-00003.jpg", "tile-00004.jpg"}, periodStartMs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		presentationMs int64
+		expected       string
+	}{
+		{presentationMs: 0, expected: "tile-00003.jpg"},
+		{presentationMs: videos.ThumbnailTileMs - 4001, expected: "tile-00003.jpg"},
+		{presentationMs: videos.ThumbnailTileMs - 4000, expected: "tile-00004.jpg"},
+		{presentationMs: 2*videos.ThumbnailTileMs - 4001, expected: "tile-00004.jpg"},
+	}
+	for _, c := range cases {
+		if tile := fetchedTile(t, segmentList, c.presentationMs); tile != c.expected {
+			t.Errorf("Expected %s to be shown at %dms, but got %q", c.expected, c.presentationMs, tile)
+		}
+	}
+}
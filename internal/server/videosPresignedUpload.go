@@ -0,0 +1,163 @@
+package server
+
+import (
+	"dewarrum/vocabulary-leveling/internal/subtitles"
+	"dewarrum/vocabulary-leveling/internal/videos"
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type DtoCreateVideoRequest struct {
+	VideoName            string  `json:"videoName"`
+	VideoContentType     string  `json:"videoContentType"`
+	SubtitlesContentType *string `json:"subtitlesContentType"`
+	SubtitlesLanguage    *string `json:"subtitlesLanguage"`
+}
+
+type DtoCreateVideoResponse struct {
+	VideoId            string  `json:"videoId"`
+	VideoUploadUrl     string  `json:"videoUploadUrl"`
+	SubtitlesTrackId   *string `json:"subtitlesTrackId,omitempty"`
+	SubtitlesUploadUrl *string `json:"subtitlesUploadUrl,omitempty"`
+}
+
+type DtoCompleteVideoRequest struct {
+	ExtractSubtitles bool `json:"extractSubtitles"`
+}
+
+// VideosCreate registers a video and hands out presigned PUT URLs so the media
+// goes straight to S3. VideosComplete publishes the export messages once the
+// client has finished uploading.
+func (s *Server) VideosCreate(router fiber.Router) {
+	router.Post("/videos", func(c *fiber.Ctx) error {
+		var request DtoCreateVideoRequest
+		err := c.BodyParser(&request)
+		if err != nil {
+			return badRequest(CodeInvalidBody, "the body could not be parsed").withCause(err)
+		}
+
+		if request.VideoName == "" {
+			return badRequest(CodeInvalidBody, "name is required")
+		}
+
+		if request.VideoContentType == "" {
+			return badRequest(CodeInvalidBody, "videoContentType is required")
+		}
+
+		video := videos.NewDbVideo(request.VideoName)
+		video.Status = videos.VideoStatusUploading
+		_, err = s.Videos.Repository.Insert(video, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		videoUploadUrl, err := s.Videos.FileStorage.PresignUpload(video.Id, request.VideoContentType, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		response := &DtoCreateVideoResponse{
+			VideoId:        video.Id.String(),
+			VideoUploadUrl: videoUploadUrl,
+		}
+
+		if request.SubtitlesContentType != nil {
+			track := subtitles.NewUploadedDbSubtitleTrack(video.Id, request.SubtitlesLanguage)
+			_, err = s.Subtitles.Tracks.Insert(track, c.Context())
+			if err != nil {
+				return internalError(err)
+			}
+
+			subtitlesUploadUrl, err := s.Subtitles.FileStorage.PresignUpload(video.Id, track.Id, *request.SubtitlesContentType, c.Context())
+			if err != nil {
+				return internalError(err)
+			}
+
+			trackId := track.Id.String()
+			response.SubtitlesTrackId = &trackId
+			response.SubtitlesUploadUrl = &subtitlesUploadUrl
+		}
+
+		return c.Status(http.StatusCreated).JSON(response)
+	})
+}
+
+func (s *Server) VideosComplete(router fiber.Router) {
+	router.Post("/videos/:videoId/complete", func(c *fiber.Ctx) error {
+		videoId, err := uuid.Parse(c.Params("videoId"))
+		if err != nil {
+			return badRequest(CodeInvalidParameter, "videoId must be a valid uuid")
+		}
+
+		var request DtoCompleteVideoRequest
+		if len(c.Body()) > 0 {
+			err = c.BodyParser(&request)
+			if err != nil {
+				return badRequest(CodeInvalidBody, "the body could not be parsed").withCause(err)
+			}
+		}
+
+		_, err = s.Videos.Repository.GetById(videoId, c.Context())
+		if err != nil {
+			return notFoundOr(err, CodeVideoNotFound, "video not found")
+		}
+
+		exists, err := s.Videos.FileStorage.Exists(videoId, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+		if !exists {
+			return conflict(CodeVideoNotUploaded, "video has not been uploaded")
+		}
+
+		dbTracks, err := s.Subtitles.Tracks.GetByVideoId(videoId, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		var uploadedTracks []*subtitles.DbSubtitleTrack
+		for _, track := range dbTracks {
+			if track.Source != subtitles.TrackSourceUpload {
+				continue
+			}
+
+			exists, err := s.Subtitles.FileStorage.Exists(videoId, track.Id, c.Context())
+			if err != nil {
+				return internalError(err)
+			}
+			if !exists {
+				return conflict(CodeSubtitlesNotUploaded, "subtitles have not been uploaded")
+			}
+
+			uploadedTracks = append(uploadedTracks, track)
+		}
+
+		// Only the request that moves the video out of uploading publishes
+		// the exports, completing a video twice would export it twice.
+		started, err := s.Videos.Repository.UpdateStatusFrom(videoId, videos.VideoStatusUploading, videos.VideoStatusProcessing, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+		if !started {
+			return conflict(CodeVideoAlreadyCompleted, "video has already been completed")
+		}
+
+		extractSubtitles := len(uploadedTracks) == 0 || request.ExtractSubtitles
+		err = s.Videos.Messages.Send(videos.NewExportVideoMessage(videoId, extractSubtitles), c.UserContext())
+		if err != nil {
+			return internalError(errors.Join(err, s.Videos.Repository.UpdateStatus(videoId, videos.VideoStatusUploading, c.Context())))
+		}
+
+		for _, track := range uploadedTracks {
+			err = s.Subtitles.Messages.Send(subtitles.NewExportSubtitlesMessage(videoId, track.Id), c.UserContext())
+			if err != nil {
+				return internalError(err)
+			}
+		}
+
+		return c.Status(http.StatusAccepted).JSON(DtoExportingVideo{VideoId: videoId.String()})
+	})
+}
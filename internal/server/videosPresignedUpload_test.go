@@ -0,0 +1,174 @@
+package server_test
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/server"
+	"dewarrum/vocabulary-leveling/internal/videos"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newCompleteApp(t *testing.T) (*testServer, *fiber.App, uuid.UUID) {
+	srv := newTestServer(t)
+	app := fiber.New(fiber.Config{ErrorHandler: srv.ErrorHandler})
+	srv.VideosComplete(app)
+
+	videoId := uuid.New()
+	err := srv.Videos.FileStorage.Upload(videoId, strings.NewReader("video"), "video/mp4", context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return srv, app, videoId
+}
+
+// expectCompletion expects a request completing the video, which finds it in
+// status and moves it to processing when it is still uploading.
+func expectCompletion(mock sqlmock.Sqlmock, videoId uuid.UUID, status string) {
+	mock.ExpectQuery("SELECT .* FROM videos WHERE id = \\$1").
+		WithArgs(videoId).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "status", "created_at"}).AddRow(videoId, "video", status, time.Now()))
+	mock.ExpectQuery("SELECT \\* FROM subtitle_tracks WHERE video_id = \\$1").
+		WithArgs(videoId).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "video_id", "source", "status", "created_at"}))
+
+	moved := int64(0)
+	if status == videos.VideoStatusUploading {
+		moved = 1
+	}
+	mock.ExpectExec("UPDATE videos SET status = \\$1 WHERE id = \\$2 AND status = \\$3").
+		WithArgs(videos.VideoStatusProcessing, videoId, videos.VideoStatusUploading).
+		WillReturnResult(sqlmock.NewResult(0, moved))
+}
+
+func completeVideo(t *testing.T, app *fiber.App, videoId uuid.UUID) *http.Response {
+	request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/videos/%s/complete", videoId), nil)
+	response, err := app.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return response
+}
+
+func TestVideosCompletePublishesTheExportOnce(t *testing.T) {
+	srv, app, videoId := newCompleteApp(t)
+
+	expectCompletion(srv.mock, videoId, videos.VideoStatusUploading)
+	response := completeVideo(t, app, videoId)
+	if response.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected status %d, but got %d", http.StatusAccepted, response.StatusCode)
+	}
+
+	expectCompletion(srv.mock, videoId, videos.VideoStatusProcessing)
+	response = completeVideo(t, app, videoId)
+	if response.StatusCode != http.StatusConflict {
+		t.Fatalf("Expected status %d, but got %d", http.StatusConflict, response.StatusCode)
+	}
+
+	var problem server.Problem
+	json.NewDecoder(response.Body).Decode(&problem)
+	if problem.Code != server.CodeVideoAlreadyCompleted {
+		t.Errorf("Expected code %s, but got %s", server.CodeVideoAlreadyCompleted, problem.Code)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	messages, err := srv.Videos.Messages.Consume(10, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var exported []uuid.UUID
+	for message := range messages {
+		exported = append(exported, message.VideoId)
+		message.Ack()
+	}
+	if len(exported) != 1 || exported[0] != videoId {
+		t.Errorf("Expected one export of %s, but got %v", videoId, exported)
+	}
+}
+
+func TestVideosCompleteReopensTheVideoWhenTheExportIsNotPublished(t *testing.T) {
+	srv, app, videoId := newCompleteApp(t)
+	srv.bus.Close()
+
+	expectCompletion(srv.mock, videoId, videos.VideoStatusUploading)
+	srv.mock.ExpectExec("UPDATE videos SET status = \\$1 WHERE id = \\$2").
+		WithArgs(videos.VideoStatusUploading, videoId).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	response := completeVideo(t, app, videoId)
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, but got %d", http.StatusInternalServerError, response.StatusCode)
+	}
+}
+
+func TestVideosCompleteRequiresTheUploadedFiles(t *testing.T) {
+	srv, app, _ := newCompleteApp(t)
+
+	videoId := uuid.New()
+	srv.mock.ExpectQuery("SELECT .* FROM videos WHERE id = \\$1").
+		WithArgs(videoId).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "status", "created_at"}).AddRow(videoId, "video", videos.VideoStatusUploading, time.Now()))
+
+	response := completeVideo(t, app, videoId)
+	if response.StatusCode != http.StatusConflict {
+		t.Errorf("Expected status %d, but got %d", http.StatusConflict, response.StatusCode)
+	}
+}
+
+func TestVideosCompleteContinuesTheTraceOfTheRequest(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	srv, _, videoId := newCompleteApp(t)
+
+	// The tracing middleware keeps the span of the request in the user
+	// context, the fasthttp context does not carry it.
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01},
+		SpanID:     trace.SpanID{0x02},
+		TraceFlags: trace.FlagsSampled,
+	})
+	app := fiber.New(fiber.Config{ErrorHandler: srv.ErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.SetUserContext(trace.ContextWithSpanContext(c.UserContext(), spanContext))
+		return c.Next()
+	})
+	srv.VideosComplete(app)
+
+	expectCompletion(srv.mock, videoId, videos.VideoStatusUploading)
+	response := completeVideo(t, app, videoId)
+	if response.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected status %d, but got %d", http.StatusAccepted, response.StatusCode)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	messages, err := srv.Videos.Messages.Consume(10, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message, ok := <-messages
+	if !ok {
+		t.Fatal("Expected the export to be published")
+	}
+	message.Ack()
+
+	received := trace.SpanContextFromContext(message.Context(context.Background()))
+	if received.TraceID() != spanContext.TraceID() {
+		t.Errorf("Expected trace id to be %s, but got %s", spanContext.TraceID(), received.TraceID())
+	}
+}
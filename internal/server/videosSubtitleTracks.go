@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type DtoSubtitleTrack struct {
+	Id          string    `json:"id"`
+	Source      string    `json:"source"`
+	Status      string    `json:"status"`
+	Language    *string   `json:"language"`
+	StreamIndex *int      `json:"streamIndex"`
+	Codec       *string   `json:"codec"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func (s *Server) VideosSubtitleTracks(router fiber.Router) {
+	router.Get("/videos/:videoId/subtitle-tracks", func(c *fiber.Ctx) error {
+		videoId, err := uuid.Parse(c.Params("videoId"))
+		if err != nil {
+			return badRequest(CodeInvalidParameter, "videoId must be a valid uuid")
+		}
+
+		dbTracks, err := s.Subtitles.Tracks.GetByVideoId(videoId, c.Context())
+		if err != nil {
+			return internalError(err)
+		}
+
+		dtoTracks := make([]*DtoSubtitleTrack, len(dbTracks))
+		for i, track := range dbTracks {
+			dtoTracks[i] = &DtoSubtitleTrack{
+				Id:          track.Id.String(),
+				Source:      track.Source,
+				Status:      track.Status,
+				Language:    track.Language,
+				StreamIndex: track.StreamIndex,
+				Codec:       track.Codec,
+				CreatedAt:   track.CreatedAt,
+			}
+		}
+
+		return c.Status(http.StatusOK).JSON(dtoTracks)
+	})
+}
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"dewarrum/vocabulary-leveling/internal/uploads"
+	"dewarrum/vocabulary-leveling/internal/videos"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,termination"
+)
+
+// VideosTusUpload implements the core tus 1.0.0 protocol with the creation and
+// termination extensions. Finished uploads are exported like VideosUpload
+// ones, with subtitles extracted from the video itself.
+func (s *Server) VideosTusUpload(router fiber.Router) {
+	uploadsRouter := router.Group("/videos/uploads", func(c *fiber.Ctx) error {
+		c.Set("Tus-Resumable", tusVersion)
+		if c.Method() == fiber.MethodOptions {
+			return c.Next()
+		}
+
+		if c.Get("Tus-Resumable") != tusVersion {
+			c.Set("Tus-Version", tusVersion)
+			return newProblem(http.StatusPreconditionFailed, CodeUnsupportedTusVersion, "only tus "+tusVersion+" is supported")
+		}
+
+		return c.Next()
+	})
+
+	uploadsRouter.Options("/", func(c *fiber.Ctx) error {
+		c.Set("Tus-Version", tusVersion)
+		c.Set("Tus-Extension", tusExtensions)
+		c.Set("Tus-Max-Size", strconv.FormatInt(uploads.MaxLength, 10))
+		return c.SendStatus(http.StatusNoContent)
+	})
+
+	uploadsRouter.Post("/", func(c *fiber.Ctx) error {
+		length, err := strconv.ParseInt(c.Get("Upload-Length"), 10, 64)
+		if err != nil {
+			return badRequest(CodeInvalidHeader, "Upload-Length is required").withCause(err)
+		}
+		if length < 0 {
+			return badRequest(CodeInvalidHeader, "Upload-Length must not be negative")
+		}
+
+		metadata, err := uploads.ParseMetadata(c.Get("Upload-Metadata"))
+		if err != nil {
+			return badRequest(CodeInvalidHeader, uploads.ErrInvalidMetadata.Error()).withCause(err)
+		}
+
+		videoName := metadata["videoName"]
+		if videoName == "" {
+			videoName = metadata["filename"]
+		}
+		if videoName == "" {
+			return badRequest(CodeInvalidHeader, "videoName or filename metadata is required")
+		}
+
+		upload, err := s.Uploads.Create(videoName, metadata["filetype"], length, s.exportTusUpload, c.UserContext())
+		if errors.Is(err, uploads.ErrInvalidLength) {
+			return newProblem(http.StatusRequestEntityTooLarge, CodeUploadTooLarge, "Upload-Length exceeds Tus-Max-Size").withCause(err)
+		}
+		if err != nil {
+			return internalError(err)
+		}
+
+		c.Set("Location", fmt.Sprintf("%s/%s", strings.TrimSuffix(c.Path(), "/"), upload.Id))
+		return c.SendStatus(http.StatusCreated)
+	})
+
+	uploadsRouter.Head("/:uploadId", func(c *fiber.Ctx) error {
+		upload, err := s.getTusUpload(c)
+		if err != nil {
+			return err
+		}
+
+		upload, err = s.Uploads.Resume(upload, s.exportTusUpload, c.UserContext())
+		if errors.Is(err, uploads.ErrUploadLocked) {
+			return newProblem(http.StatusLocked, CodeUploadLocked, uploads.ErrUploadLocked.Error()).withCause(err)
+		}
+		if err != nil {
+			return internalError(err)
+		}
+
+		c.Set("Cache-Control", "no-store")
+		c.Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		c.Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+		return c.SendStatus(http.StatusOK)
+	})
+
+	uploadsRouter.Patch("/:uploadId", func(c *fiber.Ctx) error {
+		if c.Get("Content-Type") != "application/offset+octet-stream" {
+			return newProblem(http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, "Content-Type must be application/offset+octet-stream")
+		}
+
+		offset, err := strconv.ParseInt(c.Get("Upload-Offset"), 10, 64)
+		if err != nil {
+			return badRequest(CodeInvalidHeader, "Upload-Offset is required").withCause(err)
+		}
+
+		upload, err := s.getTusUpload(c)
+		if err != nil {
+			return err
+		}
+
+		var body io.Reader = c.Context().RequestBodyStream()
+		if body == nil {
+			body = bytes.NewReader(c.Body())
+		}
+
+		upload, err = s.Uploads.Append(upload, offset, body, s.exportTusUpload, c.UserContext())
+		if errors.Is(err, uploads.ErrUploadLocked) {
+			return newProblem(http.StatusLocked, CodeUploadLocked, uploads.ErrUploadLocked.Error()).withCause(err)
+		}
+		if errors.Is(err, uploads.ErrOffsetMismatch) {
+			return conflict(CodeUploadOffsetMismatch, uploads.ErrOffsetMismatch.Error()).withCause(err)
+		}
+		if errors.Is(err, uploads.ErrUploadCompleted) {
+			return conflict(CodeUploadCompleted, uploads.ErrUploadCompleted.Error()).withCause(err)
+		}
+		if err != nil {
+			return internalError(err)
+		}
+
+		c.Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		return c.SendStatus(http.StatusNoContent)
+	})
+
+	uploadsRouter.Delete("/:uploadId", func(c *fiber.Ctx) error {
+		upload, err := s.getTusUpload(c)
+		if err != nil {
+			return err
+		}
+
+		err = s.Uploads.Terminate(upload, c.UserContext())
+		if errors.Is(err, uploads.ErrUploadLocked) {
+			return newProblem(http.StatusLocked, CodeUploadLocked, uploads.ErrUploadLocked.Error()).withCause(err)
+		}
+		if errors.Is(err, uploads.ErrUploadCompleted) {
+			return conflict(CodeUploadCompleted, uploads.ErrUploadCompleted.Error()).withCause(err)
+		}
+		if err != nil {
+			return internalError(err)
+		}
+
+		return c.SendStatus(http.StatusNoContent)
+	})
+}
+
+// getTusUpload returns the upload of the route. Ids that are not uuids are
+// reported as unknown uploads, as the tus clients expect a 404 for them.
+func (s *Server) getTusUpload(c *fiber.Ctx) (*uploads.DbUpload, error) {
+	uploadId, err := uuid.Parse(c.Params("uploadId"))
+	if err != nil {
+		return nil, notFound(CodeUploadNotFound, "upload not found").withCause(err)
+	}
+
+	upload, err := s.Uploads.Get(uploadId, c.Context())
+	if err != nil {
+		return nil, notFoundOr(err, CodeUploadNotFound, "upload not found")
+	}
+
+	return upload, nil
+}
+
+// exportTusUpload inserts the video of a received upload and queues its export.
+// A video inserted by an earlier attempt is queued again.
+func (s *Server) exportTusUpload(upload *uploads.DbUpload, ctx context.Context) error {
+	_, err := s.Videos.Repository.GetById(upload.VideoId, ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = s.Videos.Repository.Insert(videos.NewDbVideoWithId(upload.VideoId, upload.VideoName), ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.Videos.Messages.Send(videos.NewExportVideoMessage(upload.VideoId, true), ctx)
+}
@@ -1,79 +1,131 @@
 package server
 
 import (
+	"database/sql"
 	"dewarrum/vocabulary-leveling/internal/subtitles"
 	"dewarrum/vocabulary-leveling/internal/videos"
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
 )
 
+// DtoExportingVideo answers an upload, the video is exported in the background.
+type DtoExportingVideo struct {
+	VideoId string `json:"videoId" format:"uuid"`
+}
+
+// DtoDuplicateVideo lists the members a duplicate_video problem adds.
+type DtoDuplicateVideo struct {
+	DuplicateOf string `json:"duplicateOf" format:"uuid"`
+	Location    string `json:"location"`
+}
+
 func (s *Server) VideosUpload(router fiber.Router) {
 	router.Post("/videos/upload", func(c *fiber.Ctx) error {
 		videoHeader, err := c.FormFile("video")
 		if err != nil {
-			c.Status(http.StatusBadRequest).JSON(map[string]string{"error": err.Error()})
-			return nil
+			return badRequest(CodeInvalidBody, "video is required").withCause(err)
 		}
 
 		videoFile, err := videoHeader.Open()
 		if err != nil {
-			c.Status(http.StatusBadRequest).JSON(map[string]string{"error": err.Error()})
-			return nil
+			return internalError(err)
 		}
 		defer videoFile.Close()
 
 		videoName := c.FormValue("videoName")
 		if videoName == "" {
-			return c.Status(http.StatusBadRequest).JSON(map[string]string{"error": "name is required"})
+			return badRequest(CodeInvalidBody, "name is required")
 		}
 
+		// Without a sidecar file the embedded subtitle streams are the only
+		// source of subtitles, so extraction is implied.
 		subtitlesHeader, err := c.FormFile("subtitles")
-		if err != nil {
-			c.Status(http.StatusBadRequest).JSON(map[string]string{"error": err.Error()})
-			return nil
+		if errors.Is(err, fasthttp.ErrMissingFile) {
+			subtitlesHeader = nil
+		} else if err != nil {
+			return badRequest(CodeInvalidBody, "subtitles could not be read").withCause(err)
 		}
+		extractSubtitles := subtitlesHeader == nil || c.FormValue("extractSubtitles") == "true"
 
-		subtitlesFile, err := subtitlesHeader.Open()
+		// The content is hashed while it is streamed to storage, so the video
+		// is read only once. A copy of an existing video is removed again.
+		video := videos.NewDbVideo(videoName)
+		contentHash := videos.NewContentHash(videoFile)
+		err = s.Videos.FileStorage.Upload(video.Id, contentHash, videoHeader.Header.Get("Content-Type"), c.Context())
 		if err != nil {
-			c.Status(http.StatusBadRequest).JSON(map[string]string{"error": err.Error()})
-			return nil
+			return internalError(err)
 		}
-		defer subtitlesFile.Close()
 
-		video := videos.NewDbVideo(videoName)
+		contentSha256 := contentHash.Sum()
+		original, err := s.Videos.Repository.GetOriginalByContentSha256(contentSha256, video.Id, video.CreatedAt, c.Context())
+		if err == nil {
+			err = s.Videos.FileStorage.Delete(video.Id, c.Context())
+			if err != nil {
+				s.Logger.Warn().Err(err).Str("videoId", video.Id.String()).Msg("Failed to delete duplicate upload")
+			}
+			return s.duplicateVideo(c, original)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return internalError(err)
+		}
+
+		video.ContentSha256 = &contentSha256
 		_, err = s.Videos.Repository.Insert(video, c.Context())
 		if err != nil {
-			c.Status(http.StatusInternalServerError).JSON(map[string]string{"error": err.Error()})
-			return nil
+			return internalError(err)
 		}
 
-		err = s.Videos.FileStorage.Upload(video.Id, videoFile, videoHeader.Header.Get("Content-Type"), c.Context())
+		exportVideoMessage := videos.NewExportVideoMessage(video.Id, extractSubtitles)
+		err = s.Videos.Messages.Send(exportVideoMessage, c.UserContext())
 		if err != nil {
-			c.Status(http.StatusInternalServerError).JSON(map[string]string{"error": err.Error()})
-			return nil
+			return internalError(err)
 		}
 
-		exportVideoMessage := videos.NewExportVideoMessage(video.Id)
-		err = s.Videos.Messages.Send(exportVideoMessage, c.Context())
+		if subtitlesHeader == nil {
+			return c.Status(http.StatusOK).JSON(DtoExportingVideo{VideoId: video.Id.String()})
+		}
+
+		subtitlesFile, err := subtitlesHeader.Open()
 		if err != nil {
-			c.Status(http.StatusInternalServerError).JSON(map[string]string{"error": err.Error()})
-			return nil
+			return internalError(err)
+		}
+		defer subtitlesFile.Close()
+
+		var subtitlesLanguage *string
+		if language := c.FormValue("subtitlesLanguage"); language != "" {
+			subtitlesLanguage = &language
 		}
 
-		err = s.Subtitles.FileStorage.Upload(video.Id, subtitlesFile, subtitlesHeader.Header.Get("Content-Type"), c.Context())
+		track := subtitles.NewUploadedDbSubtitleTrack(video.Id, subtitlesLanguage)
+		_, err = s.Subtitles.Tracks.Insert(track, c.Context())
 		if err != nil {
-			c.Status(http.StatusInternalServerError).JSON(map[string]string{"error": err.Error()})
-			return nil
+			return internalError(err)
 		}
 
-		exportSubtitlesMessage := subtitles.NewExportSubtitlesMessage(video.Id)
-		err = s.Subtitles.Messages.Send(exportSubtitlesMessage, c.Context())
+		err = s.Subtitles.FileStorage.Upload(video.Id, track.Id, subtitlesFile, subtitlesHeader.Header.Get("Content-Type"), c.Context())
 		if err != nil {
-			c.Status(http.StatusInternalServerError).JSON(map[string]string{"error": err.Error()})
-			return nil
+			return internalError(err)
 		}
 
-		return c.Status(http.StatusOK).JSON(map[string]string{"videoId": video.Id.String()})
+		exportSubtitlesMessage := subtitles.NewExportSubtitlesMessage(video.Id, track.Id)
+		err = s.Subtitles.Messages.Send(exportSubtitlesMessage, c.UserContext())
+		if err != nil {
+			return internalError(err)
+		}
+
+		return c.Status(http.StatusOK).JSON(DtoExportingVideo{VideoId: video.Id.String()})
 	})
 }
+
+// duplicateVideo rejects an upload whose content matches an existing video
+// and points the client at the original.
+func (s *Server) duplicateVideo(c *fiber.Ctx, original *videos.DbVideo) error {
+	location := fmt.Sprintf("/api/videos/%s", original.Id)
+	c.Set(fiber.HeaderLocation, location)
+	return conflict(CodeDuplicateVideo, "video has already been uploaded").
+		withMembers(DtoDuplicateVideo{DuplicateOf: original.Id.String(), Location: location})
+}
@@ -0,0 +1,110 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"dewarrum/vocabulary-leveling/internal/server"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// helloSha256 is the SHA-256 of the uploaded video content.
+const helloSha256 = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+func uploadVideo(t *testing.T, app *fiber.App, content string) *http.Response {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("videoName", "video")
+	form.WriteField("extractSubtitles", "true")
+	part, err := form.CreateFormFile("video", "video.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(content))
+	form.Close()
+
+	request := httptest.NewRequest(http.MethodPost, "/videos/upload", &body)
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	response, err := app.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return response
+}
+
+func newUploadApp(t *testing.T) (*testServer, *fiber.App) {
+	srv := newTestServer(t)
+	app := fiber.New(fiber.Config{ErrorHandler: srv.ErrorHandler})
+	srv.VideosUpload(app)
+
+	return srv, app
+}
+
+func TestVideosUploadStoresTheHashOfTheUploadedContent(t *testing.T) {
+	srv, app := newUploadApp(t)
+
+	srv.mock.ExpectQuery("SELECT .* FROM videos WHERE content_sha256 = \\$1").
+		WithArgs(helloSha256, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	srv.mock.ExpectExec("INSERT INTO videos").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	response := uploadVideo(t, app, "hello")
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	var uploaded map[string]string
+	json.NewDecoder(response.Body).Decode(&uploaded)
+	videoId, err := uuid.Parse(uploaded["videoId"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exists, err := srv.Videos.FileStorage.Exists(videoId, context.Background())
+	if err != nil || !exists {
+		t.Errorf("Expected the video to be stored, but exists is %t (%v)", exists, err)
+	}
+}
+
+func TestVideosUploadRemovesDuplicates(t *testing.T) {
+	srv, app := newUploadApp(t)
+
+	originalId := uuid.New()
+	srv.mock.ExpectQuery("SELECT .* FROM videos WHERE content_sha256 = \\$1").
+		WithArgs(helloSha256, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "status", "created_at"}).AddRow(originalId, "video", "ready", time.Now()))
+
+	response := uploadVideo(t, app, "hello")
+	if response.StatusCode != http.StatusConflict {
+		t.Fatalf("Expected status %d, but got %d", http.StatusConflict, response.StatusCode)
+	}
+
+	var problem struct {
+		server.Problem
+		server.DtoDuplicateVideo
+	}
+	json.NewDecoder(response.Body).Decode(&problem)
+	if problem.Code != server.CodeDuplicateVideo {
+		t.Errorf("Expected code %s, but got %s", server.CodeDuplicateVideo, problem.Code)
+	}
+	if problem.DuplicateOf != originalId.String() || problem.Location != "/api/videos/"+originalId.String() {
+		t.Errorf("Expected the problem to point at %s, but got %+v", originalId, problem.DtoDuplicateVideo)
+	}
+
+	stored, err := srv.store.List("", context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 0 {
+		t.Errorf("Expected the duplicate to be removed, but got %v", stored)
+	}
+}
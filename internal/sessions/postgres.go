@@ -0,0 +1,132 @@
+package sessions
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+)
+
+const (
+	DefaultSweepInterval = 10 * time.Minute
+)
+
+var (
+	ErrFailedToGetSession    = errors.New("failed to get session")
+	ErrFailedToSaveSession   = errors.New("failed to save session")
+	ErrFailedToDeleteSession = errors.New("failed to delete session")
+)
+
+// PostgresStorage keeps Fiber sessions in the sessions table. Expired rows are
+// never returned and are removed by a background sweep.
+type PostgresStorage struct {
+	db     *sqlx.DB
+	logger zerolog.Logger
+	done   chan struct{}
+	once   sync.Once
+}
+
+func NewPostgresStorage(db *sqlx.DB, sweepInterval time.Duration, logger zerolog.Logger) *PostgresStorage {
+	storage := &PostgresStorage{
+		db:     db,
+		logger: logger,
+		done:   make(chan struct{}),
+	}
+
+	go storage.sweep(sweepInterval)
+
+	return storage
+}
+
+func (s *PostgresStorage) Get(key string) ([]byte, error) {
+	if key == "" {
+		return nil, nil
+	}
+
+	var value []byte
+	err := s.db.Get(&value, "SELECT value FROM sessions WHERE key = $1 AND (expires_at IS NULL OR expires_at > NOW())", key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToGetSession)
+	}
+
+	return value, nil
+}
+
+func (s *PostgresStorage) Set(key string, value []byte, expiration time.Duration) error {
+	if key == "" || len(value) == 0 {
+		return nil
+	}
+
+	var expiresAt *time.Time
+	if expiration > 0 {
+		at := time.Now().Add(expiration).In(time.UTC)
+		expiresAt = &at
+	}
+
+	_, err := s.db.Exec("INSERT INTO sessions (key, value, expires_at) VALUES ($1, $2, $3) ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, expires_at = EXCLUDED.expires_at", key, value, expiresAt)
+	if err != nil {
+		return errors.Join(err, ErrFailedToSaveSession)
+	}
+
+	return nil
+}
+
+func (s *PostgresStorage) Delete(key string) error {
+	if key == "" {
+		return nil
+	}
+
+	_, err := s.db.Exec("DELETE FROM sessions WHERE key = $1", key)
+	if err != nil {
+		return errors.Join(err, ErrFailedToDeleteSession)
+	}
+
+	return nil
+}
+
+func (s *PostgresStorage) Reset() error {
+	_, err := s.db.Exec("DELETE FROM sessions")
+	if err != nil {
+		return errors.Join(err, ErrFailedToDeleteSession)
+	}
+
+	return nil
+}
+
+// Close stops the sweep. The database connection is shared and stays open.
+func (s *PostgresStorage) Close() error {
+	s.once.Do(func() {
+		close(s.done)
+	})
+
+	return nil
+}
+
+func (s *PostgresStorage) sweep(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			result, err := s.db.Exec("DELETE FROM sessions WHERE expires_at <= NOW()")
+			if err != nil {
+				s.logger.Error().Err(err).Msg("Failed to sweep expired sessions")
+				continue
+			}
+
+			swept, err := result.RowsAffected()
+			if err == nil && swept > 0 {
+				s.logger.Debug().Int64("sessions", swept).Msg("Swept expired sessions")
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
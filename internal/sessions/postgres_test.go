@@ -0,0 +1,143 @@
+package sessions_test
+
+import (
+	"dewarrum/vocabulary-leveling/internal/sessions"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/rs/zerolog"
+)
+
+// newTestStorage connects to TEST_POSTGRES_URL and migrates it, or skips the
+// test when it is not set.
+func newTestStorage(t *testing.T, sweepInterval time.Duration) (*sessions.PostgresStorage, *sqlx.DB) {
+	url := os.Getenv("TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("TEST_POSTGRES_URL is not set")
+	}
+
+	db, err := sqlx.Connect("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	driver, err := postgres.WithInstance(db.DB, &postgres.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := migrate.NewWithDatabaseInstance("file://../../db/migrations", "vocabulary-leveling", driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Up()
+	if err != nil && err != migrate.ErrNoChange {
+		t.Fatal(err)
+	}
+
+	storage := sessions.NewPostgresStorage(db, sweepInterval, zerolog.Nop())
+	t.Cleanup(func() { storage.Close() })
+
+	return storage, db
+}
+
+func get(t *testing.T, storage *sessions.PostgresStorage, key string) string {
+	t.Helper()
+
+	value, err := storage.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(value)
+}
+
+func TestPostgresStorageKeepsSessionsUntilDeleted(t *testing.T) {
+	storage, _ := newTestStorage(t, time.Hour)
+	key := uuid.NewString()
+
+	err := storage.Set(key, []byte("first"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = storage.Set(key, []byte("second"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value := get(t, storage, key); value != "second" {
+		t.Errorf("Expected the session to be %q, but got %q", "second", value)
+	}
+
+	err = storage.Delete(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value := get(t, storage, key); value != "" {
+		t.Errorf("Expected the session to be deleted, but got %q", value)
+	}
+}
+
+func TestPostgresStorageHidesAndSweepsExpiredSessions(t *testing.T) {
+	storage, db := newTestStorage(t, 50*time.Millisecond)
+	key := uuid.NewString()
+
+	err := storage.Set(key, []byte("session"), time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	if value := get(t, storage, key); value != "" {
+		t.Errorf("Expected the expired session to be hidden, but got %q", value)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		var stored int
+		err = db.Get(&stored, "SELECT COUNT(*) FROM sessions WHERE key = $1", key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the expired session to be swept")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPostgresStorageIgnoresEmptyKeysAndValues(t *testing.T) {
+	storage, db := newTestStorage(t, time.Hour)
+	key := uuid.NewString()
+
+	err := storage.Set(key, nil, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stored int
+	err = db.Get(&stored, "SELECT COUNT(*) FROM sessions WHERE key = $1", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != 0 {
+		t.Errorf("Expected an empty session not to be stored, but got %d rows", stored)
+	}
+
+	if value := get(t, storage, ""); value != "" {
+		t.Errorf("Expected no session for an empty key, but got %q", value)
+	}
+}
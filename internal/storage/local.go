@@ -0,0 +1,290 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// LocalStore keeps objects on disk below root. Content types live next to the
+// objects in a parallel tree, so listing objects never sees them. Presigned
+// URLs point at baseUrl and carry an HMAC that the serving handler checks with
+// Verify.
+type LocalStore struct {
+	root    string
+	baseUrl string
+	secret  []byte
+}
+
+func NewLocalStore(root string, baseUrl string, secret []byte) *LocalStore {
+	return &LocalStore{
+		root:    root,
+		baseUrl: strings.TrimRight(baseUrl, "/"),
+		secret:  secret,
+	}
+}
+
+// Path returns the file an object is stored in. Keys that would escape the
+// root are rejected.
+func (s *LocalStore) Path(key string) (string, error) {
+	return s.path("objects", key)
+}
+
+func (s *LocalStore) path(tree string, key string) (string, error) {
+	cleanKey := path.Clean("/" + key)
+	if key == "" || cleanKey == "/" || cleanKey != "/"+key {
+		return "", ErrInvalidKey
+	}
+
+	return filepath.Join(s.root, tree, filepath.FromSlash(cleanKey)), nil
+}
+
+func (s *LocalStore) Put(key string, body io.Reader, contentType string, ctx context.Context) error {
+	objectPath, err := s.Path(key)
+	if err != nil {
+		return err
+	}
+
+	typePath, err := s.path("types", key)
+	if err != nil {
+		return err
+	}
+
+	err = writeFile(objectPath, body)
+	if err != nil {
+		return errors.Join(err, ErrFailedToPut)
+	}
+
+	err = writeFile(typePath, strings.NewReader(contentType))
+	if err != nil {
+		return errors.Join(err, ErrFailedToPut)
+	}
+
+	return nil
+}
+
+// writeFile writes to a temporary file first so that readers never observe a
+// partially written object.
+func writeFile(filePath string, body io.Reader) error {
+	err := os.MkdirAll(filepath.Dir(filePath), 0755)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = io.Copy(file, body)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), filePath)
+}
+
+func (s *LocalStore) Get(key string, ctx context.Context) (*Object, error) {
+	objectPath, err := s.Path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(objectPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToGet)
+	}
+
+	return &Object{
+		Body:        file,
+		ContentType: s.ContentType(key),
+	}, nil
+}
+
+// ContentType returns the type the object was stored with.
+func (s *LocalStore) ContentType(key string) string {
+	typePath, err := s.path("types", key)
+	if err != nil {
+		return ""
+	}
+
+	contentType, err := os.ReadFile(typePath)
+	if err != nil {
+		return ""
+	}
+
+	return string(contentType)
+}
+
+func (s *LocalStore) Exists(key string, ctx context.Context) (bool, error) {
+	objectPath, err := s.Path(key)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = os.Stat(objectPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Join(err, ErrFailedToGet)
+	}
+
+	return true, nil
+}
+
+// Ping checks that the root is a directory, creating it like Put would.
+func (s *LocalStore) Ping(ctx context.Context) error {
+	err := os.MkdirAll(s.root, 0755)
+	if err != nil {
+		return errors.Join(err, ErrUnavailable)
+	}
+
+	return nil
+}
+
+func (s *LocalStore) List(prefix string, ctx context.Context) ([]string, error) {
+	objectsRoot := filepath.Join(s.root, "objects")
+
+	var keys []string
+	err := filepath.WalkDir(objectsRoot, func(filePath string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".upload-") {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(objectsRoot, filePath)
+		if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(relativePath)
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToList)
+	}
+
+	return keys, nil
+}
+
+func (s *LocalStore) Delete(key string, ctx context.Context) error {
+	objectPath, err := s.Path(key)
+	if err != nil {
+		return err
+	}
+
+	typePath, err := s.path("types", key)
+	if err != nil {
+		return err
+	}
+
+	for _, filePath := range []string{objectPath, typePath} {
+		err = os.Remove(filePath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return errors.Join(err, ErrFailedToDelete)
+		}
+	}
+
+	return nil
+}
+
+func (s *LocalStore) PresignGet(key string, options PresignOptions, ctx context.Context) (string, error) {
+	return s.presign(http.MethodGet, key, options)
+}
+
+func (s *LocalStore) PresignPut(key string, options PresignOptions, ctx context.Context) (string, error) {
+	return s.presign(http.MethodPut, key, options)
+}
+
+func (s *LocalStore) presign(method string, key string, options PresignOptions) (string, error) {
+	_, err := s.Path(key)
+	if err != nil {
+		return "", errors.Join(err, ErrFailedToPresign)
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(options.expiration()).Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	if options.Filename != "" {
+		query.Set("filename", options.Filename)
+	}
+	if options.ContentType != "" {
+		query.Set("contentType", options.ContentType)
+	}
+	query.Set("signature", s.sign(method, key, query))
+
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return s.baseUrl + "/" + strings.Join(segments, "/") + "?" + query.Encode(), nil
+}
+
+func (s *LocalStore) sign(method string, key string, query url.Values) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(strings.Join([]string{
+		method,
+		key,
+		query.Get("expires"),
+		query.Get("filename"),
+		query.Get("contentType"),
+	}, "\n")))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks that a request for key carries a valid, unexpired signature
+// for method and returns the options the URL was presigned with.
+func (s *LocalStore) Verify(method string, key string, query url.Values) (PresignOptions, error) {
+	expected := s.sign(method, key, query)
+	if !hmac.Equal([]byte(expected), []byte(query.Get("signature"))) {
+		return PresignOptions{}, ErrInvalidSignature
+	}
+
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return PresignOptions{}, ErrInvalidSignature
+	}
+
+	return PresignOptions{
+		Filename:    query.Get("filename"),
+		ContentType: query.Get("contentType"),
+	}, nil
+}
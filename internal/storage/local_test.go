@@ -0,0 +1,128 @@
+package storage_test
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/storage"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newLocalStore(t *testing.T) *storage.LocalStore {
+	return storage.NewLocalStore(t.TempDir(), "http://localhost/storage", []byte("secret"))
+}
+
+func TestLocalStorePutAndGet(t *testing.T) {
+	store := newLocalStore(t)
+	ctx := context.Background()
+
+	err := store.Put("video/chunks/0/stream-00001.m4s", strings.NewReader("chunk"), "video/iso.segment", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	object, err := store.Get("video/chunks/0/stream-00001.m4s", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer object.Body.Close()
+
+	body, err := io.ReadAll(object.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(body) != "chunk" {
+		t.Errorf("Expected body to be %s, but got %s", "chunk", body)
+	}
+
+	if object.ContentType != "video/iso.segment" {
+		t.Errorf("Expected content type to be %s, but got %s", "video/iso.segment", object.ContentType)
+	}
+}
+
+func TestLocalStoreListAndDelete(t *testing.T) {
+	store := newLocalStore(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"video/chunks/0/a", "video/chunks/1/b", "video/inits/0/c"} {
+		err := store.Put(key, strings.NewReader(key), "application/octet-stream", ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys, err := store.List("video/chunks", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keys) != 2 {
+		t.Errorf("Expected %d keys, but got %d", 2, len(keys))
+	}
+
+	err = store.Delete("video/chunks/0/a", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exists, err := store.Exists("video/chunks/0/a", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exists {
+		t.Error("Expected deleted object to be gone")
+	}
+
+	_, err = store.Get("video/chunks/0/a", ctx)
+	if err != storage.ErrNotFound {
+		t.Errorf("Expected %v, but got %v", storage.ErrNotFound, err)
+	}
+}
+
+func TestLocalStoreRejectsEscapingKeys(t *testing.T) {
+	store := newLocalStore(t)
+
+	for _, key := range []string{"../secret", "video/../../secret", "/absolute", ""} {
+		err := store.Put(key, strings.NewReader(""), "text/plain", context.Background())
+		if err == nil {
+			t.Errorf("Expected key %q to be rejected", key)
+		}
+	}
+}
+
+func TestLocalStoreVerifiesPresignedUrls(t *testing.T) {
+	store := newLocalStore(t)
+
+	presignedUrl, err := store.PresignGet("video/original", storage.PresignOptions{Filename: "clip.mp4"}, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsedUrl, err := url.Parse(presignedUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options, err := store.Verify(http.MethodGet, "video/original", parsedUrl.Query())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if options.Filename != "clip.mp4" {
+		t.Errorf("Expected filename to be %s, but got %s", "clip.mp4", options.Filename)
+	}
+
+	_, err = store.Verify(http.MethodPut, "video/original", parsedUrl.Query())
+	if err == nil {
+		t.Error("Expected signature for a different method to be rejected")
+	}
+
+	_, err = store.Verify(http.MethodGet, "video/other", parsedUrl.Query())
+	if err == nil {
+		t.Error("Expected signature for a different key to be rejected")
+	}
+}
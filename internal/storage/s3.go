@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// S3Store keeps objects in a single bucket, optionally below a key prefix so
+// that several environments can share one bucket.
+type S3Store struct {
+	client        *s3.Client
+	presignClient *s3.PresignClient
+	bucket        string
+	prefix        string
+}
+
+func NewS3Store(client *s3.Client, presignClient *s3.PresignClient, bucket string, prefix string) *S3Store {
+	return &S3Store{
+		client:        client,
+		presignClient: presignClient,
+		bucket:        bucket,
+		prefix:        strings.Trim(prefix, "/"),
+	}
+}
+
+// Client, Bucket and Key give multipart uploads, which ObjectStore does not
+// cover, access to the objects Put writes.
+func (s *S3Store) Client() *s3.Client {
+	return s.client
+}
+
+func (s *S3Store) Bucket() string {
+	return s.bucket
+}
+
+func (s *S3Store) Key(key string) string {
+	return s.key(key)
+}
+
+func (s *S3Store) key(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+
+	return path.Join(s.prefix, key)
+}
+
+func (s *S3Store) Put(key string, body io.Reader, contentType string, ctx context.Context) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(s.key(key)),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return errors.Join(err, ErrFailedToPut)
+	}
+
+	return nil
+}
+
+func (s *S3Store) Get(key string, ctx context.Context) (*Object, error) {
+	response, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(key)),
+	})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToGet)
+	}
+
+	return &Object{
+		Body:        response.Body,
+		ContentType: aws.StringValue(response.ContentType),
+	}, nil
+}
+
+func (s *S3Store) Exists(key string, ctx context.Context) (bool, error) {
+	_, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(key)),
+	})
+	if err == nil {
+		return true, nil
+	}
+
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return false, nil
+	}
+
+	return false, errors.Join(err, ErrFailedToGet)
+}
+
+// Ping checks that the bucket exists and the credentials may access it.
+func (s *S3Store) Ping(ctx context.Context) error {
+	_, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(s.bucket),
+	})
+	if err != nil {
+		return errors.Join(err, ErrUnavailable)
+	}
+
+	return nil
+}
+
+func (s *S3Store) List(prefix string, ctx context.Context) ([]string, error) {
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.key(prefix)),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, errors.Join(err, ErrFailedToList)
+		}
+
+		for _, object := range page.Contents {
+			key := aws.StringValue(object.Key)
+			if s.prefix != "" {
+				key = strings.TrimPrefix(key, s.prefix+"/")
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+func (s *S3Store) Delete(key string, ctx context.Context) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(key)),
+	})
+	if err != nil {
+		return errors.Join(err, ErrFailedToDelete)
+	}
+
+	return nil
+}
+
+func (s *S3Store) PresignGet(key string, options PresignOptions, ctx context.Context) (string, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(key)),
+	}
+	if options.Filename != "" {
+		input.ResponseContentDisposition = aws.String(fmt.Sprintf("attachment; filename=%q", options.Filename))
+	}
+
+	presignedUrl, err := s.presignClient.PresignGetObject(ctx, input, s3.WithPresignExpires(options.expiration()))
+	if err != nil {
+		return "", errors.Join(err, ErrFailedToPresign)
+	}
+
+	return presignedUrl.URL, nil
+}
+
+func (s *S3Store) PresignPut(key string, options PresignOptions, ctx context.Context) (string, error) {
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(key)),
+	}
+	if options.ContentType != "" {
+		input.ContentType = aws.String(options.ContentType)
+	}
+
+	presignedUrl, err := s.presignClient.PresignPutObject(ctx, input, s3.WithPresignExpires(options.expiration()))
+	if err != nil {
+		return "", errors.Join(err, ErrFailedToPresign)
+	}
+
+	return presignedUrl.URL, nil
+}
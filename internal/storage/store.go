@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+const (
+	DefaultPresignExpiration = 15 * time.Minute
+)
+
+var (
+	ErrNotFound         = errors.New("object not found")
+	ErrFailedToPut      = errors.New("failed to put object")
+	ErrFailedToGet      = errors.New("failed to get object")
+	ErrFailedToList     = errors.New("failed to list objects")
+	ErrFailedToDelete   = errors.New("failed to delete object")
+	ErrFailedToPresign  = errors.New("failed to presign object")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrUnavailable      = errors.New("object store is unavailable")
+)
+
+type Object struct {
+	Body        io.ReadCloser
+	ContentType string
+}
+
+type PresignOptions struct {
+	// Expiration defaults to DefaultPresignExpiration.
+	Expiration time.Duration
+	// Filename makes the object download as an attachment with that name.
+	Filename string
+	// ContentType is the type a presigned upload has to be sent with.
+	ContentType string
+}
+
+func (o PresignOptions) expiration() time.Duration {
+	if o.Expiration <= 0 {
+		return DefaultPresignExpiration
+	}
+
+	return o.Expiration
+}
+
+// ObjectStore is the blob storage every package keeps its files in. Keys are
+// slash separated paths; implementations map them onto their own namespace.
+type ObjectStore interface {
+	Put(key string, body io.Reader, contentType string, ctx context.Context) error
+	Get(key string, ctx context.Context) (*Object, error)
+	Exists(key string, ctx context.Context) (bool, error)
+	List(prefix string, ctx context.Context) ([]string, error)
+	Delete(key string, ctx context.Context) error
+	// PresignGet returns a URL that lets anyone holding it read the object
+	// until it expires.
+	PresignGet(key string, options PresignOptions, ctx context.Context) (string, error)
+	// PresignPut returns a URL that lets anyone holding it upload the object
+	// until it expires.
+	PresignPut(key string, options PresignOptions, ctx context.Context) (string, error)
+	// Ping reports whether the store can currently be reached.
+	Ping(ctx context.Context) error
+}
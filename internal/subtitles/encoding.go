@@ -0,0 +1,93 @@
+package subtitles
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/korean"
+	textunicode "golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// Encodings subtitle files are detected in, named as in the WHATWG Encoding
+// Standard. Its euc-kr is the CP949 superset Windows writes Korean files in.
+const (
+	EncodingUtf8        = "utf-8"
+	EncodingUtf16le     = "utf-16le"
+	EncodingUtf16be     = "utf-16be"
+	EncodingEucKr       = "euc-kr"
+	EncodingWindows1252 = "windows-1252"
+)
+
+var (
+	ErrFailedToDecode = errors.New("failed to decode subtitles")
+
+	charsetRegex = regexp.MustCompile(`(?i)charset\s*=\s*["']?([\w.:-]+)`)
+)
+
+// DecodeText transcodes a subtitle file to UTF-8 and returns the encoding it
+// was detected in. Byte order marks and valid UTF-8 are trusted first, then
+// the charset the file declares, as SAMI and HTML based files may. Files
+// without either are taken for CP949 when they decode to Korean text and for
+// Windows-1252 otherwise.
+func DecodeText(data []byte) ([]byte, string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return data[3:], EncodingUtf8, nil
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeWith(data, textunicode.UTF16(textunicode.LittleEndian, textunicode.ExpectBOM), EncodingUtf16le)
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeWith(data, textunicode.UTF16(textunicode.BigEndian, textunicode.ExpectBOM), EncodingUtf16be)
+	case utf8.Valid(data):
+		return data, EncodingUtf8, nil
+	}
+
+	if match := charsetRegex.FindSubmatch(data); match != nil {
+		declared, err := htmlindex.Get(string(match[1]))
+		if err == nil {
+			name, _ := htmlindex.Name(declared)
+			if name != EncodingUtf8 {
+				return decodeWith(data, declared, name)
+			}
+		}
+	}
+
+	decoded, _, err := transform.Bytes(korean.EUCKR.NewDecoder(), data)
+	if err == nil && looksKorean(decoded) {
+		return decoded, EncodingEucKr, nil
+	}
+
+	return decodeWith(data, charmap.Windows1252, EncodingWindows1252)
+}
+
+func decodeWith(data []byte, enc encoding.Encoding, name string) ([]byte, string, error) {
+	decoded, _, err := transform.Bytes(enc.NewDecoder(), data)
+	if err != nil {
+		return nil, "", errors.Join(err, ErrFailedToDecode)
+	}
+
+	return decoded, name, nil
+}
+
+// looksKorean tells whether text decoded as CP949 reads as Korean. Western
+// files decoded as CP949 either hit byte pairs it does not map, which become
+// replacement characters, or turn into scattered Hanja.
+func looksKorean(text []byte) bool {
+	hangul, replaced := 0, 0
+	for _, r := range string(text) {
+		switch {
+		case r == utf8.RuneError:
+			replaced++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		}
+	}
+
+	return hangul > 0 && replaced*20 <= hangul
+}
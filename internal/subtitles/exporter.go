@@ -3,15 +3,18 @@ package subtitles
 import (
 	"context"
 	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/utils"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/martinlindhe/subtitles"
 	"github.com/rs/zerolog"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/text/language"
 )
 
 var (
@@ -21,10 +24,16 @@ var (
 type Exporter struct {
 	MessageQueue        *MessageQueue
 	SubtitlesRepository *SubtitlesRepository
+	Tracks              *SubtitleTracksRepository
 	FileStorage         *FileStorage
-	FullTextSearch      *FullTextSearch
+	SearchIndex         SearchIndex
+	Normalizer          *Normalizer
 	Logger              zerolog.Logger
 	Tracer              trace.Tracer
+	ExportDuration      metric.Float64Histogram
+	FfmpegFailures      metric.Int64Counter
+
+	jobs *utils.Jobs
 }
 
 func NewExporter(dependencies *app.Dependencies, context context.Context) (*Exporter, error) {
@@ -32,7 +41,22 @@ func NewExporter(dependencies *app.Dependencies, context context.Context) (*Expo
 	if err != nil {
 		return nil, err
 	}
-	fullTextSearch, err := NewFullTextSearch(dependencies.ElasticsearchClient, context)
+	searchIndex, err := NewSearchIndex(dependencies, context)
+	if err != nil {
+		return nil, err
+	}
+	exportDuration, err := dependencies.Meter.Float64Histogram(
+		"subtitles.export.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of subtitle exports"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	ffmpegFailures, err := dependencies.Meter.Int64Counter(
+		"ffmpeg.failures",
+		metric.WithDescription("Number of ffmpeg runs that failed"),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -40,19 +64,25 @@ func NewExporter(dependencies *app.Dependencies, context context.Context) (*Expo
 	return &Exporter{
 		MessageQueue:        messageQueue,
 		SubtitlesRepository: NewSubtitlesRepository(dependencies),
-		FileStorage:         NewFileStorage(dependencies.S3Client, dependencies.S3PresignClient),
-		FullTextSearch:      fullTextSearch,
+		Tracks:              NewSubtitleTracksRepository(dependencies),
+		FileStorage:         NewFileStorage(dependencies.ObjectStore),
+		SearchIndex:         searchIndex,
+		Normalizer:          NewNormalizer(dependencies.Config.Normalization),
 		Logger:              dependencies.Logger,
 		Tracer:              dependencies.Tracer,
+		ExportDuration:      exportDuration,
+		FfmpegFailures:      ffmpegFailures,
 	}, nil
 }
 
-func (e *Exporter) Run(ctx context.Context) error {
-	e.Logger.Info().Msg("Starting subtitle exporter")
+// Run starts concurrency goroutines that export subtitles in parallel. At
+// most prefetch messages are taken off the queue before being acknowledged.
+func (e *Exporter) Run(concurrency int, prefetch int, ctx context.Context) error {
+	e.Logger.Info().Int("concurrency", concurrency).Int("prefetch", prefetch).Msg("Starting subtitle exporter")
 	ctx, span := e.Tracer.Start(ctx, "subtitles.exporter")
 	defer span.End()
 
-	messages, err := e.MessageQueue.Consume(ctx)
+	messages, err := e.MessageQueue.Consume(prefetch, ctx)
 	if err != nil {
 		e.Logger.Fatal().Err(err).Msg("Failed to register a consumer")
 		span.RecordError(err, trace.WithStackTrace(true))
@@ -60,45 +90,206 @@ func (e *Exporter) Run(ctx context.Context) error {
 		return errors.Join(err, ErrFailedToRunExporter)
 	}
 
-	go func() {
-		for message := range messages {
-			ctx, span := e.Tracer.Start(ctx, "subtitles.exporter.handleMessage", trace.WithAttributes(attribute.String("videoId", message.VideoId.String())))
-			err = e.handleMessage(message, ctx)
-			if err != nil {
-				e.Logger.Error().Str("videoId", message.VideoId.String()).Err(err).Msg("Failed to handle message")
-				span.RecordError(err, trace.WithStackTrace(true))
+	// Exports outlive ctx so that they can finish while shutting down,
+	// Shutdown aborts them once the grace period is over.
+	e.jobs = utils.NewJobs(ctx)
+	for range concurrency {
+		e.jobs.Go(func(ctx context.Context) {
+			for message := range messages {
+				e.processMessage(message, ctx)
 			}
-			span.End()
-		}
-	}()
+		})
+	}
 
 	return nil
 }
 
+// Shutdown waits for the exports in flight after the context passed to Run is
+// done. Exports still running when ctx is done are aborted and requeued.
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	err := e.jobs.Shutdown(ctx)
+	if err != nil {
+		e.Logger.Warn().Msg("Aborted subtitle exports that did not finish in time")
+	}
+
+	return err
+}
+
+func (e *Exporter) processMessage(message ExportSubtitlesMessage, ctx context.Context) {
+	ctx, span := e.Tracer.Start(
+		message.Context(ctx),
+		"subtitles.exporter.handleMessage",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attribute.String("videoId", message.VideoId.String())),
+	)
+	defer span.End()
+
+	start := time.Now()
+	err := e.handleMessage(message, ctx)
+	e.ExportDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attribute.Bool("error", err != nil)))
+	if err != nil {
+		e.Logger.Error().Str("videoId", message.VideoId.String()).Err(err).Msg("Failed to handle message")
+		span.RecordError(err, trace.WithStackTrace(true))
+		// An export aborted by Shutdown is picked up again after the restart.
+		aborted := ctx.Err() != nil
+		if !aborted {
+			e.updateTrackStatus(message, TrackStatusFailed, ctx)
+		}
+		err = message.Nack(aborted)
+	} else {
+		e.updateTrackStatus(message, TrackStatusReady, ctx)
+		err = message.Ack()
+	}
+	if err != nil {
+		e.Logger.Error().Str("videoId", message.VideoId.String()).Err(err).Msg("Failed to acknowledge message")
+	}
+}
+
+// updateTrackStatus records how the export of the track ended. Subtitles
+// uploaded before tracks existed have none.
+func (e *Exporter) updateTrackStatus(message ExportSubtitlesMessage, status string, ctx context.Context) {
+	if message.TrackId == uuid.Nil {
+		return
+	}
+
+	err := e.Tracks.UpdateStatus(message.TrackId, status, ctx)
+	if err != nil {
+		e.Logger.Error().Str("videoId", message.VideoId.String()).Str("trackId", message.TrackId.String()).Err(err).Msg("Failed to update subtitle track status")
+	}
+}
+
 func (e *Exporter) handleMessage(message ExportSubtitlesMessage, ctx context.Context) error {
-	subtitle, err := e.FileStorage.Download(message.VideoId.String(), ctx)
+	parsedTracks, err := e.FileStorage.Download(message.VideoId, message.TrackId, ctx)
+	if err != nil {
+		return err
+	}
+	for _, parsedTrack := range parsedTracks {
+		for _, skipped := range parsedTrack.Skipped {
+			e.Logger.Warn().Str("videoId", message.VideoId.String()).Str("trackId", message.TrackId.String()).Err(skipped).Msg("Skipped subtitle that could not be parsed")
+		}
+	}
+
+	parsedTrack, err := e.splitTracks(message, parsedTracks, ctx)
 	if err != nil {
 		return err
 	}
 
-	for _, caption := range subtitle.Captions {
-		dbSubtitle, err := e.saveToDatabase(message.VideoId, caption, ctx)
+	var dbSubtitles []*DbSubtitle
+	for _, caption := range parsedTrack.Captions {
+		dbSubtitle, err := e.saveToDatabase(message.VideoId, message.TrackId, caption, ctx)
 		if err != nil {
 			return err
 		}
 
-		err = e.saveToFullTextSearch(dbSubtitle.Id, dbSubtitle.VideoId, caption, ctx)
+		err = e.saveToFullTextSearch(dbSubtitle, ctx)
 		if err != nil {
 			return err
 		}
+
+		dbSubtitles = append(dbSubtitles, dbSubtitle)
 	}
 
-	return nil
+	return e.savePosterFrames(message.VideoId, dbSubtitles, ctx)
+}
+
+// splitTracks returns the track of a file with several languages, such as a
+// SAMI file with KRCC and ENCC classes, that matches the language of the
+// uploaded track, or the first one. The others are moved into tracks of their
+// own, exported like embedded subtitle streams are.
+func (e *Exporter) splitTracks(message ExportSubtitlesMessage, parsedTracks []*ParsedTrack, ctx context.Context) (*ParsedTrack, error) {
+	if len(parsedTracks) == 1 {
+		return parsedTracks[0], nil
+	}
+
+	kept := 0
+	if message.TrackId != uuid.Nil {
+		track, err := e.Tracks.GetById(message.TrackId, ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if track.Language == nil {
+			err = e.Tracks.UpdateLanguage(track.Id, parsedTracks[kept].Language, ctx)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			for i, parsedTrack := range parsedTracks {
+				if sameLanguage(parsedTrack.Language, *track.Language) {
+					kept = i
+					break
+				}
+			}
+		}
+	}
+
+	for i, parsedTrack := range parsedTracks {
+		if i == kept {
+			continue
+		}
+
+		err := e.exportSplitTrack(message, parsedTrack, ctx)
+		if err != nil {
+			return nil, errors.Join(err, fmt.Errorf("failed to split %s track", parsedTrack.Name))
+		}
+	}
+
+	return parsedTracks[kept], nil
+}
+
+// exportSplitTrack queues the export of a track split off the uploaded one.
+// A redelivered or retried export finds the tracks split before by their id
+// and only queues the ones that were not exported yet.
+func (e *Exporter) exportSplitTrack(message ExportSubtitlesMessage, parsedTrack *ParsedTrack, ctx context.Context) error {
+	uploadedId := message.TrackId
+	if uploadedId == uuid.Nil {
+		uploadedId = message.VideoId
+	}
+
+	track, err := e.Tracks.UpsertSplit(NewSplitDbSubtitleTrack(message.VideoId, uploadedId, parsedTrack.Name, parsedTrack.Language), ctx)
+	if err != nil {
+		return err
+	}
+	if track.Status != TrackStatusQueued {
+		e.Logger.Info().Str("videoId", message.VideoId.String()).Str("trackId", track.Id.String()).Str("class", parsedTrack.Name).Msg("Subtitle track was split before")
+		return nil
+	}
+
+	e.Logger.Info().Str("videoId", message.VideoId.String()).Str("trackId", track.Id.String()).Str("class", parsedTrack.Name).Msg("Splitting subtitle track")
+	err = e.FileStorage.Upload(message.VideoId, track.Id, strings.NewReader(parsedTrack.AsSRT()), "application/x-subrip", ctx)
+	if err != nil {
+		return err
+	}
+
+	return e.MessageQueue.Send(NewExportSubtitlesMessage(message.VideoId, track.Id), ctx)
 }
 
-func (e *Exporter) saveToDatabase(videoId uuid.UUID, caption subtitles.Caption, context context.Context) (*DbSubtitle, error) {
-	emptyDate := (time.Time{}).AddDate(-1, 0, 0)
-	subtitle := newDbSubtitle(videoId, strings.Join(caption.Text, "\n"), caption.Seq, caption.Start.Sub(emptyDate).Milliseconds(), caption.End.Sub(emptyDate).Milliseconds())
+// sameLanguage compares languages given in any of the forms tracks carry
+// them in, such as "ko", "kor" and "ko-KR".
+func sameLanguage(parsed *string, other string) bool {
+	if parsed == nil {
+		return false
+	}
+
+	parsedTag, err := language.Parse(*parsed)
+	if err != nil {
+		return false
+	}
+	otherTag, err := language.Parse(other)
+	if err != nil {
+		return false
+	}
+
+	parsedBase, _ := parsedTag.Base()
+	otherBase, _ := otherTag.Base()
+	return parsedBase == otherBase
+}
+
+func (e *Exporter) saveToDatabase(videoId uuid.UUID, trackId uuid.UUID, caption *Caption, context context.Context) (*DbSubtitle, error) {
+	subtitle, err := newDbSubtitle(videoId, trackId, caption, e.Normalizer.Normalize(caption))
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToInsertSubtitle)
+	}
 
 	inserted, err := e.SubtitlesRepository.Insert(subtitle, context)
 	if err != nil {
@@ -108,9 +299,15 @@ func (e *Exporter) saveToDatabase(videoId uuid.UUID, caption subtitles.Caption,
 	return inserted, nil
 }
 
-func (e *Exporter) saveToFullTextSearch(id string, videoId uuid.UUID, caption subtitles.Caption, context context.Context) error {
-	subtitle := NewFtsSubtitle(id, videoId, caption.Seq, strings.Join(caption.Text, "\n"))
-	err := e.FullTextSearch.Insert(subtitle, context)
+// saveToFullTextSearch indexes the normalized text of a subtitle. Sound
+// descriptions are left out of the index.
+func (e *Exporter) saveToFullTextSearch(dbSubtitle *DbSubtitle, context context.Context) error {
+	if dbSubtitle.NonSpeech {
+		return nil
+	}
+
+	subtitle := NewFtsSubtitle(dbSubtitle.Id, dbSubtitle.VideoId, dbSubtitle.Sequence, dbSubtitle.Text)
+	err := e.SearchIndex.Insert(subtitle, context)
 	if err != nil {
 		return err
 	}
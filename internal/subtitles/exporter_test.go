@@ -0,0 +1,298 @@
+package subtitles_test
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/bus"
+	"dewarrum/vocabulary-leveling/internal/config"
+	"dewarrum/vocabulary-leveling/internal/storage"
+	"dewarrum/vocabulary-leveling/internal/subtitles"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// fakeFfmpeg writes the position a poster frame was taken at into the frame.
+// It fails when the frame already exists, which happens when two exports share
+// a directory. FAKE_FFMPEG_SECONDS sets how long it takes.
+const fakeFfmpeg = `#!/bin/sh
+position=""
+while [ $# -gt 1 ]; do
+	if [ "$1" = "-ss" ]; then
+		position="$2"
+	fi
+	shift
+done
+if [ -e "$1" ]; then
+	exit 1
+fi
+sleep "${FAKE_FFMPEG_SECONDS:-0.1}"
+printf '%s' "$position" > "$1"
+`
+
+type discardIndex struct{}
+
+func (discardIndex) Insert(*subtitles.FtsSubtitle, context.Context) error { return nil }
+
+func (discardIndex) Delete(string, context.Context) error { return nil }
+
+func (discardIndex) Search(string, context.Context) ([]*subtitles.FtsSubtitle, error) {
+	return nil, nil
+}
+
+func newTestExporter(t *testing.T) (*subtitles.Exporter, sqlmock.Sqlmock, *storage.LocalStore) {
+	bin := t.TempDir()
+	err := os.WriteFile(filepath.Join(bin, "ffmpeg"), []byte(fakeFfmpeg), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	mock.MatchExpectationsInOrder(false)
+
+	memoryBus := bus.NewMemoryBus()
+	t.Cleanup(func() { memoryBus.Close() })
+
+	store := storage.NewLocalStore(t.TempDir(), "http://localhost/storage", []byte("secret"))
+	dependencies := &app.Dependencies{
+		Postgres:    sqlx.NewDb(db, "postgres"),
+		Bus:         memoryBus,
+		ObjectStore: store,
+		Logger:      zerolog.Nop(),
+		Tracer:      noop.NewTracerProvider().Tracer(""),
+		Meter:       metricnoop.NewMeterProvider().Meter(""),
+	}
+
+	messages, err := subtitles.NewMessageQueue(dependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exportDuration, _ := dependencies.Meter.Float64Histogram("subtitles.export.duration")
+	ffmpegFailures, _ := dependencies.Meter.Int64Counter("ffmpeg.failures")
+
+	return &subtitles.Exporter{
+		MessageQueue:        messages,
+		SubtitlesRepository: subtitles.NewSubtitlesRepository(dependencies),
+		Tracks:              subtitles.NewSubtitleTracksRepository(dependencies),
+		FileStorage:         subtitles.NewFileStorage(store),
+		SearchIndex:         discardIndex{},
+		Normalizer:          subtitles.NewNormalizer(config.Normalization{}),
+		Logger:              dependencies.Logger,
+		Tracer:              dependencies.Tracer,
+		ExportDuration:      exportDuration,
+		FfmpegFailures:      ffmpegFailures,
+	}, mock, store
+}
+
+func TestExporterExportsTheTracksOfAVideoAtTheSameTime(t *testing.T) {
+	exporter, mock, store := newTestExporter(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	videoId := uuid.New()
+	tracks := []struct {
+		id       uuid.UUID
+		srt      string
+		position string
+	}{
+		{id: uuid.New(), srt: "1\n00:00:01,000 --> 00:00:02,000\nHello\n", position: "1.500"},
+		{id: uuid.New(), srt: "1\n00:00:03,000 --> 00:00:04,000\nHello\n", position: "3.500"},
+	}
+	for _, track := range tracks {
+		err := exporter.FileStorage.Upload(videoId, track.id, strings.NewReader(track.srt), "application/x-subrip", ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mock.ExpectExec("INSERT INTO subtitles").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE subtitles SET thumbnail_location").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE subtitle_tracks SET status").
+			WithArgs(subtitles.TrackStatusReady, track.id).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err = exporter.MessageQueue.Send(subtitles.NewExportSubtitlesMessage(videoId, track.id), ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := exporter.Run(2, 2, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for mock.ExpectationsWereMet() != nil {
+		if time.Now().After(deadline) {
+			t.Fatal(mock.ExpectationsWereMet())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	err = exporter.Shutdown(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both tracks have a subtitle with sequence 1, each one has to get the
+	// frame taken at its own position.
+	for _, track := range tracks {
+		object, err := store.Get(fmt.Sprintf("%s/thumbnails/subtitles/%s/1.jpg", videoId, track.id), context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		frame, err := io.ReadAll(object.Body)
+		object.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(frame) != track.position {
+			t.Errorf("Expected the poster frame of track %s to be taken at %s, but got %q", track.id, track.position, frame)
+		}
+	}
+}
+
+func TestExporterRequeuesExportsAbortedByShutdown(t *testing.T) {
+	exporter, mock, _ := newTestExporter(t)
+	t.Setenv("FAKE_FFMPEG_SECONDS", "10")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	videoId, trackId := uuid.New(), uuid.New()
+	err := exporter.FileStorage.Upload(videoId, trackId, strings.NewReader("1\n00:00:01,000 --> 00:00:02,000\nHello\n"), "application/x-subrip", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The track is neither marked ready nor failed.
+	mock.ExpectExec("INSERT INTO subtitles").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = exporter.MessageQueue.Send(subtitles.NewExportSubtitlesMessage(videoId, trackId), ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = exporter.Run(1, 1, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for mock.ExpectationsWereMet() != nil {
+		if time.Now().After(deadline) {
+			t.Fatal(mock.ExpectationsWereMet())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The poster frame is rendering when the grace period ends.
+	cancel()
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancelShutdown()
+	err = exporter.Shutdown(shutdownCtx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected %v, but got %v", context.DeadlineExceeded, err)
+	}
+
+	restartCtx, cancelRestart := context.WithTimeout(context.Background(), time.Second)
+	defer cancelRestart()
+	messages, err := exporter.MessageQueue.Consume(1, restartCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case message := <-messages:
+		if message.TrackId != trackId {
+			t.Errorf("Expected track %s to be requeued, but got %s", trackId, message.TrackId)
+		}
+		message.Ack()
+	case <-restartCtx.Done():
+		t.Error("Expected the aborted export to be requeued")
+	}
+}
+
+func TestExporterSkipsTracksThatWereSplitBefore(t *testing.T) {
+	exporter, mock, _ := newTestExporter(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	videoId, trackId := uuid.New(), uuid.New()
+	err := exporter.FileStorage.Upload(videoId, trackId, strings.NewReader(samiFile), "text/plain", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trackColumns := []string{"id", "video_id", "source", "status", "language", "stream_index", "codec", "created_at"}
+	mock.ExpectQuery("SELECT \\* FROM subtitle_tracks WHERE id = \\$1").
+		WithArgs(trackId).
+		WillReturnRows(sqlmock.NewRows(trackColumns).AddRow(trackId, videoId, subtitles.TrackSourceUpload, subtitles.TrackStatusQueued, "kor", nil, nil, time.Now()))
+	// The English track was split off by an export that was redelivered.
+	split := subtitles.NewSplitDbSubtitleTrack(videoId, trackId, "ENCC", nil)
+	mock.ExpectQuery("INSERT INTO subtitle_tracks .* ON CONFLICT \\(id\\)").
+		WithArgs(split.Id, videoId, subtitles.TrackSourceUpload, subtitles.TrackStatusQueued, sqlmock.AnyArg(), nil, nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(trackColumns).AddRow(split.Id, videoId, subtitles.TrackSourceUpload, subtitles.TrackStatusReady, "eng", nil, nil, time.Now()))
+	for range 2 {
+		mock.ExpectExec("INSERT INTO subtitles").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE subtitles SET thumbnail_location").WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectExec("UPDATE subtitle_tracks SET status").
+		WithArgs(subtitles.TrackStatusReady, trackId).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = exporter.MessageQueue.Send(subtitles.NewExportSubtitlesMessage(videoId, trackId), ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = exporter.Run(1, 1, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for mock.ExpectationsWereMet() != nil {
+		if time.Now().After(deadline) {
+			t.Fatal(mock.ExpectationsWereMet())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	err = exporter.Shutdown(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Neither the file nor the export of the ready track is queued again.
+	_, err = exporter.FileStorage.Download(videoId, split.Id, context.Background())
+	if err == nil {
+		t.Error("Expected the split track not to be uploaded again")
+	}
+
+	restartCtx, cancelRestart := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancelRestart()
+	messages, err := exporter.MessageQueue.Consume(1, restartCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for message := range messages {
+		t.Errorf("Expected no export to be queued, but got track %s", message.TrackId)
+		message.Ack()
+	}
+}
@@ -2,41 +2,45 @@ package subtitles
 
 import (
 	"context"
+	"dewarrum/vocabulary-leveling/internal/storage"
 	"errors"
 	"fmt"
 	"io"
+	"time"
 
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/google/uuid"
-	gosubs "github.com/martinlindhe/subtitles"
 )
 
 const (
+	PresignedUploadExpiration = time.Hour
+
 	FailedToUpload   = "failed to upload"
 	FailedToDownload = "failed to download"
 	FailedToParse    = "failed to parse"
 )
 
 type FileStorage struct {
-	s3Client        *s3.Client
-	s3PresignClient *s3.PresignClient
+	objectStore storage.ObjectStore
 }
 
-func NewFileStorage(s3Client *s3.Client, s3PresignClient *s3.PresignClient) *FileStorage {
+func NewFileStorage(objectStore storage.ObjectStore) *FileStorage {
 	return &FileStorage{
-		s3Client:        s3Client,
-		s3PresignClient: s3PresignClient,
+		objectStore: objectStore,
+	}
+}
+
+// trackKey keeps messages without a track id pointing at the single
+// subtitles object uploaded before tracks were introduced.
+func trackKey(videoId uuid.UUID, trackId uuid.UUID) string {
+	if trackId == uuid.Nil {
+		return fmt.Sprintf("%s/subtitles", videoId)
 	}
+
+	return fmt.Sprintf("%s/subtitles/%s", videoId, trackId)
 }
 
-func (f *FileStorage) Upload(videoId uuid.UUID, body io.Reader, contentType string, context context.Context) error {
-	_, err := f.s3Client.PutObject(context, &s3.PutObjectInput{
-		Bucket:      aws.String("default"),
-		Key:         aws.String(fmt.Sprintf("%s/subtitles", videoId)),
-		Body:        body,
-		ContentType: &contentType,
-	})
+func (f *FileStorage) Upload(videoId uuid.UUID, trackId uuid.UUID, body io.Reader, contentType string, context context.Context) error {
+	err := f.objectStore.Put(trackKey(videoId, trackId), body, contentType, context)
 	if err != nil {
 		return errors.Join(err, errors.New(FailedToUpload))
 	}
@@ -44,24 +48,48 @@ func (f *FileStorage) Upload(videoId uuid.UUID, body io.Reader, contentType stri
 	return nil
 }
 
-func (f *FileStorage) Download(videoId string, context context.Context) (*gosubs.Subtitle, error) {
-	response, err := f.s3Client.GetObject(context, &s3.GetObjectInput{
-		Bucket: aws.String("default"),
-		Key:    aws.String(fmt.Sprintf("%s/subtitles", videoId)),
-	})
+func (f *FileStorage) PresignUpload(videoId uuid.UUID, trackId uuid.UUID, contentType string, ctx context.Context) (string, error) {
+	return f.objectStore.PresignPut(trackKey(videoId, trackId), storage.PresignOptions{
+		Expiration:  PresignedUploadExpiration,
+		ContentType: contentType,
+	}, ctx)
+}
+
+func (f *FileStorage) Exists(videoId uuid.UUID, trackId uuid.UUID, ctx context.Context) (bool, error) {
+	return f.objectStore.Exists(trackKey(videoId, trackId), ctx)
+}
+
+// Download returns the tracks of the subtitle file uploaded for the track,
+// several for SAMI files with more than one language class.
+func (f *FileStorage) Download(videoId uuid.UUID, trackId uuid.UUID, context context.Context) ([]*ParsedTrack, error) {
+	object, err := f.objectStore.Get(trackKey(videoId, trackId), context)
 	if err != nil {
 		return nil, errors.Join(err, errors.New(FailedToDownload))
 	}
+	defer object.Body.Close()
 
-	responseBody, err := io.ReadAll(response.Body)
+	responseBody, err := io.ReadAll(object.Body)
 	if err != nil {
 		return nil, errors.Join(err, errors.New(FailedToDownload))
 	}
 
-	subtitle, err := gosubs.Parse(responseBody)
+	return Parse(responseBody)
+}
+
+func (f *FileStorage) UploadThumbnail(videoId uuid.UUID, subtitleId string, body io.Reader, ctx context.Context) (string, error) {
+	key := fmt.Sprintf("%s/thumbnails/subtitles/%s.jpg", videoId, subtitleId)
+	err := f.objectStore.Put(key, body, "image/jpeg", ctx)
 	if err != nil {
-		return nil, errors.Join(err, errors.New(FailedToParse))
+		return "", errors.Join(err, errors.New(FailedToUpload))
 	}
 
-	return &subtitle, nil
+	return key, nil
+}
+
+func (f *FileStorage) PresignVideo(videoId uuid.UUID, ctx context.Context) (string, error) {
+	return f.PresignObject(fmt.Sprintf("%s/original", videoId), ctx)
+}
+
+func (f *FileStorage) PresignObject(key string, ctx context.Context) (string, error) {
+	return f.objectStore.PresignGet(key, storage.PresignOptions{}, ctx)
 }
@@ -9,58 +9,39 @@ import (
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/typedapi/indices/create"
 	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
-	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 )
 
 const (
-	indexName = "subtitles"
+	IndexName = "subtitles"
 )
 
 var (
-	ErrFailedToCreateIndex = errors.New("failed to create index")
-	ErrFailedToInsert      = errors.New("failed to insert")
-	ErrFailedToSearch      = errors.New("failed to search")
-	ErrFailedToDeserialize = errors.New("failed to deserialize")
-	analyzer               = "nori"
+	analyzer = "nori"
 )
 
-type FtsSubtitle struct {
-	Id       string    `json:"id"`
-	VideoId  uuid.UUID `json:"video_id"`
-	Sequence int       `json:"sequence"`
-	Text     string    `json:"text"`
-}
-
-func NewFtsSubtitle(id string, videoId uuid.UUID, sequence int, text string) *FtsSubtitle {
-	return &FtsSubtitle{
-		Id:       id,
-		VideoId:  videoId,
-		Sequence: sequence,
-		Text:     text,
-	}
-}
-
-type FullTextSearch struct {
+// ElasticsearchIndex searches subtitles with the nori analyzer, which
+// understands Korean morphology.
+type ElasticsearchIndex struct {
 	elasticsearchClient *elasticsearch.TypedClient
 	logger              zerolog.Logger
 }
 
-func NewFullTextSearch(elasticsearchClient *elasticsearch.TypedClient, context context.Context) (*FullTextSearch, error) {
+func NewElasticsearchIndex(elasticsearchClient *elasticsearch.TypedClient, context context.Context) (*ElasticsearchIndex, error) {
 	err := initializeIndex(elasticsearchClient, context)
 	if err != nil {
 		return nil, errors.Join(err, ErrFailedToCreateIndex)
 	}
 
-	return &FullTextSearch{
+	return &ElasticsearchIndex{
 		elasticsearchClient: elasticsearchClient,
 	}, nil
 }
 
-func (f *FullTextSearch) Insert(subtitle *FtsSubtitle, context context.Context) error {
+func (f *ElasticsearchIndex) Insert(subtitle *FtsSubtitle, context context.Context) error {
 	f.logger.Debug().Str("videoId", subtitle.VideoId.String()).Int32("sequence", int32(subtitle.Sequence)).Msg("Inserting subtitle into full text search")
 
-	_, err := f.elasticsearchClient.Index(indexName).
+	_, err := f.elasticsearchClient.Index(IndexName).
 		Id(base64.StdEncoding.EncodeToString([]byte(subtitle.Id))).
 		Request(subtitle).
 		Do(context)
@@ -77,7 +58,20 @@ func (f *FullTextSearch) Insert(subtitle *FtsSubtitle, context context.Context)
 	return errors.Join(err, ErrFailedToInsert)
 }
 
-func (f *FullTextSearch) Search(queryText string, context context.Context) ([]*FtsSubtitle, error) {
+func (f *ElasticsearchIndex) Delete(id string, context context.Context) error {
+	f.logger.Debug().Str("id", id).Msg("Deleting subtitle from full text search")
+
+	// Deleting a missing document reports the result not_found, not an error.
+	_, err := f.elasticsearchClient.Delete(IndexName, base64.StdEncoding.EncodeToString([]byte(id))).
+		Do(context)
+	if err != nil {
+		return errors.Join(err, ErrFailedToDelete)
+	}
+
+	return nil
+}
+
+func (f *ElasticsearchIndex) Search(queryText string, context context.Context) ([]*FtsSubtitle, error) {
 	query := types.Query{
 		Match: map[string]types.MatchQuery{
 			"text": {
@@ -87,8 +81,9 @@ func (f *FullTextSearch) Search(queryText string, context context.Context) ([]*F
 	}
 
 	response, err := f.elasticsearchClient.Search().
-		Index(indexName).
+		Index(IndexName).
 		Query(&query).
+		Size(searchResultLimit).
 		Do(context)
 
 	if err != nil {
@@ -110,7 +105,7 @@ func (f *FullTextSearch) Search(queryText string, context context.Context) ([]*F
 
 func initializeIndex(elasticsearchClient *elasticsearch.TypedClient, context context.Context) error {
 	_, err := elasticsearchClient.Indices.
-		Create(indexName).
+		Create(IndexName).
 		Request(&create.Request{
 			Mappings: &types.TypeMapping{
 				Properties: map[string]types.Property{
@@ -0,0 +1,87 @@
+package subtitles
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"errors"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+)
+
+// PostgresIndex searches the subtitles table itself. Its search_vector column
+// is generated from the text with subtitles_search_terms, which splits Hangul
+// into bigrams, and a trigram index on the text catches the queries the
+// bigrams miss, such as single syllables. Captions marked as non-speech are
+// never found.
+type PostgresIndex struct {
+	db     *sqlx.DB
+	logger zerolog.Logger
+	tracer trace.Tracer
+}
+
+func NewPostgresIndex(dependencies *app.Dependencies) *PostgresIndex {
+	return &PostgresIndex{
+		db:     dependencies.Postgres,
+		logger: dependencies.Logger,
+		tracer: dependencies.Tracer,
+	}
+}
+
+// Insert does nothing: Postgres indexes a subtitle as soon as
+// SubtitlesRepository.Insert stores it.
+func (p *PostgresIndex) Insert(subtitle *FtsSubtitle, ctx context.Context) error {
+	return nil
+}
+
+// Delete does nothing: Postgres finds the subtitles that are stored and not
+// marked as non-speech.
+func (p *PostgresIndex) Delete(id string, ctx context.Context) error {
+	return nil
+}
+
+func (p *PostgresIndex) Search(queryText string, ctx context.Context) ([]*FtsSubtitle, error) {
+	ctx, span := p.tracer.Start(ctx, "subtitles.postgresIndex.search", trace.WithAttributes(attribute.String("query", queryText)))
+	defer span.End()
+
+	p.logger.Debug().Str("query", queryText).Msg("Searching subtitles")
+
+	pattern := "%" + likeEscaper.Replace(queryText) + "%"
+
+	var subtitles []*FtsSubtitle
+	rows, err := p.db.QueryxContext(ctx, `
+		SELECT id, video_id, sequence, text
+		FROM subtitles, plainto_tsquery('simple', subtitles_search_terms($1)) query
+		WHERE (search_vector @@ query OR text ILIKE $2) AND NOT non_speech
+		ORDER BY ts_rank_cd(search_vector, query) DESC, similarity(text, $1) DESC, id
+		LIMIT $3`,
+		queryText, pattern, searchResultLimit)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		return nil, errors.Join(err, ErrFailedToSearch)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var subtitle FtsSubtitle
+		err := rows.Scan(&subtitle.Id, &subtitle.VideoId, &subtitle.Sequence, &subtitle.Text)
+		if err != nil {
+			return nil, errors.Join(err, ErrFailedToDeserialize)
+		}
+		subtitles = append(subtitles, &subtitle)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToSearch)
+	}
+
+	return subtitles, nil
+}
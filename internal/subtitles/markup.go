@@ -0,0 +1,169 @@
+package subtitles
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	// inlineMarkupRegex matches ASS override blocks such as {\an8} and HTML
+	// like tags such as <i>, which SubRip files borrow from both.
+	inlineMarkupRegex = regexp.MustCompile(`\{\\[^}]*\}|</?([a-zA-Z]+)[^>]*>`)
+	positionRegex     = regexp.MustCompile(`^pos\(\s*(-?[\d.]+)\s*,\s*(-?[\d.]+)\s*\)$`)
+)
+
+// styledText collects the visible text of a caption while markup switches
+// italics on and off, to tell whether all of it is italic.
+type styledText struct {
+	text          strings.Builder
+	italic        bool
+	visible       int
+	visibleItalic int
+}
+
+func (t *styledText) write(text string) {
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			continue
+		}
+
+		t.visible++
+		if t.italic {
+			t.visibleItalic++
+		}
+	}
+	t.text.WriteString(text)
+}
+
+func (t *styledText) allItalic() bool {
+	return t.visible > 0 && t.visible == t.visibleItalic
+}
+
+// lines splits the text on line breaks, collapsing white space and dropping
+// the lines left empty.
+func (t *styledText) lines() []string {
+	var lines []string
+	for _, line := range strings.Split(t.text.String(), "\n") {
+		line = strings.Join(strings.FieldsFunc(line, unicode.IsSpace), " ")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// overrides is the state ASS override tags change in the middle of a line.
+type overrides struct {
+	italic   bool
+	position string
+	drawing  bool
+	x, y     float64
+	placed   bool
+}
+
+// apply applies the tags of an override block, given without its braces.
+// Tags that only change the look of the text, such as colors, fonts and
+// karaoke timing, are ignored. \r resets to defaults.
+func (o *overrides) apply(block string, defaults overrides) {
+	for _, tag := range strings.Split(block, `\`) {
+		tag = strings.TrimSpace(tag)
+		switch {
+		case tag == "i0":
+			o.italic = false
+		case tag == "i1":
+			o.italic = true
+		case tag == "i":
+			o.italic = defaults.italic
+		case strings.HasPrefix(tag, "an"):
+			if n, err := strconv.Atoi(tag[2:]); err == nil && n >= 1 && n <= 9 {
+				o.position = numpadPositions[n]
+			}
+		case strings.HasPrefix(tag, "a") && isDigits(tag[1:]):
+			if position := legacyAlignment(tag[1:]); position != "" {
+				o.position = position
+			}
+		case strings.HasPrefix(tag, "p") && isDigits(tag[1:]):
+			o.drawing = tag[1:] != "0"
+		case strings.HasPrefix(tag, "r"):
+			*o = defaults
+		default:
+			if match := positionRegex.FindStringSubmatch(tag); match != nil {
+				x, errX := strconv.ParseFloat(match[1], 64)
+				y, errY := strconv.ParseFloat(match[2], 64)
+				if errX == nil && errY == nil {
+					o.x, o.y, o.placed = x, y, true
+				}
+			}
+		}
+	}
+}
+
+// legacyAlignment maps the SSA v4 alignments, 1 to 3 at the bottom with 4
+// added for the top and 8 for the middle, to positions.
+func legacyAlignment(value string) string {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > 11 || n&3 == 0 {
+		return ""
+	}
+
+	switch {
+	case n&4 != 0:
+		return numpadPositions[n&3+6]
+	case n&8 != 0:
+		return numpadPositions[n&3+3]
+	default:
+		return numpadPositions[n&3]
+	}
+}
+
+func isDigits(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// cleanInlineMarkup removes the ASS override blocks and HTML like tags
+// SubRip files carry, keeping their position and italics in the style.
+func cleanInlineMarkup(lines []string) ([]string, *CaptionStyle) {
+	source := strings.Join(lines, "\n")
+
+	var text styledText
+	var state overrides
+	last := 0
+	for _, match := range inlineMarkupRegex.FindAllStringSubmatchIndex(source, -1) {
+		text.write(html.UnescapeString(source[last:match[0]]))
+		last = match[1]
+
+		markup := source[match[0]:match[1]]
+		if strings.HasPrefix(markup, "{") {
+			state.apply(markup[1:len(markup)-1], overrides{})
+			text.italic = state.italic
+			continue
+		}
+
+		switch strings.ToLower(source[match[2]:match[3]]) {
+		case "i", "em":
+			text.italic = !strings.HasPrefix(markup, "</")
+			state.italic = text.italic
+		case "br":
+			text.write("\n")
+		}
+	}
+	text.write(html.UnescapeString(source[last:]))
+
+	return text.lines(), &CaptionStyle{
+		Position: state.position,
+		Italic:   text.allItalic(),
+	}
+}
@@ -0,0 +1,127 @@
+package subtitles
+
+import (
+	"dewarrum/vocabulary-leveling/internal/config"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	// sdhRegex matches the sound descriptions of subtitles for the deaf and
+	// hard of hearing, such as [음악] or (웃음), and music notes.
+	sdhRegex          = regexp.MustCompile(`\[[^\]]*\]|\([^)]*\)|（[^）]*）|【[^】]*】|[♪♫♬]`)
+	dialogueDashRegex = regexp.MustCompile(`^[-‐–—]\s*`)
+	// speakerRegex matches a label such as "민수:" at the start of a line. The
+	// colon has to be followed by white space, which leaves out times and
+	// URLs, and the label may not contain the punctuation of a sentence.
+	speakerRegex = regexp.MustCompile(`^([^\s\d:：.?!,][^:：.?!,]*?)\s*[:：](?:\s+|$)`)
+)
+
+// Utterance is what one speaker says in a caption.
+type Utterance struct {
+	Speaker string `json:"speaker,omitempty"`
+	Text    string `json:"text"`
+}
+
+// NormalizedCaption is the text of a caption as search and statistics see it.
+// Captions made only of sound descriptions are NonSpeech.
+type NormalizedCaption struct {
+	Text       string
+	Utterances []*Utterance
+	NonSpeech  bool
+}
+
+// Normalizer cleans up the text of captions for search, which would otherwise
+// match on sound descriptions, dialogue dashes and speaker labels.
+type Normalizer struct {
+	stripSdh         bool
+	splitSpeakers    bool
+	maxSpeakerLength int
+}
+
+func NewNormalizer(config config.Normalization) *Normalizer {
+	return &Normalizer{
+		stripSdh:         config.StripSdh,
+		splitSpeakers:    config.SplitSpeakers,
+		maxSpeakerLength: config.MaxSpeakerLength,
+	}
+}
+
+// Normalize splits the lines of a caption into utterances. A line starts a
+// new utterance when it opens with a dialogue dash or a speaker label, other
+// lines continue the one before. The speaker the file gave the caption, such
+// as the actor of an ASS line, is kept when there is a single utterance
+// without a label.
+func (n *Normalizer) Normalize(caption *Caption) *NormalizedCaption {
+	var utterances []*Utterance
+	speech := false
+	for _, line := range caption.Text {
+		line = norm.NFC.String(line)
+		withoutSdh := collapseSpaces(sdhRegex.ReplaceAllString(line, " "))
+		if hasSpeech(withoutSdh) {
+			speech = true
+		} else if n.stripSdh {
+			continue
+		}
+
+		if n.stripSdh {
+			line = withoutSdh
+		} else {
+			line = collapseSpaces(line)
+		}
+
+		started := len(utterances) == 0
+		speaker := ""
+		if n.splitSpeakers {
+			if dash := dialogueDashRegex.FindString(line); dash != "" {
+				line = line[len(dash):]
+				started = true
+			}
+			if label := speakerRegex.FindStringSubmatch(line); label != nil && utf8.RuneCountInString(label[1]) <= n.maxSpeakerLength {
+				line = line[len(label[0]):]
+				speaker = strings.TrimSpace(label[1])
+				started = true
+			}
+		}
+
+		if started {
+			utterances = append(utterances, &Utterance{Speaker: speaker})
+		}
+		last := utterances[len(utterances)-1]
+		last.Text = strings.TrimSpace(last.Text + " " + line)
+	}
+
+	normalized := &NormalizedCaption{NonSpeech: !speech}
+	var texts []string
+	for _, utterance := range utterances {
+		if utterance.Text == "" {
+			continue
+		}
+
+		normalized.Utterances = append(normalized.Utterances, utterance)
+		texts = append(texts, utterance.Text)
+	}
+	normalized.Text = strings.Join(texts, "\n")
+
+	if len(normalized.Utterances) == 1 && normalized.Utterances[0].Speaker == "" && caption.Style != nil {
+		normalized.Utterances[0].Speaker = caption.Style.Speaker
+	}
+
+	return normalized
+}
+
+func collapseSpaces(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// hasSpeech tells whether text has any letters or numbers left, rather than
+// only the dashes and punctuation around a sound description.
+func hasSpeech(text string) bool {
+	return strings.IndexFunc(text, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsNumber(r)
+	}) >= 0
+}
@@ -0,0 +1,88 @@
+package subtitles_test
+
+import (
+	"dewarrum/vocabulary-leveling/internal/config"
+	"dewarrum/vocabulary-leveling/internal/subtitles"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizerSplitsUtterancesAndStripsSdh(t *testing.T) {
+	normalizer := subtitles.NewNormalizer(config.Default().Normalization)
+
+	tests := []struct {
+		name       string
+		caption    *subtitles.Caption
+		text       string
+		utterances []subtitles.Utterance
+		nonSpeech  bool
+	}{
+		{
+			name:       "dialogue dashes",
+			caption:    &subtitles.Caption{Text: []string{"- 어디 가?", "- 학교에 가."}},
+			text:       "어디 가?\n학교에 가.",
+			utterances: []subtitles.Utterance{{Text: "어디 가?"}, {Text: "학교에 가."}},
+		},
+		{
+			name:       "speaker labels",
+			caption:    &subtitles.Caption{Text: []string{"민수: 안녕", "지수 (속으로): 또 왔네"}},
+			text:       "안녕\n또 왔네",
+			utterances: []subtitles.Utterance{{Speaker: "민수", Text: "안녕"}, {Speaker: "지수", Text: "또 왔네"}},
+		},
+		{
+			name:       "continued line",
+			caption:    &subtitles.Caption{Text: []string{"(웃음) 오늘은", "정말 좋은 날이야"}},
+			text:       "오늘은 정말 좋은 날이야",
+			utterances: []subtitles.Utterance{{Text: "오늘은 정말 좋은 날이야"}},
+		},
+		{
+			name:       "speaker from style",
+			caption:    &subtitles.Caption{Text: []string{"10:30에 만나"}, Style: &subtitles.CaptionStyle{Speaker: "민수"}},
+			text:       "10:30에 만나",
+			utterances: []subtitles.Utterance{{Speaker: "민수", Text: "10:30에 만나"}},
+		},
+		{
+			name:      "sound descriptions only",
+			caption:   &subtitles.Caption{Text: []string{"[음악]", "- ♪ ♪"}},
+			nonSpeech: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			normalized := normalizer.Normalize(test.caption)
+
+			var utterances []subtitles.Utterance
+			for _, utterance := range normalized.Utterances {
+				utterances = append(utterances, *utterance)
+			}
+
+			if normalized.Text != test.text {
+				t.Errorf("Expected text %q, got %q", test.text, normalized.Text)
+			}
+			if !reflect.DeepEqual(utterances, test.utterances) {
+				t.Errorf("Expected utterances %+v, got %+v", test.utterances, utterances)
+			}
+			if normalized.NonSpeech != test.nonSpeech {
+				t.Errorf("Expected non-speech to be %t, got %t", test.nonSpeech, normalized.NonSpeech)
+			}
+		})
+	}
+}
+
+func TestNormalizerKeepsTextWhenDisabled(t *testing.T) {
+	normalizer := subtitles.NewNormalizer(config.Normalization{})
+
+	normalized := normalizer.Normalize(&subtitles.Caption{Text: []string{"- 민수: 안녕  [웃음]", "[음악]"}})
+	if normalized.Text != "- 민수: 안녕 [웃음] [음악]" {
+		t.Errorf("Expected the text to only have its spaces collapsed, got %q", normalized.Text)
+	}
+	if normalized.NonSpeech {
+		t.Error("Expected a caption with speech not to be marked as non-speech")
+	}
+
+	normalized = normalizer.Normalize(&subtitles.Caption{Text: []string{"(문 닫히는 소리)"}})
+	if !normalized.NonSpeech || normalized.Text != "(문 닫히는 소리)" {
+		t.Errorf("Expected the sound description to be kept and marked as non-speech, got %+v", normalized)
+	}
+}
@@ -0,0 +1,166 @@
+package subtitles
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	gosubs "github.com/martinlindhe/subtitles"
+)
+
+// Positions of captions on the screen, named after the keys of a numeric
+// keypad as ASS alignments are.
+const (
+	PositionBottomLeft  = "bottom-left"
+	PositionBottom      = "bottom"
+	PositionBottomRight = "bottom-right"
+	PositionMiddleLeft  = "middle-left"
+	PositionMiddle      = "middle"
+	PositionMiddleRight = "middle-right"
+	PositionTopLeft     = "top-left"
+	PositionTop         = "top"
+	PositionTopRight    = "top-right"
+)
+
+// emptyDate is the day gosubs times captions from.
+var emptyDate = time.Date(0, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// numpadPositions maps the ASS \an alignments to positions.
+var numpadPositions = [10]string{
+	"",
+	PositionBottomLeft, PositionBottom, PositionBottomRight,
+	PositionMiddleLeft, PositionMiddle, PositionMiddleRight,
+	PositionTopLeft, PositionTop, PositionTopRight,
+}
+
+// CaptionStyle is the styling of a caption that the formats express with tags
+// in the text or with style sheets.
+type CaptionStyle struct {
+	Position string `json:"position,omitempty"`
+	Italic   bool   `json:"italic,omitempty"`
+	Speaker  string `json:"speaker,omitempty"`
+	Style    string `json:"style,omitempty"`
+}
+
+func (s *CaptionStyle) isZero() bool {
+	return s == nil || *s == CaptionStyle{}
+}
+
+// Caption is a cue of a subtitle file. Its text is free of markup, which
+// leaves its traces in Style instead.
+type Caption struct {
+	Seq     int
+	StartMs int64
+	EndMs   int64
+	Text    []string
+	Style   *CaptionStyle
+}
+
+// ParsedTrack is the captions of one language of a subtitle file. Only SAMI
+// files hold more than one, told apart by the name of their class.
+type ParsedTrack struct {
+	Name     string
+	Language *string
+	Captions []*Caption
+	// Skipped lists the entries that could not be parsed and were left out
+	// of the track.
+	Skipped []error
+}
+
+// AsSRT renders the track as SubRip, which keeps the timing and the text but
+// none of the styling.
+func (t *ParsedTrack) AsSRT() string {
+	var b strings.Builder
+	for _, caption := range t.Captions {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n",
+			caption.Seq,
+			gosubs.TimeSRT(emptyDate.Add(time.Duration(caption.StartMs)*time.Millisecond)),
+			gosubs.TimeSRT(emptyDate.Add(time.Duration(caption.EndMs)*time.Millisecond)),
+			strings.Join(caption.Text, "\n"))
+	}
+
+	return b.String()
+}
+
+// Parse decodes a subtitle file in any of the encodings DecodeText detects
+// and returns its tracks. SAMI, ASS/SSA, TTML/DFXP and WebVTT files have
+// parsers of their own that separate styling from the text, other formats are
+// left to gosubs and only cleaned of the tags SubRip files commonly carry.
+func Parse(data []byte) ([]*ParsedTrack, error) {
+	text, _, err := DecodeText(data)
+	if err != nil {
+		return nil, err
+	}
+	text = bytes.ReplaceAll(text, []byte("\r\n"), []byte("\n"))
+
+	var tracks []*ParsedTrack
+	switch {
+	case LooksLikeSami(text):
+		tracks, err = ParseSami(text)
+	case LooksLikeSsa(text):
+		tracks, err = single(ParseSsa(text))
+	case LooksLikeTtml(text):
+		tracks, err = single(ParseTtml(text))
+	case LooksLikeWebVtt(text):
+		tracks, err = single(ParseWebVtt(text))
+	default:
+		tracks, err = single(parseWithGosubs(text))
+	}
+	if err != nil {
+		return nil, errors.Join(err, errors.New(FailedToParse))
+	}
+
+	return tracks, nil
+}
+
+func single(track *ParsedTrack, err error) ([]*ParsedTrack, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return []*ParsedTrack{track}, nil
+}
+
+func parseWithGosubs(text []byte) (*ParsedTrack, error) {
+	subtitle, err := gosubs.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var captions []*Caption
+	for _, caption := range subtitle.Captions {
+		lines, style := cleanInlineMarkup(caption.Text)
+		captions = append(captions, &Caption{
+			StartMs: caption.Start.Sub(emptyDate).Milliseconds(),
+			EndMs:   caption.End.Sub(emptyDate).Milliseconds(),
+			Text:    lines,
+			Style:   style,
+		})
+	}
+
+	return &ParsedTrack{Captions: sequenced(captions)}, nil
+}
+
+// sequenced orders captions by their start, drops the ones left without text
+// or duration, and numbers the rest from 1.
+func sequenced(captions []*Caption) []*Caption {
+	sort.SliceStable(captions, func(i, j int) bool { return captions[i].StartMs < captions[j].StartMs })
+
+	kept := captions[:0]
+	for _, caption := range captions {
+		if len(caption.Text) == 0 || caption.EndMs <= caption.StartMs {
+			continue
+		}
+		if caption.Style.isZero() {
+			caption.Style = nil
+		}
+
+		caption.Seq = len(kept) + 1
+		kept = append(kept, caption)
+	}
+
+	return kept
+}
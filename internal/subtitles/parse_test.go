@@ -0,0 +1,352 @@
+package subtitles_test
+
+import (
+	"dewarrum/vocabulary-leveling/internal/subtitles"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/korean"
+)
+
+const samiFile = `<SAMI>
+<HEAD>
+<TITLE>Episode 1</TITLE>
+<STYLE TYPE="text/css">
+<!--
+P { margin-left: 8pt; font-size: 20pt; }
+.KRCC { Name: Korean; lang: ko-KR; SAMIType: CC; }
+.ENCC { Name: English; lang: en-US; SAMIType: CC; }
+-->
+</STYLE>
+</HEAD>
+<BODY>
+<SYNC Start=1000><P Class=KRCC>안녕하세요<br>반갑습니다
+<SYNC Start=1000><P Class=ENCC>Hello<br>Nice to meet you
+<SYNC Start=2500><P Class=KRCC>&nbsp;
+<SYNC Start=3000><P Class=ENCC>&nbsp;
+<SYNC Start=4000><P Class=KRCC><font color="yellow">잘 가요</font>
+</BODY>
+</SAMI>
+`
+
+func encodeCp949(t *testing.T, text string) []byte {
+	encoded, err := korean.EUCKR.NewEncoder().String(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return []byte(encoded)
+}
+
+func TestDecodeTextDetectsEncodings(t *testing.T) {
+	windows1252, err := charmap.Windows1252.NewEncoder().String("Ça va, café?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	windows1251, err := charmap.Windows1251.NewEncoder().String("Привет")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		text     string
+		encoding string
+	}{
+		{"utf-8", []byte("안녕하세요"), "안녕하세요", subtitles.EncodingUtf8},
+		{"utf-8 with bom", append([]byte{0xEF, 0xBB, 0xBF}, "안녕"...), "안녕", subtitles.EncodingUtf8},
+		{"utf-16le", []byte{0xFF, 0xFE, 0x48, 0xC5, 0x55, 0xB1}, "안녕", subtitles.EncodingUtf16le},
+		{"cp949", encodeCp949(t, "1\n00:00:01,000 --> 00:00:02,000\n안녕하세요, 똠방각하\n"), "1\n00:00:01,000 --> 00:00:02,000\n안녕하세요, 똠방각하\n", subtitles.EncodingEucKr},
+		{"windows-1252", []byte(windows1252), "Ça va, café?", subtitles.EncodingWindows1252},
+		{"declared charset", append([]byte(`<meta charset="windows-1251">`), windows1251...), `<meta charset="windows-1251">Привет`, "windows-1251"},
+	}
+
+	for _, test := range tests {
+		text, encoding, err := subtitles.DecodeText(test.data)
+		if err != nil {
+			t.Fatalf("Expected %s to decode, got %v", test.name, err)
+		}
+
+		if string(text) != test.text || encoding != test.encoding {
+			t.Errorf("Expected %s to decode to %q in %s, got %q in %s", test.name, test.text, test.encoding, text, encoding)
+		}
+	}
+}
+
+func TestParseSplitsSamiLanguageClasses(t *testing.T) {
+	tracks, err := subtitles.Parse(encodeCp949(t, samiFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tracks) != 2 {
+		t.Fatalf("Expected a track for KRCC and ENCC, got %d", len(tracks))
+	}
+
+	expected := []struct {
+		name     string
+		language string
+		texts    [][]string
+		starts   []int64
+		ends     []int64
+	}{
+		{
+			name:     "KRCC",
+			language: "kor",
+			texts:    [][]string{{"안녕하세요", "반갑습니다"}, {"잘 가요"}},
+			starts:   []int64{1000, 4000},
+			ends:     []int64{2500, 9000},
+		},
+		{
+			name:     "ENCC",
+			language: "eng",
+			texts:    [][]string{{"Hello", "Nice to meet you"}},
+			starts:   []int64{1000},
+			ends:     []int64{3000},
+		},
+	}
+
+	for i, track := range tracks {
+		want := expected[i]
+		if track.Name != want.name || track.Language == nil || *track.Language != want.language {
+			t.Errorf("Expected track %d to be %s in %s, got %s in %v", i, want.name, want.language, track.Name, track.Language)
+		}
+
+		var texts [][]string
+		var starts, ends []int64
+		for j, caption := range track.Captions {
+			if caption.Seq != j+1 {
+				t.Errorf("Expected caption %d of %s to be numbered %d, got %d", j, track.Name, j+1, caption.Seq)
+			}
+			texts = append(texts, caption.Text)
+			starts = append(starts, caption.StartMs)
+			ends = append(ends, caption.EndMs)
+		}
+
+		if !reflect.DeepEqual(texts, want.texts) {
+			t.Errorf("Expected %s captions %q, got %q", track.Name, want.texts, texts)
+		}
+		if !reflect.DeepEqual(starts, want.starts) || !reflect.DeepEqual(ends, want.ends) {
+			t.Errorf("Expected %s captions from %v to %v, got from %v to %v", track.Name, want.starts, want.ends, starts, ends)
+		}
+	}
+}
+
+func TestParseSamiKeepsTheOrderOfUndeclaredClasses(t *testing.T) {
+	file := "<SAMI><BODY>\n<SYNC Start=1000><P Class=ENCC>Hello<P Class=KRCC>안녕<P Class=JPCC>こんにちは\n</BODY></SAMI>"
+
+	// Classes used to come out in map order, which differs between runs.
+	for range 20 {
+		tracks, err := subtitles.ParseSami([]byte(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		for _, track := range tracks {
+			names = append(names, track.Name)
+		}
+		if !reflect.DeepEqual(names, []string{"ENCC", "KRCC", "JPCC"}) {
+			t.Fatalf("Expected the classes in file order, got %v", names)
+		}
+	}
+}
+
+func TestParseKeepsSingleTrackFormats(t *testing.T) {
+	tracks, err := subtitles.Parse(encodeCp949(t, "1\n00:00:01,000 --> 00:00:02,000\n안녕하세요\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tracks) != 1 || tracks[0].Language != nil || len(tracks[0].Captions) != 1 {
+		t.Fatalf("Expected a single track without a language, got %+v", tracks)
+	}
+
+	if text := tracks[0].Captions[0].Text; !reflect.DeepEqual(text, []string{"안녕하세요"}) {
+		t.Errorf("Expected the caption to be transcoded, got %q", text)
+	}
+}
+
+func TestParseSsaSkipsEventsWithInvalidTimes(t *testing.T) {
+	file := "[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n" +
+		"Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,안녕\n" +
+		"Dialogue: 0,0:00:xx.00,0:00:04.00,Default,,0,0,0,,깨진 줄\n" +
+		"Dialogue: 0,0:00:05.00,0:00:06.00,Default,,0,0,0,,잘 가\n"
+
+	track, err := subtitles.ParseSsa([]byte(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var texts [][]string
+	for _, caption := range track.Captions {
+		texts = append(texts, caption.Text)
+	}
+	if !reflect.DeepEqual(texts, [][]string{{"안녕"}, {"잘 가"}}) {
+		t.Errorf("Expected the valid events to be kept, got %q", texts)
+	}
+
+	if len(track.Skipped) != 1 || !errors.Is(track.Skipped[0], subtitles.ErrInvalidSsaTime) || !strings.HasPrefix(track.Skipped[0].Error(), "line 4:") {
+		t.Errorf("Expected line 4 to be skipped with %v, got %v", subtitles.ErrInvalidSsaTime, track.Skipped)
+	}
+}
+
+func TestParseDetectsTtmlByItsRootElement(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		ttml bool
+	}{
+		{"document", ttmlFile, true},
+		{"prefixed root", "<tt:tt xmlns:tt=\"http://www.w3.org/ns/ttml\">", true},
+		{"prolog", "<?xml version=\"1.0\"?>\n<!-- exported -->\n<!DOCTYPE tt>\n<tt>", true},
+		{"srt with a tt tag", "1\n00:00:01,000 --> 00:00:02,000\nType <tt>ls</tt> here\n", false},
+	}
+
+	for _, test := range tests {
+		if looksLikeTtml := subtitles.LooksLikeTtml([]byte(test.text)); looksLikeTtml != test.ttml {
+			t.Errorf("Expected %s to be detected as TTML %t, got %t", test.name, test.ttml, looksLikeTtml)
+		}
+	}
+}
+
+const assFile = `[Script Info]
+ScriptType: v4.00+
+PlayResX: 1920
+PlayResY: 1080
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
+Style: Default,Arial,60,&H00FFFFFF,&H000000FF,&H00000000,&H00000000,0,0,0,0,100,100,0,0,1,2,2,2,10,10,10,1
+Style: Thoughts,Arial,60,&H00FFFFFF,&H000000FF,&H00000000,&H00000000,0,-1,0,0,100,100,0,0,1,2,2,2,10,10,10,1
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: 0,0:00:01.00,0:00:02.50,Default,민수,0,0,0,,{\an8}안녕, 지수야
+Dialogue: 0,0:00:03.00,0:00:04.00,Default,,0,0,0,,{\k20}사{\k30}랑{\k25}해
+Comment: 0,0:00:03.00,0:00:04.00,Default,,0,0,0,,번역 메모
+Dialogue: 0,0:00:05.00,0:00:06.00,Thoughts,,0,0,0,,정말일까?\N{\i0}아니야
+Dialogue: 0,0:00:07.00,0:00:08.00,Default,,0,0,0,,{\pos(960,100)\i1}표지판
+Dialogue: 0,0:00:07.00,0:00:08.00,Default,,0,0,0,,{\p1}m 0 0 l 100 0 100 100{\p0}
+`
+
+const ttmlFile = `<?xml version="1.0" encoding="UTF-8"?>
+<tt xmlns="http://www.w3.org/ns/ttml" xmlns:tts="http://www.w3.org/ns/ttml#styling" xmlns:ttp="http://www.w3.org/ns/ttml#parameter" xmlns:ttm="http://www.w3.org/ns/ttml#metadata" ttp:tickRate="10000000">
+  <head>
+    <metadata>
+      <ttm:agent xml:id="minsu" type="person"><ttm:name type="full">민수</ttm:name></ttm:agent>
+    </metadata>
+    <styling>
+      <style xml:id="italic" tts:fontStyle="italic"/>
+    </styling>
+    <layout>
+      <region xml:id="bottom" tts:origin="10% 80%" tts:extent="80% 20%" tts:displayAlign="after" tts:textAlign="center"/>
+      <region xml:id="top" tts:origin="10% 0%" tts:extent="80% 20%" tts:displayAlign="before" tts:textAlign="center"/>
+    </layout>
+  </head>
+  <body region="bottom">
+    <div begin="10s">
+      <p begin="10000000t" end="25000000t" ttm:agent="minsu">안녕,<br/>지수야</p>
+      <p begin="00:00:03.000" end="00:00:04.000"><span style="italic">속으로</span> 생각했다</p>
+      <p begin="00:00:05.000" end="00:00:06.000" style="italic" region="top">표지판</p>
+    </div>
+  </body>
+</tt>`
+
+const webVttFile = `WEBVTT - Episode 1
+
+NOTE translated by 지수
+
+STYLE
+::cue(.yellow) { color: yellow; }
+
+1
+00:00:01.000 --> 00:00:02.500
+<v 민수>안녕, 지수야</v>
+
+00:03.000 --> 00:04.000 line:0 align:center
+<i>표지판</i>
+
+00:00:05.000 --> 00:00:06.000
+<c.yellow>노란</c> 글씨 &amp; <00:00:05.500>노래
+`
+
+func TestParseSeparatesStylingFromText(t *testing.T) {
+	type caption struct {
+		text    []string
+		startMs int64
+		endMs   int64
+		style   *subtitles.CaptionStyle
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		expected []caption
+	}{
+		{
+			name: "ass",
+			file: assFile,
+			expected: []caption{
+				{[]string{"안녕, 지수야"}, 1000, 2500, &subtitles.CaptionStyle{Position: subtitles.PositionTop, Speaker: "민수", Style: "Default"}},
+				{[]string{"사랑해"}, 3000, 4000, &subtitles.CaptionStyle{Position: subtitles.PositionBottom, Style: "Default"}},
+				{[]string{"정말일까?", "아니야"}, 5000, 6000, &subtitles.CaptionStyle{Position: subtitles.PositionBottom, Style: "Thoughts"}},
+				{[]string{"표지판"}, 7000, 8000, &subtitles.CaptionStyle{Position: subtitles.PositionTop, Italic: true, Style: "Default"}},
+			},
+		},
+		{
+			name: "ttml",
+			file: ttmlFile,
+			expected: []caption{
+				{[]string{"안녕,", "지수야"}, 11000, 12500, &subtitles.CaptionStyle{Position: subtitles.PositionBottom, Speaker: "민수"}},
+				{[]string{"속으로 생각했다"}, 13000, 14000, &subtitles.CaptionStyle{Position: subtitles.PositionBottom}},
+				{[]string{"표지판"}, 15000, 16000, &subtitles.CaptionStyle{Position: subtitles.PositionTop, Italic: true, Style: "italic"}},
+			},
+		},
+		{
+			name: "webvtt",
+			file: webVttFile,
+			expected: []caption{
+				{[]string{"안녕, 지수야"}, 1000, 2500, &subtitles.CaptionStyle{Speaker: "민수"}},
+				{[]string{"표지판"}, 3000, 4000, &subtitles.CaptionStyle{Position: subtitles.PositionTop, Italic: true}},
+				{[]string{"노란 글씨 & 노래"}, 5000, 6000, &subtitles.CaptionStyle{Style: "yellow"}},
+			},
+		},
+		{
+			name: "srt",
+			file: "1\n00:00:01,000 --> 00:00:02,000\n{\\an8}<i>표지판</i>\n\n2\n00:00:03,000 --> 00:00:04,000\n<font color=\"#ffff00\">안녕</font>\n",
+			expected: []caption{
+				{[]string{"표지판"}, 1000, 2000, &subtitles.CaptionStyle{Position: subtitles.PositionTop, Italic: true}},
+				{[]string{"안녕"}, 3000, 4000, nil},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracks, err := subtitles.Parse([]byte(test.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tracks) != 1 {
+				t.Fatalf("Expected a single track, got %d", len(tracks))
+			}
+
+			var captions []caption
+			for i, parsed := range tracks[0].Captions {
+				if parsed.Seq != i+1 {
+					t.Errorf("Expected caption %d to be numbered %d, got %d", i, i+1, parsed.Seq)
+				}
+				captions = append(captions, caption{parsed.Text, parsed.StartMs, parsed.EndMs, parsed.Style})
+			}
+
+			if !reflect.DeepEqual(captions, test.expected) {
+				t.Errorf("Expected captions %+v, got %+v", test.expected, captions)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package subtitles
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"errors"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrFailedToRenormalize = errors.New("failed to renormalize subtitles")
+)
+
+// Renormalizer normalizes the subtitles stored before migration 000017, whose
+// text is still the text as shown on screen, and indexes them again. Exporting
+// their tracks again would keep them as they are, subtitles are only inserted
+// once.
+type Renormalizer struct {
+	SubtitlesRepository *SubtitlesRepository
+	SearchIndex         SearchIndex
+	Normalizer          *Normalizer
+	Logger              zerolog.Logger
+	Tracer              trace.Tracer
+}
+
+func NewRenormalizer(dependencies *app.Dependencies, ctx context.Context) (*Renormalizer, error) {
+	searchIndex, err := NewSearchIndex(dependencies, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Renormalizer{
+		SubtitlesRepository: NewSubtitlesRepository(dependencies),
+		SearchIndex:         searchIndex,
+		Normalizer:          NewNormalizer(dependencies.Config.Normalization),
+		Logger:              dependencies.Logger,
+		Tracer:              dependencies.Tracer,
+	}, nil
+}
+
+// Run renormalizes the subtitles batchSize at a time and returns how many it
+// renormalized. It can be stopped and run again, it picks up the subtitles
+// that are left.
+func (r *Renormalizer) Run(batchSize int, ctx context.Context) (int, error) {
+	ctx, span := r.Tracer.Start(ctx, "subtitles.renormalizer.run")
+	defer span.End()
+
+	renormalized := 0
+	afterId := ""
+	for {
+		batch, err := r.SubtitlesRepository.GetUnnormalized(afterId, batchSize, ctx)
+		if err != nil {
+			return renormalized, errors.Join(err, ErrFailedToRenormalize)
+		}
+		if len(batch) == 0 {
+			return renormalized, nil
+		}
+
+		for _, subtitle := range batch {
+			err := r.renormalize(subtitle, ctx)
+			if err != nil {
+				return renormalized, errors.Join(err, ErrFailedToRenormalize)
+			}
+			renormalized++
+		}
+
+		// Captions without any text stay unnormalized, the batches continue
+		// after them instead of fetching them again.
+		afterId = batch[len(batch)-1].Id
+		r.Logger.Info().Int("renormalized", renormalized).Msg("Renormalized subtitles")
+	}
+}
+
+func (r *Renormalizer) renormalize(subtitle *DbSubtitle, ctx context.Context) error {
+	style, err := subtitle.GetStyle()
+	if err != nil {
+		return err
+	}
+
+	caption := &Caption{
+		Seq:     subtitle.Sequence,
+		StartMs: subtitle.StartMs,
+		EndMs:   subtitle.EndMs,
+		Text:    strings.Split(subtitle.RawText, "\n"),
+		Style:   style,
+	}
+	err = subtitle.setNormalized(r.Normalizer.Normalize(caption))
+	if err != nil {
+		return err
+	}
+
+	err = r.SubtitlesRepository.UpdateNormalization(subtitle, ctx)
+	if err != nil {
+		return err
+	}
+
+	// Sound descriptions were indexed with the rest before.
+	if subtitle.NonSpeech {
+		return r.SearchIndex.Delete(subtitle.Id, ctx)
+	}
+
+	return r.SearchIndex.Insert(NewFtsSubtitle(subtitle.Id, subtitle.VideoId, subtitle.Sequence, subtitle.Text), ctx)
+}
@@ -0,0 +1,98 @@
+package subtitles_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/config"
+	"dewarrum/vocabulary-leveling/internal/subtitles"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// recordingIndex remembers what was indexed and deleted.
+type recordingIndex struct {
+	discardIndex
+	inserted []*subtitles.FtsSubtitle
+	deleted  []string
+}
+
+func (i *recordingIndex) Insert(subtitle *subtitles.FtsSubtitle, ctx context.Context) error {
+	i.inserted = append(i.inserted, subtitle)
+	return nil
+}
+
+func (i *recordingIndex) Delete(id string, ctx context.Context) error {
+	i.deleted = append(i.deleted, id)
+	return nil
+}
+
+func TestRenormalizerNormalizesSubtitlesStoredBefore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	dependencies := &app.Dependencies{
+		Postgres: sqlx.NewDb(db, "postgres"),
+		Logger:   zerolog.Nop(),
+		Tracer:   noop.NewTracerProvider().Tracer(""),
+	}
+	index := &recordingIndex{}
+	renormalizer := &subtitles.Renormalizer{
+		SubtitlesRepository: subtitles.NewSubtitlesRepository(dependencies),
+		SearchIndex:         index,
+		Normalizer:          subtitles.NewNormalizer(config.Default().Normalization),
+		Logger:              dependencies.Logger,
+		Tracer:              dependencies.Tracer,
+	}
+
+	videoId := uuid.New()
+	columns := []string{"id", "video_id", "track_id", "sequence", "start_ms", "end_ms", "text", "raw_text", "utterances", "non_speech", "style", "created_at", "thumbnail_location"}
+	legacy := func(sequence int, text string) []driver.Value {
+		id := fmt.Sprintf("%s/%d", videoId, sequence)
+		return []driver.Value{id, videoId, nil, sequence, 0, 1000, text, text, nil, false, nil, time.Now(), nil}
+	}
+
+	mock.ExpectQuery("SELECT .* FROM subtitles WHERE utterances IS NULL AND NOT non_speech AND id > \\$1").
+		WithArgs("", 2).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(legacy(1, "민수: 안녕\n- 잘 가")...).
+			AddRow(legacy(2, "[음악]")...))
+	mock.ExpectExec("UPDATE subtitles SET text").
+		WithArgs("안녕\n잘 가", []byte(`[{"speaker":"민수","text":"안녕"},{"text":"잘 가"}]`), false, fmt.Sprintf("%s/1", videoId)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE subtitles SET text").
+		WithArgs("", nil, true, fmt.Sprintf("%s/2", videoId)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT .* FROM subtitles WHERE utterances IS NULL AND NOT non_speech AND id > \\$1").
+		WithArgs(fmt.Sprintf("%s/2", videoId), 2).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	renormalized, err := renormalizer.Run(2, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if renormalized != 2 {
+		t.Errorf("Expected 2 subtitles to be renormalized, but got %d", renormalized)
+	}
+	if len(index.inserted) != 1 || index.inserted[0].Text != "안녕\n잘 가" {
+		t.Errorf("Expected the normalized text to be indexed, but got %v", index.inserted)
+	}
+	// The sound description was indexed as it was shown before.
+	if len(index.deleted) != 1 || index.deleted[0] != fmt.Sprintf("%s/2", videoId) {
+		t.Errorf("Expected the non-speech subtitle to be removed from the index, but got %v", index.deleted)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -3,12 +3,15 @@ package subtitles
 import (
 	"context"
 	"dewarrum/vocabulary-leveling/internal/app"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/types"
 	"github.com/rs/zerolog"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -17,28 +20,105 @@ var (
 	ErrFailedToInsertSubtitle  = errors.New("failed to insert subtitle")
 	ErrFailedToGetAffectedRows = errors.New("failed to get affected rows")
 	ErrFailedToGetSubtitle     = errors.New("failed to get subtitle")
+	ErrFailedToUpdateSubtitle  = errors.New("failed to update subtitle")
+)
+
+const (
+	// subtitleColumns leaves out search_vector, which only the Postgres search
+	// index reads.
+	subtitleColumns = "id, video_id, track_id, sequence, start_ms, end_ms, text, raw_text, utterances, non_speech, style, created_at, thumbnail_location"
 )
 
 type DbSubtitle struct {
-	Id        string    `db:"id"`
-	VideoId   uuid.UUID `db:"video_id"`
-	Sequence  int       `db:"sequence"`
-	StartMs   int64     `db:"start_ms"`
-	EndMs     int64     `db:"end_ms"`
-	Text      string    `db:"text"`
-	CreatedAt time.Time `db:"created_at"`
+	Id                string             `db:"id"`
+	VideoId           uuid.UUID          `db:"video_id"`
+	TrackId           *uuid.UUID         `db:"track_id"`
+	Sequence          int                `db:"sequence"`
+	StartMs           int64              `db:"start_ms"`
+	EndMs             int64              `db:"end_ms"`
+	Text              string             `db:"text"`
+	RawText           string             `db:"raw_text"`
+	Utterances        types.NullJSONText `db:"utterances"`
+	NonSpeech         bool               `db:"non_speech"`
+	Style             types.NullJSONText `db:"style"`
+	CreatedAt         time.Time          `db:"created_at"`
+	ThumbnailLocation *string            `db:"thumbnail_location"`
 }
 
-func newDbSubtitle(videoId uuid.UUID, text string, sequence int, startMs int64, endMs int64) *DbSubtitle {
-	return &DbSubtitle{
-		Id:        fmt.Sprintf("%s/%d", videoId, sequence),
+// newDbSubtitle stores the normalized text of a caption as its text, which
+// search indexes, next to the text as shown on screen.
+func newDbSubtitle(videoId uuid.UUID, trackId uuid.UUID, caption *Caption, normalized *NormalizedCaption) (*DbSubtitle, error) {
+	subtitle := &DbSubtitle{
+		Id:        fmt.Sprintf("%s/%d", videoId, caption.Seq),
 		VideoId:   videoId,
-		Sequence:  sequence,
-		StartMs:   startMs,
-		EndMs:     endMs,
-		Text:      text,
+		Sequence:  caption.Seq,
+		StartMs:   caption.StartMs,
+		EndMs:     caption.EndMs,
+		RawText:   strings.Join(caption.Text, "\n"),
 		CreatedAt: time.Now().In(time.UTC),
 	}
+
+	if trackId != uuid.Nil {
+		subtitle.Id = fmt.Sprintf("%s/%d", trackId, caption.Seq)
+		subtitle.TrackId = &trackId
+	}
+
+	err := subtitle.setNormalized(normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	if style := caption.Style; style != nil {
+		styleJson, err := json.Marshal(style)
+		if err != nil {
+			return nil, err
+		}
+		subtitle.Style = types.NullJSONText{JSONText: styleJson, Valid: true}
+	}
+
+	return subtitle, nil
+}
+
+func (s *DbSubtitle) setNormalized(normalized *NormalizedCaption) error {
+	s.Text = normalized.Text
+	s.NonSpeech = normalized.NonSpeech
+	s.Utterances = types.NullJSONText{}
+
+	if len(normalized.Utterances) > 0 {
+		utterancesJson, err := json.Marshal(normalized.Utterances)
+		if err != nil {
+			return err
+		}
+		s.Utterances = types.NullJSONText{JSONText: utterancesJson, Valid: true}
+	}
+
+	return nil
+}
+
+// GetStyle returns the styling the subtitle file gave the caption, or nil when
+// it had none.
+func (s *DbSubtitle) GetStyle() (*CaptionStyle, error) {
+	if !s.Style.Valid {
+		return nil, nil
+	}
+
+	var style CaptionStyle
+	err := json.Unmarshal(s.Style.JSONText, &style)
+
+	return &style, err
+}
+
+// GetUtterances returns what each speaker says in the subtitle, or nil when
+// nothing is said.
+func (s *DbSubtitle) GetUtterances() ([]*Utterance, error) {
+	if !s.Utterances.Valid {
+		return nil, nil
+	}
+
+	var utterances []*Utterance
+	err := json.Unmarshal(s.Utterances.JSONText, &utterances)
+
+	return utterances, err
 }
 
 type SubtitlesRepository struct {
@@ -48,9 +128,11 @@ type SubtitlesRepository struct {
 }
 
 func (r *SubtitlesRepository) Insert(subtitle *DbSubtitle, context context.Context) (*DbSubtitle, error) {
+	context, span := r.tracer.Start(context, "subtitles.repository.insert")
+	defer span.End()
 	r.logger.Debug().Str("videoId", subtitle.VideoId.String()).Int32("sequence", int32(subtitle.Sequence)).Msg("Inserting subtitle")
 
-	result, err := r.db.NamedExecContext(context, "INSERT INTO subtitles (id, video_id, sequence, start_ms, end_ms, text, created_at) VALUES (:id,:video_id, :sequence, :start_ms, :end_ms, :text, :created_at) ON CONFLICT (video_id, sequence) DO NOTHING", subtitle)
+	result, err := r.db.NamedExecContext(context, "INSERT INTO subtitles (id, video_id, track_id, sequence, start_ms, end_ms, text, raw_text, utterances, non_speech, style, created_at) VALUES (:id,:video_id, :track_id, :sequence, :start_ms, :end_ms, :text, :raw_text, :utterances, :non_speech, :style, :created_at) ON CONFLICT (id) DO NOTHING", subtitle)
 	if err != nil {
 		return nil, errors.Join(err, ErrFailedToInsertSubtitle)
 	}
@@ -64,7 +146,7 @@ func (r *SubtitlesRepository) Insert(subtitle *DbSubtitle, context context.Conte
 		return subtitle, nil
 	}
 
-	rows, err := r.db.QueryxContext(context, "SELECT * FROM subtitles WHERE video_id = $1 AND sequence = $2", subtitle.VideoId, subtitle.Sequence)
+	rows, err := r.db.QueryxContext(context, "SELECT "+subtitleColumns+" FROM subtitles WHERE id = $1", subtitle.Id)
 	if err != nil {
 		return nil, err
 	}
@@ -82,7 +164,7 @@ func (r *SubtitlesRepository) Insert(subtitle *DbSubtitle, context context.Conte
 func (r *SubtitlesRepository) GetManyByIds(ids []string, context context.Context) ([]*DbSubtitle, error) {
 	r.logger.Debug().Msg("Searching subtitles by ids")
 
-	query, args, err := sqlx.In("SELECT id, video_id, sequence, start_ms, end_ms, text, created_at FROM subtitles WHERE id IN (?)", ids)
+	query, args, err := sqlx.In("SELECT "+subtitleColumns+" FROM subtitles WHERE id IN (?)", ids)
 	if err != nil {
 		return nil, err
 	}
@@ -113,7 +195,7 @@ func (r *SubtitlesRepository) GetById(id string, ctx context.Context) (*DbSubtit
 	r.logger.Debug().Str("id", id).Msg("Searching subtitle by id")
 
 	var subtitle DbSubtitle
-	err := sqlx.GetContext(ctx, r.db, &subtitle, "SELECT * FROM subtitles WHERE id = $1 LIMIT 1", id)
+	err := sqlx.GetContext(ctx, r.db, &subtitle, "SELECT "+subtitleColumns+" FROM subtitles WHERE id = $1 LIMIT 1", id)
 	if err != nil {
 		return nil, errors.Join(err, ErrFailedToGetSubtitle)
 	}
@@ -121,6 +203,65 @@ func (r *SubtitlesRepository) GetById(id string, ctx context.Context) (*DbSubtit
 	return &subtitle, nil
 }
 
+func (r *SubtitlesRepository) UpdateThumbnailLocation(id string, thumbnailLocation string, ctx context.Context) error {
+	ctx, span := r.tracer.Start(ctx, "subtitles.repository.updateThumbnailLocation")
+	defer span.End()
+	r.logger.Debug().Str("id", id).Msg("Updating subtitle thumbnail location")
+
+	_, err := r.db.ExecContext(ctx, "UPDATE subtitles SET thumbnail_location = $1 WHERE id = $2", thumbnailLocation, id)
+	if err != nil {
+		return errors.Join(err, ErrFailedToUpdateSubtitle)
+	}
+
+	return nil
+}
+
+// GetUnnormalized returns up to limit subtitles stored before their text was
+// normalized, ordered by id and starting after afterId. These have neither
+// utterances nor are they marked as non-speech.
+func (r *SubtitlesRepository) GetUnnormalized(afterId string, limit int, ctx context.Context) ([]*DbSubtitle, error) {
+	ctx, span := r.tracer.Start(ctx, "subtitles.repository.getUnnormalized")
+	defer span.End()
+	r.logger.Debug().Str("afterId", afterId).Int("limit", limit).Msg("Searching subtitles that are not normalized")
+
+	var subtitles []*DbSubtitle
+	err := sqlx.SelectContext(ctx, r.db, &subtitles, "SELECT "+subtitleColumns+" FROM subtitles WHERE utterances IS NULL AND NOT non_speech AND id > $1 ORDER BY id LIMIT $2", afterId, limit)
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToGetSubtitle)
+	}
+
+	return subtitles, nil
+}
+
+// UpdateNormalization stores the normalized text, the utterances and whether
+// the subtitle is non-speech.
+func (r *SubtitlesRepository) UpdateNormalization(subtitle *DbSubtitle, ctx context.Context) error {
+	ctx, span := r.tracer.Start(ctx, "subtitles.repository.updateNormalization")
+	defer span.End()
+	r.logger.Debug().Str("id", subtitle.Id).Msg("Updating subtitle normalization")
+
+	_, err := r.db.NamedExecContext(ctx, "UPDATE subtitles SET text = :text, utterances = :utterances, non_speech = :non_speech WHERE id = :id", subtitle)
+	if err != nil {
+		return errors.Join(err, ErrFailedToUpdateSubtitle)
+	}
+
+	return nil
+}
+
+func (r *SubtitlesRepository) CountByVideoId(videoId uuid.UUID, ctx context.Context) (int64, error) {
+	ctx, span := r.tracer.Start(ctx, "subtitles.repository.countByVideoId")
+	defer span.End()
+	r.logger.Debug().Str("videoId", videoId.String()).Msg("Counting subtitles of video")
+
+	var count int64
+	err := r.db.GetContext(ctx, &count, "SELECT COUNT(*) FROM subtitles WHERE video_id = $1", videoId)
+	if err != nil {
+		return 0, errors.Join(err, ErrFailedToGetSubtitle)
+	}
+
+	return count, nil
+}
+
 func NewSubtitlesRepository(dependencies *app.Dependencies) *SubtitlesRepository {
 	return &SubtitlesRepository{
 		db:     dependencies.Postgres,
@@ -0,0 +1,217 @@
+package subtitles
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// samiTrailingDuration is how long the last caption of a class is shown, as
+// nothing clears it.
+const samiTrailingDuration = 5 * time.Second
+
+var (
+	ErrNoSamiCaptions = errors.New("sami file has no captions")
+
+	samiRegex      = regexp.MustCompile(`(?i)<sami[\s>]`)
+	styleRegex     = regexp.MustCompile(`(?is)<style[^>]*>(.*?)</style>`)
+	classRuleRegex = regexp.MustCompile(`(?s)\.([\w-]+)\s*\{([^}]*)\}`)
+	langRegex      = regexp.MustCompile(`(?i)\blang\s*:\s*([\w-]+)`)
+	bodyRegex      = regexp.MustCompile(`(?is)<body[^>]*>(.*?)(?:</body>|$)`)
+	commentRegex   = regexp.MustCompile(`(?s)<!--.*?-->`)
+	syncRegex      = regexp.MustCompile(`(?i)<sync\b([^>]*)>`)
+	startRegex     = regexp.MustCompile(`(?i)\bstart\s*=\s*["']?(-?\d+)`)
+	paragraphRegex = regexp.MustCompile(`(?i)<p\b([^>]*)>`)
+	classRegex     = regexp.MustCompile(`(?i)\bclass\s*=\s*["']?([\w-]+)`)
+
+	// samiClassLanguages covers the classes files use without declaring
+	// their language in the style sheet.
+	samiClassLanguages = map[string]string{
+		"KRCC":   "ko",
+		"KOCC":   "ko",
+		"ENCC":   "en",
+		"ENUSCC": "en",
+		"EGCC":   "en",
+		"JPCC":   "ja",
+		"CNCC":   "zh",
+	}
+)
+
+type samiCue struct {
+	startMs int64
+	content string
+}
+
+// LooksLikeSami tells whether text is a SAMI (.smi) file.
+func LooksLikeSami(text []byte) bool {
+	return samiRegex.Match(text)
+}
+
+// ParseSami splits a SAMI file into a track for every language class, such as
+// KRCC and ENCC, in the order the style sheet declares them. Classes it does
+// not declare follow in the order they appear in. A paragraph is shown until
+// the next one of its class, usually a blank one clearing it.
+func ParseSami(text []byte) ([]*ParsedTrack, error) {
+	source := string(text)
+
+	var classes []string
+	languages := make(map[string]*string)
+	if style := styleRegex.FindStringSubmatch(source); style != nil {
+		for _, rule := range classRuleRegex.FindAllStringSubmatch(style[1], -1) {
+			class := strings.ToUpper(rule[1])
+			if _, ok := languages[class]; ok {
+				continue
+			}
+
+			classes = append(classes, class)
+			languages[class] = samiLanguage(class, langRegex.FindStringSubmatch(rule[2]))
+		}
+	}
+
+	body := source
+	if match := bodyRegex.FindStringSubmatch(source); match != nil {
+		body = match[1]
+	}
+	body = commentRegex.ReplaceAllString(body, "")
+
+	cues := make(map[string][]samiCue)
+	syncs := syncRegex.FindAllStringSubmatchIndex(body, -1)
+	for i, sync := range syncs {
+		start := startRegex.FindStringSubmatch(body[sync[2]:sync[3]])
+		if start == nil {
+			continue
+		}
+		startMs, err := strconv.ParseInt(start[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		end := len(body)
+		if i+1 < len(syncs) {
+			end = syncs[i+1][0]
+		}
+
+		for _, paragraph := range samiParagraphs(body[sync[1]:end]) {
+			class := paragraph.class
+			if class == "" && len(classes) == 1 {
+				class = classes[0]
+			}
+			if _, ok := languages[class]; !ok {
+				classes = append(classes, class)
+				languages[class] = samiLanguage(class, nil)
+			}
+
+			cues[class] = append(cues[class], samiCue{startMs: startMs, content: paragraph.content})
+		}
+	}
+
+	var tracks []*ParsedTrack
+	for _, class := range classes {
+		captions := samiCaptions(cues[class])
+		if len(captions) == 0 {
+			continue
+		}
+
+		tracks = append(tracks, &ParsedTrack{
+			Name:     class,
+			Language: languages[class],
+			Captions: captions,
+		})
+	}
+
+	if len(tracks) == 0 {
+		return nil, ErrNoSamiCaptions
+	}
+
+	return tracks, nil
+}
+
+type samiParagraph struct {
+	class   string
+	content string
+}
+
+// samiParagraphs returns the content of the paragraphs of a sync by their
+// upper case class, in the order the classes first appear in. Text outside of
+// any paragraph has no class.
+func samiParagraphs(sync string) []samiParagraph {
+	matches := paragraphRegex.FindAllStringSubmatchIndex(sync, -1)
+	if len(matches) == 0 {
+		return []samiParagraph{{content: sync}}
+	}
+
+	var paragraphs []samiParagraph
+	for i, match := range matches {
+		class := ""
+		if attribute := classRegex.FindStringSubmatch(sync[match[2]:match[3]]); attribute != nil {
+			class = strings.ToUpper(attribute[1])
+		}
+
+		end := len(sync)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+
+		j := slices.IndexFunc(paragraphs, func(paragraph samiParagraph) bool { return paragraph.class == class })
+		if j < 0 {
+			paragraphs = append(paragraphs, samiParagraph{class: class})
+			j = len(paragraphs) - 1
+		}
+		paragraphs[j].content += sync[match[1]:end]
+	}
+
+	return paragraphs
+}
+
+func samiCaptions(cues []samiCue) []*Caption {
+	sort.SliceStable(cues, func(i, j int) bool { return cues[i].startMs < cues[j].startMs })
+
+	var captions []*Caption
+	for i, cue := range cues {
+		// Line breaks in the source are white space, only <br> breaks lines.
+		lines, style := cleanInlineMarkup([]string{strings.ReplaceAll(cue.content, "\n", " ")})
+
+		endMs := cue.startMs + samiTrailingDuration.Milliseconds()
+		if i+1 < len(cues) {
+			endMs = cues[i+1].startMs
+		}
+
+		captions = append(captions, &Caption{
+			StartMs: cue.startMs,
+			EndMs:   endMs,
+			Text:    lines,
+			Style:   style,
+		})
+	}
+
+	return sequenced(captions)
+}
+
+// samiLanguage is the ISO 639-2 code of a class, the form ffprobe reports the
+// languages of embedded tracks in, from its lang property or its well-known
+// name.
+func samiLanguage(class string, lang []string) *string {
+	tag := samiClassLanguages[class]
+	if lang != nil {
+		tag = lang[1]
+	}
+
+	parsed, err := language.Parse(tag)
+	if err != nil {
+		return nil
+	}
+
+	base, confidence := parsed.Base()
+	if confidence == language.No {
+		return nil
+	}
+
+	code := base.ISO3()
+	return &code
+}
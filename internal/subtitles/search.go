@@ -0,0 +1,107 @@
+package subtitles
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/config"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	searchResultLimit = 10
+)
+
+var (
+	ErrFailedToCreateIndex        = errors.New("failed to create index")
+	ErrFailedToInsert             = errors.New("failed to insert")
+	ErrFailedToDelete             = errors.New("failed to delete")
+	ErrFailedToSearch             = errors.New("failed to search")
+	ErrFailedToDeserialize        = errors.New("failed to deserialize")
+	ErrUnsupportedSearchBackend   = errors.New("unsupported search backend")
+	ErrElasticsearchNotConfigured = errors.New("elasticsearch client is not configured")
+)
+
+type FtsSubtitle struct {
+	Id       string    `json:"id"`
+	VideoId  uuid.UUID `json:"video_id"`
+	Sequence int       `json:"sequence"`
+	Text     string    `json:"text"`
+}
+
+func NewFtsSubtitle(id string, videoId uuid.UUID, sequence int, text string) *FtsSubtitle {
+	return &FtsSubtitle{
+		Id:       id,
+		VideoId:  videoId,
+		Sequence: sequence,
+		Text:     text,
+	}
+}
+
+// SearchIndex finds subtitles by their text. Search returns at most
+// searchResultLimit subtitles, best matches first.
+type SearchIndex interface {
+	Insert(subtitle *FtsSubtitle, ctx context.Context) error
+	// Delete removes a subtitle from the index, subtitles that are not in it
+	// are ignored.
+	Delete(id string, ctx context.Context) error
+	Search(queryText string, ctx context.Context) ([]*FtsSubtitle, error)
+}
+
+func NewSearchIndex(dependencies *app.Dependencies, ctx context.Context) (SearchIndex, error) {
+	var index SearchIndex
+	switch dependencies.Config.Search.Backend {
+	case config.SearchBackendElasticsearch:
+		if dependencies.ElasticsearchClient == nil {
+			return nil, ErrElasticsearchNotConfigured
+		}
+
+		elasticsearchIndex, err := NewElasticsearchIndex(dependencies.ElasticsearchClient, ctx)
+		if err != nil {
+			return nil, err
+		}
+		index = elasticsearchIndex
+	case config.SearchBackendPostgres:
+		index = NewPostgresIndex(dependencies)
+	default:
+		return nil, ErrUnsupportedSearchBackend
+	}
+
+	return newMeteredSearchIndex(index, dependencies.Config.Search.Backend, dependencies.Meter)
+}
+
+// meteredSearchIndex records how long searches take on the index it wraps.
+type meteredSearchIndex struct {
+	SearchIndex
+	backend        attribute.KeyValue
+	searchDuration metric.Float64Histogram
+}
+
+func newMeteredSearchIndex(index SearchIndex, backend string, meter metric.Meter) (*meteredSearchIndex, error) {
+	searchDuration, err := meter.Float64Histogram(
+		"subtitles.search.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of subtitle searches"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &meteredSearchIndex{
+		SearchIndex:    index,
+		backend:        attribute.String("backend", backend),
+		searchDuration: searchDuration,
+	}, nil
+}
+
+func (i *meteredSearchIndex) Search(queryText string, ctx context.Context) ([]*FtsSubtitle, error) {
+	start := time.Now()
+	subtitles, err := i.SearchIndex.Search(queryText, ctx)
+	i.searchDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(i.backend, attribute.Bool("error", err != nil)))
+
+	return subtitles, err
+}
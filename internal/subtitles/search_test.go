@@ -0,0 +1,217 @@
+package subtitles_test
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/config"
+	"dewarrum/vocabulary-leveling/internal/subtitles"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/rs/zerolog"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// searchBackend is a search index under test together with a way to store the
+// subtitles it should find.
+type searchBackend struct {
+	index subtitles.SearchIndex
+	seed  func(t *testing.T, subtitles []*subtitles.FtsSubtitle)
+}
+
+// The contract runs against every backend configured through TEST_POSTGRES_URL
+// and TEST_ELASTICSEARCH_URL and is skipped for the others.
+func TestPostgresIndexSearchContract(t *testing.T) {
+	testSearchContract(t, newPostgresBackend(t))
+}
+
+func TestElasticsearchIndexSearchContract(t *testing.T) {
+	testSearchContract(t, newElasticsearchBackend(t))
+}
+
+func testSearchContract(t *testing.T, backend *searchBackend) {
+	videoId := uuid.New()
+	seeded := []*subtitles.FtsSubtitle{
+		subtitles.NewFtsSubtitle(fmt.Sprintf("%s/1", videoId), videoId, 1, "안녕하세요, 여러분"),
+		subtitles.NewFtsSubtitle(fmt.Sprintf("%s/2", videoId), videoId, 2, "정말 사랑해요"),
+		subtitles.NewFtsSubtitle(fmt.Sprintf("%s/3", videoId), videoId, 3, "Hello world"),
+	}
+	backend.seed(t, seeded)
+
+	cases := []struct {
+		query    string
+		expected []int
+	}{
+		{query: "안녕하세요", expected: []int{1}},
+		{query: "사랑", expected: []int{2}},
+		{query: "hello", expected: []int{3}},
+		{query: "고양이", expected: nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.query, func(t *testing.T) {
+			found, err := backend.index.Search(c.query, context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Other tests and earlier runs share the index, only the subtitles
+			// seeded here are compared.
+			var sequences []int
+			for _, subtitle := range found {
+				if subtitle.VideoId == videoId {
+					sequences = append(sequences, subtitle.Sequence)
+				}
+			}
+
+			if fmt.Sprint(sequences) != fmt.Sprint(c.expected) {
+				t.Errorf("Expected sequences %v, but got %v", c.expected, sequences)
+			}
+		})
+	}
+}
+
+func newPostgresBackend(t *testing.T) *searchBackend {
+	db := newTestDatabase(t)
+
+	dependencies := &app.Dependencies{
+		Postgres: db,
+		Config:   &config.Config{Search: config.Search{Backend: config.SearchBackendPostgres}},
+		Logger:   zerolog.Nop(),
+		Tracer:   noop.NewTracerProvider().Tracer(""),
+		Meter:    metricnoop.NewMeterProvider().Meter(""),
+	}
+
+	index, err := subtitles.NewSearchIndex(dependencies, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &searchBackend{
+		index: index,
+		seed: func(t *testing.T, seeded []*subtitles.FtsSubtitle) {
+			for _, subtitle := range seeded {
+				_, err := db.Exec("INSERT INTO subtitles (id, video_id, sequence, start_ms, end_ms, text, raw_text, created_at) VALUES ($1, $2, $3, 0, 0, $4, $4, $5)",
+					subtitle.Id, subtitle.VideoId, subtitle.Sequence, subtitle.Text, time.Now().In(time.UTC))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				err = index.Insert(subtitle, context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			t.Cleanup(func() {
+				db.Exec("DELETE FROM subtitles WHERE video_id = $1", seeded[0].VideoId)
+			})
+		},
+	}
+}
+
+// newTestDatabase connects to TEST_POSTGRES_URL and migrates it, or skips the
+// test when it is not set.
+func newTestDatabase(t *testing.T) *sqlx.DB {
+	url := os.Getenv("TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("TEST_POSTGRES_URL is not set")
+	}
+
+	db, err := sqlx.Connect("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	driver, err := postgres.WithInstance(db.DB, &postgres.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := migrate.NewWithDatabaseInstance("file://../../db/migrations", "vocabulary-leveling", driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Up()
+	if err != nil && err != migrate.ErrNoChange {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func newElasticsearchBackend(t *testing.T) *searchBackend {
+	url := os.Getenv("TEST_ELASTICSEARCH_URL")
+	if url == "" {
+		t.Skip("TEST_ELASTICSEARCH_URL is not set")
+	}
+
+	client, err := elasticsearch.NewTypedClient(elasticsearch.Config{
+		Addresses: []string{url},
+		Username:  os.Getenv("TEST_ELASTICSEARCH_USERNAME"),
+		Password:  os.Getenv("TEST_ELASTICSEARCH_PASSWORD"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dependencies := &app.Dependencies{
+		ElasticsearchClient: client,
+		Config:              &config.Config{Search: config.Search{Backend: config.SearchBackendElasticsearch}},
+		Logger:              zerolog.Nop(),
+		Tracer:              noop.NewTracerProvider().Tracer(""),
+		Meter:               metricnoop.NewMeterProvider().Meter(""),
+	}
+
+	index, err := subtitles.NewSearchIndex(dependencies, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &searchBackend{
+		index: index,
+		seed: func(t *testing.T, seeded []*subtitles.FtsSubtitle) {
+			for _, subtitle := range seeded {
+				err := index.Insert(subtitle, context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// Documents only become searchable once the index is refreshed.
+			_, err := client.Indices.Refresh().Index(subtitles.IndexName).Do(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+		},
+	}
+}
+
+// The CI database is created with the C locale, which neither lowercases nor
+// counts as letters anything beyond ASCII.
+func TestSubtitlesSearchTermsDoNotDependOnTheDatabaseLocale(t *testing.T) {
+	db := newTestDatabase(t)
+
+	var terms string
+	err := db.Get(&terms, "SELECT subtitles_search_terms($1)", "ÉCOLE, Привет! 안녕하세요")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "école привет 안녕 녕하 하세 세요"
+	if terms != expected {
+		t.Errorf("Expected terms to be %q, but got %q", expected, terms)
+	}
+}
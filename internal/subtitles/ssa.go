@@ -0,0 +1,277 @@
+package subtitles
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidSsaTime = errors.New("invalid ssa time")
+	ErrNoSsaEvents    = errors.New("ssa file has no dialogue")
+
+	ssaRegex           = regexp.MustCompile(`(?im)^\s*\[(script info|events)\]`)
+	ssaOverrideRegex   = regexp.MustCompile(`\{[^}]*\}`)
+	ssaLineBreakRegex  = regexp.MustCompile(`\\[Nnh]`)
+	defaultEventFormat = []string{"layer", "start", "end", "style", "name", "marginl", "marginr", "marginv", "effect", "text"}
+)
+
+// ssaPlayRes is the script resolution ASS assumes when the script does not
+// declare one.
+const (
+	ssaPlayResX = 384
+	ssaPlayResY = 288
+)
+
+type ssaScript struct {
+	playResX    float64
+	playResY    float64
+	legacy      bool
+	styleFormat []string
+	eventFormat []string
+	styles      map[string]overrides
+}
+
+// LooksLikeSsa tells whether text is an ASS or SSA script.
+func LooksLikeSsa(text []byte) bool {
+	return ssaRegex.Match(text)
+}
+
+// ParseSsa parses the dialogue of an ASS or SSA script. Override tags are
+// removed from the text, keeping the alignment, \pos placement and italics
+// they set along with the style and actor of the line. Vector drawings and
+// comments are dropped, and so are events whose times cannot be parsed.
+func ParseSsa(text []byte) (*ParsedTrack, error) {
+	script := &ssaScript{
+		eventFormat: defaultEventFormat,
+		styles:      make(map[string]overrides),
+	}
+
+	var captions []*Caption
+	var skipped []error
+	section := ""
+	for i, line := range strings.Split(string(text), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(line)
+			if section == "[v4 styles]" {
+				script.legacy = true
+			}
+			continue
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimLeft(value, " ")
+
+		switch section {
+		case "[script info]":
+			script.parseInfo(key, value)
+		case "[v4+ styles]", "[v4 styles]":
+			script.parseStyle(key, value)
+		case "[events]":
+			if key == "Format" {
+				script.eventFormat = ssaFormat(value)
+			} else if key == "Dialogue" {
+				caption, err := script.parseDialogue(value)
+				if err != nil {
+					skipped = append(skipped, fmt.Errorf("line %d: %w", i+1, err))
+					continue
+				}
+				captions = append(captions, caption)
+			}
+		}
+	}
+
+	captions = sequenced(captions)
+	if len(captions) == 0 {
+		return nil, ErrNoSsaEvents
+	}
+
+	return &ParsedTrack{Captions: captions, Skipped: skipped}, nil
+}
+
+func (s *ssaScript) parseInfo(key string, value string) {
+	resolution, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || resolution <= 0 {
+		return
+	}
+
+	switch key {
+	case "PlayResX":
+		s.playResX = resolution
+	case "PlayResY":
+		s.playResY = resolution
+	}
+}
+
+func (s *ssaScript) parseStyle(key string, value string) {
+	if key == "Format" {
+		s.styleFormat = ssaFormat(value)
+		return
+	}
+	if key != "Style" {
+		return
+	}
+
+	fields := ssaFields(value, s.styleFormat)
+	style := overrides{
+		italic:   fields["italic"] != "" && fields["italic"] != "0",
+		position: PositionBottom,
+	}
+	if s.legacy {
+		if position := legacyAlignment(fields["alignment"]); position != "" {
+			style.position = position
+		}
+	} else if n, err := strconv.Atoi(fields["alignment"]); err == nil && n >= 1 && n <= 9 {
+		style.position = numpadPositions[n]
+	}
+
+	s.styles[ssaStyleName(fields["name"])] = style
+}
+
+func (s *ssaScript) parseDialogue(value string) (*Caption, error) {
+	fields := ssaFields(value, s.eventFormat)
+	startMs, err := parseSsaTime(fields["start"])
+	if err != nil {
+		return nil, err
+	}
+	endMs, err := parseSsaTime(fields["end"])
+	if err != nil {
+		return nil, err
+	}
+
+	styleName := ssaStyleName(fields["style"])
+	defaults, ok := s.styles[styleName]
+	if !ok {
+		defaults = overrides{position: PositionBottom}
+	}
+
+	state := defaults
+	text := styledText{italic: state.italic}
+	source := fields["text"]
+	last := 0
+	for _, block := range ssaOverrideRegex.FindAllStringIndex(source, -1) {
+		if !state.drawing {
+			text.write(ssaText(source[last:block[0]]))
+		}
+		last = block[1]
+
+		state.apply(source[block[0]+1:block[1]-1], defaults)
+		text.italic = state.italic
+	}
+	if !state.drawing {
+		text.write(ssaText(source[last:]))
+	}
+
+	position := state.position
+	if state.placed {
+		position = s.positionAt(state.x, state.y)
+	}
+
+	return &Caption{
+		StartMs: startMs,
+		EndMs:   endMs,
+		Text:    text.lines(),
+		Style: &CaptionStyle{
+			Position: position,
+			Italic:   text.allItalic(),
+			Speaker:  strings.TrimSpace(fields["name"]),
+			Style:    styleName,
+		},
+	}, nil
+}
+
+// positionAt maps a \pos placement to the third of the screen it falls in.
+func (s *ssaScript) positionAt(x float64, y float64) string {
+	width, height := s.playResX, s.playResY
+	if width == 0 && height == 0 {
+		width, height = ssaPlayResX, ssaPlayResY
+	} else if width == 0 {
+		width = height * 4 / 3
+	} else if height == 0 {
+		height = width * 3 / 4
+	}
+
+	column := min(max(int(3*x/width), 0), 2)
+	row := min(max(int(3*y/height), 0), 2)
+	return numpadPositions[7-3*row+column]
+}
+
+// ssaText turns the escapes of ASS text into plain text. \N is a line break,
+// while \n only breaks lines in a wrapping style hardly used and \h is a non
+// breaking space.
+func ssaText(text string) string {
+	return ssaLineBreakRegex.ReplaceAllStringFunc(text, func(escape string) string {
+		if escape == `\N` {
+			return "\n"
+		}
+
+		return " "
+	})
+}
+
+func ssaFormat(value string) []string {
+	var format []string
+	for _, field := range strings.Split(value, ",") {
+		format = append(format, strings.ToLower(strings.TrimSpace(field)))
+	}
+
+	return format
+}
+
+// ssaFields maps the values of a line to the lowercase names of its format.
+// The last field, the text of a dialogue, may itself contain commas.
+func ssaFields(value string, format []string) map[string]string {
+	fields := make(map[string]string, len(format))
+	values := strings.SplitN(value, ",", len(format))
+	for i, name := range format {
+		if i >= len(values) {
+			break
+		}
+
+		if i == len(format)-1 {
+			fields[name] = values[i]
+		} else {
+			fields[name] = strings.TrimSpace(values[i])
+		}
+	}
+
+	return fields
+}
+
+func ssaStyleName(name string) string {
+	return strings.TrimPrefix(strings.TrimSpace(name), "*")
+}
+
+// parseSsaTime parses the H:MM:SS.cc timestamps of ASS into milliseconds.
+func parseSsaTime(value string) (int64, error) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) != 3 {
+		return 0, ErrInvalidSsaTime
+	}
+
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, errors.Join(err, ErrInvalidSsaTime)
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, errors.Join(err, ErrInvalidSsaTime)
+	}
+	seconds, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return 0, errors.Join(err, ErrInvalidSsaTime)
+	}
+
+	return int64(hours)*3600_000 + int64(minutes)*60_000 + int64(math.Round(seconds*1000)), nil
+}
@@ -0,0 +1,83 @@
+package subtitles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+func (e *Exporter) savePosterFrames(videoId uuid.UUID, dbSubtitles []*DbSubtitle, ctx context.Context) error {
+	ctx, span := e.Tracer.Start(ctx, "subtitles.exporter.savePosterFrames")
+	defer span.End()
+
+	// Every export gets a directory of its own, as the tracks of a video may
+	// be exported at the same time.
+	directory, err := os.MkdirTemp("", fmt.Sprintf("posters-%s-*", videoId))
+	if err != nil {
+		return errors.Join(err, errors.New("failed to create directory"))
+	}
+	defer os.RemoveAll(directory)
+
+	videoUrl, err := e.FileStorage.PresignVideo(videoId, ctx)
+	if err != nil {
+		return errors.Join(err, errors.New("failed to presign video"))
+	}
+
+	for _, dbSubtitle := range dbSubtitles {
+		err = e.savePosterFrame(dbSubtitle, videoUrl, directory, ctx)
+		if err != nil {
+			// An aborted export is requeued and takes the missing frames then.
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			e.Logger.Warn().Str("videoId", videoId.String()).Int32("sequence", int32(dbSubtitle.Sequence)).Err(err).Msg("Failed to save poster frame")
+		}
+	}
+
+	return nil
+}
+
+func (e *Exporter) savePosterFrame(dbSubtitle *DbSubtitle, videoUrl string, directory string, ctx context.Context) error {
+	midpointMs := (dbSubtitle.StartMs + dbSubtitle.EndMs) / 2
+	path := fmt.Sprintf("%s/%05d.jpg", directory, dbSubtitle.Sequence)
+
+	cmd := exec.CommandContext(
+		ctx,
+		"ffmpeg",
+		"-y",
+		"-ss", fmt.Sprintf("%d.%03d", midpointMs/1000, midpointMs%1000),
+		"-i", videoUrl,
+		"-frames:v", "1",
+		"-q:v", "3",
+		path)
+
+	err := cmd.Run()
+	if err != nil {
+		e.FfmpegFailures.Add(ctx, 1, metric.WithAttributes(attribute.String("operation", "posterFrame")))
+		return errors.Join(err, errors.New("failed to run ffmpeg"))
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.Join(err, errors.New("failed to open file"))
+	}
+	defer file.Close()
+
+	thumbnailLocation, err := e.FileStorage.UploadThumbnail(dbSubtitle.VideoId, dbSubtitle.Id, file, ctx)
+	if err != nil {
+		return errors.Join(err, errors.New("failed to upload thumbnail"))
+	}
+
+	err = e.SubtitlesRepository.UpdateThumbnailLocation(dbSubtitle.Id, thumbnailLocation, ctx)
+	if err != nil {
+		return errors.Join(err, errors.New("failed to save thumbnail location"))
+	}
+
+	return nil
+}
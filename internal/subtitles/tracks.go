@@ -0,0 +1,204 @@
+package subtitles
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TrackSourceUpload   = "upload"
+	TrackSourceEmbedded = "embedded"
+
+	TrackStatusQueued      = "queued"
+	TrackStatusReady       = "ready"
+	TrackStatusFailed      = "failed"
+	TrackStatusUnsupported = "unsupported"
+)
+
+var (
+	ErrTrackAlreadyExists  = errors.New("subtitle track already exists")
+	ErrFailedToInsertTrack = errors.New("failed to insert subtitle track")
+	ErrFailedToGetTracks   = errors.New("failed to get subtitle tracks")
+	ErrFailedToUpdateTrack = errors.New("failed to update subtitle track")
+)
+
+type DbSubtitleTrack struct {
+	Id          uuid.UUID `db:"id"`
+	VideoId     uuid.UUID `db:"video_id"`
+	Source      string    `db:"source"`
+	Status      string    `db:"status"`
+	Language    *string   `db:"language"`
+	StreamIndex *int      `db:"stream_index"`
+	Codec       *string   `db:"codec"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+func NewUploadedDbSubtitleTrack(videoId uuid.UUID, language *string) *DbSubtitleTrack {
+	return &DbSubtitleTrack{
+		Id:        uuid.New(),
+		VideoId:   videoId,
+		Source:    TrackSourceUpload,
+		Status:    TrackStatusQueued,
+		Language:  language,
+		CreatedAt: time.Now().In(time.UTC),
+	}
+}
+
+// NewSplitDbSubtitleTrack derives the id of the track from the uploaded track
+// and the class it was split off by, so that exporting the upload again finds
+// the same tracks.
+func NewSplitDbSubtitleTrack(videoId uuid.UUID, uploadedId uuid.UUID, class string, language *string) *DbSubtitleTrack {
+	track := NewUploadedDbSubtitleTrack(videoId, language)
+	track.Id = uuid.NewSHA1(uploadedId, []byte("split/"+class))
+
+	return track
+}
+
+// NewEmbeddedDbSubtitleTrack derives the id of the track from the video and the
+// stream, so that extracting the streams of a video again finds the same tracks.
+func NewEmbeddedDbSubtitleTrack(videoId uuid.UUID, streamIndex int, codec string, language *string, status string) *DbSubtitleTrack {
+	return &DbSubtitleTrack{
+		Id:          uuid.NewSHA1(videoId, []byte(fmt.Sprintf("embedded/%d", streamIndex))),
+		VideoId:     videoId,
+		Source:      TrackSourceEmbedded,
+		Status:      status,
+		Language:    language,
+		StreamIndex: &streamIndex,
+		Codec:       &codec,
+		CreatedAt:   time.Now().In(time.UTC),
+	}
+}
+
+type SubtitleTracksRepository struct {
+	db     *sqlx.DB
+	logger zerolog.Logger
+	tracer trace.Tracer
+}
+
+func NewSubtitleTracksRepository(dependencies *app.Dependencies) *SubtitleTracksRepository {
+	return &SubtitleTracksRepository{
+		db:     dependencies.Postgres,
+		logger: dependencies.Logger,
+		tracer: dependencies.Tracer,
+	}
+}
+
+func (r *SubtitleTracksRepository) Insert(track *DbSubtitleTrack, ctx context.Context) (*DbSubtitleTrack, error) {
+	ctx, span := r.tracer.Start(ctx, "subtitleTracks.repository.insert")
+	defer span.End()
+	r.logger.Debug().Str("videoId", track.VideoId.String()).Str("trackId", track.Id.String()).Msg("Inserting subtitle track")
+
+	_, err := r.db.NamedExecContext(ctx, "INSERT INTO subtitle_tracks (id, video_id, source, status, language, stream_index, codec, created_at) VALUES (:id, :video_id, :source, :status, :language, :stream_index, :codec, :created_at)", track)
+	if pgError, ok := err.(*pq.Error); ok && pgError.Code == "23505" {
+		return nil, ErrTrackAlreadyExists
+	}
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToInsertTrack)
+	}
+
+	return track, nil
+}
+
+// UpsertEmbedded inserts a track extracted from a stream of the video and
+// returns the track stored for that stream, which is the existing one when the
+// stream was extracted before.
+func (r *SubtitleTracksRepository) UpsertEmbedded(track *DbSubtitleTrack, ctx context.Context) (*DbSubtitleTrack, error) {
+	ctx, span := r.tracer.Start(ctx, "subtitleTracks.repository.upsertEmbedded")
+	defer span.End()
+	r.logger.Debug().Str("videoId", track.VideoId.String()).Str("trackId", track.Id.String()).Msg("Upserting embedded subtitle track")
+
+	return r.upsert("INSERT INTO subtitle_tracks (id, video_id, source, status, language, stream_index, codec, created_at) VALUES (:id, :video_id, :source, :status, :language, :stream_index, :codec, :created_at) ON CONFLICT (video_id, stream_index) WHERE source = 'embedded' DO UPDATE SET codec = EXCLUDED.codec RETURNING *", track, ctx)
+}
+
+// UpsertSplit inserts a track split off an uploaded file and returns the track
+// stored under its id, which is the existing one when the file was split
+// before.
+func (r *SubtitleTracksRepository) UpsertSplit(track *DbSubtitleTrack, ctx context.Context) (*DbSubtitleTrack, error) {
+	ctx, span := r.tracer.Start(ctx, "subtitleTracks.repository.upsertSplit")
+	defer span.End()
+	r.logger.Debug().Str("videoId", track.VideoId.String()).Str("trackId", track.Id.String()).Msg("Upserting split subtitle track")
+
+	return r.upsert("INSERT INTO subtitle_tracks (id, video_id, source, status, language, stream_index, codec, created_at) VALUES (:id, :video_id, :source, :status, :language, :stream_index, :codec, :created_at) ON CONFLICT (id) DO UPDATE SET id = EXCLUDED.id RETURNING *", track, ctx)
+}
+
+func (r *SubtitleTracksRepository) upsert(query string, track *DbSubtitleTrack, ctx context.Context) (*DbSubtitleTrack, error) {
+	rows, err := r.db.NamedQueryContext(ctx, query, track)
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToInsertTrack)
+	}
+	defer rows.Close()
+
+	var stored DbSubtitleTrack
+	if !rows.Next() {
+		return nil, errors.Join(rows.Err(), ErrFailedToInsertTrack)
+	}
+	err = rows.StructScan(&stored)
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToInsertTrack)
+	}
+
+	return &stored, nil
+}
+
+func (r *SubtitleTracksRepository) GetByVideoId(videoId uuid.UUID, ctx context.Context) ([]*DbSubtitleTrack, error) {
+	ctx, span := r.tracer.Start(ctx, "subtitleTracks.repository.getByVideoId")
+	defer span.End()
+	r.logger.Debug().Str("videoId", videoId.String()).Msg("Searching subtitle tracks by video id")
+
+	var tracks []*DbSubtitleTrack
+	err := r.db.SelectContext(ctx, &tracks, "SELECT * FROM subtitle_tracks WHERE video_id = $1 ORDER BY created_at", videoId)
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToGetTracks)
+	}
+
+	return tracks, nil
+}
+
+func (r *SubtitleTracksRepository) GetById(id uuid.UUID, ctx context.Context) (*DbSubtitleTrack, error) {
+	ctx, span := r.tracer.Start(ctx, "subtitleTracks.repository.getById")
+	defer span.End()
+	r.logger.Debug().Str("trackId", id.String()).Msg("Searching subtitle track by id")
+
+	var track DbSubtitleTrack
+	err := r.db.GetContext(ctx, &track, "SELECT * FROM subtitle_tracks WHERE id = $1 LIMIT 1", id)
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToGetTracks)
+	}
+
+	return &track, nil
+}
+
+func (r *SubtitleTracksRepository) UpdateLanguage(id uuid.UUID, language *string, ctx context.Context) error {
+	ctx, span := r.tracer.Start(ctx, "subtitleTracks.repository.updateLanguage")
+	defer span.End()
+	r.logger.Debug().Str("trackId", id.String()).Msg("Updating subtitle track language")
+
+	_, err := r.db.ExecContext(ctx, "UPDATE subtitle_tracks SET language = $1 WHERE id = $2", language, id)
+	if err != nil {
+		return errors.Join(err, ErrFailedToUpdateTrack)
+	}
+
+	return nil
+}
+
+func (r *SubtitleTracksRepository) UpdateStatus(id uuid.UUID, status string, ctx context.Context) error {
+	ctx, span := r.tracer.Start(ctx, "subtitleTracks.repository.updateStatus")
+	defer span.End()
+	r.logger.Debug().Str("trackId", id.String()).Str("status", status).Msg("Updating subtitle track status")
+
+	_, err := r.db.ExecContext(ctx, "UPDATE subtitle_tracks SET status = $1 WHERE id = $2", status, id)
+	if err != nil {
+		return errors.Join(err, ErrFailedToUpdateTrack)
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package subtitles_test
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/subtitles"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestEmbeddedTrackIdsDependOnTheStream(t *testing.T) {
+	videoId := uuid.New()
+
+	first := subtitles.NewEmbeddedDbSubtitleTrack(videoId, 2, "subrip", nil, subtitles.TrackStatusQueued)
+	again := subtitles.NewEmbeddedDbSubtitleTrack(videoId, 2, "subrip", nil, subtitles.TrackStatusQueued)
+	other := subtitles.NewEmbeddedDbSubtitleTrack(videoId, 3, "subrip", nil, subtitles.TrackStatusQueued)
+
+	if first.Id != again.Id {
+		t.Errorf("Expected the same stream to get the same track id, but got %s and %s", first.Id, again.Id)
+	}
+	if first.Id == other.Id {
+		t.Errorf("Expected another stream to get another track id, but both got %s", first.Id)
+	}
+}
+
+func TestSplitTrackIdsDependOnTheUploadAndClass(t *testing.T) {
+	videoId, uploadedId := uuid.New(), uuid.New()
+
+	first := subtitles.NewSplitDbSubtitleTrack(videoId, uploadedId, "ENCC", nil)
+	again := subtitles.NewSplitDbSubtitleTrack(videoId, uploadedId, "ENCC", nil)
+	other := subtitles.NewSplitDbSubtitleTrack(videoId, uploadedId, "JPCC", nil)
+	otherUpload := subtitles.NewSplitDbSubtitleTrack(videoId, uuid.New(), "ENCC", nil)
+
+	if first.Id != again.Id {
+		t.Errorf("Expected the same class to get the same track id, but got %s and %s", first.Id, again.Id)
+	}
+	if first.Id == other.Id || first.Id == otherUpload.Id {
+		t.Errorf("Expected another class or upload to get another track id, but got %s, %s and %s", first.Id, other.Id, otherUpload.Id)
+	}
+}
+
+func TestUpsertEmbeddedKeepsTheTrackOfAStream(t *testing.T) {
+	db := newTestDatabase(t)
+	tracks := subtitles.NewSubtitleTracksRepository(&app.Dependencies{
+		Postgres: db,
+		Logger:   zerolog.Nop(),
+		Tracer:   noop.NewTracerProvider().Tracer(""),
+	})
+	ctx := context.Background()
+
+	videoId := uuid.New()
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM subtitle_tracks WHERE video_id = $1", videoId)
+	})
+
+	track, err := tracks.UpsertEmbedded(subtitles.NewEmbeddedDbSubtitleTrack(videoId, 2, "subrip", nil, subtitles.TrackStatusQueued), ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tracks.UpdateStatus(track.Id, subtitles.TrackStatusReady, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := tracks.UpsertEmbedded(subtitles.NewEmbeddedDbSubtitleTrack(videoId, 2, "subrip", nil, subtitles.TrackStatusQueued), ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Id != track.Id || again.Status != subtitles.TrackStatusReady {
+		t.Errorf("Expected the ready track %s, but got %s in status %s", track.Id, again.Id, again.Status)
+	}
+
+	stored, err := tracks.GetByVideoId(videoId, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 {
+		t.Errorf("Expected 1 track, but got %d", len(stored))
+	}
+}
+
+func TestUpsertSplitKeepsTheTrackOfAClass(t *testing.T) {
+	db := newTestDatabase(t)
+	tracks := subtitles.NewSubtitleTracksRepository(&app.Dependencies{
+		Postgres: db,
+		Logger:   zerolog.Nop(),
+		Tracer:   noop.NewTracerProvider().Tracer(""),
+	})
+	ctx := context.Background()
+
+	videoId, uploadedId := uuid.New(), uuid.New()
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM subtitle_tracks WHERE video_id = $1", videoId)
+	})
+
+	track, err := tracks.UpsertSplit(subtitles.NewSplitDbSubtitleTrack(videoId, uploadedId, "ENCC", nil), ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if track.Status != subtitles.TrackStatusQueued {
+		t.Errorf("Expected a new track to be %s, but got %s", subtitles.TrackStatusQueued, track.Status)
+	}
+
+	err = tracks.UpdateStatus(track.Id, subtitles.TrackStatusReady, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := tracks.UpsertSplit(subtitles.NewSplitDbSubtitleTrack(videoId, uploadedId, "ENCC", nil), ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Id != track.Id || again.Status != subtitles.TrackStatusReady {
+		t.Errorf("Expected the ready track %s, but got %s in status %s", track.Id, again.Id, again.Status)
+	}
+
+	stored, err := tracks.GetByVideoId(videoId, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 {
+		t.Errorf("Expected 1 track, but got %d", len(stored))
+	}
+}
@@ -0,0 +1,436 @@
+package subtitles
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidTtmlTime = errors.New("invalid ttml time")
+	ErrNoTtmlCaptions  = errors.New("ttml file has no captions")
+
+	// ttmlRegex matches a tt root element, which may only follow the XML
+	// declaration, comments and a doctype.
+	ttmlRegex       = regexp.MustCompile(`(?is)^(?:\s|\x{FEFF}|<\?.*?\?>|<!--.*?-->|<!DOCTYPE[^>]*>)*<(?:[\w.-]+:)?tt[\s>]`)
+	clockTimeRegex  = regexp.MustCompile(`^(\d+):(\d{2}):(\d{2})(?:(\.\d+)|:(\d+)(?:\.(\d+))?)?$`)
+	offsetTimeRegex = regexp.MustCompile(`^(\d+(?:\.\d+)?)(h|ms|m|s|f|t)$`)
+	whitespaceRegex = regexp.MustCompile(`\s+`)
+)
+
+// ttmlNode is an element of a TTML document, with the text it contains kept
+// as children of its own so that the order of text and <br/> survives.
+type ttmlNode struct {
+	name       string
+	attributes map[string]string
+	children   []*ttmlNode
+	text       string
+}
+
+type ttmlDocument struct {
+	frameRate float64
+	tickRate  float64
+	styles    map[string]*ttmlNode
+	regions   map[string]*ttmlNode
+	agents    map[string]string
+}
+
+// LooksLikeTtml tells whether text is a TTML document, DFXP being its older
+// name. Only the root element counts, a <tt> in the captions of another format
+// does not.
+func LooksLikeTtml(text []byte) bool {
+	return ttmlRegex.Match(text)
+}
+
+// ParseTtml parses the paragraphs of a TTML or DFXP document. Times nested in
+// divisions are resolved against their parents, the position is taken from
+// the region, italics from tts:fontStyle in styles and spans, and the speaker
+// from ttm:agent.
+func ParseTtml(text []byte) (*ParsedTrack, error) {
+	root, err := parseTtmlTree(text)
+	if err != nil {
+		return nil, err
+	}
+
+	document := &ttmlDocument{
+		frameRate: 30,
+		styles:    make(map[string]*ttmlNode),
+		regions:   make(map[string]*ttmlNode),
+		agents:    make(map[string]string),
+	}
+	if rate, err := strconv.ParseFloat(root.attributes["frameRate"], 64); err == nil && rate > 0 {
+		document.frameRate = rate
+		if multiplier := strings.Fields(root.attributes["frameRateMultiplier"]); len(multiplier) == 2 {
+			numerator, errNumerator := strconv.ParseFloat(multiplier[0], 64)
+			denominator, errDenominator := strconv.ParseFloat(multiplier[1], 64)
+			if errNumerator == nil && errDenominator == nil && denominator > 0 {
+				document.frameRate *= numerator / denominator
+			}
+		}
+	}
+	document.tickRate = document.frameRate
+	if rate, err := strconv.ParseFloat(root.attributes["tickRate"], 64); err == nil && rate > 0 {
+		document.tickRate = rate
+	}
+	root.walk(func(node *ttmlNode) {
+		id := node.attributes["id"]
+		switch node.name {
+		case "style":
+			document.styles[id] = node
+		case "region":
+			document.regions[id] = node
+		case "agent":
+			name := id
+			for _, child := range node.children {
+				if child.name == "name" {
+					name = strings.TrimSpace(child.innerText())
+				}
+			}
+			document.agents[id] = name
+		}
+	})
+
+	var captions []*Caption
+	for _, child := range root.children {
+		if child.name != "body" {
+			continue
+		}
+
+		captions, err = document.collect(child, 0, child.attributes, captions)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	captions = sequenced(captions)
+	if len(captions) == 0 {
+		return nil, ErrNoTtmlCaptions
+	}
+
+	return &ParsedTrack{Captions: captions}, nil
+}
+
+// collect walks the body down to its paragraphs, passing the begin of each
+// element to its children and the region, style and agent they inherit.
+func (d *ttmlDocument) collect(node *ttmlNode, offsetMs int64, inherited map[string]string, captions []*Caption) ([]*Caption, error) {
+	beginMs, err := d.parseTime(node.attributes["begin"], 0)
+	if err != nil {
+		return nil, err
+	}
+	beginMs += offsetMs
+
+	attributes := make(map[string]string, len(inherited))
+	for name, value := range inherited {
+		attributes[name] = value
+	}
+	for _, name := range []string{"region", "style", "agent"} {
+		if value, ok := node.attributes[name]; ok {
+			attributes[name] = value
+		}
+	}
+
+	if node.name != "p" {
+		for _, child := range node.children {
+			if child.name == "" {
+				continue
+			}
+
+			captions, err = d.collect(child, beginMs, attributes, captions)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return captions, nil
+	}
+
+	var endMs int64
+	if end, ok := node.attributes["end"]; ok {
+		endMs, err = d.parseTime(end, offsetMs)
+	} else {
+		endMs, err = d.parseTime(node.attributes["dur"], beginMs)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var text styledText
+	d.writeText(node, d.italicOf(node, attributes["style"], false), &text)
+
+	style := &CaptionStyle{
+		Position: d.position(attributes["region"], node),
+		Italic:   text.allItalic(),
+		Speaker:  d.agents[firstField(attributes["agent"])],
+		Style:    firstField(attributes["style"]),
+	}
+
+	return append(captions, &Caption{
+		StartMs: beginMs,
+		EndMs:   endMs,
+		Text:    text.lines(),
+		Style:   style,
+	}), nil
+}
+
+// writeText writes the text of an element, following the italics its spans
+// switch on and off.
+func (d *ttmlDocument) writeText(node *ttmlNode, italic bool, text *styledText) {
+	for _, child := range node.children {
+		switch child.name {
+		case "":
+			// Line breaks in the source are white space like any other,
+			// only <br/> breaks lines.
+			text.italic = italic
+			text.write(whitespaceRegex.ReplaceAllString(child.text, " "))
+		case "br":
+			text.write("\n")
+		default:
+			d.writeText(child, d.italicOf(child, child.attributes["style"], italic), text)
+		}
+	}
+}
+
+// italicOf tells whether the text of an element is italic, from its own
+// tts:fontStyle, the styles it references or otherwise its parent.
+func (d *ttmlDocument) italicOf(node *ttmlNode, styleIds string, inherited bool) bool {
+	italic := inherited
+	if fontStyle := d.styleAttribute(styleIds, "fontStyle", 0); fontStyle != "" {
+		italic = fontStyle == "italic" || fontStyle == "oblique"
+	}
+	if fontStyle, ok := node.attributes["fontStyle"]; ok {
+		italic = fontStyle == "italic" || fontStyle == "oblique"
+	}
+
+	return italic
+}
+
+func firstField(value string) string {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	return fields[0]
+}
+
+func (d *ttmlDocument) styleAttribute(styleIds string, name string, depth int) string {
+	var value string
+	for _, id := range strings.Fields(styleIds) {
+		style, ok := d.styles[id]
+		if !ok || depth > len(d.styles) {
+			continue
+		}
+
+		if referenced := d.styleAttribute(style.attributes["style"], name, depth+1); referenced != "" {
+			value = referenced
+		}
+		if own, ok := style.attributes[name]; ok {
+			value = own
+		}
+	}
+
+	return value
+}
+
+// position is where the region of a paragraph is, from its origin and extent
+// or otherwise from its display alignment, with the text alignment telling
+// the sides apart.
+func (d *ttmlDocument) position(regionId string, paragraph *ttmlNode) string {
+	region, ok := d.regions[regionId]
+	if !ok {
+		return ""
+	}
+
+	displayAlign := d.regionAttribute(region, "displayAlign")
+	row := 0
+	switch displayAlign {
+	case "before":
+		row = 2
+	case "center":
+		row = 1
+	}
+	if origin := percentages(d.regionAttribute(region, "origin")); len(origin) == 2 {
+		// The text sits at the edge of the region its display alignment
+		// points to.
+		y := origin[1]
+		if extent := percentages(d.regionAttribute(region, "extent")); len(extent) == 2 {
+			switch displayAlign {
+			case "after":
+				y += extent[1]
+			case "center":
+				y += extent[1] / 2
+			}
+		}
+		row = 2 - min(max(int(y*3/100), 0), 2)
+	}
+
+	column := 1
+	textAlign := paragraph.attributes["textAlign"]
+	if textAlign == "" {
+		textAlign = d.regionAttribute(region, "textAlign")
+	}
+	switch textAlign {
+	case "left", "start":
+		column = 0
+	case "right", "end":
+		column = 2
+	}
+
+	return numpadPositions[1+3*row+column]
+}
+
+// regionAttribute reads a style attribute of a region, which it may carry
+// itself, in nested style elements or through the styles it references.
+func (d *ttmlDocument) regionAttribute(region *ttmlNode, name string) string {
+	if value, ok := region.attributes[name]; ok {
+		return value
+	}
+	for _, child := range region.children {
+		if value, ok := child.attributes[name]; ok && child.name == "style" {
+			return value
+		}
+	}
+
+	return d.styleAttribute(region.attributes["style"], name, 0)
+}
+
+func percentages(value string) []float64 {
+	var values []float64
+	for _, field := range strings.Fields(value) {
+		percentage, err := strconv.ParseFloat(strings.TrimSuffix(field, "%"), 64)
+		if err != nil || !strings.HasSuffix(field, "%") {
+			return nil
+		}
+		values = append(values, percentage)
+	}
+
+	return values
+}
+
+// parseTime parses a TTML clock time, such as 00:01:02.500 or 00:01:02:12 in
+// frames, or an offset time, such as 62.5s or 625000000t in ticks, adding
+// offsetMs to it. Empty times are at the offset.
+func (d *ttmlDocument) parseTime(value string, offsetMs int64) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return offsetMs, nil
+	}
+
+	if match := clockTimeRegex.FindStringSubmatch(value); match != nil {
+		hours, _ := strconv.ParseFloat(match[1], 64)
+		minutes, _ := strconv.ParseFloat(match[2], 64)
+		seconds, _ := strconv.ParseFloat(match[3], 64)
+		seconds += hours*3600 + minutes*60
+		if match[4] != "" {
+			fraction, _ := strconv.ParseFloat(match[4], 64)
+			seconds += fraction
+		}
+		if match[5] != "" {
+			frames, _ := strconv.ParseFloat(match[5], 64)
+			seconds += frames / d.frameRate
+		}
+
+		return offsetMs + int64(math.Round(seconds*1000)), nil
+	}
+
+	if match := offsetTimeRegex.FindStringSubmatch(value); match != nil {
+		count, _ := strconv.ParseFloat(match[1], 64)
+		var seconds float64
+		switch match[2] {
+		case "h":
+			seconds = count * 3600
+		case "m":
+			seconds = count * 60
+		case "s":
+			seconds = count
+		case "ms":
+			seconds = count / 1000
+		case "f":
+			seconds = count / d.frameRate
+		case "t":
+			seconds = count / d.tickRate
+		}
+
+		return offsetMs + int64(math.Round(seconds*1000)), nil
+	}
+
+	return 0, ErrInvalidTtmlTime
+}
+
+// parseTtmlTree reads the document into nodes named by their local names,
+// as documents disagree on the prefixes of the TTML namespaces.
+func parseTtmlTree(text []byte) (*ttmlNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(text))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		// DecodeText has already transcoded the document to UTF-8.
+		return input, nil
+	}
+
+	root := &ttmlNode{}
+	stack := []*ttmlNode{root}
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		parent := stack[len(stack)-1]
+		switch token := token.(type) {
+		case xml.StartElement:
+			node := &ttmlNode{name: token.Name.Local, attributes: make(map[string]string, len(token.Attr))}
+			for _, attribute := range token.Attr {
+				node.attributes[attribute.Name.Local] = attribute.Value
+			}
+			parent.children = append(parent.children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			parent.children = append(parent.children, &ttmlNode{text: string(token)})
+		}
+	}
+
+	for _, child := range root.children {
+		if child.name == "tt" {
+			return child, nil
+		}
+	}
+
+	return nil, ErrNoTtmlCaptions
+}
+
+func (n *ttmlNode) walk(visit func(node *ttmlNode)) {
+	for _, child := range n.children {
+		if child.name == "" {
+			continue
+		}
+
+		visit(child)
+		child.walk(visit)
+	}
+}
+
+func (n *ttmlNode) innerText() string {
+	var b strings.Builder
+	for _, child := range n.children {
+		if child.name == "" {
+			b.WriteString(child.text)
+		} else {
+			b.WriteString(child.innerText())
+		}
+	}
+
+	return b.String()
+}
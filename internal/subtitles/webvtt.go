@@ -0,0 +1,174 @@
+package subtitles
+
+import (
+	"errors"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidWebVttTime = errors.New("invalid webvtt time")
+	ErrNoWebVttCues      = errors.New("webvtt file has no cues")
+
+	webVttRegex         = regexp.MustCompile(`^\s*WEBVTT(?:[ \t]|\n|$)`)
+	webVttTimeRegex     = regexp.MustCompile(`^(?:(\d+):)?(\d{2}):(\d{2})\.(\d{3})$`)
+	webVttTagRegex      = regexp.MustCompile(`<(/?)([a-zA-Z]+)?([.\w-]*)(?:\s+([^>]*))?>|<\d[\d:.]*>`)
+	webVttRubyTextRegex = regexp.MustCompile(`(?s)<rt\b[^>]*>.*?</rt>`)
+)
+
+// LooksLikeWebVtt tells whether text is a WebVTT file.
+func LooksLikeWebVtt(text []byte) bool {
+	return webVttRegex.Match(text)
+}
+
+// ParseWebVtt parses the cues of a WebVTT file. The position is taken from
+// the line and align settings of the cue, italics from <i>, the speaker from
+// the first <v> span and the style from the first class of a <c> span. NOTE,
+// STYLE and REGION blocks are skipped.
+func ParseWebVtt(text []byte) (*ParsedTrack, error) {
+	var captions []*Caption
+	blocks := strings.Split(string(text), "\n\n")
+	for _, block := range blocks[1:] {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		if len(lines) == 0 || lines[0] == "" {
+			continue
+		}
+		if strings.HasPrefix(lines[0], "NOTE") || lines[0] == "STYLE" || lines[0] == "REGION" {
+			continue
+		}
+
+		// The cue identifier is optional.
+		if !strings.Contains(lines[0], "-->") {
+			lines = lines[1:]
+		}
+		if len(lines) == 0 || !strings.Contains(lines[0], "-->") {
+			continue
+		}
+
+		caption, err := parseWebVttCue(lines[0], lines[1:])
+		if err != nil {
+			return nil, err
+		}
+		captions = append(captions, caption)
+	}
+
+	captions = sequenced(captions)
+	if len(captions) == 0 {
+		return nil, ErrNoWebVttCues
+	}
+
+	return &ParsedTrack{Captions: captions}, nil
+}
+
+func parseWebVttCue(timing string, lines []string) (*Caption, error) {
+	start, rest, _ := strings.Cut(timing, "-->")
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return nil, ErrInvalidWebVttTime
+	}
+
+	startMs, err := parseWebVttTime(strings.TrimSpace(start))
+	if err != nil {
+		return nil, err
+	}
+	endMs, err := parseWebVttTime(fields[0])
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make(map[string]string)
+	for _, setting := range fields[1:] {
+		name, value, found := strings.Cut(setting, ":")
+		if found {
+			settings[name] = value
+		}
+	}
+
+	source := webVttRubyTextRegex.ReplaceAllString(strings.Join(lines, "\n"), "")
+	style := &CaptionStyle{Position: webVttPosition(settings)}
+
+	var text styledText
+	last := 0
+	for _, match := range webVttTagRegex.FindAllStringSubmatchIndex(source, -1) {
+		text.write(html.UnescapeString(source[last:match[0]]))
+		last = match[1]
+
+		if match[4] < 0 {
+			continue
+		}
+		closing := match[3] > match[2]
+		switch source[match[4]:match[5]] {
+		case "i":
+			text.italic = !closing
+		case "v":
+			if !closing && style.Speaker == "" && match[8] >= 0 {
+				style.Speaker = strings.TrimSpace(source[match[8]:match[9]])
+			}
+		case "c":
+			if !closing && style.Style == "" && match[6] < match[7] {
+				style.Style = strings.Split(strings.TrimPrefix(source[match[6]:match[7]], "."), ".")[0]
+			}
+		}
+	}
+	text.write(html.UnescapeString(source[last:]))
+	style.Italic = text.allItalic()
+
+	return &Caption{
+		StartMs: startMs,
+		EndMs:   endMs,
+		Text:    text.lines(),
+		Style:   style,
+	}, nil
+}
+
+// webVttPosition maps the line and align settings of a cue to a position.
+// Lines given as numbers count from the top when positive and from the
+// bottom when negative, percentages are the share of the height.
+func webVttPosition(settings map[string]string) string {
+	line, hasLine := settings["line"]
+	align, hasAlign := settings["align"]
+	if !hasLine && !hasAlign {
+		return ""
+	}
+
+	row := 0
+	line, _, _ = strings.Cut(line, ",")
+	if percentage, found := strings.CutSuffix(line, "%"); found {
+		if value, err := strconv.ParseFloat(percentage, 64); err == nil {
+			row = 2 - min(max(int(value*3/100), 0), 2)
+		}
+	} else if value, err := strconv.Atoi(line); err == nil && value >= 0 {
+		row = 2
+	}
+
+	column := 1
+	switch align {
+	case "start", "left":
+		column = 0
+	case "end", "right":
+		column = 2
+	}
+
+	return numpadPositions[1+3*row+column]
+}
+
+// parseWebVttTime parses the [hh:]mm:ss.ttt timestamps of WebVTT into
+// milliseconds.
+func parseWebVttTime(value string) (int64, error) {
+	match := webVttTimeRegex.FindStringSubmatch(value)
+	if match == nil {
+		return 0, ErrInvalidWebVttTime
+	}
+
+	var hours int64
+	if match[1] != "" {
+		hours, _ = strconv.ParseInt(match[1], 10, 64)
+	}
+	minutes, _ := strconv.ParseInt(match[2], 10, 64)
+	seconds, _ := strconv.ParseInt(match[3], 10, 64)
+	milliseconds, _ := strconv.ParseInt(match[4], 10, 64)
+
+	return hours*3600_000 + minutes*60_000 + seconds*1000 + milliseconds, nil
+}
@@ -0,0 +1,152 @@
+package uploads
+
+import (
+	"bytes"
+	"context"
+	"dewarrum/vocabulary-leveling/internal/storage"
+	"dewarrum/vocabulary-leveling/internal/videos"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/google/uuid"
+)
+
+const (
+	FailedToUpload   = "failed to upload"
+	FailedToDownload = "failed to download"
+	FailedToComplete = "failed to complete multipart upload"
+	FailedToAbort    = "failed to abort multipart upload"
+)
+
+type FileStorage struct {
+	s3Store *storage.S3Store
+}
+
+func NewFileStorage(s3Store *storage.S3Store) *FileStorage {
+	return &FileStorage{
+		s3Store: s3Store,
+	}
+}
+
+func incompletePartKey(uploadId uuid.UUID) string {
+	return fmt.Sprintf("uploads/%s/incomplete", uploadId)
+}
+
+func (f *FileStorage) CreateMultipartUpload(videoId uuid.UUID, contentType string, ctx context.Context) (string, error) {
+	response, err := f.s3Store.Client().CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(f.s3Store.Bucket()),
+		Key:         aws.String(f.s3Store.Key(videos.OriginalKey(videoId))),
+		ContentType: &contentType,
+	})
+	if err != nil {
+		return "", errors.Join(err, errors.New(FailedToUpload))
+	}
+
+	return *response.UploadId, nil
+}
+
+func (f *FileStorage) UploadPart(upload *DbUpload, partNumber int32, body []byte, ctx context.Context) error {
+	_, err := f.s3Store.Client().UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:        aws.String(f.s3Store.Bucket()),
+		Key:           aws.String(f.s3Store.Key(videos.OriginalKey(upload.VideoId))),
+		UploadId:      &upload.S3UploadId,
+		PartNumber:    &partNumber,
+		Body:          bytes.NewReader(body),
+		ContentLength: aws.Int64(int64(len(body))),
+	})
+	if err != nil {
+		return errors.Join(err, errors.New(FailedToUpload))
+	}
+
+	return nil
+}
+
+func (f *FileStorage) CompleteMultipartUpload(upload *DbUpload, ctx context.Context) error {
+	var completedParts []types.CompletedPart
+	paginator := s3.NewListPartsPaginator(f.s3Store.Client(), &s3.ListPartsInput{
+		Bucket:   aws.String(f.s3Store.Bucket()),
+		Key:      aws.String(f.s3Store.Key(videos.OriginalKey(upload.VideoId))),
+		UploadId: &upload.S3UploadId,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return errors.Join(err, errors.New(FailedToComplete))
+		}
+
+		for _, part := range page.Parts {
+			completedParts = append(completedParts, types.CompletedPart{
+				ETag:       part.ETag,
+				PartNumber: part.PartNumber,
+			})
+		}
+	}
+
+	_, err := f.s3Store.Client().CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(f.s3Store.Bucket()),
+		Key:             aws.String(f.s3Store.Key(videos.OriginalKey(upload.VideoId))),
+		UploadId:        &upload.S3UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completedParts},
+	})
+	if err != nil {
+		return errors.Join(err, errors.New(FailedToComplete))
+	}
+
+	return nil
+}
+
+func (f *FileStorage) AbortMultipartUpload(upload *DbUpload, ctx context.Context) error {
+	_, err := f.s3Store.Client().AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(f.s3Store.Bucket()),
+		Key:      aws.String(f.s3Store.Key(videos.OriginalKey(upload.VideoId))),
+		UploadId: &upload.S3UploadId,
+	})
+	if err != nil {
+		return errors.Join(err, errors.New(FailedToAbort))
+	}
+
+	return nil
+}
+
+func (f *FileStorage) UploadIncompletePart(uploadId uuid.UUID, body []byte, ctx context.Context) error {
+	_, err := f.s3Store.Client().PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(f.s3Store.Bucket()),
+		Key:    aws.String(f.s3Store.Key(incompletePartKey(uploadId))),
+		Body:   bytes.NewReader(body),
+	})
+	if err != nil {
+		return errors.Join(err, errors.New(FailedToUpload))
+	}
+
+	return nil
+}
+
+func (f *FileStorage) DownloadIncompletePart(uploadId uuid.UUID, ctx context.Context) ([]byte, error) {
+	response, err := f.s3Store.Client().GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(f.s3Store.Bucket()),
+		Key:    aws.String(f.s3Store.Key(incompletePartKey(uploadId))),
+	})
+	if err != nil {
+		return nil, errors.Join(err, errors.New(FailedToDownload))
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, errors.Join(err, errors.New(FailedToDownload))
+	}
+
+	return body, nil
+}
+
+func (f *FileStorage) DeleteIncompletePart(uploadId uuid.UUID, ctx context.Context) error {
+	_, err := f.s3Store.Client().DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(f.s3Store.Bucket()),
+		Key:    aws.String(f.s3Store.Key(incompletePartKey(uploadId))),
+	})
+	return err
+}
@@ -0,0 +1,58 @@
+package uploads_test
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/uploads"
+	"dewarrum/vocabulary-leveling/internal/videos"
+	"path"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFileStorageUsesTheBucketAndPrefixOfTheStore(t *testing.T) {
+	fake, store := newFakeS3(t, testBucket, testPrefix)
+	fileStorage := uploads.NewFileStorage(store)
+	ctx := context.Background()
+
+	upload := &uploads.DbUpload{Id: uuid.New(), VideoId: uuid.New()}
+	uploadId, err := fileStorage.CreateMultipartUpload(upload.VideoId, "video/mp4", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload.S3UploadId = uploadId
+
+	err = fileStorage.UploadPart(upload, 1, []byte("hel"), ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = fileStorage.UploadIncompletePart(upload.Id, []byte("lo"), ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	incomplete, err := fileStorage.DownloadIncompletePart(upload.Id, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fileStorage.UploadPart(upload, 2, incomplete, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fileStorage.DeleteIncompletePart(upload.Id, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = fileStorage.CompleteMultipartUpload(upload, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if object, _ := fake.object(path.Join(testPrefix, videos.OriginalKey(upload.VideoId))); string(object) != "hello" {
+		t.Errorf("Expected the video to be %q, but got %q", "hello", object)
+	}
+	if _, ok := fake.object(path.Join(testPrefix, "uploads", upload.Id.String(), "incomplete")); ok {
+		t.Error("Expected the incomplete part to be deleted")
+	}
+}
@@ -0,0 +1,38 @@
+package uploads
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalidMetadata = errors.New("invalid Upload-Metadata header")
+)
+
+// ParseMetadata decodes a tus Upload-Metadata header: comma separated pairs of
+// a key and an optional base64 encoded value.
+func ParseMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		fields := strings.Fields(pair)
+		switch len(fields) {
+		case 1:
+			metadata[fields[0]] = ""
+		case 2:
+			value, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				return nil, errors.Join(err, ErrInvalidMetadata)
+			}
+			metadata[fields[0]] = string(value)
+		default:
+			return nil, ErrInvalidMetadata
+		}
+	}
+
+	return metadata, nil
+}
@@ -0,0 +1,44 @@
+package uploads_test
+
+import (
+	"dewarrum/vocabulary-leveling/internal/uploads"
+	"errors"
+	"testing"
+)
+
+func TestParseMetadataEmptyHeader(t *testing.T) {
+	metadata, err := uploads.ParseMetadata("")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(metadata) != 0 {
+		t.Errorf("Expected no metadata, but got %d entries", len(metadata))
+	}
+}
+
+func TestParseMetadataDecodesValues(t *testing.T) {
+	metadata, err := uploads.ParseMetadata("videoName 7JWI64WV7ZWY7IS47JqU,filetype dmlkZW8vbXA0,is_confidential")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if metadata["videoName"] != "안녕하세요" {
+		t.Errorf("Expected videoName to be %s, but got %s", "안녕하세요", metadata["videoName"])
+	}
+
+	if metadata["filetype"] != "video/mp4" {
+		t.Errorf("Expected filetype to be %s, but got %s", "video/mp4", metadata["filetype"])
+	}
+
+	if value, ok := metadata["is_confidential"]; !ok || value != "" {
+		t.Errorf("Expected is_confidential to be present and empty, but got %q", value)
+	}
+}
+
+func TestParseMetadataRejectsInvalidBase64(t *testing.T) {
+	_, err := uploads.ParseMetadata("videoName not-base64!")
+	if !errors.Is(err, uploads.ErrInvalidMetadata) {
+		t.Errorf("Expected %v, but got %v", uploads.ErrInvalidMetadata, err)
+	}
+}
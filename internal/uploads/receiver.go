@@ -0,0 +1,299 @@
+package uploads
+
+import (
+	"bytes"
+	"context"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/storage"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// PartSize is the size of every S3 multipart part except the last one.
+	// S3 rejects non-final parts smaller than 5MiB.
+	PartSize  = 8 * 1024 * 1024
+	MaxLength = 50 * 1024 * 1024 * 1024
+)
+
+var (
+	ErrOffsetMismatch  = errors.New("upload offset does not match")
+	ErrUploadCompleted = errors.New("upload is already completed")
+	ErrInvalidLength   = errors.New("upload length is invalid")
+	ErrUploadLocked    = errors.New("upload is being written by another request")
+)
+
+// ExportFunc hands a received upload over to be exported. It is called again
+// when it fails and the client resumes the upload, so it must be idempotent.
+type ExportFunc func(upload *DbUpload, ctx context.Context) error
+
+// Receiver appends tus PATCH requests to an S3 multipart upload. Bytes that do
+// not fill a whole part yet are parked in S3 until the next request arrives.
+//
+// An upload whose every byte is stored has its offset at its length, but is
+// only completed once it was exported. Until then resuming it retries the
+// export.
+type Receiver struct {
+	repository  *UploadsRepository
+	fileStorage *FileStorage
+	logger      zerolog.Logger
+	tracer      trace.Tracer
+}
+
+// NewReceiver needs the s3 storage backend, multipart uploads are not
+// supported by the others.
+func NewReceiver(dependencies *app.Dependencies) *Receiver {
+	s3Store, _ := dependencies.ObjectStore.(*storage.S3Store)
+
+	return &Receiver{
+		repository:  NewUploadsRepository(dependencies),
+		fileStorage: NewFileStorage(s3Store),
+		logger:      dependencies.Logger,
+		tracer:      dependencies.Tracer,
+	}
+}
+
+// Create starts an upload of length bytes. An empty upload has nothing left to
+// receive and is exported right away.
+func (r *Receiver) Create(videoName string, contentType string, length int64, export ExportFunc, ctx context.Context) (*DbUpload, error) {
+	if length < 0 || length > MaxLength {
+		return nil, ErrInvalidLength
+	}
+
+	videoId := uuid.New()
+	s3UploadId, err := r.fileStorage.CreateMultipartUpload(videoId, contentType, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	upload, err := r.repository.Insert(NewDbUpload(videoName, contentType, length, s3UploadId, videoId), ctx)
+	if err != nil || length > 0 {
+		return upload, err
+	}
+
+	err = r.assemble(upload, nil, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.finish(upload, export, ctx)
+}
+
+func (r *Receiver) Get(id uuid.UUID, ctx context.Context) (*DbUpload, error) {
+	return r.repository.GetById(id, ctx)
+}
+
+// Append writes body at offset and returns the updated upload, exporting it
+// once every byte is stored. Whatever was received before a read error is
+// kept, so the client can resume from the returned offset.
+func (r *Receiver) Append(upload *DbUpload, offset int64, body io.Reader, export ExportFunc, ctx context.Context) (*DbUpload, error) {
+	ctx, span := r.tracer.Start(ctx, "uploads.receiver.append")
+	defer span.End()
+
+	upload, lock, err := r.lock(upload.Id, ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock(ctx)
+
+	if upload.IsCompleted() {
+		return nil, ErrUploadCompleted
+	}
+	if offset != upload.Offset {
+		return nil, ErrOffsetMismatch
+	}
+	if upload.Offset == upload.Length {
+		return r.finish(upload, export, ctx)
+	}
+
+	reader := io.LimitReader(body, upload.Length-upload.Offset)
+	if upload.IncompletePartSize > 0 {
+		incompletePart, err := r.fileStorage.DownloadIncompletePart(upload.Id, ctx)
+		if err != nil {
+			return nil, err
+		}
+		reader = io.MultiReader(bytes.NewReader(incompletePart), reader)
+	}
+
+	persisted := upload.Offset - upload.IncompletePartSize
+	buffer := make([]byte, PartSize)
+	var pending []byte
+	var readErr error
+	for {
+		n, err := io.ReadFull(reader, buffer)
+		if err == nil {
+			err = r.fileStorage.UploadPart(upload, upload.PartsCount+1, buffer, ctx)
+			if err != nil {
+				return nil, r.rewind(upload, persisted, err, ctx)
+			}
+			upload.PartsCount++
+			persisted += PartSize
+			continue
+		}
+
+		pending = buffer[:n]
+		if err != io.EOF && err != io.ErrUnexpectedEOF {
+			readErr = err
+		}
+		break
+	}
+
+	if persisted+int64(len(pending)) == upload.Length {
+		err = r.assemble(upload, pending, ctx)
+		if err != nil {
+			return nil, r.rewind(upload, persisted, err, ctx)
+		}
+
+		return r.finish(upload, export, ctx)
+	}
+
+	upload.Offset = persisted + int64(len(pending))
+	if len(pending) > 0 {
+		err := r.fileStorage.UploadIncompletePart(upload.Id, pending, ctx)
+		if err != nil {
+			return nil, r.rewind(upload, persisted, err, ctx)
+		}
+		upload.IncompletePartSize = int64(len(pending))
+	} else {
+		upload.IncompletePartSize = 0
+	}
+
+	err = r.repository.Update(upload, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return upload, readErr
+}
+
+// Resume retries the export of an upload whose every byte is stored, for
+// clients that ask for the offset before appending again. Other uploads are
+// returned as they are.
+func (r *Receiver) Resume(upload *DbUpload, export ExportFunc, ctx context.Context) (*DbUpload, error) {
+	if upload.IsCompleted() || upload.Offset != upload.Length {
+		return upload, nil
+	}
+
+	upload, lock, err := r.lock(upload.Id, ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock(ctx)
+
+	if upload.IsCompleted() {
+		return upload, nil
+	}
+
+	return r.finish(upload, export, ctx)
+}
+
+// assemble stores the last part of the upload and completes the multipart
+// upload, which moves the offset of the upload to its length.
+func (r *Receiver) assemble(upload *DbUpload, lastPart []byte, ctx context.Context) error {
+	partsCount := upload.PartsCount
+	if len(lastPart) > 0 || upload.PartsCount == 0 {
+		err := r.fileStorage.UploadPart(upload, upload.PartsCount+1, lastPart, ctx)
+		if err != nil {
+			return err
+		}
+		upload.PartsCount++
+	}
+
+	err := r.fileStorage.CompleteMultipartUpload(upload, ctx)
+	if err != nil {
+		// The last part is sent again under the same number.
+		upload.PartsCount = partsCount
+		return err
+	}
+
+	err = r.fileStorage.DeleteIncompletePart(upload.Id, ctx)
+	if err != nil {
+		r.logger.Warn().Str("uploadId", upload.Id.String()).Err(err).Msg("Failed to delete incomplete part")
+	}
+
+	upload.Offset = upload.Length
+	upload.IncompletePartSize = 0
+
+	return r.repository.Update(upload, ctx)
+}
+
+// finish exports an upload whose every byte is stored and completes it. An
+// upload that fails to export stays incomplete, so that it is retried.
+func (r *Receiver) finish(upload *DbUpload, export ExportFunc, ctx context.Context) (*DbUpload, error) {
+	err := export(upload, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	completedAt := time.Now().In(time.UTC)
+	upload.CompletedAt = &completedAt
+
+	err = r.repository.Update(upload, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+// lock keeps other requests, on any replica, from writing to the upload until
+// the lock is released and returns the upload as it is stored once no other
+// request writes to it. Parts are numbered by the offset they start at, two
+// requests writing at once would overwrite each other's parts.
+func (r *Receiver) lock(id uuid.UUID, ctx context.Context) (*DbUpload, *UploadLock, error) {
+	lock, err := r.repository.TryLock(id, ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	upload, err := r.repository.GetById(id, ctx)
+	if err != nil {
+		lock.Unlock(ctx)
+		return nil, nil, err
+	}
+
+	return upload, lock, nil
+}
+
+// rewind moves the offset back to the last byte stored in a multipart part
+// after a failed write, dropping anything that was only held in memory.
+func (r *Receiver) rewind(upload *DbUpload, persisted int64, cause error, ctx context.Context) error {
+	upload.Offset = persisted
+	upload.IncompletePartSize = 0
+
+	err := r.repository.Update(upload, ctx)
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+
+	return cause
+}
+
+func (r *Receiver) Terminate(upload *DbUpload, ctx context.Context) error {
+	upload, lock, err := r.lock(upload.Id, ctx)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock(ctx)
+
+	if upload.IsCompleted() {
+		return ErrUploadCompleted
+	}
+
+	err = r.fileStorage.AbortMultipartUpload(upload, ctx)
+	if err != nil {
+		return err
+	}
+
+	err = r.fileStorage.DeleteIncompletePart(upload.Id, ctx)
+	if err != nil {
+		r.logger.Warn().Str("uploadId", upload.Id.String()).Err(err).Msg("Failed to delete incomplete part")
+	}
+
+	return r.repository.Delete(upload.Id, ctx)
+}
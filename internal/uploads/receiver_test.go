@@ -0,0 +1,217 @@
+package uploads_test
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/uploads"
+	"dewarrum/vocabulary-leveling/internal/videos"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const (
+	testBucket = "media"
+	testPrefix = "test"
+)
+
+var uploadColumns = []string{"id", "video_id", "video_name", "content_type", "length", "upload_offset", "s3_upload_id", "parts_count", "incomplete_part_size", "created_at", "completed_at"}
+
+func newTestReceiver(t *testing.T) (*uploads.Receiver, sqlmock.Sqlmock, *fakeS3) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	fake, store := newFakeS3(t, testBucket, testPrefix)
+
+	receiver := uploads.NewReceiver(&app.Dependencies{
+		Postgres:    sqlx.NewDb(db, "postgres"),
+		ObjectStore: store,
+		Logger:      zerolog.Nop(),
+		Tracer:      noop.NewTracerProvider().Tracer(""),
+	})
+
+	return receiver, mock, fake
+}
+
+// expectLocked locks the upload and returns it as it is stored when the
+// receiver reads it.
+func expectLocked(mock sqlmock.Sqlmock, upload *uploads.DbUpload) {
+	expectLock(mock, true)
+	mock.ExpectQuery("SELECT \\* FROM uploads WHERE id = \\$1").
+		WithArgs(upload.Id).
+		WillReturnRows(sqlmock.NewRows(uploadColumns).AddRow(
+			upload.Id, upload.VideoId, upload.VideoName, upload.ContentType, upload.Length, upload.Offset,
+			upload.S3UploadId, upload.PartsCount, upload.IncompletePartSize, upload.CreatedAt, upload.CompletedAt))
+}
+
+// expectLock expects an attempt to lock an upload, which another request
+// holds unless locked.
+func expectLock(mock sqlmock.Sqlmock, locked bool) {
+	mock.ExpectQuery("SELECT pg_try_advisory_lock\\(\\$1\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(locked))
+}
+
+func expectUnlock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec("SELECT pg_advisory_unlock\\(\\$1\\)").WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+// expectUpdate expects the upload to be stored at offset, completed or not.
+func expectUpdate(mock sqlmock.Sqlmock, upload *uploads.DbUpload, offset int64, completed bool) {
+	var completedAt any
+	if completed {
+		completedAt = sqlmock.AnyArg()
+	}
+
+	mock.ExpectExec("UPDATE uploads SET").
+		WithArgs(offset, sqlmock.AnyArg(), int64(0), completedAt, upload.Id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func createUpload(t *testing.T, receiver *uploads.Receiver, mock sqlmock.Sqlmock, length int64, export uploads.ExportFunc) *uploads.DbUpload {
+	mock.ExpectExec("INSERT INTO uploads").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	upload, err := receiver.Create("video", "video/mp4", length, export, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return upload
+}
+
+func TestReceiverKeepsUploadsThatFailToExportIncomplete(t *testing.T) {
+	receiver, mock, fake := newTestReceiver(t)
+	upload := createUpload(t, receiver, mock, 5, nil)
+
+	failure := errors.New("queue is down")
+	expectLocked(mock, upload)
+	expectUpdate(mock, upload, 5, false)
+	expectUnlock(mock)
+
+	_, err := receiver.Append(upload, 0, strings.NewReader("hello"), func(*uploads.DbUpload, context.Context) error { return failure }, context.Background())
+	if !errors.Is(err, failure) {
+		t.Fatalf("Expected %v, but got %v", failure, err)
+	}
+
+	// Every byte is stored, the client resumes at the end of the upload and
+	// the export is retried.
+	stored := *upload
+	stored.Offset = 5
+	stored.PartsCount = 1
+	expectLocked(mock, &stored)
+	expectUpdate(mock, upload, 5, true)
+	expectUnlock(mock)
+
+	exported := 0
+	completed, err := receiver.Append(upload, 5, strings.NewReader(""), func(*uploads.DbUpload, context.Context) error {
+		exported++
+		return nil
+	}, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exported != 1 || !completed.IsCompleted() {
+		t.Errorf("Expected the upload to be exported once and completed, but it was exported %d times and completed is %t", exported, completed.IsCompleted())
+	}
+	if object, _ := fake.object(path.Join(testPrefix, videos.OriginalKey(upload.VideoId))); string(object) != "hello" {
+		t.Errorf("Expected the video to be %q, but got %q", "hello", object)
+	}
+}
+
+func TestReceiverExportsEmptyUploadsRightAway(t *testing.T) {
+	receiver, mock, fake := newTestReceiver(t)
+
+	exported := 0
+	mock.ExpectExec("INSERT INTO uploads").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE uploads SET").
+		WithArgs(int64(0), int32(1), int64(0), nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE uploads SET").
+		WithArgs(int64(0), int32(1), int64(0), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	upload, err := receiver.Create("video", "video/mp4", 0, func(*uploads.DbUpload, context.Context) error {
+		exported++
+		return nil
+	}, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exported != 1 || !upload.IsCompleted() {
+		t.Errorf("Expected the upload to be exported once and completed, but it was exported %d times and completed is %t", exported, upload.IsCompleted())
+	}
+	if object, ok := fake.object(path.Join(testPrefix, videos.OriginalKey(upload.VideoId))); !ok || len(object) != 0 {
+		t.Errorf("Expected an empty video, but got %q", object)
+	}
+}
+
+func TestReceiverRejectsRequestsWhileAnotherOneWrites(t *testing.T) {
+	receiver, mock, _ := newTestReceiver(t)
+	upload := createUpload(t, receiver, mock, 5, nil)
+
+	// The other requests may come to any replica, the lock is held in
+	// Postgres.
+	expectLocked(mock, upload)
+	expectLock(mock, false)
+	expectLock(mock, false)
+	expectUpdate(mock, upload, 5, false)
+	expectUpdate(mock, upload, 5, true)
+	expectUnlock(mock)
+
+	body, writer := io.Pipe()
+	reading := make(chan struct{})
+	appended := make(chan error, 1)
+	go func() {
+		_, err := receiver.Append(upload, 0, &signalingReader{reader: body, reading: reading}, func(*uploads.DbUpload, context.Context) error { return nil }, context.Background())
+		appended <- err
+	}()
+	<-reading
+
+	_, err := receiver.Append(upload, 0, strings.NewReader("hello"), nil, context.Background())
+	if !errors.Is(err, uploads.ErrUploadLocked) {
+		t.Errorf("Expected %v, but got %v", uploads.ErrUploadLocked, err)
+	}
+	err = receiver.Terminate(upload, context.Background())
+	if !errors.Is(err, uploads.ErrUploadLocked) {
+		t.Errorf("Expected %v, but got %v", uploads.ErrUploadLocked, err)
+	}
+
+	writer.Write([]byte("hello"))
+	writer.Close()
+	if err := <-appended; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// signalingReader closes reading on the first read, once the receiver holds
+// the lock of the upload.
+type signalingReader struct {
+	reader  io.Reader
+	reading chan struct{}
+	once    bool
+}
+
+func (r *signalingReader) Read(p []byte) (int, error) {
+	if !r.once {
+		r.once = true
+		close(r.reading)
+	}
+
+	return r.reader.Read(p)
+}
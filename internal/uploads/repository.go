@@ -0,0 +1,178 @@
+package uploads
+
+import (
+	"context"
+	"database/sql/driver"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrFailedToInsertUpload = errors.New("failed to insert upload")
+	ErrFailedToGetUpload    = errors.New("failed to get upload")
+	ErrFailedToUpdateUpload = errors.New("failed to update upload")
+	ErrFailedToDeleteUpload = errors.New("failed to delete upload")
+	ErrFailedToLockUpload   = errors.New("failed to lock upload")
+)
+
+type DbUpload struct {
+	Id                 uuid.UUID  `db:"id"`
+	VideoId            uuid.UUID  `db:"video_id"`
+	VideoName          string     `db:"video_name"`
+	ContentType        string     `db:"content_type"`
+	Length             int64      `db:"length"`
+	Offset             int64      `db:"upload_offset"`
+	S3UploadId         string     `db:"s3_upload_id"`
+	PartsCount         int32      `db:"parts_count"`
+	IncompletePartSize int64      `db:"incomplete_part_size"`
+	CreatedAt          time.Time  `db:"created_at"`
+	CompletedAt        *time.Time `db:"completed_at"`
+}
+
+func NewDbUpload(videoName string, contentType string, length int64, s3UploadId string, videoId uuid.UUID) *DbUpload {
+	return &DbUpload{
+		Id:          uuid.New(),
+		VideoId:     videoId,
+		VideoName:   videoName,
+		ContentType: contentType,
+		Length:      length,
+		S3UploadId:  s3UploadId,
+		CreatedAt:   time.Now().In(time.UTC),
+	}
+}
+
+func (u *DbUpload) IsCompleted() bool {
+	return u.CompletedAt != nil
+}
+
+type UploadsRepository struct {
+	db     *sqlx.DB
+	logger zerolog.Logger
+	tracer trace.Tracer
+}
+
+func NewUploadsRepository(dependencies *app.Dependencies) *UploadsRepository {
+	return &UploadsRepository{
+		db:     dependencies.Postgres,
+		logger: dependencies.Logger,
+		tracer: dependencies.Tracer,
+	}
+}
+
+func (r *UploadsRepository) Insert(upload *DbUpload, ctx context.Context) (*DbUpload, error) {
+	ctx, span := r.tracer.Start(ctx, "uploads.repository.insert")
+	defer span.End()
+	r.logger.Debug().Str("uploadId", upload.Id.String()).Msg("Inserting upload")
+
+	_, err := r.db.NamedExecContext(ctx, "INSERT INTO uploads (id, video_id, video_name, content_type, length, upload_offset, s3_upload_id, parts_count, incomplete_part_size, created_at, completed_at) VALUES (:id, :video_id, :video_name, :content_type, :length, :upload_offset, :s3_upload_id, :parts_count, :incomplete_part_size, :created_at, :completed_at)", upload)
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToInsertUpload)
+	}
+
+	return upload, nil
+}
+
+func (r *UploadsRepository) GetById(id uuid.UUID, ctx context.Context) (*DbUpload, error) {
+	ctx, span := r.tracer.Start(ctx, "uploads.repository.getById")
+	defer span.End()
+	r.logger.Debug().Str("uploadId", id.String()).Msg("Searching upload by id")
+
+	var upload DbUpload
+	err := r.db.GetContext(ctx, &upload, "SELECT * FROM uploads WHERE id = $1 LIMIT 1", id)
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToGetUpload)
+	}
+
+	return &upload, nil
+}
+
+func (r *UploadsRepository) Update(upload *DbUpload, ctx context.Context) error {
+	ctx, span := r.tracer.Start(ctx, "uploads.repository.update")
+	defer span.End()
+	r.logger.Debug().Str("uploadId", upload.Id.String()).Int64("offset", upload.Offset).Msg("Updating upload")
+
+	_, err := r.db.NamedExecContext(ctx, "UPDATE uploads SET upload_offset = :upload_offset, parts_count = :parts_count, incomplete_part_size = :incomplete_part_size, completed_at = :completed_at WHERE id = :id", upload)
+	if err != nil {
+		return errors.Join(err, ErrFailedToUpdateUpload)
+	}
+
+	return nil
+}
+
+func (r *UploadsRepository) Delete(id uuid.UUID, ctx context.Context) error {
+	ctx, span := r.tracer.Start(ctx, "uploads.repository.delete")
+	defer span.End()
+	r.logger.Debug().Str("uploadId", id.String()).Msg("Deleting upload")
+
+	_, err := r.db.ExecContext(ctx, "DELETE FROM uploads WHERE id = $1", id)
+	if err != nil {
+		return errors.Join(err, ErrFailedToDeleteUpload)
+	}
+
+	return nil
+}
+
+// UploadLock keeps the requests of every api replica but one from writing to
+// an upload. It is an advisory lock of the session of a connection of its own,
+// which Postgres releases when the replica holding it dies.
+type UploadLock struct {
+	conn *sqlx.Conn
+	key  int64
+}
+
+// TryLock locks the upload or fails with ErrUploadLocked when another request
+// holds the lock. The lock holds on to a connection until Unlock.
+func (r *UploadsRepository) TryLock(id uuid.UUID, ctx context.Context) (*UploadLock, error) {
+	ctx, span := r.tracer.Start(ctx, "uploads.repository.tryLock")
+	defer span.End()
+	r.logger.Debug().Str("uploadId", id.String()).Msg("Locking upload")
+
+	conn, err := r.db.Connx(ctx)
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToLockUpload)
+	}
+
+	lock := &UploadLock{conn: conn, key: lockKey(id)}
+	var locked bool
+	err = conn.GetContext(ctx, &locked, "SELECT pg_try_advisory_lock($1)", lock.key)
+	if err != nil {
+		lock.discard()
+		return nil, errors.Join(err, ErrFailedToLockUpload)
+	}
+	if !locked {
+		conn.Close()
+		return nil, ErrUploadLocked
+	}
+
+	return lock, nil
+}
+
+// Unlock releases the lock, also when ctx is done. A connection that fails to
+// release it is closed instead of going back to the pool still holding it.
+func (l *UploadLock) Unlock(ctx context.Context) {
+	_, err := l.conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", l.key)
+	if err != nil {
+		l.discard()
+		return
+	}
+
+	l.conn.Close()
+}
+
+func (l *UploadLock) discard() {
+	// database/sql closes connections that report ErrBadConn.
+	l.conn.Raw(func(any) error { return driver.ErrBadConn })
+	l.conn.Close()
+}
+
+// lockKey folds the id of an upload into the bigint advisory locks take.
+func lockKey(id uuid.UUID) int64 {
+	return int64(binary.BigEndian.Uint64(id[:8]) ^ binary.BigEndian.Uint64(id[8:]))
+}
@@ -0,0 +1,61 @@
+package uploads_test
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/uploads"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// newTestRepository connects to TEST_POSTGRES_URL, or skips the test when it
+// is not set. Every repository has a pool of its own, like an api replica.
+func newTestRepository(t *testing.T) *uploads.UploadsRepository {
+	url := os.Getenv("TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("TEST_POSTGRES_URL is not set")
+	}
+
+	db, err := sqlx.Connect("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return uploads.NewUploadsRepository(&app.Dependencies{
+		Postgres: db,
+		Logger:   zerolog.Nop(),
+		Tracer:   noop.NewTracerProvider().Tracer(""),
+	})
+}
+
+func TestTryLockLocksUploadsAcrossReplicas(t *testing.T) {
+	replica, otherReplica := newTestRepository(t), newTestRepository(t)
+	ctx := context.Background()
+	id := uuid.New()
+
+	lock, err := replica.TryLock(id, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = otherReplica.TryLock(id, ctx)
+	if !errors.Is(err, uploads.ErrUploadLocked) {
+		t.Errorf("Expected %v, but got %v", uploads.ErrUploadLocked, err)
+	}
+
+	lock.Unlock(ctx)
+
+	lock, err = otherReplica.TryLock(id, ctx)
+	if err != nil {
+		t.Fatalf("Expected the released upload to be locked, but got %v", err)
+	}
+	lock.Unlock(ctx)
+}
@@ -0,0 +1,167 @@
+package uploads_test
+
+import (
+	"bytes"
+	"dewarrum/vocabulary-leveling/internal/storage"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// fakeS3 keeps the objects and multipart uploads of a single bucket in memory,
+// serving the few S3 operations the receiver uses.
+type fakeS3 struct {
+	bucket string
+
+	mutex     sync.Mutex
+	objects   map[string][]byte
+	multipart map[string]map[int][]byte
+	uploads   int
+}
+
+func newFakeS3(t *testing.T, bucket string, prefix string) (*fakeS3, *storage.S3Store) {
+	fake := &fakeS3{
+		bucket:    bucket,
+		objects:   make(map[string][]byte),
+		multipart: make(map[string]map[int][]byte),
+	}
+
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client := s3.New(s3.Options{
+		Credentials:  credentials.NewStaticCredentialsProvider("access", "secret", ""),
+		Region:       "us-east-1",
+		BaseEndpoint: &server.URL,
+		UsePathStyle: true,
+	})
+
+	return fake, storage.NewS3Store(client, s3.NewPresignClient(client), bucket, prefix)
+}
+
+func (f *fakeS3) object(key string) ([]byte, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	object, ok := f.objects[key]
+	return object, ok
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	query := r.URL.Query()
+	uploadId := query.Get("uploadId")
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.uploads++
+		uploadId = strconv.Itoa(f.uploads)
+		f.multipart[uploadId] = make(map[int][]byte)
+		writeXml(w, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			Bucket   string
+			Key      string
+			UploadId string
+		}{Bucket: bucket, Key: key, UploadId: uploadId})
+
+	case r.Method == http.MethodPut && uploadId != "":
+		parts, ok := f.multipart[uploadId]
+		if !ok {
+			http.Error(w, "NoSuchUpload", http.StatusNotFound)
+			return
+		}
+		partNumber, _ := strconv.Atoi(query.Get("partNumber"))
+		parts[partNumber] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"%d"`, partNumber))
+
+	case r.Method == http.MethodGet && uploadId != "":
+		type part struct {
+			PartNumber int
+			ETag       string
+			Size       int
+		}
+		var listed []part
+		for partNumber, data := range f.multipart[uploadId] {
+			listed = append(listed, part{PartNumber: partNumber, ETag: fmt.Sprintf(`"%d"`, partNumber), Size: len(data)})
+		}
+		sort.Slice(listed, func(i, j int) bool { return listed[i].PartNumber < listed[j].PartNumber })
+		writeXml(w, struct {
+			XMLName     xml.Name `xml:"ListPartsResult"`
+			Bucket      string
+			Key         string
+			UploadId    string
+			IsTruncated bool
+			Part        []part
+		}{Bucket: bucket, Key: key, UploadId: uploadId, Part: listed})
+
+	case r.Method == http.MethodPost && uploadId != "":
+		parts, ok := f.multipart[uploadId]
+		if !ok {
+			http.Error(w, "NoSuchUpload", http.StatusNotFound)
+			return
+		}
+		var object []byte
+		for partNumber := 1; partNumber <= len(parts); partNumber++ {
+			object = append(object, parts[partNumber]...)
+		}
+		f.objects[key] = object
+		delete(f.multipart, uploadId)
+		writeXml(w, struct {
+			XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+			Bucket  string
+			Key     string
+		}{Bucket: bucket, Key: key})
+
+	case r.Method == http.MethodDelete && uploadId != "":
+		delete(f.multipart, uploadId)
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == http.MethodPut:
+		f.objects[key] = body
+
+	case r.Method == http.MethodGet:
+		object, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Write(object)
+
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "NotImplemented", http.StatusNotImplemented)
+	}
+}
+
+func writeXml(w http.ResponseWriter, response any) {
+	var buffer bytes.Buffer
+	xml.NewEncoder(&buffer).Encode(response)
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write(buffer.Bytes())
+}
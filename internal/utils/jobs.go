@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"context"
+	"sync"
+)
+
+// Jobs runs work that outlives the context it was started from, so that it can
+// finish while shutting down. Shutdown aborts it once the grace period is over.
+type Jobs struct {
+	running sync.WaitGroup
+	ctx     context.Context
+	abort   context.CancelFunc
+}
+
+// NewJobs creates jobs whose context keeps the values of ctx, such as its
+// trace, but is only cancelled by Shutdown.
+func NewJobs(ctx context.Context) *Jobs {
+	ctx, abort := context.WithCancel(context.WithoutCancel(ctx))
+
+	return &Jobs{ctx: ctx, abort: abort}
+}
+
+// Go runs job in a goroutine of its own.
+func (j *Jobs) Go(job func(ctx context.Context)) {
+	j.running.Add(1)
+	go func() {
+		defer j.running.Done()
+		job(j.ctx)
+	}()
+}
+
+// Shutdown waits for the jobs in flight. Jobs still running when ctx is done
+// are aborted, Shutdown then waits for them to return and reports ctx.Err().
+func (j *Jobs) Shutdown(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		j.running.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		j.abort()
+		<-stopped
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,80 @@
+package utils_test
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/utils"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type traceKey struct{}
+
+func TestJobsOutliveTheirContextButKeepItsValues(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), traceKey{}, "trace"))
+	jobs := utils.NewJobs(ctx)
+	cancel()
+
+	values := make(chan any, 1)
+	jobs.Go(func(ctx context.Context) {
+		if ctx.Err() != nil {
+			values <- ctx.Err()
+			return
+		}
+		values <- ctx.Value(traceKey{})
+	})
+
+	if value := <-values; value != "trace" {
+		t.Errorf("Expected the job to keep running with the trace, but got %v", value)
+	}
+}
+
+func TestJobsShutdownDrainsJobsInFlight(t *testing.T) {
+	jobs := utils.NewJobs(context.Background())
+
+	var finished atomic.Int32
+	for range 3 {
+		jobs.Go(func(ctx context.Context) {
+			select {
+			case <-time.After(50 * time.Millisecond):
+				finished.Add(1)
+			case <-ctx.Done():
+			}
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := jobs.Shutdown(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if finished.Load() != 3 {
+		t.Errorf("Expected %d jobs to finish, but got %d", 3, finished.Load())
+	}
+}
+
+func TestJobsShutdownAbortsJobsThatDoNotFinish(t *testing.T) {
+	jobs := utils.NewJobs(context.Background())
+
+	var aborted atomic.Bool
+	jobs.Go(func(ctx context.Context) {
+		<-ctx.Done()
+		// Shutdown waits for the job to clean up after being aborted.
+		time.Sleep(20 * time.Millisecond)
+		aborted.Store(true)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := jobs.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected %v, but got %v", context.DeadlineExceeded, err)
+	}
+
+	if !aborted.Load() {
+		t.Error("Expected Shutdown to return after the job was aborted")
+	}
+}
@@ -0,0 +1,196 @@
+package videos
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/bits"
+	"os/exec"
+	"strconv"
+)
+
+const (
+	// FingerprintFrames is how many frames are sampled across the whole video.
+	// Each frame is reduced to a 64 bit difference hash of a 9x8 thumbnail.
+	FingerprintFrames = 8
+	// MaxFingerprintDistance is the average number of differing bits per
+	// frame below which two videos are considered the same content.
+	MaxFingerprintDistance = 10
+	// MaxFingerprintDurationDifferenceMs bounds how much the duration of a
+	// re-encode may differ from the original. Only videos this close in
+	// duration are compared, so the lookup stays on an index.
+	MaxFingerprintDurationDifferenceMs = 1000
+
+	fingerprintWidth  = 9
+	fingerprintHeight = 8
+)
+
+var (
+	ErrInvalidFingerprint = errors.New("invalid fingerprint")
+)
+
+// ContentHash computes the SHA-256 of everything read through it, so the hash
+// is available as soon as the body has been streamed to its destination.
+type ContentHash struct {
+	reader io.Reader
+	hash   hash.Hash
+}
+
+func NewContentHash(reader io.Reader) *ContentHash {
+	hash := sha256.New()
+	return &ContentHash{
+		reader: io.TeeReader(reader, hash),
+		hash:   hash,
+	}
+}
+
+func (c *ContentHash) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+func (c *ContentHash) Sum() string {
+	return hex.EncodeToString(c.hash.Sum(nil))
+}
+
+// FingerprintFromFrames turns raw 8 bit grayscale frames of 9x8 pixels into a
+// fingerprint with one difference hash per frame.
+func FingerprintFromFrames(raw []byte) (string, error) {
+	frameSize := fingerprintWidth * fingerprintHeight
+	if len(raw) == 0 || len(raw)%frameSize != 0 {
+		return "", ErrInvalidFingerprint
+	}
+
+	var fingerprint bytes.Buffer
+	for offset := 0; offset < len(raw); offset += frameSize {
+		frame := raw[offset : offset+frameSize]
+
+		var frameHash uint64
+		for y := 0; y < fingerprintHeight; y++ {
+			for x := 0; x < fingerprintWidth-1; x++ {
+				frameHash <<= 1
+				if frame[y*fingerprintWidth+x] < frame[y*fingerprintWidth+x+1] {
+					frameHash |= 1
+				}
+			}
+		}
+
+		fmt.Fprintf(&fingerprint, "%016x", frameHash)
+	}
+
+	return fingerprint.String(), nil
+}
+
+// FingerprintDistance returns the average number of differing bits between
+// the frame hashes of two fingerprints. Fingerprints with a different number
+// of frames cannot be compared.
+func FingerprintDistance(a string, b string) (float64, error) {
+	if len(a) != len(b) || len(a) == 0 || len(a)%16 != 0 {
+		return 0, ErrInvalidFingerprint
+	}
+
+	var distance int
+	for offset := 0; offset < len(a); offset += 16 {
+		frameA, err := strconv.ParseUint(a[offset:offset+16], 16, 64)
+		if err != nil {
+			return 0, errors.Join(err, ErrInvalidFingerprint)
+		}
+		frameB, err := strconv.ParseUint(b[offset:offset+16], 16, 64)
+		if err != nil {
+			return 0, errors.Join(err, ErrInvalidFingerprint)
+		}
+
+		distance += bits.OnesCount64(frameA ^ frameB)
+	}
+
+	return float64(distance) / float64(len(a)/16), nil
+}
+
+func (e *Exporter) computeFingerprint(directory string, durationMs int64, ctx context.Context) (string, error) {
+	ctx, span := e.tracer.Start(ctx, "videos.exporter.computeFingerprint")
+	defer span.End()
+
+	if durationMs <= 0 {
+		return "", ErrInvalidFingerprint
+	}
+
+	// Sampling at frames/duration fps spreads the frames evenly over the
+	// whole video without seeking once per frame.
+	fps := float64(FingerprintFrames) * 1000 / float64(durationMs)
+	cmd := exec.CommandContext(
+		ctx,
+		"ffmpeg",
+		"-v", "error",
+		"-i", fmt.Sprintf("%s/original", directory),
+		"-an", "-sn",
+		"-vf", fmt.Sprintf("fps=%f,scale=%d:%d,format=gray", fps, fingerprintWidth, fingerprintHeight),
+		"-frames:v", strconv.Itoa(FingerprintFrames),
+		"-f", "rawvideo",
+		"pipe:1")
+
+	output, err := cmd.Output()
+	if err != nil {
+		e.recordFfmpegFailure("fingerprint", ctx)
+		return "", errors.Join(err, errors.New("failed to run ffmpeg"))
+	}
+
+	return FingerprintFromFrames(output)
+}
+
+// checkContentDuplicate stores the hash of the original and reports the
+// video it is an exact copy of, if any.
+func (e *Exporter) checkContentDuplicate(video *DbVideo, contentSha256 string, ctx context.Context) (*DbVideo, error) {
+	if video.ContentSha256 == nil {
+		err := e.videosRepository.UpdateContentSha256(video.Id, contentSha256, ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	original, err := e.videosRepository.GetOriginalByContentSha256(contentSha256, video.Id, video.CreatedAt, ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return original, nil
+}
+
+// flagPerceptualDuplicate fingerprints the original and flags the video when
+// it looks like an earlier one. Re-encodes are not rejected outright because
+// the match is approximate.
+func (e *Exporter) flagPerceptualDuplicate(video *DbVideo, directory string, durationMs int64, ctx context.Context) error {
+	fingerprint, err := e.computeFingerprint(directory, durationMs, ctx)
+	if err != nil {
+		return err
+	}
+
+	err = e.videosRepository.UpdateFingerprint(video.Id, fingerprint, durationMs, ctx)
+	if err != nil {
+		return err
+	}
+
+	candidates, err := e.videosRepository.GetFingerprintedBefore(video.Id, video.CreatedAt, durationMs-MaxFingerprintDurationDifferenceMs, durationMs+MaxFingerprintDurationDifferenceMs, ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, candidate := range candidates {
+		distance, err := FingerprintDistance(fingerprint, *candidate.Fingerprint)
+		if err != nil || distance > MaxFingerprintDistance {
+			continue
+		}
+
+		e.logger.Warn().Str("videoId", video.Id.String()).Str("duplicateOf", candidate.Id.String()).Float64("distance", distance).Msg("Video looks like a duplicate")
+		return e.videosRepository.UpdateDuplicateOf(video.Id, candidate.Id, ctx)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package videos_test
+
+import (
+	"bytes"
+	"dewarrum/vocabulary-leveling/internal/videos"
+	"io"
+	"testing"
+)
+
+func gradientFrames(frames int, invert bool) []byte {
+	var raw []byte
+	for f := 0; f < frames; f++ {
+		for y := 0; y < 8; y++ {
+			for x := 0; x < 9; x++ {
+				value := byte(x*20 + f)
+				if invert {
+					value = 255 - value
+				}
+				raw = append(raw, value)
+			}
+		}
+	}
+	return raw
+}
+
+func TestFingerprintDistanceOfSameFrames(t *testing.T) {
+	a, err := videos.FingerprintFromFrames(gradientFrames(videos.FingerprintFrames, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := videos.FingerprintFromFrames(gradientFrames(videos.FingerprintFrames, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	distance, err := videos.FingerprintDistance(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if distance != 0 {
+		t.Errorf("Expected distance to be %d, but got %f", 0, distance)
+	}
+}
+
+func TestFingerprintDistanceOfInvertedFrames(t *testing.T) {
+	a, err := videos.FingerprintFromFrames(gradientFrames(videos.FingerprintFrames, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := videos.FingerprintFromFrames(gradientFrames(videos.FingerprintFrames, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	distance, err := videos.FingerprintDistance(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if distance != 64 {
+		t.Errorf("Expected distance to be %d, but got %f", 64, distance)
+	}
+}
+
+func TestFingerprintFromFramesRejectsPartialFrames(t *testing.T) {
+	_, err := videos.FingerprintFromFrames(make([]byte, 10))
+	if err == nil {
+		t.Error("Expected partial frame to be rejected")
+	}
+}
+
+func TestContentHashHashesWhatIsRead(t *testing.T) {
+	contentHash := videos.NewContentHash(bytes.NewReader([]byte("hello")))
+
+	var copied bytes.Buffer
+	_, err := io.Copy(&copied, contentHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if contentHash.Sum() != expected {
+		t.Errorf("Expected hash to be %s, but got %s", expected, contentHash.Sum())
+	}
+	if copied.String() != "hello" {
+		t.Errorf("Expected the content to be passed through, but got %q", copied.String())
+	}
+}
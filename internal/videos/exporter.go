@@ -7,6 +7,8 @@ import (
 	"dewarrum/vocabulary-leveling/internal/inits"
 	"dewarrum/vocabulary-leveling/internal/manifests"
 	"dewarrum/vocabulary-leveling/internal/mpd"
+	"dewarrum/vocabulary-leveling/internal/subtitles"
+	"dewarrum/vocabulary-leveling/internal/utils"
 	"errors"
 	"fmt"
 	"io"
@@ -14,9 +16,12 @@ import (
 	"os/exec"
 	"regexp"
 	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -26,13 +31,21 @@ var (
 )
 
 type Exporter struct {
-	manifestsRepository *manifests.ManifestsRepository
-	initsRepository     *inits.InitsRepository
-	chunksRepository    *chunks.ChunksRepository
-	fileStorage         *FileStorage
-	messageQueue        *MessageQueue
-	logger              zerolog.Logger
-	tracer              trace.Tracer
+	videosRepository         *VideosRepository
+	manifestsRepository      *manifests.ManifestsRepository
+	initsRepository          *inits.InitsRepository
+	chunksRepository         *chunks.ChunksRepository
+	subtitleTracksRepository *subtitles.SubtitleTracksRepository
+	fileStorage              *FileStorage
+	subtitlesFileStorage     *subtitles.FileStorage
+	messageQueue             *MessageQueue
+	subtitlesMessageQueue    *subtitles.MessageQueue
+	logger                   zerolog.Logger
+	tracer                   trace.Tracer
+	exportDuration           metric.Float64Histogram
+	ffmpegFailures           metric.Int64Counter
+	segmentDuration          time.Duration
+	jobs                     *utils.Jobs
 }
 
 func NewExporter(dependencies *app.Dependencies) (*Exporter, error) {
@@ -41,41 +54,128 @@ func NewExporter(dependencies *app.Dependencies) (*Exporter, error) {
 		return nil, errors.Join(err, errors.New("failed to create message queue"))
 	}
 
+	subtitlesMessageQueue, err := subtitles.NewMessageQueue(dependencies)
+	if err != nil {
+		return nil, errors.Join(err, errors.New("failed to create subtitles message queue"))
+	}
+
+	exportDuration, err := dependencies.Meter.Float64Histogram(
+		"videos.export.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of video exports"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ffmpegFailures, err := dependencies.Meter.Int64Counter(
+		"ffmpeg.failures",
+		metric.WithDescription("Number of ffmpeg runs that failed"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Exporter{
-		manifestsRepository: manifests.NewManifestsRepository(dependencies),
-		initsRepository:     inits.NewInitsRepository(dependencies),
-		chunksRepository:    chunks.NewChunksRepository(dependencies),
-		fileStorage:         NewFileStorage(dependencies.S3Client, dependencies.S3PresignClient),
-		messageQueue:        messageQueue,
-		logger:              dependencies.Logger,
-		tracer:              dependencies.Tracer,
+		videosRepository:         NewVideosRepository(dependencies),
+		manifestsRepository:      manifests.NewManifestsRepository(dependencies),
+		initsRepository:          inits.NewInitsRepository(dependencies),
+		chunksRepository:         chunks.NewChunksRepository(dependencies),
+		subtitleTracksRepository: subtitles.NewSubtitleTracksRepository(dependencies),
+		fileStorage:              NewFileStorage(dependencies.ObjectStore),
+		subtitlesFileStorage:     subtitles.NewFileStorage(dependencies.ObjectStore),
+		messageQueue:             messageQueue,
+		subtitlesMessageQueue:    subtitlesMessageQueue,
+		logger:                   dependencies.Logger,
+		tracer:                   dependencies.Tracer,
+		exportDuration:           exportDuration,
+		ffmpegFailures:           ffmpegFailures,
+		segmentDuration:          dependencies.Config.Export.SegmentDuration,
 	}, nil
 }
 
-func (e *Exporter) Run(context context.Context) error {
-	e.logger.Info().Msg("Starting video exporter")
+// Run starts concurrency goroutines that export videos in parallel. At most
+// prefetch messages are taken off the queue before being acknowledged.
+func (e *Exporter) Run(concurrency int, prefetch int, ctx context.Context) error {
+	e.logger.Info().Int("concurrency", concurrency).Int("prefetch", prefetch).Msg("Starting video exporter")
 
-	messages, err := e.messageQueue.Consume()
+	messages, err := e.messageQueue.Consume(prefetch, ctx)
 	if err != nil {
 		e.logger.Fatal().Err(err).Msg("Failed to register a consumer")
 		return errors.Join(err, ErrFailedToRun)
 	}
 
-	go func() {
-		for message := range messages {
-			e.logger.Info().Str("videoId", message.VideoId.String()).Msg("Exporting video")
+	// Exports outlive ctx so that they can finish while shutting down,
+	// Shutdown aborts them once the grace period is over.
+	e.jobs = utils.NewJobs(ctx)
+	for range concurrency {
+		e.jobs.Go(func(ctx context.Context) {
+			for message := range messages {
+				e.processMessage(message, ctx)
+			}
+		})
+	}
+
+	return nil
+}
+
+// Shutdown waits for the exports in flight after the context passed to Run is
+// done. Exports still running when ctx is done are aborted and requeued.
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	err := e.jobs.Shutdown(ctx)
+	if err != nil {
+		e.logger.Warn().Msg("Aborted video exports that did not finish in time")
+	}
+
+	return err
+}
+
+func (e *Exporter) processMessage(message ExportVideoMessage, context context.Context) {
+	context, span := e.tracer.Start(
+		message.Context(context),
+		"videos.exporter.handleMessage",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attribute.String("videoId", message.VideoId.String())),
+	)
+	defer span.End()
+	e.logger.Info().Str("videoId", message.VideoId.String()).Msg("Exporting video")
+
+	start := time.Now()
+	err := e.handleMessage(message, context)
+	e.exportDuration.Record(context, time.Since(start).Seconds(), metric.WithAttributes(attribute.Bool("error", err != nil)))
+	if err != nil {
+		e.logger.Error().Str("videoId", message.VideoId.String()).Err(err).Msg("Failed to export video")
+		span.RecordError(err, trace.WithStackTrace(true))
 
-			err = e.handleMessage(message, context)
+		// An export aborted by Shutdown is picked up again after the restart.
+		if context.Err() != nil {
+			err = message.Nack(true)
 			if err != nil {
-				e.logger.Error().Str("videoId", message.VideoId.String()).Err(err).Msg("Failed to export video")
-				continue
+				e.logger.Error().Str("videoId", message.VideoId.String()).Err(err).Msg("Failed to requeue message")
 			}
+			return
+		}
 
-			e.logger.Info().Str("videoId", message.VideoId.String()).Msg("Video exported successfully")
+		err = e.videosRepository.UpdateStatus(message.VideoId, VideoStatusFailed, context)
+		if err != nil {
+			e.logger.Error().Str("videoId", message.VideoId.String()).Err(err).Msg("Failed to mark video as failed")
 		}
-	}()
 
-	return nil
+		// Exports are not retried, the video stays failed until it is
+		// uploaded again.
+		err = message.Nack(false)
+		if err != nil {
+			e.logger.Error().Str("videoId", message.VideoId.String()).Err(err).Msg("Failed to reject message")
+		}
+		return
+	}
+
+	err = message.Ack()
+	if err != nil {
+		e.logger.Error().Str("videoId", message.VideoId.String()).Err(err).Msg("Failed to acknowledge message")
+	}
+
+	e.logger.Info().Str("videoId", message.VideoId.String()).Msg("Video exported successfully")
 }
 
 func (e *Exporter) handleMessage(message ExportVideoMessage, context context.Context) error {
@@ -96,70 +196,144 @@ func (e *Exporter) handleMessage(message ExportVideoMessage, context context.Con
 	if err != nil {
 		return errors.Join(err, errors.New("failed to create directory"))
 	}
+	err = os.MkdirAll(fmt.Sprintf("%s/thumbnails", directory), 0755)
+	if err != nil {
+		return errors.Join(err, errors.New("failed to create directory"))
+	}
 	defer os.RemoveAll(directory)
 
-	err = e.downloadVideo(message.VideoId, directory, context)
+	video, err := e.videosRepository.GetById(message.VideoId, context)
+	if err != nil {
+		return errors.Join(err, errors.New("failed to get video"))
+	}
+
+	contentSha256, err := e.downloadVideo(message.VideoId, directory, context)
 	if err != nil {
 		return errors.Join(err, errors.New("failed to download video"))
 	}
 
-	err = e.convertToDash(directory)
+	original, err := e.checkContentDuplicate(video, contentSha256, context)
+	if err != nil {
+		return errors.Join(err, errors.New("failed to check for duplicates"))
+	}
+	if original != nil {
+		e.logger.Warn().Str("videoId", message.VideoId.String()).Str("duplicateOf", original.Id.String()).Msg("Skipping export of duplicate video")
+
+		err = e.videosRepository.UpdateDuplicateOf(message.VideoId, original.Id, context)
+		if err != nil {
+			return errors.Join(err, errors.New("failed to flag duplicate video"))
+		}
+
+		return e.videosRepository.UpdateStatus(message.VideoId, VideoStatusDuplicate, context)
+	}
+
+	err = e.convertToDash(directory, context)
 	if err != nil {
 		return errors.Join(err, errors.New("failed to run ffmpeg"))
 	}
 
-	err = e.saveContents(message.VideoId, directory, context)
+	manifest, err := e.saveContents(message.VideoId, directory, context)
 	if err != nil {
 		return errors.Join(err, errors.New("failed to upload video"))
 	}
 
+	durationMs, err := manifest.GetDurationMs()
+	if err != nil {
+		return errors.Join(err, errors.New("failed to get video duration"))
+	}
+
+	err = e.flagPerceptualDuplicate(video, directory, durationMs, context)
+	if err != nil {
+		e.logger.Warn().Str("videoId", message.VideoId.String()).Err(err).Msg("Failed to fingerprint video")
+	}
+
+	err = e.generateThumbnailTiles(directory, context)
+	if err != nil {
+		return errors.Join(err, errors.New("failed to generate thumbnail tiles"))
+	}
+
+	err = e.saveThumbnailTiles(message.VideoId, directory, context)
+	if err != nil {
+		return errors.Join(err, errors.New("failed to save thumbnail tiles"))
+	}
+
+	if message.ExtractSubtitles {
+		err = e.extractSubtitleStreams(message.VideoId, directory, context)
+		if err != nil {
+			return errors.Join(err, errors.New("failed to extract subtitle streams"))
+		}
+	}
+
+	err = e.videosRepository.UpdateExported(message.VideoId, durationMs, context)
+	if err != nil {
+		return errors.Join(err, errors.New("failed to mark video as exported"))
+	}
+
 	return nil
 }
 
-func (e *Exporter) downloadVideo(videoId uuid.UUID, directory string, context context.Context) error {
+// downloadVideo copies the original to directory and returns its SHA-256.
+func (e *Exporter) downloadVideo(videoId uuid.UUID, directory string, context context.Context) (string, error) {
+	context, span := e.tracer.Start(context, "videos.exporter.download")
+	defer span.End()
 	e.logger.Info().Str("videoId", videoId.String()).Msg("Start downloading video")
 
-	response, err := e.fileStorage.Download(videoId, context)
+	body, err := e.fileStorage.Download(videoId, context)
 	if err != nil {
-		return errors.Join(err, errors.New("failed to download video"))
+		return "", errors.Join(err, errors.New("failed to download video"))
 	}
+	defer body.Close()
 
 	e.logger.Info().Str("videoId", videoId.String()).Msg("Finished downloading video")
 
 	path := fmt.Sprintf("%s/original", directory)
 	fi, err := os.Create(path)
 	if err != nil {
-		return errors.Join(err, errors.New("failed to create file"))
+		return "", errors.Join(err, errors.New("failed to create file"))
 	}
 	defer fi.Close()
 
-	_, err = io.Copy(fi, response.Body)
+	contentHash := NewContentHash(body)
+	_, err = io.Copy(fi, contentHash)
 	if err != nil {
-		return errors.Join(err, errors.New("failed to download video"))
+		return "", errors.Join(err, errors.New("failed to download video"))
 	}
 
 	e.logger.Info().Str("videoId", videoId.String()).Msg("Saved downloaded video to file system")
 
-	return nil
+	return contentHash.Sum(), nil
 }
 
-func (e *Exporter) convertToDash(directory string) error {
+func (e *Exporter) convertToDash(directory string, ctx context.Context) error {
+	ctx, span := e.tracer.Start(ctx, "videos.exporter.ffmpeg")
+	defer span.End()
 	e.logger.Info().Str("videoId", directory).Msg("Running ffmpeg")
 
-	cmd := exec.Command(
+	frameRate, err := probeFrameRate(directory, ctx)
+	if err != nil {
+		e.logger.Warn().Err(err).Str("videoId", directory).Float64("frameRate", DefaultFrameRate).Msg("Failed to probe frame rate, using the default")
+		frameRate = DefaultFrameRate
+	}
+	keyframeInterval := strconv.Itoa(KeyframeInterval(frameRate, e.segmentDuration))
+
+	segmentSeconds := strconv.FormatFloat(e.segmentDuration.Seconds(), 'f', -1, 64)
+	cmd := exec.CommandContext(
+		ctx,
 		"ffmpeg",
 		"-i", fmt.Sprintf("%s/original", directory),
-		"-g", "30",
-		"-keyint_min", "30",
-		"-seg_duration", "2",
-		"-force_key_frames", "expr:gte(t,n_forced*2)",
+		"-g", keyframeInterval,
+		"-keyint_min", keyframeInterval,
+		"-seg_duration", segmentSeconds,
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%s)", segmentSeconds),
 		"-init_seg_name", "inits/$RepresentationID$/stream.$ext$",
 		"-media_seg_name", "chunks/$RepresentationID$/stream-$Number%05d$.$ext$",
 		"-f", "dash",
 		fmt.Sprintf("%s/manifest.mpd", directory))
 
-	err := cmd.Run()
+	err = cmd.Run()
 	if err != nil {
+		e.recordFfmpegFailure("dash", ctx)
+		span.RecordError(err)
 		return errors.Join(err, errors.New("failed to run ffmpeg"))
 	}
 
@@ -168,6 +342,10 @@ func (e *Exporter) convertToDash(directory string) error {
 	return nil
 }
 
+func (e *Exporter) recordFfmpegFailure(operation string, ctx context.Context) {
+	e.ffmpegFailures.Add(ctx, 1, metric.WithAttributes(attribute.String("operation", operation)))
+}
+
 func (e *Exporter) saveInitStream(videoId uuid.UUID, representationId string, directory string, ctx context.Context) error {
 	e.logger.Info().Str("videoId", videoId.String()).Msg("Start saving chunks")
 
@@ -181,8 +359,12 @@ func (e *Exporter) saveInitStream(videoId uuid.UUID, representationId string, di
 		return errors.Join(err, errors.New("failed to upload chunk stream"))
 	}
 
+	// A requeued export stores the same init again, under the same key.
 	init := inits.NewDbInit(videoId, representationId, contentLocation)
 	_, err = e.initsRepository.Insert(init, ctx)
+	if errors.Is(err, inits.ErrInitAlreadyExists) {
+		return nil
+	}
 	if err != nil {
 		return errors.Join(err, errors.New("failed to save init to database"))
 	}
@@ -204,6 +386,8 @@ func getChunkStreamNumber(filename string) (int64, error) {
 }
 
 func (e *Exporter) saveChunkStreams(videoId uuid.UUID, representationId string, directory string, segmentTemplate *mpd.SegmentTemplate, ctx context.Context) error {
+	ctx, span := e.tracer.Start(ctx, "videos.exporter.saveChunkStreams", trace.WithAttributes(attribute.String("representationId", representationId)))
+	defer span.End()
 	e.logger.Info().Str("videoId", videoId.String()).Msg("Start saving chunks")
 
 	entries, err := os.ReadDir(fmt.Sprintf("%s/chunks/%s", directory, representationId))
@@ -241,6 +425,9 @@ func (e *Exporter) saveChunkStreams(videoId uuid.UUID, representationId string,
 
 		chunk := chunks.NewDbChunk(videoId, representationId, int(chunkStreamNumber), contentLocation, segmentInfo.TimestampMs, segmentInfo.TimestampMs+segmentInfo.DurationMs)
 		_, err = e.chunksRepository.Insert(chunk, ctx)
+		if errors.Is(err, chunks.ErrChunkAlreadyExists) {
+			continue
+		}
 		if err != nil {
 			return errors.Join(err, errors.New("failed to save chunk to database"))
 		}
@@ -249,43 +436,45 @@ func (e *Exporter) saveChunkStreams(videoId uuid.UUID, representationId string,
 	return nil
 }
 
-func (e *Exporter) saveContents(videoId uuid.UUID, directory string, ctx context.Context) error {
+func (e *Exporter) saveContents(videoId uuid.UUID, directory string, ctx context.Context) (*mpd.MPD, error) {
+	ctx, span := e.tracer.Start(ctx, "videos.exporter.uploadSegments")
+	defer span.End()
 	e.logger.Info().Str("videoId", videoId.String()).Msg("Start uploading video")
 
 	e.logger.Info().Str("mifestPath", fmt.Sprintf("%s/manifest.mpd", directory)).Msg("Opening manifest file")
 	manifestFile, err := os.Open(fmt.Sprintf("%s/manifest.mpd", directory))
 	if err != nil {
-		return errors.Join(err, errors.New("failed to open manifest file"))
+		return nil, errors.Join(err, errors.New("failed to open manifest file"))
 	}
 
 	// TODO: don't upload manifest to s3
 	err = e.fileStorage.UploadManifest(videoId, manifestFile, ctx)
 	if err != nil {
-		return errors.Join(err, errors.New("failed to upload manifest"))
+		return nil, errors.Join(err, errors.New("failed to upload manifest"))
 	}
 
 	// TODO: don't read manifest file twice
 	manifestFile, err = os.Open(fmt.Sprintf("%s/manifest.mpd", directory))
 	if err != nil {
-		return errors.Join(err, errors.New("failed to open manifest file"))
+		return nil, errors.Join(err, errors.New("failed to open manifest file"))
 	}
 	manifestBody, err := io.ReadAll(manifestFile)
 	if err != nil {
-		return errors.Join(err, errors.New("failed to read manifest file"))
+		return nil, errors.Join(err, errors.New("failed to read manifest file"))
 	}
 	manifest, err := mpd.Parse(manifestBody)
 	if err != nil {
-		return errors.Join(err, errors.New("failed to parse manifest"))
+		return nil, errors.Join(err, errors.New("failed to parse manifest"))
 	}
 
 	err = e.saveManifest(videoId, manifest, ctx)
 	if err != nil {
-		return errors.Join(err, errors.New("failed to save manifest to database"))
+		return nil, errors.Join(err, errors.New("failed to save manifest to database"))
 	}
 
 	entries, err := os.ReadDir(fmt.Sprintf("%s/inits", directory))
 	if err != nil {
-		return errors.Join(err, errors.New("failed to read directory"))
+		return nil, errors.Join(err, errors.New("failed to read directory"))
 	}
 
 	for _, entry := range entries {
@@ -296,13 +485,13 @@ func (e *Exporter) saveContents(videoId uuid.UUID, directory string, ctx context
 		representationId := entry.Name()
 		err = e.saveInitStream(videoId, representationId, directory, ctx)
 		if err != nil {
-			return errors.Join(err, errors.New("failed to save init stream"))
+			return nil, errors.Join(err, errors.New("failed to save init stream"))
 		}
 	}
 
 	entries, err = os.ReadDir(fmt.Sprintf("%s/chunks", directory))
 	if err != nil {
-		return errors.Join(err, errors.New("failed to read directory"))
+		return nil, errors.Join(err, errors.New("failed to read directory"))
 	}
 
 	for _, entry := range entries {
@@ -317,12 +506,12 @@ func (e *Exporter) saveContents(videoId uuid.UUID, directory string, ctx context
 		}
 		err = e.saveChunkStreams(videoId, representationId, directory, representation.SegmentTemplate, ctx)
 		if err != nil {
-			return errors.Join(err, errors.New("failed to save chunk stream"))
+			return nil, errors.Join(err, errors.New("failed to save chunk stream"))
 		}
 	}
 
 	e.logger.Info().Str("videoId", videoId.String()).Msg("Finished uploading video")
-	return nil
+	return manifest, nil
 }
 
 func (e *Exporter) saveManifest(videoId uuid.UUID, manifest *mpd.MPD, ctx context.Context) error {
@@ -0,0 +1,160 @@
+package videos_test
+
+import (
+	"context"
+	"database/sql"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/bus"
+	"dewarrum/vocabulary-leveling/internal/config"
+	"dewarrum/vocabulary-leveling/internal/storage"
+	"dewarrum/vocabulary-leveling/internal/videos"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// fakeFfmpeg writes a DASH export with one segment per representation and a
+// single thumbnail tile. Fingerprinting fails, which the exporter tolerates.
+const fakeFfmpeg = `#!/bin/sh
+for output; do :; done
+case "$output" in
+*manifest.mpd)
+	directory=$(dirname "$output")
+	for representation in 0 1; do
+		printf 'init' > "$directory/inits/$representation/stream.m4s"
+		printf 'chunk' > "$directory/chunks/$representation/stream-00001.m4s"
+	done
+	cat > "$output" <<'EOF'
+<MPD type="static" mediaPresentationDuration="PT4.0S">
+	<Period id="0" start="PT0.0S">
+		<AdaptationSet id="0" contentType="video">
+			<Representation id="0"><SegmentTemplate timescale="1000"><SegmentTimeline><S t="0" d="4000"/></SegmentTimeline></SegmentTemplate></Representation>
+		</AdaptationSet>
+		<AdaptationSet id="1" contentType="audio">
+			<Representation id="1"><SegmentTemplate timescale="1000"><SegmentTimeline><S t="0" d="4000"/></SegmentTimeline></SegmentTemplate></Representation>
+		</AdaptationSet>
+	</Period>
+</MPD>
+EOF
+	;;
+pipe:1)
+	exit 1
+	;;
+*tile-*)
+	printf 'tile' > "$(dirname "$output")/tile-00001.jpg"
+	;;
+esac
+`
+
+const fakeFfprobe = `#!/bin/sh
+exit 1
+`
+
+func TestExporterFinishesRedeliveredExportsWhoseChunksAreStored(t *testing.T) {
+	bin := t.TempDir()
+	for name, script := range map[string]string{"ffmpeg": fakeFfmpeg, "ffprobe": fakeFfprobe} {
+		err := os.WriteFile(filepath.Join(bin, name), []byte(script), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	// Exports are unpacked below the working directory.
+	workingDirectory, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(workingDirectory) })
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	memoryBus := bus.NewMemoryBus()
+	defer memoryBus.Close()
+
+	store := storage.NewLocalStore(t.TempDir(), "http://localhost/storage", []byte("secret"))
+	dependencies := &app.Dependencies{
+		Config:      config.Default(),
+		Postgres:    sqlx.NewDb(db, "postgres"),
+		Bus:         memoryBus,
+		ObjectStore: store,
+		Logger:      zerolog.Nop(),
+		Tracer:      noop.NewTracerProvider().Tracer(""),
+		Meter:       metricnoop.NewMeterProvider().Meter(""),
+	}
+	exporter, err := videos.NewExporter(dependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages, err := videos.NewMessageQueue(dependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	videoId := uuid.New()
+	err = videos.NewFileStorage(store).Upload(videoId, strings.NewReader("original"), "video/mp4", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The previous delivery stored everything before it was aborted.
+	stored := &pq.Error{Code: "23505"}
+	mock.ExpectQuery("SELECT .* FROM videos WHERE id = \\$1").
+		WithArgs(videoId).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "status", "content_sha256", "created_at"}).
+			AddRow(videoId, "Episode 1", videos.VideoStatusProcessing, "sha256", time.Now()))
+	mock.ExpectQuery("SELECT .* FROM videos WHERE content_sha256 = \\$1").WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec("INSERT INTO manifests").WillReturnError(stored)
+	mock.ExpectExec("INSERT INTO inits").WithArgs(sqlmock.AnyArg(), videoId, "0", sqlmock.AnyArg()).WillReturnError(stored)
+	mock.ExpectExec("INSERT INTO inits").WithArgs(sqlmock.AnyArg(), videoId, "1", sqlmock.AnyArg()).WillReturnError(stored)
+	mock.ExpectExec("INSERT INTO chunks").WithArgs(sqlmock.AnyArg(), videoId, "0", 1, sqlmock.AnyArg(), 0, 4000).WillReturnError(stored)
+	mock.ExpectExec("INSERT INTO chunks").WithArgs(sqlmock.AnyArg(), videoId, "1", 1, sqlmock.AnyArg(), 0, 4000).WillReturnError(stored)
+	mock.ExpectExec("INSERT INTO chunks").WithArgs(sqlmock.AnyArg(), videoId, "thumbnails", 1, sqlmock.AnyArg(), 0, videos.ThumbnailTileMs).WillReturnError(stored)
+	mock.ExpectExec("UPDATE videos SET status = \\$1, duration_ms = \\$2").
+		WithArgs(videos.VideoStatusReady, 4000, videoId).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = messages.Send(videos.NewExportVideoMessage(videoId, false), ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = exporter.Run(1, 1, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for mock.ExpectationsWereMet() != nil {
+		if time.Now().After(deadline) {
+			t.Fatal(mock.ExpectationsWereMet())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	err = exporter.Shutdown(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
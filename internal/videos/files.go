@@ -3,40 +3,40 @@ package videos
 import (
 	"context"
 	"dewarrum/vocabulary-leveling/internal/mpd"
+	"dewarrum/vocabulary-leveling/internal/storage"
 	"errors"
 	"fmt"
 	"io"
+	"time"
 
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/google/uuid"
 )
 
 const (
+	PresignedUploadExpiration = time.Hour
+
 	FailedToUpload   = "failed to upload"
 	FailedToDownload = "failed to download"
 	FailedToList     = "failed to list"
+	FailedToDelete   = "failed to delete"
 )
 
 type FileStorage struct {
-	s3Client        *s3.Client
-	s3PresignClient *s3.PresignClient
+	objectStore storage.ObjectStore
 }
 
-func NewFileStorage(s3Client *s3.Client, s3PresignClient *s3.PresignClient) *FileStorage {
+func NewFileStorage(objectStore storage.ObjectStore) *FileStorage {
 	return &FileStorage{
-		s3Client:        s3Client,
-		s3PresignClient: s3PresignClient,
+		objectStore: objectStore,
 	}
 }
 
+func OriginalKey(videoId uuid.UUID) string {
+	return fmt.Sprintf("%s/original", videoId)
+}
+
 func (f *FileStorage) Upload(videoId uuid.UUID, body io.Reader, contentType string, context context.Context) error {
-	_, err := f.s3Client.PutObject(context, &s3.PutObjectInput{
-		Bucket:      aws.String("default"),
-		Key:         aws.String(fmt.Sprintf("%s/original", videoId)),
-		Body:        body,
-		ContentType: &contentType,
-	})
+	err := f.objectStore.Put(OriginalKey(videoId), body, contentType, context)
 	if err != nil {
 		return errors.Join(err, errors.New(FailedToUpload))
 	}
@@ -44,27 +44,50 @@ func (f *FileStorage) Upload(videoId uuid.UUID, body io.Reader, contentType stri
 	return nil
 }
 
-func (f *FileStorage) Download(videoId uuid.UUID, context context.Context) (*s3.GetObjectOutput, error) {
-	result, err := f.s3Client.GetObject(context, &s3.GetObjectInput{
-		Bucket: aws.String("default"),
-		Key:    aws.String(fmt.Sprintf("%s/original", videoId)),
-	})
+// Delete removes the original of a video, e.g. once it turns out to be a copy
+// of another one.
+func (f *FileStorage) Delete(videoId uuid.UUID, ctx context.Context) error {
+	err := f.objectStore.Delete(OriginalKey(videoId), ctx)
+	if err != nil {
+		return errors.Join(err, errors.New(FailedToDelete))
+	}
+
+	return nil
+}
+
+func (f *FileStorage) UploadPoster(videoId uuid.UUID, body io.Reader, contentType string, ctx context.Context) (string, error) {
+	key := fmt.Sprintf("%s/poster", videoId)
+	err := f.objectStore.Put(key, body, contentType, ctx)
+	if err != nil {
+		return "", errors.Join(err, errors.New(FailedToUpload))
+	}
+
+	return key, nil
+}
+
+func (f *FileStorage) PresignUpload(videoId uuid.UUID, contentType string, ctx context.Context) (string, error) {
+	return f.objectStore.PresignPut(OriginalKey(videoId), storage.PresignOptions{
+		Expiration:  PresignedUploadExpiration,
+		ContentType: contentType,
+	}, ctx)
+}
+
+func (f *FileStorage) Exists(videoId uuid.UUID, ctx context.Context) (bool, error) {
+	return f.objectStore.Exists(OriginalKey(videoId), ctx)
+}
+
+func (f *FileStorage) Download(videoId uuid.UUID, context context.Context) (io.ReadCloser, error) {
+	object, err := f.objectStore.Get(OriginalKey(videoId), context)
 	if err != nil {
 		return nil, errors.Join(err, errors.New(FailedToDownload))
 	}
 
-	return result, nil
+	return object.Body, nil
 }
 
 func (f *FileStorage) UploadChunkStream(videoId uuid.UUID, representationId string, chunkStreamName string, body io.Reader, context context.Context) (string, error) {
-	contentType := "video/iso.segment"
 	key := fmt.Sprintf("%s/chunks/%s/%s", videoId, representationId, chunkStreamName)
-	_, err := f.s3Client.PutObject(context, &s3.PutObjectInput{
-		Bucket:      aws.String("default"),
-		Key:         aws.String(key),
-		Body:        body,
-		ContentType: &contentType,
-	})
+	err := f.objectStore.Put(key, body, "video/iso.segment", context)
 	if err != nil {
 		return "", errors.Join(err, errors.New(FailedToUpload))
 	}
@@ -72,60 +95,36 @@ func (f *FileStorage) UploadChunkStream(videoId uuid.UUID, representationId stri
 	return key, nil
 }
 
-func (f *FileStorage) ListChunkStreams(videoId uuid.UUID, context context.Context) (*s3.ListObjectsV2Output, error) {
-	response, err := f.s3Client.ListObjectsV2(context, &s3.ListObjectsV2Input{
-		Bucket: aws.String("default"),
-		Prefix: aws.String(fmt.Sprintf("%s/chunks", videoId)),
-	})
+func (f *FileStorage) ListChunkStreams(videoId uuid.UUID, context context.Context) ([]string, error) {
+	keys, err := f.objectStore.List(fmt.Sprintf("%s/chunks", videoId), context)
 	if err != nil {
 		return nil, errors.Join(err, errors.New(FailedToList))
 	}
 
-	return response, nil
+	return keys, nil
 }
 
 func (f *FileStorage) PresignChunkStreams(videoId uuid.UUID, context context.Context) ([]string, error) {
-	response, err := f.ListChunkStreams(videoId, context)
+	keys, err := f.ListChunkStreams(videoId, context)
 	if err != nil {
 		return nil, errors.Join(err, errors.New(FailedToList))
 	}
 
-	var presignedUrls []string
-	for _, chunkStream := range response.Contents {
-		presignedUrl, err := f.s3PresignClient.PresignGetObject(context, &s3.GetObjectInput{
-			Bucket: aws.String("default"),
-			Key:    aws.String(*chunkStream.Key),
-		})
-		if err != nil {
-			return nil, errors.Join(err, errors.New("failed to presign object"))
-		}
-		presignedUrls = append(presignedUrls, presignedUrl.URL)
-	}
-
-	return presignedUrls, nil
+	return f.presignAll(keys, context)
 }
 
-func (f *FileStorage) DownloadChunkStream(videoId uuid.UUID, chunkStreamName string, context context.Context) (*s3.GetObjectOutput, error) {
-	result, err := f.s3Client.GetObject(context, &s3.GetObjectInput{
-		Bucket: aws.String("default"),
-		Key:    aws.String(fmt.Sprintf("%s/chunks/%s", videoId, chunkStreamName)),
-	})
+func (f *FileStorage) DownloadChunkStream(videoId uuid.UUID, chunkStreamName string, context context.Context) (io.ReadCloser, error) {
+	object, err := f.objectStore.Get(fmt.Sprintf("%s/chunks/%s", videoId, chunkStreamName), context)
 	if err != nil {
 		return nil, errors.Join(err, errors.New(FailedToDownload))
 	}
 
-	return result, nil
+	return object.Body, nil
 }
 
 func (f *FileStorage) UploadInitStream(videoId uuid.UUID, representationId string, initStreamName string, body io.Reader, ctx context.Context) (string, error) {
-	contentType := "video/iso.segment"
 	key := fmt.Sprintf("%s/inits/%s/%s", videoId, representationId, initStreamName)
-	_, err := f.s3Client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket:      aws.String("default"),
-		Key:         aws.String(key),
-		Body:        body,
-		ContentType: &contentType,
-	})
+	err := f.objectStore.Put(key, body, "video/iso.segment", ctx)
 	if err != nil {
 		return "", errors.Join(err, errors.New(FailedToUpload))
 	}
@@ -133,59 +132,45 @@ func (f *FileStorage) UploadInitStream(videoId uuid.UUID, representationId strin
 	return key, nil
 }
 
-func (f *FileStorage) ListInitStreams(videoId uuid.UUID, context context.Context) (*s3.ListObjectsV2Output, error) {
-	response, err := f.s3Client.ListObjectsV2(context, &s3.ListObjectsV2Input{
-		Bucket: aws.String("default"),
-		Prefix: aws.String(fmt.Sprintf("%s/init", videoId)),
-	})
+func (f *FileStorage) UploadThumbnailTile(videoId uuid.UUID, tileName string, body io.Reader, ctx context.Context) (string, error) {
+	key := fmt.Sprintf("%s/thumbnails/tiles/%s", videoId, tileName)
+	err := f.objectStore.Put(key, body, "image/jpeg", ctx)
 	if err != nil {
-		return nil, errors.Join(err, errors.New(FailedToList))
+		return "", errors.Join(err, errors.New(FailedToUpload))
 	}
 
-	return response, nil
+	return key, nil
 }
 
-func (f *FileStorage) PresignInitStreams(videoId uuid.UUID, context context.Context) ([]string, error) {
-	response, err := f.ListInitStreams(videoId, context)
+func (f *FileStorage) ListInitStreams(videoId uuid.UUID, context context.Context) ([]string, error) {
+	keys, err := f.objectStore.List(fmt.Sprintf("%s/init", videoId), context)
 	if err != nil {
 		return nil, errors.Join(err, errors.New(FailedToList))
 	}
 
-	var presignedUrls []string
-	for _, initStream := range response.Contents {
-		presignedUrl, err := f.s3PresignClient.PresignGetObject(context, &s3.GetObjectInput{
-			Bucket: aws.String("default"),
-			Key:    aws.String(*initStream.Key),
-		})
-		if err != nil {
-			return nil, errors.Join(err, errors.New("failed to presign object"))
-		}
-		presignedUrls = append(presignedUrls, presignedUrl.URL)
+	return keys, nil
+}
+
+func (f *FileStorage) PresignInitStreams(videoId uuid.UUID, context context.Context) ([]string, error) {
+	keys, err := f.ListInitStreams(videoId, context)
+	if err != nil {
+		return nil, errors.Join(err, errors.New(FailedToList))
 	}
 
-	return presignedUrls, nil
+	return f.presignAll(keys, context)
 }
 
-func (f *FileStorage) DownloadInitStream(videoId uuid.UUID, initStreamName string, context context.Context) (*s3.GetObjectOutput, error) {
-	result, err := f.s3Client.GetObject(context, &s3.GetObjectInput{
-		Bucket: aws.String("default"),
-		Key:    aws.String(fmt.Sprintf("%s/init/%s", videoId, initStreamName)),
-	})
+func (f *FileStorage) DownloadInitStream(videoId uuid.UUID, initStreamName string, context context.Context) (io.ReadCloser, error) {
+	object, err := f.objectStore.Get(fmt.Sprintf("%s/init/%s", videoId, initStreamName), context)
 	if err != nil {
 		return nil, errors.Join(err, errors.New(FailedToDownload))
 	}
 
-	return result, nil
+	return object.Body, nil
 }
 
 func (f *FileStorage) UploadManifest(videoId uuid.UUID, body io.Reader, context context.Context) error {
-	contentType := "application/dash+xml"
-	_, err := f.s3Client.PutObject(context, &s3.PutObjectInput{
-		Bucket:      aws.String("default"),
-		Key:         aws.String(fmt.Sprintf("%s/manifest.mpd", videoId)),
-		Body:        body,
-		ContentType: &contentType,
-	})
+	err := f.objectStore.Put(fmt.Sprintf("%s/manifest.mpd", videoId), body, "application/dash+xml", context)
 	if err != nil {
 		return errors.Join(err, errors.New(FailedToUpload))
 	}
@@ -194,15 +179,13 @@ func (f *FileStorage) UploadManifest(videoId uuid.UUID, body io.Reader, context
 }
 
 func (f *FileStorage) DownloadManifest(videoId uuid.UUID, context context.Context) (*mpd.MPD, error) {
-	response, err := f.s3Client.GetObject(context, &s3.GetObjectInput{
-		Bucket: aws.String("default"),
-		Key:    aws.String(fmt.Sprintf("%s/manifest", videoId)),
-	})
+	object, err := f.objectStore.Get(fmt.Sprintf("%s/manifest", videoId), context)
 	if err != nil {
 		return nil, errors.Join(err, errors.New(FailedToDownload))
 	}
+	defer object.Body.Close()
 
-	responseBody, err := io.ReadAll(response.Body)
+	responseBody, err := io.ReadAll(object.Body)
 	if err != nil {
 		return nil, err
 	}
@@ -211,13 +194,18 @@ func (f *FileStorage) DownloadManifest(videoId uuid.UUID, context context.Contex
 }
 
 func (f *FileStorage) PresignObject(key string, ctx context.Context) (string, error) {
-	presignedUrl, err := f.s3PresignClient.PresignGetObject(ctx, &s3.GetObjectInput{
-		Bucket: aws.String("default"),
-		Key:    aws.String(key),
-	})
-	if err != nil {
-		return "", errors.Join(err, errors.New("failed to presign object"))
+	return f.objectStore.PresignGet(key, storage.PresignOptions{}, ctx)
+}
+
+func (f *FileStorage) presignAll(keys []string, ctx context.Context) ([]string, error) {
+	var presignedUrls []string
+	for _, key := range keys {
+		presignedUrl, err := f.PresignObject(key, ctx)
+		if err != nil {
+			return nil, err
+		}
+		presignedUrls = append(presignedUrls, presignedUrl)
 	}
 
-	return presignedUrl.URL, nil
+	return presignedUrls, nil
 }
@@ -0,0 +1,90 @@
+package videos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultFrameRate is assumed when the frame rate of a video cannot be probed.
+const DefaultFrameRate = 30
+
+var (
+	ErrInvalidFrameRate = errors.New("invalid frame rate")
+)
+
+// ParseFrameRate parses a frame rate the way ffprobe reports it, as a
+// fraction such as 30000/1001 or as a plain number.
+func ParseFrameRate(rate string) (float64, error) {
+	numerator, denominator, isFraction := strings.Cut(rate, "/")
+	value, err := strconv.ParseFloat(numerator, 64)
+	if err != nil {
+		return 0, errors.Join(err, ErrInvalidFrameRate)
+	}
+	if isFraction {
+		divisor, err := strconv.ParseFloat(denominator, 64)
+		if err != nil {
+			return 0, errors.Join(err, ErrInvalidFrameRate)
+		}
+		if divisor == 0 {
+			return 0, ErrInvalidFrameRate
+		}
+		value /= divisor
+	}
+	if value <= 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, ErrInvalidFrameRate
+	}
+
+	return value, nil
+}
+
+// KeyframeInterval is the number of frames in one segment, so that every
+// segment starts on a keyframe of its own.
+func KeyframeInterval(frameRate float64, segmentDuration time.Duration) int {
+	return max(1, int(math.Round(frameRate*segmentDuration.Seconds())))
+}
+
+func probeFrameRate(directory string, ctx context.Context) (float64, error) {
+	cmd := exec.CommandContext(
+		ctx,
+		"ffprobe",
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=avg_frame_rate,r_frame_rate",
+		"-of", "json",
+		fmt.Sprintf("%s/original", directory))
+
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, errors.Join(err, errors.New("failed to run ffprobe"))
+	}
+
+	var probe struct {
+		Streams []struct {
+			AvgFrameRate string `json:"avg_frame_rate"`
+			RFrameRate   string `json:"r_frame_rate"`
+		} `json:"streams"`
+	}
+	err = json.Unmarshal(output, &probe)
+	if err != nil {
+		return 0, errors.Join(err, errors.New("failed to parse ffprobe output"))
+	}
+	if len(probe.Streams) == 0 {
+		return 0, errors.New("video has no video stream")
+	}
+
+	// Variable frame rate streams report 0/0 as their average.
+	stream := probe.Streams[0]
+	frameRate, err := ParseFrameRate(stream.AvgFrameRate)
+	if err != nil {
+		return ParseFrameRate(stream.RFrameRate)
+	}
+
+	return frameRate, nil
+}
@@ -0,0 +1,42 @@
+package videos_test
+
+import (
+	"dewarrum/vocabulary-leveling/internal/videos"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestKeyframeIntervalCoversOneSegment(t *testing.T) {
+	cases := []struct {
+		rate            string
+		segmentDuration time.Duration
+		expected        int
+	}{
+		{rate: "30/1", segmentDuration: 2 * time.Second, expected: 60},
+		{rate: "30000/1001", segmentDuration: 2 * time.Second, expected: 60},
+		{rate: "25", segmentDuration: 4 * time.Second, expected: 100},
+		{rate: "24000/1001", segmentDuration: 6 * time.Second, expected: 144},
+		{rate: "60/1", segmentDuration: 10 * time.Millisecond, expected: 1},
+	}
+
+	for _, c := range cases {
+		frameRate, err := videos.ParseFrameRate(c.rate)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if interval := videos.KeyframeInterval(frameRate, c.segmentDuration); interval != c.expected {
+			t.Errorf("Expected %d frames per %s segment at %s, but got %d", c.expected, c.segmentDuration, c.rate, interval)
+		}
+	}
+}
+
+func TestParseFrameRateRejectsUnknownRates(t *testing.T) {
+	for _, rate := range []string{"0/0", "", "abc", "30/x", "-25/1"} {
+		_, err := videos.ParseFrameRate(rate)
+		if !errors.Is(err, videos.ErrInvalidFrameRate) {
+			t.Errorf("Expected %v for %q, but got %v", videos.ErrInvalidFrameRate, rate, err)
+		}
+	}
+}
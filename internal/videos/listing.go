@@ -0,0 +1,188 @@
+package videos
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SortNewest   = "newest"
+	SortName     = "name"
+	SortDuration = "duration"
+)
+
+var (
+	ErrUnsupportedSort = errors.New("unsupported sort")
+	ErrInvalidCursor   = errors.New("invalid cursor")
+)
+
+// sortOrders maps every supported sort to the key it orders by and the
+// direction it walks in. The key is always paired with the id so that the
+// order is total and a cursor identifies exactly one position.
+var sortOrders = map[string]struct {
+	key        string
+	descending bool
+}{
+	SortNewest:   {key: "v.created_at", descending: true},
+	SortName:     {key: "v.name", descending: false},
+	SortDuration: {key: "COALESCE(v.duration_ms, 0)", descending: true},
+}
+
+type ListFilter struct {
+	SeriesId *uuid.UUID
+	Genre    *string
+	Status   *string
+	Language *string
+}
+
+// Cursor is the position right after the last video of a page. It carries the
+// sort key of that video so the next page can be fetched with a keyset query.
+type Cursor struct {
+	Sort       string     `json:"s"`
+	Id         uuid.UUID  `json:"i"`
+	CreatedAt  *time.Time `json:"c,omitempty"`
+	Name       *string    `json:"n,omitempty"`
+	DurationMs *int64     `json:"d,omitempty"`
+}
+
+func newCursor(sort string, video *DbVideo) *Cursor {
+	cursor := &Cursor{Sort: sort, Id: video.Id}
+	switch sort {
+	case SortNewest:
+		cursor.CreatedAt = &video.CreatedAt
+	case SortName:
+		cursor.Name = &video.Name
+	case SortDuration:
+		var durationMs int64
+		if video.DurationMs != nil {
+			durationMs = *video.DurationMs
+		}
+		cursor.DurationMs = &durationMs
+	}
+
+	return cursor
+}
+
+func (c *Cursor) key() (interface{}, error) {
+	switch {
+	case c.Sort == SortNewest && c.CreatedAt != nil:
+		return *c.CreatedAt, nil
+	case c.Sort == SortName && c.Name != nil:
+		return *c.Name, nil
+	case c.Sort == SortDuration && c.DurationMs != nil:
+		return *c.DurationMs, nil
+	}
+
+	return nil, ErrInvalidCursor
+}
+
+func (c *Cursor) Encode() (string, error) {
+	body, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(body), nil
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	body, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.Join(err, ErrInvalidCursor)
+	}
+
+	var cursor Cursor
+	err = json.Unmarshal(body, &cursor)
+	if err != nil {
+		return nil, errors.Join(err, ErrInvalidCursor)
+	}
+
+	return &cursor, nil
+}
+
+// List returns up to limit videos matching filter in the given sort order,
+// starting after cursor. The returned cursor is nil on the last page.
+func (r *VideosRepository) List(filter *ListFilter, sort string, cursor *Cursor, limit int, ctx context.Context) ([]*DbVideo, *Cursor, error) {
+	ctx, span := r.tracer.Start(ctx, "videos.repository.list")
+	defer span.End()
+	r.logger.Debug().Str("sort", sort).Int("limit", limit).Msg("Listing videos")
+
+	order, ok := sortOrders[sort]
+	if !ok {
+		return nil, nil, ErrUnsupportedSort
+	}
+
+	var conditions []string
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.SeriesId != nil {
+		conditions = append(conditions, "s.series_id = "+arg(*filter.SeriesId))
+	}
+	if filter.Genre != nil {
+		genre := arg(*filter.Genre)
+		conditions = append(conditions, fmt.Sprintf("(%s = ANY(v.genres) OR %s = ANY(se.genres))", genre, genre))
+	}
+	if filter.Status != nil {
+		conditions = append(conditions, "v.status = "+arg(*filter.Status))
+	}
+	if filter.Language != nil {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM subtitle_tracks t WHERE t.video_id = v.id AND t.language = "+arg(*filter.Language)+")")
+	}
+
+	comparison, direction := ">", "ASC"
+	if order.descending {
+		comparison, direction = "<", "DESC"
+	}
+
+	if cursor != nil {
+		if cursor.Sort != sort {
+			return nil, nil, ErrInvalidCursor
+		}
+
+		key, err := cursor.key()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		conditions = append(conditions, fmt.Sprintf("(%s, v.id) %s (%s, %s)", order.key, comparison, arg(key), arg(cursor.Id)))
+	}
+
+	query := "SELECT " + prefixedVideoColumns("v") + " FROM videos v LEFT JOIN seasons s ON s.id = v.season_id LEFT JOIN series se ON se.id = s.series_id"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, v.id %s LIMIT %s", order.key, direction, direction, arg(limit+1))
+
+	var videos []*DbVideo
+	err := r.db.SelectContext(ctx, &videos, query, args...)
+	if err != nil {
+		return nil, nil, errors.Join(err, errors.New("failed to list videos"))
+	}
+
+	if len(videos) <= limit {
+		return videos, nil, nil
+	}
+
+	videos = videos[:limit]
+	return videos, newCursor(sort, videos[limit-1]), nil
+}
+
+func prefixedVideoColumns(alias string) string {
+	columns := strings.Split(videoColumns, ", ")
+	for i, column := range columns {
+		columns[i] = alias + "." + column
+	}
+
+	return strings.Join(columns, ", ")
+}
@@ -3,45 +3,52 @@ package videos
 import (
 	"context"
 	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/bus"
 	"encoding/json"
 	"errors"
 
 	"github.com/google/uuid"
-	"github.com/rabbitmq/amqp091-go"
 	"github.com/rs/zerolog"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	FailedToDeclareQueue    = "failed to declare queue"
-	FailedToSendMessage     = "failed to send message"
-	FailedToConsume         = "failed to consume"
-	FailedToDeclareExchange = "failed to declare exchange"
-	FailedToBindQueue       = "failed to bind queue"
-	queue                   = "queue.videos.export"
-	exchange                = "exchange.videos.export"
+	FailedToSendMessage = "failed to send message"
+	FailedToConsume     = "failed to consume"
+	queue               = "queue.videos.export"
+	exchange            = "exchange.videos.export"
+	consumer            = "consumer.videos.export"
 )
 
+var exportTopic = bus.Topic{
+	Exchange:        exchange,
+	Queue:           queue,
+	DurableExchange: true,
+}
+
 type MessageQueue struct {
-	channel *amqp091.Channel
-	queue   *amqp091.Queue
-	logger  zerolog.Logger
-	tracer  trace.Tracer
+	bus      bus.Bus
+	logger   zerolog.Logger
+	tracer   trace.Tracer
+	queueLag *bus.QueueLag
 }
 
 type ExportVideoMessage struct {
-	VideoId uuid.UUID `json:"videoId"`
+	VideoId          uuid.UUID `json:"videoId"`
+	ExtractSubtitles bool      `json:"extractSubtitles"`
+	bus.Received
 }
 
-func NewExportVideoMessage(videoId uuid.UUID) *ExportVideoMessage {
+func NewExportVideoMessage(videoId uuid.UUID, extractSubtitles bool) *ExportVideoMessage {
 	return &ExportVideoMessage{
-		VideoId: videoId,
+		VideoId:          videoId,
+		ExtractSubtitles: extractSubtitles,
 	}
 }
 
 func (mq *MessageQueue) Send(message *ExportVideoMessage, ctx context.Context) error {
-	ctx, span := mq.tracer.Start(ctx, "mq.send.videos.export")
+	ctx, span := mq.tracer.Start(ctx, "mq.send.videos.export", trace.WithSpanKind(trace.SpanKindProducer))
 	span.SetAttributes(attribute.String("videoId", message.VideoId.String()))
 	defer span.End()
 
@@ -52,17 +59,10 @@ func (mq *MessageQueue) Send(message *ExportVideoMessage, ctx context.Context) e
 		return err
 	}
 
-	err = mq.channel.PublishWithContext(
-		ctx,
-		exchange, // exchange
-		"",       // routing key
-		false,    // mandatory
-		false,    // immediate
-		amqp091.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		},
-	)
+	err = mq.bus.Publish(exportTopic, bus.Message{
+		ContentType: "application/json",
+		Body:        body,
+	}, ctx)
 	if err != nil {
 		return errors.Join(err, errors.New(FailedToSendMessage))
 	}
@@ -70,74 +70,28 @@ func (mq *MessageQueue) Send(message *ExportVideoMessage, ctx context.Context) e
 	return nil
 }
 
-func (mq *MessageQueue) Consume() (<-chan ExportVideoMessage, error) {
-	deliveries, err := mq.channel.Consume(
-		mq.queue.Name,
-		"consumer.videos.export",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
+// Consume delivers export messages until ctx is done. Every message has to be
+// acknowledged with Ack or Nack once it is handled, and no more than prefetch
+// messages are delivered before that.
+func (mq *MessageQueue) Consume(prefetch int, ctx context.Context) (<-chan ExportVideoMessage, error) {
+	messages, err := bus.ConsumeJson[ExportVideoMessage](mq.bus, exportTopic, consumer, prefetch, mq.queueLag, mq.logger, ctx)
 	if err != nil {
 		return nil, errors.Join(err, errors.New(FailedToConsume))
 	}
 
-	messages := make(chan ExportVideoMessage)
-
-	go func() {
-		for delivery := range deliveries {
-			var message ExportVideoMessage
-			err := json.Unmarshal(delivery.Body, &message)
-			if err != nil {
-				mq.logger.Error().Err(err).Msg("Failed to unmarshal message")
-				continue
-			}
-
-			messages <- message
-		}
-	}()
-
 	return messages, nil
 }
 
 func NewMessageQueue(dependencies *app.Dependencies) (*MessageQueue, error) {
-	queue, err := createQueue(dependencies.RabbitMqChannel)
+	queueLag, err := bus.NewQueueLag(dependencies.Meter)
 	if err != nil {
 		return nil, err
 	}
 
-	err = dependencies.RabbitMqChannel.ExchangeDeclare(exchange, "direct", true, false, false, false, nil)
-	if err != nil {
-		return nil, errors.Join(err, errors.New(FailedToDeclareExchange))
-	}
-
-	err = dependencies.RabbitMqChannel.QueueBind(queue.Name, "", exchange, false, nil)
-	if err != nil {
-		return nil, errors.Join(err, errors.New(FailedToBindQueue))
-	}
-
 	return &MessageQueue{
-		channel: dependencies.RabbitMqChannel,
-		queue:   queue,
-		logger:  dependencies.Logger,
-		tracer:  dependencies.Tracer,
+		bus:      dependencies.Bus,
+		logger:   dependencies.Logger,
+		tracer:   dependencies.Tracer,
+		queueLag: queueLag,
 	}, nil
 }
-
-func createQueue(channel *amqp091.Channel) (*amqp091.Queue, error) {
-	q, err := channel.QueueDeclare(
-		queue, // name
-		true,  // durable
-		false, // delete when unused
-		false, // exclusive
-		false, // no-wait
-		nil,   // arguments
-	)
-	if err != nil {
-		return nil, errors.Join(err, errors.New(FailedToDeclareQueue))
-	}
-
-	return &q, nil
-}
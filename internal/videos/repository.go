@@ -3,25 +3,65 @@ package videos
 import (
 	"context"
 	"dewarrum/vocabulary-leveling/internal/app"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/rs/zerolog"
 	"go.opentelemetry.io/otel/trace"
 )
 
+const (
+	videoColumns = "id, name, created_at, season_id, episode_number, air_year, description, genres, poster_location, status, duration_ms, content_sha256, fingerprint, duplicate_of"
+)
+
+const (
+	VideoStatusUploading  = "uploading"
+	VideoStatusProcessing = "processing"
+	VideoStatusReady      = "ready"
+	VideoStatusFailed     = "failed"
+	VideoStatusDuplicate  = "duplicate"
+)
+
+var (
+	VideoStatuses = []string{VideoStatusUploading, VideoStatusProcessing, VideoStatusReady, VideoStatusFailed, VideoStatusDuplicate}
+)
+
+var (
+	ErrVideoAlreadyExists  = errors.New("video already exists")
+	ErrFailedToUpdateVideo = errors.New("failed to update video")
+)
+
 type DbVideo struct {
-	Id        uuid.UUID `db:"id"`
-	Name      string    `db:"name"`
-	CreatedAt time.Time `db:"created_at"`
+	Id             uuid.UUID      `db:"id"`
+	Name           string         `db:"name"`
+	CreatedAt      time.Time      `db:"created_at"`
+	SeasonId       *uuid.UUID     `db:"season_id"`
+	EpisodeNumber  *int           `db:"episode_number"`
+	AirYear        *int           `db:"air_year"`
+	Description    *string        `db:"description"`
+	Genres         pq.StringArray `db:"genres"`
+	PosterLocation *string        `db:"poster_location"`
+	Status         string         `db:"status"`
+	DurationMs     *int64         `db:"duration_ms"`
+	ContentSha256  *string        `db:"content_sha256"`
+	Fingerprint    *string        `db:"fingerprint"`
+	DuplicateOf    *uuid.UUID     `db:"duplicate_of"`
 }
 
 func NewDbVideo(name string) *DbVideo {
+	return NewDbVideoWithId(uuid.New(), name)
+}
+
+func NewDbVideoWithId(id uuid.UUID, name string) *DbVideo {
 	return &DbVideo{
-		Id:        uuid.New(),
+		Id:        id,
 		Name:      name,
 		CreatedAt: time.Now().In(time.UTC),
+		Genres:    pq.StringArray{},
+		Status:    VideoStatusProcessing,
 	}
 }
 
@@ -40,20 +80,41 @@ func NewVideosRepository(dependencies *app.Dependencies) *VideosRepository {
 }
 
 func (r *VideosRepository) Insert(video *DbVideo, ctx context.Context) (*DbVideo, error) {
+	ctx, span := r.tracer.Start(ctx, "videos.repository.insert")
+	defer span.End()
 	r.logger.Debug().Str("videoId", video.Id.String()).Msg("Inserting video")
 
-	_, err := r.db.NamedExecContext(ctx, "INSERT INTO videos (id, name, created_at) VALUES (:id,:name, :created_at)", video)
+	_, err := r.db.NamedExecContext(ctx, "INSERT INTO videos (id, name, created_at, season_id, episode_number, air_year, description, genres, poster_location, status, duration_ms, content_sha256, fingerprint, duplicate_of) VALUES (:id,:name, :created_at, :season_id, :episode_number, :air_year, :description, :genres, :poster_location, :status, :duration_ms, :content_sha256, :fingerprint, :duplicate_of)", video)
 	if err == nil {
 		return video, nil
 	}
 
+	pgError, ok := err.(*pq.Error)
+	if ok && pgError.Code == "23505" {
+		return nil, ErrVideoAlreadyExists
+	}
+
 	return nil, err
 }
 
+func (r *VideosRepository) GetById(id uuid.UUID, ctx context.Context) (*DbVideo, error) {
+	ctx, span := r.tracer.Start(ctx, "videos.repository.getById")
+	defer span.End()
+	r.logger.Debug().Str("videoId", id.String()).Msg("Searching video by id")
+
+	var video DbVideo
+	err := r.db.GetContext(ctx, &video, "SELECT "+videoColumns+" FROM videos WHERE id = $1 LIMIT 1", id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &video, nil
+}
+
 func (r *VideosRepository) GetManyByIds(ids []uuid.UUID, ctx context.Context) ([]*DbVideo, error) {
 	r.logger.Debug().Msg("Searching videos by ids")
 
-	query, args, err := sqlx.In("SELECT id, name, created_at FROM videos WHERE id IN (?)", ids)
+	query, args, err := sqlx.In("SELECT "+videoColumns+" FROM videos WHERE id IN (?)", ids)
 	if err != nil {
 		return nil, err
 	}
@@ -77,3 +138,175 @@ func (r *VideosRepository) GetManyByIds(ids []uuid.UUID, ctx context.Context) ([
 
 	return videos, nil
 }
+
+func (r *VideosRepository) GetManyBySeasonIds(seasonIds []uuid.UUID, ctx context.Context) ([]*DbVideo, error) {
+	ctx, span := r.tracer.Start(ctx, "videos.repository.getManyBySeasonIds")
+	defer span.End()
+	r.logger.Debug().Msg("Searching videos by season ids")
+
+	if len(seasonIds) == 0 {
+		return nil, nil
+	}
+
+	query, args, err := sqlx.In("SELECT "+videoColumns+" FROM videos WHERE season_id IN (?) ORDER BY episode_number, name", seasonIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var videos []*DbVideo
+	err = r.db.SelectContext(ctx, &videos, r.db.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return videos, nil
+}
+
+func (r *VideosRepository) UpdateCatalogue(video *DbVideo, ctx context.Context) error {
+	ctx, span := r.tracer.Start(ctx, "videos.repository.updateCatalogue")
+	defer span.End()
+	r.logger.Debug().Str("videoId", video.Id.String()).Msg("Updating video catalogue information")
+
+	_, err := r.db.NamedExecContext(ctx, "UPDATE videos SET name = :name, season_id = :season_id, episode_number = :episode_number, air_year = :air_year, description = :description, genres = :genres WHERE id = :id", video)
+	if err != nil {
+		return errors.Join(err, ErrFailedToUpdateVideo)
+	}
+
+	return nil
+}
+
+func (r *VideosRepository) UpdatePosterLocation(id uuid.UUID, posterLocation string, ctx context.Context) error {
+	ctx, span := r.tracer.Start(ctx, "videos.repository.updatePosterLocation")
+	defer span.End()
+	r.logger.Debug().Str("videoId", id.String()).Msg("Updating video poster location")
+
+	_, err := r.db.ExecContext(ctx, "UPDATE videos SET poster_location = $1 WHERE id = $2", posterLocation, id)
+	if err != nil {
+		return errors.Join(err, ErrFailedToUpdateVideo)
+	}
+
+	return nil
+}
+
+func (r *VideosRepository) UpdateStatus(id uuid.UUID, status string, ctx context.Context) error {
+	ctx, span := r.tracer.Start(ctx, "videos.repository.updateStatus")
+	defer span.End()
+	r.logger.Debug().Str("videoId", id.String()).Str("status", status).Msg("Updating video status")
+
+	_, err := r.db.ExecContext(ctx, "UPDATE videos SET status = $1 WHERE id = $2", status, id)
+	if err != nil {
+		return errors.Join(err, ErrFailedToUpdateVideo)
+	}
+
+	return nil
+}
+
+// UpdateStatusFrom moves the video from one status to another and reports
+// whether it was in the first one. Of two requests moving the same video only
+// one succeeds.
+func (r *VideosRepository) UpdateStatusFrom(id uuid.UUID, from string, to string, ctx context.Context) (bool, error) {
+	ctx, span := r.tracer.Start(ctx, "videos.repository.updateStatusFrom")
+	defer span.End()
+	r.logger.Debug().Str("videoId", id.String()).Str("from", from).Str("to", to).Msg("Updating video status")
+
+	result, err := r.db.ExecContext(ctx, "UPDATE videos SET status = $1 WHERE id = $2 AND status = $3", to, id, from)
+	if err != nil {
+		return false, errors.Join(err, ErrFailedToUpdateVideo)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Join(err, ErrFailedToUpdateVideo)
+	}
+
+	return affected == 1, nil
+}
+
+func (r *VideosRepository) UpdateExported(id uuid.UUID, durationMs int64, ctx context.Context) error {
+	ctx, span := r.tracer.Start(ctx, "videos.repository.updateExported")
+	defer span.End()
+	r.logger.Debug().Str("videoId", id.String()).Int64("durationMs", durationMs).Msg("Marking video as exported")
+
+	_, err := r.db.ExecContext(ctx, "UPDATE videos SET status = $1, duration_ms = $2 WHERE id = $3", VideoStatusReady, durationMs, id)
+	if err != nil {
+		return errors.Join(err, ErrFailedToUpdateVideo)
+	}
+
+	return nil
+}
+
+// GetOriginalByContentSha256 returns the oldest non-duplicate video with the
+// given content hash that was created before the video identified by id and
+// createdAt. Comparing creation order means two identical uploads processed
+// at the same time agree on which of them is the original.
+func (r *VideosRepository) GetOriginalByContentSha256(contentSha256 string, id uuid.UUID, createdAt time.Time, ctx context.Context) (*DbVideo, error) {
+	ctx, span := r.tracer.Start(ctx, "videos.repository.getOriginalByContentSha256")
+	defer span.End()
+	r.logger.Debug().Str("videoId", id.String()).Msg("Searching video by content hash")
+
+	var video DbVideo
+	err := r.db.GetContext(ctx, &video, "SELECT "+videoColumns+" FROM videos WHERE content_sha256 = $1 AND duplicate_of IS NULL AND (created_at, id) < ($2, $3) ORDER BY created_at, id LIMIT 1", contentSha256, createdAt, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &video, nil
+}
+
+// GetFingerprintedBefore returns the non-duplicate videos with a fingerprint
+// and a duration between minDurationMs and maxDurationMs that were created
+// before the given video.
+func (r *VideosRepository) GetFingerprintedBefore(id uuid.UUID, createdAt time.Time, minDurationMs int64, maxDurationMs int64, ctx context.Context) ([]*DbVideo, error) {
+	ctx, span := r.tracer.Start(ctx, "videos.repository.getFingerprintedBefore")
+	defer span.End()
+	r.logger.Debug().Str("videoId", id.String()).Msg("Searching fingerprinted videos")
+
+	var videos []*DbVideo
+	err := r.db.SelectContext(ctx, &videos, "SELECT "+videoColumns+" FROM videos WHERE fingerprint IS NOT NULL AND duplicate_of IS NULL AND duration_ms BETWEEN $1 AND $2 AND (created_at, id) < ($3, $4) ORDER BY created_at, id", minDurationMs, maxDurationMs, createdAt, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return videos, nil
+}
+
+func (r *VideosRepository) UpdateContentSha256(id uuid.UUID, contentSha256 string, ctx context.Context) error {
+	ctx, span := r.tracer.Start(ctx, "videos.repository.updateContentSha256")
+	defer span.End()
+	r.logger.Debug().Str("videoId", id.String()).Msg("Updating video content hash")
+
+	_, err := r.db.ExecContext(ctx, "UPDATE videos SET content_sha256 = $1 WHERE id = $2", contentSha256, id)
+	if err != nil {
+		return errors.Join(err, ErrFailedToUpdateVideo)
+	}
+
+	return nil
+}
+
+// UpdateFingerprint stores the fingerprint together with the duration it was
+// sampled over, which is what fingerprinted videos are looked up by.
+func (r *VideosRepository) UpdateFingerprint(id uuid.UUID, fingerprint string, durationMs int64, ctx context.Context) error {
+	ctx, span := r.tracer.Start(ctx, "videos.repository.updateFingerprint")
+	defer span.End()
+	r.logger.Debug().Str("videoId", id.String()).Msg("Updating video fingerprint")
+
+	_, err := r.db.ExecContext(ctx, "UPDATE videos SET fingerprint = $1, duration_ms = $2 WHERE id = $3", fingerprint, durationMs, id)
+	if err != nil {
+		return errors.Join(err, ErrFailedToUpdateVideo)
+	}
+
+	return nil
+}
+
+func (r *VideosRepository) UpdateDuplicateOf(id uuid.UUID, duplicateOf uuid.UUID, ctx context.Context) error {
+	ctx, span := r.tracer.Start(ctx, "videos.repository.updateDuplicateOf")
+	defer span.End()
+	r.logger.Debug().Str("videoId", id.String()).Str("duplicateOf", duplicateOf.String()).Msg("Flagging video as duplicate")
+
+	_, err := r.db.ExecContext(ctx, "UPDATE videos SET duplicate_of = $1 WHERE id = $2", duplicateOf, id)
+	if err != nil {
+		return errors.Join(err, ErrFailedToUpdateVideo)
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package videos
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/subtitles"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// textSubtitleCodecs lists the ffmpeg decoders whose output can be
+	// converted to SubRip. Anything else (PGS, VobSub, DVB) is bitmap based.
+	textSubtitleCodecs = map[string]bool{
+		"subrip":     true,
+		"srt":        true,
+		"ass":        true,
+		"ssa":        true,
+		"webvtt":     true,
+		"mov_text":   true,
+		"text":       true,
+		"microdvd":   true,
+		"subviewer":  true,
+		"subviewer1": true,
+		"sami":       true,
+		"realtext":   true,
+		"mpl2":       true,
+		"jacosub":    true,
+		"pjs":        true,
+		"stl":        true,
+		"vplayer":    true,
+	}
+)
+
+type probedSubtitleStream struct {
+	Index     int               `json:"index"`
+	CodecName string            `json:"codec_name"`
+	Tags      map[string]string `json:"tags"`
+}
+
+func (s *probedSubtitleStream) language() *string {
+	language, ok := s.Tags["language"]
+	if !ok || language == "" || language == "und" {
+		return nil
+	}
+
+	return &language
+}
+
+func probeSubtitleStreams(directory string, ctx context.Context) ([]*probedSubtitleStream, error) {
+	cmd := exec.CommandContext(
+		ctx,
+		"ffprobe",
+		"-v", "error",
+		"-select_streams", "s",
+		"-show_entries", "stream=index,codec_name:stream_tags=language",
+		"-of", "json",
+		fmt.Sprintf("%s/original", directory))
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Join(err, errors.New("failed to run ffprobe"))
+	}
+
+	var probe struct {
+		Streams []*probedSubtitleStream `json:"streams"`
+	}
+	err = json.Unmarshal(output, &probe)
+	if err != nil {
+		return nil, errors.Join(err, errors.New("failed to parse ffprobe output"))
+	}
+
+	return probe.Streams, nil
+}
+
+func (e *Exporter) extractSubtitleStreams(videoId uuid.UUID, directory string, ctx context.Context) error {
+	ctx, span := e.tracer.Start(ctx, "videos.exporter.extractSubtitleStreams")
+	defer span.End()
+	e.logger.Info().Str("videoId", videoId.String()).Msg("Extracting embedded subtitle streams")
+
+	streams, err := probeSubtitleStreams(directory, ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(streams) == 0 {
+		e.logger.Warn().Str("videoId", videoId.String()).Msg("Video has no embedded subtitle streams")
+		return nil
+	}
+
+	err = os.MkdirAll(fmt.Sprintf("%s/subtitles", directory), 0755)
+	if err != nil {
+		return errors.Join(err, errors.New("failed to create directory"))
+	}
+
+	for _, stream := range streams {
+		if !textSubtitleCodecs[stream.CodecName] {
+			e.logger.Warn().Str("videoId", videoId.String()).Int("streamIndex", stream.Index).Str("codec", stream.CodecName).Msg("Image-based subtitle stream is not supported")
+
+			track := subtitles.NewEmbeddedDbSubtitleTrack(videoId, stream.Index, stream.CodecName, stream.language(), subtitles.TrackStatusUnsupported)
+			_, err = e.subtitleTracksRepository.UpsertEmbedded(track, ctx)
+			if err != nil {
+				return errors.Join(err, errors.New("failed to save subtitle track"))
+			}
+			continue
+		}
+
+		err = e.extractSubtitleStream(videoId, stream, directory, ctx)
+		if err != nil {
+			return errors.Join(err, fmt.Errorf("failed to extract subtitle stream %d", stream.Index))
+		}
+	}
+
+	return nil
+}
+
+// extractSubtitleStream converts a text subtitle stream to SubRip and queues it
+// for export. A stream whose track is exported already, because the message
+// was delivered again, is skipped.
+func (e *Exporter) extractSubtitleStream(videoId uuid.UUID, stream *probedSubtitleStream, directory string, ctx context.Context) error {
+	track := subtitles.NewEmbeddedDbSubtitleTrack(videoId, stream.Index, stream.CodecName, stream.language(), subtitles.TrackStatusQueued)
+	track, err := e.subtitleTracksRepository.UpsertEmbedded(track, ctx)
+	if err != nil {
+		return errors.Join(err, errors.New("failed to save subtitle track"))
+	}
+
+	if track.Status == subtitles.TrackStatusReady {
+		e.logger.Info().Str("videoId", videoId.String()).Int("streamIndex", stream.Index).Msg("Subtitle stream is exported already")
+		return nil
+	}
+
+	path := fmt.Sprintf("%s/subtitles/%d.srt", directory, stream.Index)
+
+	cmd := exec.CommandContext(
+		ctx,
+		"ffmpeg",
+		"-y",
+		"-i", fmt.Sprintf("%s/original", directory),
+		"-map", fmt.Sprintf("0:%d", stream.Index),
+		"-c:s", "srt",
+		path)
+
+	err = cmd.Run()
+	if err != nil {
+		e.recordFfmpegFailure("subtitleStream", ctx)
+		return errors.Join(err, errors.New("failed to run ffmpeg"))
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.Join(err, errors.New("failed to open file"))
+	}
+	defer file.Close()
+
+	err = e.subtitlesFileStorage.Upload(videoId, track.Id, file, "application/x-subrip", ctx)
+	if err != nil {
+		return errors.Join(err, errors.New("failed to upload subtitle track"))
+	}
+
+	if track.Status != subtitles.TrackStatusQueued {
+		err = e.subtitleTracksRepository.UpdateStatus(track.Id, subtitles.TrackStatusQueued, ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = e.subtitlesMessageQueue.Send(subtitles.NewExportSubtitlesMessage(videoId, track.Id), ctx)
+	if err != nil {
+		return errors.Join(err, errors.New("failed to send export subtitles message"))
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package videos
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/chunks"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ThumbnailIntervalMs  = 2000
+	ThumbnailTileColumns = 5
+	ThumbnailTileRows    = 5
+	ThumbnailWidth       = 160
+	ThumbnailHeight      = 90
+	ThumbnailTileMs      = ThumbnailIntervalMs * ThumbnailTileColumns * ThumbnailTileRows
+)
+
+var (
+	thumbnailTilePattern = regexp.MustCompile(`tile-(\d{5})\.jpg`)
+)
+
+func (e *Exporter) generateThumbnailTiles(directory string, ctx context.Context) error {
+	ctx, span := e.tracer.Start(ctx, "videos.exporter.generateThumbnailTiles")
+	defer span.End()
+	e.logger.Info().Str("videoId", directory).Msg("Generating thumbnail tiles")
+
+	filter := fmt.Sprintf(
+		"fps=1000/%d,scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,tile=%dx%d",
+		ThumbnailIntervalMs,
+		ThumbnailWidth, ThumbnailHeight,
+		ThumbnailWidth, ThumbnailHeight,
+		ThumbnailTileColumns, ThumbnailTileRows)
+
+	cmd := exec.CommandContext(
+		ctx,
+		"ffmpeg",
+		"-i", fmt.Sprintf("%s/original", directory),
+		"-an", "-sn",
+		"-vf", filter,
+		"-q:v", "5",
+		fmt.Sprintf("%s/thumbnails/tile-%%05d.jpg", directory))
+
+	err := cmd.Run()
+	if err != nil {
+		e.recordFfmpegFailure("thumbnailTiles", ctx)
+		return errors.Join(err, errors.New("failed to run ffmpeg"))
+	}
+
+	return nil
+}
+
+func getThumbnailTileNumber(filename string) (int64, error) {
+	matches := thumbnailTilePattern.FindStringSubmatch(filename)
+	if len(matches) != 2 {
+		return 0, errors.New("failed to find thumbnail tile number")
+	}
+
+	number, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil {
+		return 0, errors.Join(err, errors.New("failed to parse thumbnail tile number"))
+	}
+	return number, nil
+}
+
+func (e *Exporter) saveThumbnailTiles(videoId uuid.UUID, directory string, ctx context.Context) error {
+	ctx, span := e.tracer.Start(ctx, "videos.exporter.saveThumbnailTiles")
+	defer span.End()
+	e.logger.Info().Str("videoId", videoId.String()).Msg("Start saving thumbnail tiles")
+
+	entries, err := os.ReadDir(fmt.Sprintf("%s/thumbnails", directory))
+	if err != nil {
+		return errors.Join(err, errors.New("failed to read directory"))
+	}
+
+	for _, entry := range entries {
+		tileNumber, err := getThumbnailTileNumber(entry.Name())
+		if err != nil {
+			return errors.Join(err, errors.New("failed to get thumbnail tile number"))
+		}
+
+		file, err := os.Open(fmt.Sprintf("%s/thumbnails/%s", directory, entry.Name()))
+		if err != nil {
+			return errors.Join(err, errors.New("failed to open file"))
+		}
+
+		contentLocation, err := e.fileStorage.UploadThumbnailTile(videoId, entry.Name(), file, ctx)
+		file.Close()
+		if err != nil {
+			return errors.Join(err, errors.New("failed to upload thumbnail tile"))
+		}
+
+		startMs := (tileNumber - 1) * ThumbnailTileMs
+		chunk := chunks.NewDbChunk(videoId, chunks.ThumbnailTilesRepresentationId, int(tileNumber), contentLocation, startMs, startMs+ThumbnailTileMs)
+		_, err = e.chunksRepository.Insert(chunk, ctx)
+		if errors.Is(err, chunks.ErrChunkAlreadyExists) {
+			continue
+		}
+		if err != nil {
+			return errors.Join(err, errors.New("failed to save thumbnail tile to database"))
+		}
+	}
+
+	return nil
+}
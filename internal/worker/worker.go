@@ -0,0 +1,82 @@
+package worker
+
+import (
+	"context"
+	"dewarrum/vocabulary-leveling/internal/app"
+	"dewarrum/vocabulary-leveling/internal/subtitles"
+	"dewarrum/vocabulary-leveling/internal/videos"
+	"errors"
+	"sync"
+)
+
+var (
+	ErrFailedToStart = errors.New("failed to start exporters")
+)
+
+type Options struct {
+	VideoConcurrency     int
+	VideoPrefetch        int
+	SubtitlesConcurrency int
+	SubtitlesPrefetch    int
+}
+
+// DefaultOptions export one video and one subtitle track at a time, which is
+// what the API runs next to request handling.
+var DefaultOptions = Options{
+	VideoConcurrency:     1,
+	VideoPrefetch:        1,
+	SubtitlesConcurrency: 1,
+	SubtitlesPrefetch:    1,
+}
+
+// Worker is the pair of exporters started by Start.
+type Worker struct {
+	videoExporter     *videos.Exporter
+	subtitlesExporter *subtitles.Exporter
+}
+
+// Start runs the video and subtitles exporters until ctx is done. Exports in
+// flight at that point keep running until Shutdown.
+func Start(dependencies *app.Dependencies, options Options, ctx context.Context) (*Worker, error) {
+	videoExporter, err := videos.NewExporter(dependencies)
+	if err != nil {
+		return nil, errors.Join(err, errors.New("failed to create video exporter"), ErrFailedToStart)
+	}
+
+	err = videoExporter.Run(options.VideoConcurrency, options.VideoPrefetch, ctx)
+	if err != nil {
+		return nil, errors.Join(err, errors.New("failed to run video exporter"), ErrFailedToStart)
+	}
+
+	subtitlesExporter, err := subtitles.NewExporter(dependencies, ctx)
+	if err != nil {
+		return nil, errors.Join(err, errors.New("failed to create subtitles exporter"), ErrFailedToStart)
+	}
+
+	err = subtitlesExporter.Run(options.SubtitlesConcurrency, options.SubtitlesPrefetch, ctx)
+	if err != nil {
+		return nil, errors.Join(err, errors.New("failed to run subtitles exporter"), ErrFailedToStart)
+	}
+
+	return &Worker{
+		videoExporter:     videoExporter,
+		subtitlesExporter: subtitlesExporter,
+	}, nil
+}
+
+// Shutdown waits for the exports in flight once the context passed to Start is
+// done, and aborts and requeues the ones still running when ctx is done.
+func (w *Worker) Shutdown(ctx context.Context) error {
+	var videoErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		videoErr = w.videoExporter.Shutdown(ctx)
+	}()
+
+	subtitlesErr := w.subtitlesExporter.Shutdown(ctx)
+	wg.Wait()
+
+	return errors.Join(videoErr, subtitlesErr)
+}
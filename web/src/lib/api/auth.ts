@@ -1,26 +1,21 @@
 import { createQuery } from "@tanstack/svelte-query";
+import { ApiRequestError, getProfile as getProfileRequest, type Profile } from './client';
 
-export type Profile = {
-    name: string;
-    username: string;
-    roles: string[];
-}
+export type { Profile };
 
 export async function getProfile() {
-    const response = await fetch('/auth/profile', {
-        method: 'GET'
-    });
+    try {
+        return await getProfileRequest();
+    } catch (error) {
+        if (error instanceof ApiRequestError && error.status === 401) {
+            return null;
+        }
 
-    if (response.status === 401) {
-        return null;
+        throw error;
     }
-
-    const data: Profile = await response.json();
-
-    return data;
 }
 
 export const createProfileQuery = () => createQuery({
     queryKey: ['profile'],
     queryFn: () => getProfile()
-});
\ No newline at end of file
+});
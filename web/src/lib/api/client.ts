@@ -0,0 +1,350 @@
+// Code generated by `go run ./cmd/openapi`. DO NOT EDIT.
+
+export type CaptionStyle = {
+	italic?: boolean;
+	position?: string;
+	speaker?: string;
+	style?: string;
+};
+
+export type CompleteVideoRequest = {
+	extractSubtitles: boolean;
+};
+
+export type CreateVideoRequest = {
+	videoName: string;
+	videoContentType: string;
+	subtitlesContentType: string | null;
+	subtitlesLanguage: string | null;
+};
+
+export type CreateVideoResponse = {
+	videoId: string;
+	videoUploadUrl: string;
+	subtitlesTrackId?: string;
+	subtitlesUploadUrl?: string;
+};
+
+export type DependencyStatus = {
+	name: string;
+	healthy: boolean;
+	latencyMs: number;
+	error?: string;
+};
+
+export type DuplicateVideo = {
+	duplicateOf: string;
+	location: string;
+};
+
+export type Episode = {
+	id: string;
+	name: string;
+	episodeNumber: number | null;
+	airYear: number | null;
+	description: string | null;
+	genres: string[];
+	posterUrl?: string;
+};
+
+export type ExportingVideo = {
+	videoId: string;
+};
+
+export type HealthReport = {
+	healthy: boolean;
+	checkedAt: string;
+	dependencies: DependencyStatus[];
+};
+
+export type Problem = {
+	type: string;
+	title: string;
+	status: number;
+	code: string;
+	detail?: string;
+	instance?: string;
+};
+
+export type Profile = {
+	iss: string;
+	sub: string;
+	aud: string;
+	exp: number;
+	iat: number;
+	at_hash: string;
+	name: string;
+	username: string;
+	picture: string;
+	email: string;
+	email_verified: boolean;
+	phone_number: string;
+	phone_number_verified: boolean;
+	roles: string[];
+	organizations: string[];
+	organization_roles: string[];
+};
+
+export type Season = {
+	id: string;
+	seriesId: string;
+	number: number;
+	airYear: number | null;
+	description: string | null;
+	episodes: Episode[];
+};
+
+export type SeasonRequest = {
+	number: number;
+	airYear: number | null;
+	description: string | null;
+};
+
+export type Series = {
+	id: string;
+	name: string;
+	description: string | null;
+	genres: string[];
+	createdAt: string;
+	posterUrl?: string;
+	seasons?: Season[];
+};
+
+export type SeriesRequest = {
+	name: string;
+	description: string | null;
+	genres: string[];
+};
+
+export type Subtitle = {
+	id: string;
+	videoId: string;
+	videoName: string;
+	startMs: number;
+	endMs: number;
+	text: string;
+	airYear?: number;
+	episodeNumber?: number;
+	seasonId?: string;
+	seasonNumber?: number;
+	seriesId?: string;
+	seriesName?: string;
+	style?: CaptionStyle;
+	thumbnailUrl?: string;
+	utterances?: Utterance[];
+};
+
+export type SubtitleTrack = {
+	id: string;
+	source: string;
+	status: string;
+	language: string | null;
+	streamIndex: number | null;
+	codec: string | null;
+	createdAt: string;
+};
+
+export type Utterance = {
+	text: string;
+	speaker?: string;
+};
+
+export type Video = {
+	id: string;
+	name: string;
+	status: string;
+	durationMs: number | null;
+	seasonId: string | null;
+	episodeNumber: number | null;
+	airYear: number | null;
+	description: string | null;
+	genres: string[];
+	createdAt: string;
+	duplicateOf?: string;
+	posterUrl?: string;
+};
+
+export type VideoCatalogueRequest = {
+	name: string;
+	seasonId: string | null;
+	episodeNumber: number | null;
+	airYear: number | null;
+	description: string | null;
+	genres: string[];
+};
+
+export type VideoDetail = {
+	id: string;
+	name: string;
+	status: string;
+	durationMs: number | null;
+	seasonId: string | null;
+	episodeNumber: number | null;
+	airYear: number | null;
+	description: string | null;
+	genres: string[];
+	createdAt: string;
+	subtitleCount: number;
+	subtitleTracks: SubtitleTrack[];
+	duplicateOf?: string;
+	posterUrl?: string;
+	seasonNumber?: number;
+	seriesId?: string;
+	seriesName?: string;
+};
+
+export type VideoPage = {
+	items: Video[];
+	nextCursor: string | null;
+};
+
+export class ApiRequestError extends Error {
+	constructor(
+		public readonly status: number,
+		public readonly body: unknown
+	) {
+		super(`Request failed with status ${status}`);
+	}
+}
+
+async function request<T>(
+	method: string,
+	path: string,
+	query: Record<string, string | number | boolean | null | undefined>,
+	init: RequestInit
+): Promise<T> {
+	const search = new URLSearchParams();
+	for (const [key, value] of Object.entries(query)) {
+		if (value !== undefined && value !== null && value !== '') {
+			search.set(key, String(value));
+		}
+	}
+
+	const url = search.toString() ? `${path}?${search.toString()}` : path;
+	const response = await fetch(url, { ...init, method });
+	if (!response.ok) {
+		const body = await response.json().catch(() => null);
+		throw new ApiRequestError(response.status, body);
+	}
+
+	if (response.status === 204 || response.headers.get('Content-Length') === '0') {
+		return undefined as T;
+	}
+
+	return (await response.json()) as T;
+}
+
+export function getHealth(): Promise<HealthReport> {
+	return request('GET', '/api/admin/health', {}, {});
+}
+
+export function deleteSeason(params: { seasonId: string }): Promise<void> {
+	return request('DELETE', `/api/admin/seasons/${encodeURIComponent(params.seasonId)}`, {}, {});
+}
+
+export function updateSeason(params: { seasonId: string }, body: SeasonRequest): Promise<Season> {
+	return request('PUT', `/api/admin/seasons/${encodeURIComponent(params.seasonId)}`, {}, {
+		body: JSON.stringify(body),
+		headers: { 'Content-Type': 'application/json' }
+	});
+}
+
+export function createSeries(body: SeriesRequest): Promise<Series> {
+	return request('POST', '/api/admin/series', {}, {
+		body: JSON.stringify(body),
+		headers: { 'Content-Type': 'application/json' }
+	});
+}
+
+export function deleteSeries(params: { seriesId: string }): Promise<void> {
+	return request('DELETE', `/api/admin/series/${encodeURIComponent(params.seriesId)}`, {}, {});
+}
+
+export function updateSeries(params: { seriesId: string }, body: SeriesRequest): Promise<Series> {
+	return request('PUT', `/api/admin/series/${encodeURIComponent(params.seriesId)}`, {}, {
+		body: JSON.stringify(body),
+		headers: { 'Content-Type': 'application/json' }
+	});
+}
+
+export function uploadSeriesPoster(params: { seriesId: string }, body: FormData): Promise<Series> {
+	return request('PUT', `/api/admin/series/${encodeURIComponent(params.seriesId)}/poster`, {}, { body });
+}
+
+export function createSeason(params: { seriesId: string }, body: SeasonRequest): Promise<Season> {
+	return request('POST', `/api/admin/series/${encodeURIComponent(params.seriesId)}/seasons`, {}, {
+		body: JSON.stringify(body),
+		headers: { 'Content-Type': 'application/json' }
+	});
+}
+
+/** Registers a video and returns the URLs to upload its files to */
+export function createVideo(body: CreateVideoRequest): Promise<CreateVideoResponse> {
+	return request('POST', '/api/admin/videos', {}, {
+		body: JSON.stringify(body),
+		headers: { 'Content-Type': 'application/json' }
+	});
+}
+
+export function uploadVideo(body: FormData): Promise<ExportingVideo> {
+	return request('POST', '/api/admin/videos/upload', {}, { body });
+}
+
+export function getTusCapabilities(): Promise<void> {
+	return request('OPTIONS', '/api/admin/videos/uploads', {}, {});
+}
+
+export function updateVideo(params: { videoId: string }, body: VideoCatalogueRequest): Promise<Episode> {
+	return request('PUT', `/api/admin/videos/${encodeURIComponent(params.videoId)}`, {}, {
+		body: JSON.stringify(body),
+		headers: { 'Content-Type': 'application/json' }
+	});
+}
+
+export function completeVideo(params: { videoId: string }, body?: CompleteVideoRequest): Promise<ExportingVideo> {
+	return request('POST', `/api/admin/videos/${encodeURIComponent(params.videoId)}/complete`, {}, {
+		body: JSON.stringify(body),
+		headers: { 'Content-Type': 'application/json' }
+	});
+}
+
+export function uploadVideoPoster(params: { videoId: string }, body: FormData): Promise<Episode> {
+	return request('PUT', `/api/admin/videos/${encodeURIComponent(params.videoId)}/poster`, {}, { body });
+}
+
+export function listSubtitleTracks(params: { videoId: string }): Promise<SubtitleTrack[]> {
+	return request('GET', `/api/admin/videos/${encodeURIComponent(params.videoId)}/subtitle-tracks`, {}, {});
+}
+
+export function getOpenApi(): Promise<Record<string, unknown>> {
+	return request('GET', '/api/openapi.json', {}, {});
+}
+
+export function listSeries(): Promise<Series[]> {
+	return request('GET', '/api/series', {}, {});
+}
+
+export function getSeries(params: { seriesId: string }): Promise<Series> {
+	return request('GET', `/api/series/${encodeURIComponent(params.seriesId)}`, {}, {});
+}
+
+export function searchSubtitles(params: { query: string }): Promise<Subtitle[]> {
+	return request('GET', '/api/subtitles/search', { query: params.query }, {});
+}
+
+/** Redirects to the clip of a subtitle, rendering it first when needed */
+export function getSubtitleClip(params: { trackId: string; sequence: number; format: 'm4a' | 'mp3' | 'mp4' | 'webp'; burnIn?: boolean }): Promise<void> {
+	return request('GET', `/api/subtitles/${encodeURIComponent(params.trackId)}/${encodeURIComponent(params.sequence)}/clip.${encodeURIComponent(params.format)}`, { burnIn: params.burnIn }, {});
+}
+
+export function listVideos(params: { sort?: 'newest' | 'name' | 'duration'; limit?: number; cursor?: string; seriesId?: string; genre?: string; status?: 'uploading' | 'processing' | 'ready' | 'failed' | 'duplicate'; language?: string } = {}): Promise<VideoPage> {
+	return request('GET', '/api/videos', { sort: params.sort, limit: params.limit, cursor: params.cursor, seriesId: params.seriesId, genre: params.genre, status: params.status, language: params.language }, {});
+}
+
+export function getVideo(params: { videoId: string }): Promise<VideoDetail> {
+	return request('GET', `/api/videos/${encodeURIComponent(params.videoId)}`, {}, {});
+}
+
+export function getProfile(): Promise<Profile> {
+	return request('GET', '/auth/profile', {}, {});
+}
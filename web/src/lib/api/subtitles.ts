@@ -1,23 +1,13 @@
-export type Subtitle = {
-    id: string;
-    videoName: string;
-    startMs: number;
-    endMs: number;
-    text: string;
-}
+import { searchSubtitles as searchSubtitlesRequest, type Subtitle } from './client';
+
+export type { Subtitle };
 
 async function searchSubtitles(query: string) {
     if (!query) {
         return [];
     }
 
-    const response = await fetch(`/api/subtitles/search?query=${query}`, {
-        method: 'GET'
-    });
-
-    const data: Subtitle[] = await response.json();
-
-    return data;
+    return searchSubtitlesRequest({ query });
 }
 
-export { searchSubtitles };
\ No newline at end of file
+export { searchSubtitles };
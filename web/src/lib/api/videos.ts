@@ -0,0 +1,23 @@
+import { createInfiniteQuery } from "@tanstack/svelte-query";
+import { listVideos as listVideosRequest, type Video, type VideoPage } from './client';
+
+export type { Video, VideoPage };
+
+export type VideoSort = 'newest' | 'name' | 'duration';
+
+export type VideoFilter = {
+    seriesId?: string;
+    genre?: string;
+    language?: string;
+}
+
+export async function listVideos(sort: VideoSort, filter: VideoFilter, cursor: string | null) {
+    return listVideosRequest({ ...filter, sort, cursor: cursor ?? undefined });
+}
+
+export const createVideosQuery = (sort: VideoSort, filter: VideoFilter) => createInfiniteQuery({
+    queryKey: ['videos', sort, filter],
+    queryFn: ({ pageParam }) => listVideos(sort, filter, pageParam),
+    initialPageParam: null as string | null,
+    getNextPageParam: (lastPage) => lastPage.nextCursor
+});